@@ -9,11 +9,13 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
 	"github.com/iyhunko/microservices-with-sqs/internal/config"
 	httpAPI "github.com/iyhunko/microservices-with-sqs/internal/http"
 	"github.com/iyhunko/microservices-with-sqs/internal/http/controller"
 	"github.com/iyhunko/microservices-with-sqs/internal/logger"
 	"github.com/iyhunko/microservices-with-sqs/internal/metrics"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
 	"github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
 	"github.com/iyhunko/microservices-with-sqs/internal/service"
 	sqspkg "github.com/iyhunko/microservices-with-sqs/internal/sqs"
@@ -34,20 +36,51 @@ func main() {
 	userRepository := sql.NewUserRepository(db)
 	productRepository := sql.NewProductRepository(db)
 	eventRepository := sql.NewEventRepository(db)
+	idempotencyKeyRepository := sql.NewIdempotencyKeyRepository(db)
+	categoryRepository := sql.NewCategoryRepository(db)
+	inventoryRepository := sql.NewInventoryRepository(db)
+	auditLogRepository := sql.NewAuditLogRepository(db)
+	apiKeyRepository := sql.NewAPIKeyRepository(db)
 
 	// Initialize AWS SQS client (required for product service)
 	sqsClient, err := sqspkg.NewClient(ctx, conf.AWS.Region, conf.AWS.Endpoint)
 	handleErr("creating SQS client", err)
 
-	sqsPublisher := sqspkg.NewPublisher(sqsClient, conf.AWS.SQSQueueURL)
+	sqsPublisher := sqspkg.NewPublisher(sqsClient, conf.AWS.SQSQueueURL, conf.AWS.SQSFIFOQueue)
 
 	// Create services
-	productService := service.NewProductService(db, productRepository, eventRepository, sqsPublisher)
+	productService := service.NewProductService(db, productRepository, eventRepository, sqsPublisher, conf.IdempotencyKeys.Retention)
+	categoryService := service.NewCategoryService(db, categoryRepository)
+	inventoryService := service.NewInventoryService(db, inventoryRepository, conf.Inventory.LowStockThreshold)
+	auditService := service.NewAuditService(auditLogRepository)
+	eventService := service.NewEventService(db, eventRepository)
+	tokenSigner := auth.NewTokenSigner(conf.Auth.JWTSecret, conf.Auth.TokenTTL)
+	userService := service.NewUserService(db, userRepository, tokenSigner)
+
+	// Accept the tokens issued on login and, when configured, RS256 tokens signed by the keys of a JWKS file
+	var tokenVerifier auth.Verifier = tokenSigner
+	if conf.Auth.JWKSFile != "" {
+		keySet, err := auth.LoadJWKS(conf.Auth.JWKSFile, conf.Auth.Issuer, conf.Auth.Audience)
+		handleErr("loading JWKS", err)
+		tokenVerifier = auth.Verifiers{tokenSigner, keySet}
+	}
+
+	policy, err := auth.LoadPolicy(conf.Auth.PolicyFile)
+	handleErr("loading access control policy", err)
+	apiKeyService := service.NewAPIKeyService(db, apiKeyRepository, policy)
+	accessControl := httpAPI.AccessControl{Verifier: tokenVerifier, APIKeys: apiKeyService, Policy: policy, Users: userService}
 
 	// Start HTTP server
-	productCtr := controller.NewProductController(productService)
+	pageTokenSigner := repository.NewPageTokenSigner(conf.Pagination.TokenSecret, conf.Pagination.TokenTTL)
+	productCtr := controller.NewProductController(productService, pageTokenSigner)
+	categoryCtr := controller.NewCategoryController(categoryService, pageTokenSigner)
+	inventoryCtr := controller.NewInventoryController(inventoryService)
+	auditCtr := controller.NewAuditController(auditService, pageTokenSigner)
+	userCtr := controller.NewUserController(userService)
+	apiKeyCtr := controller.NewAPIKeyController(apiKeyService)
+	eventCtr := controller.NewEventController(eventService, pageTokenSigner)
 	httpServer := gin.Default()
-	httpServer = httpAPI.InitRouter(conf, userRepository, httpServer, productCtr)
+	httpServer = httpAPI.InitRouter(conf, accessControl, userCtr, httpServer, productCtr, categoryCtr, inventoryCtr, auditCtr, apiKeyCtr, eventCtr)
 
 	go func() {
 		err = httpServer.Run(":" + conf.HTTPServer.Port)
@@ -60,16 +93,29 @@ func main() {
 	metrics.StartMetricsServer(conf)
 
 	// Start event worker (outbox pattern)
-	eventWorker := service.NewEventWorker(eventRepository, sqsPublisher, 2*time.Second)
+	eventRetry := service.EventRetryPolicy{
+		MaxAttempts: int(conf.Events.MaxAttempts),
+		BaseDelay:   conf.Events.RetryBaseDelay,
+		MaxDelay:    conf.Events.RetryMaxDelay,
+	}
+	eventWorker := service.NewEventWorker(eventRepository, sqsPublisher, 2*time.Second, conf.Events.LeaseDuration, eventRetry)
 	workerCtx, workerCancel := context.WithCancel(ctx)
 	defer workerCancel()
 	go eventWorker.Start(workerCtx)
 
+	// Start purge worker for soft-deleted products
+	purgeWorker := service.NewProductPurgeWorker(productRepository, conf.ProductPurge.Retention, conf.ProductPurge.Interval)
+	go purgeWorker.Start(workerCtx)
+
+	// Start cleanup worker for expired idempotency keys
+	idempotencyWorker := service.NewIdempotencyKeyCleanupWorker(idempotencyKeyRepository, conf.IdempotencyKeys.Retention, conf.IdempotencyKeys.Interval)
+	go idempotencyWorker.Start(workerCtx)
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 	slog.Info("Shutting down gracefully...")
-	workerCancel() // Stop the event worker
+	workerCancel() // Stop the background workers
 }
 
 func handleErr(msg string, err error) {
@@ -0,0 +1,123 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
+	"github.com/iyhunko/microservices-with-sqs/internal/config"
+	httpAPI "github.com/iyhunko/microservices-with-sqs/internal/http"
+	"github.com/iyhunko/microservices-with-sqs/internal/http/controller"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAPI_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	tokens := auth.NewTokenSigner("test-jwt-secret", time.Hour)
+	policy, err := auth.LoadPolicy("../rbac_policy.json")
+	require.NoError(t, err)
+	userService := service.NewUserService(testDB.DB, reposql.NewUserRepository(testDB.DB), tokens)
+	apiKeyService := service.NewAPIKeyService(testDB.DB, reposql.NewAPIKeyRepository(testDB.DB), policy)
+	productService := service.NewProductService(testDB.DB, reposql.NewProductRepository(testDB.DB), reposql.NewEventRepository(testDB.DB), nil, config.DefaultIdempotencyKeyTTL)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	access := httpAPI.AccessControl{Verifier: tokens, APIKeys: apiKeyService, Policy: policy, Users: userService}
+	httpAPI.InitRouter(&config.Config{}, access, controller.NewUserController(userService), router,
+		controller.NewProductController(productService, NewTestPageTokenSigner()), nil, nil, nil, controller.NewAPIKeyController(apiKeyService), nil)
+
+	// do sends a request authenticated with a bearer token, or with an API key if the credential starts with "sk_"
+	do := func(t *testing.T, method, target, credential string, payload interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(credential, "sk_"):
+			req.Header.Set("X-API-Key", credential)
+		case credential != "":
+			req.Header.Set("Authorization", "Bearer "+credential)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	t.Run("create, use, list and revoke an API key", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w, alice := do(t, http.MethodPost, "/users/register", "", map[string]interface{}{"email": "alice@example.com", "password": "correct horse", "name": "Alice"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		w, session := do(t, http.MethodPost, "/auth/login", "", map[string]interface{}{"email": "alice@example.com", "password": "correct horse"})
+		require.Equal(t, http.StatusOK, w.Code)
+		token := session["access_token"].(string)
+
+		// Viewers may not create keys
+		w, _ = do(t, http.MethodPost, "/api-keys", token, map[string]interface{}{"name": "import", "scopes": []string{"products:write"}})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		_, err := testDB.DB.Exec("UPDATE users SET role = 'editor' WHERE email = 'alice@example.com'")
+		require.NoError(t, err)
+
+		// Keys cannot have more permissions than their owner
+		w, _ = do(t, http.MethodPost, "/api-keys", token, map[string]interface{}{"name": "import", "scopes": []string{"audit:read"}})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		w, _ = do(t, http.MethodPost, "/api-keys", token, map[string]interface{}{"name": "import", "scopes": []string{}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, created := do(t, http.MethodPost, "/api-keys", token, map[string]interface{}{"name": "import", "scopes": []string{"products:write"}})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		key := created["key"].(string)
+		assert.True(t, strings.HasPrefix(key, created["key_prefix"].(string)))
+		assert.Equal(t, []interface{}{"products:write"}, created["scopes"])
+		assert.NotContains(t, created, "key_hash")
+
+		var stored string
+		require.NoError(t, testDB.DB.QueryRow("SELECT key_hash FROM api_keys WHERE id = $1", created["id"]).Scan(&stored))
+		assert.NotEqual(t, key, stored)
+
+		// The key acts on behalf of its owner, within its scopes
+		w, product := do(t, http.MethodPost, "/products", key, map[string]interface{}{"name": "Laptop", "price": 999.99})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var actor string
+		require.NoError(t, testDB.DB.QueryRow("SELECT actor FROM audit_log WHERE resource_type = 'product' AND resource_id = $1", product["id"]).Scan(&actor))
+		assert.Equal(t, alice["id"], actor)
+
+		w, _ = do(t, http.MethodPost, "/products/"+product["id"].(string)+"/inventory/adjust", key, map[string]interface{}{"delta": 1})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = do(t, http.MethodPost, "/api-keys", key, map[string]interface{}{"name": "another", "scopes": []string{"products:write"}})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w, listed := do(t, http.MethodGet, "/api-keys", token, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		keys := listed["api_keys"].([]interface{})
+		require.Len(t, keys, 1)
+		assert.NotContains(t, keys[0], "key")
+		assert.NotNil(t, keys[0].(map[string]interface{})["last_used_at"])
+
+		w, _ = do(t, http.MethodDelete, "/api-keys/"+created["id"].(string), token, nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		w, _ = do(t, http.MethodDelete, "/api-keys/"+created["id"].(string), token, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w, _ = do(t, http.MethodPost, "/products", key, map[string]interface{}{"name": "Phone", "price": 499.99})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w, _ = do(t, http.MethodPost, "/products", "sk_unknown", map[string]interface{}{"name": "Phone", "price": 499.99})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,132 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iyhunko/microservices-with-sqs/internal/config"
+	httpAPI "github.com/iyhunko/microservices-with-sqs/internal/http"
+	"github.com/iyhunko/microservices-with-sqs/internal/http/controller"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditAPI_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	productRepo := reposql.NewProductRepository(testDB.DB)
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
+	auditService := service.NewAuditService(reposql.NewAuditLogRepository(testDB.DB))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
+	auditCtr := controller.NewAuditController(auditService, NewTestPageTokenSigner())
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, auditCtr, nil, nil)
+
+	do := func(t *testing.T, method, target, requestID string, payload interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		var body bytes.Buffer
+		if payload != nil {
+			require.NoError(t, json.NewEncoder(&body).Encode(payload))
+		}
+		req := httptest.NewRequest(method, target, &body)
+		req.Header.Set("Content-Type", "application/json")
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	listAudit := func(t *testing.T, target string) controller.ListAuditLogResponse {
+		t.Helper()
+		w := do(t, http.MethodGet, target, "", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response controller.ListAuditLogResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("records product changes newest first", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w := do(t, http.MethodPost, "/products", "req-create", map[string]interface{}{"name": "Laptop", "price": 999.0})
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "req-create", w.Header().Get("X-Request-ID"))
+		var created map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		productID := created["id"].(string)
+
+		w = do(t, http.MethodPatch, "/products/"+productID, "req-update", map[string]interface{}{"price": 899.0})
+		require.Equal(t, http.StatusOK, w.Code)
+		w = do(t, http.MethodDelete, "/products/"+productID, "req-delete", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		response := listAudit(t, "/audit?resource_type=product&resource_id="+productID)
+		require.Len(t, response.Entries, 3)
+		assert.False(t, response.HasMore)
+
+		deleted, updated, createdEntry := response.Entries[0], response.Entries[1], response.Entries[2]
+		assert.Equal(t, "delete", deleted.Action)
+		assert.Equal(t, "req-delete", deleted.RequestID)
+		assert.NotEmpty(t, deleted.Before)
+		assert.Empty(t, deleted.After)
+
+		assert.Equal(t, "update", updated.Action)
+		assert.Equal(t, TestUser.ID.String(), updated.Actor)
+		assert.Equal(t, productID, updated.ResourceID)
+		assert.Equal(t, "req-update", updated.RequestID)
+		assert.NotEmpty(t, updated.ClientIP)
+		var before, after map[string]interface{}
+		require.NoError(t, json.Unmarshal(updated.Before, &before))
+		require.NoError(t, json.Unmarshal(updated.After, &after))
+		assert.Equal(t, map[string]interface{}{"amount": "999.00", "currency": "USD"}, before["price"])
+		assert.Equal(t, map[string]interface{}{"amount": "899.00", "currency": "USD"}, after["price"])
+
+		assert.Equal(t, "create", createdEntry.Action)
+		assert.Equal(t, "req-create", createdEntry.RequestID)
+		assert.Empty(t, createdEntry.Before)
+		assert.NotEmpty(t, createdEntry.After)
+	})
+
+	t.Run("filters by action and paginates", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		for _, name := range []string{"Keyboard", "Mouse", "Monitor"} {
+			w := do(t, http.MethodPost, "/products", "", map[string]interface{}{"name": name, "price": 10.0})
+			require.Equal(t, http.StatusCreated, w.Code)
+		}
+
+		first := listAudit(t, "/audit?action=create&limit=2")
+		require.Len(t, first.Entries, 2)
+		assert.True(t, first.HasMore)
+		require.NotEmpty(t, first.NextPageToken)
+
+		second := listAudit(t, "/audit?action=create&limit=2&token="+first.NextPageToken)
+		require.Len(t, second.Entries, 1)
+		assert.False(t, second.HasMore)
+		assert.NotEmpty(t, second.PrevPageToken)
+
+		assert.Empty(t, listAudit(t, "/audit?action=delete").Entries)
+	})
+
+	t.Run("rejects invalid filters", func(t *testing.T) {
+		w := do(t, http.MethodGet, "/audit?resource_id=not-a-uuid", "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = do(t, http.MethodGet, "/audit?created_after=2025-02-01T00:00:00Z&created_before=2025-01-01T00:00:00Z", "", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
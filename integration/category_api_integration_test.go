@@ -0,0 +1,269 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iyhunko/microservices-with-sqs/internal/config"
+	httpAPI "github.com/iyhunko/microservices-with-sqs/internal/http"
+	"github.com/iyhunko/microservices-with-sqs/internal/http/controller"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	sqspkg "github.com/iyhunko/microservices-with-sqs/internal/sqs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryAPI_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	productRepo := reposql.NewProductRepository(testDB.DB)
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	categoryRepo := reposql.NewCategoryRepository(testDB.DB)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
+	categoryService := service.NewCategoryService(testDB.DB, categoryRepo)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
+	categoryCtr := controller.NewCategoryController(categoryService, NewTestPageTokenSigner())
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, categoryCtr, nil, nil, nil, nil)
+
+	doJSON := func(t *testing.T, method, target string, payload interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		var body bytes.Buffer
+		if payload != nil {
+			require.NoError(t, json.NewEncoder(&body).Encode(payload))
+		}
+		req := httptest.NewRequest(method, target, &body)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	createCategory := func(t *testing.T, name, parentID string) string {
+		t.Helper()
+		payload := map[string]interface{}{"name": name}
+		if parentID != "" {
+			payload["parent_id"] = parentID
+		}
+		w, response := doJSON(t, http.MethodPost, "/categories", payload)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		return response["id"].(string)
+	}
+
+	t.Run("create, get and list categories", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		electronics := createCategory(t, "Electronics", "")
+		laptops := createCategory(t, "Laptops", electronics)
+		createCategory(t, "Books", "")
+
+		w, response := doJSON(t, http.MethodGet, "/categories/"+laptops, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Laptops", response["name"])
+		assert.Equal(t, electronics, response["parent_id"])
+
+		w, response = doJSON(t, http.MethodGet, "/categories?parent_id=root", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		roots := response["categories"].([]interface{})
+		require.Len(t, roots, 2)
+		assert.Equal(t, "Electronics", roots[0].(map[string]interface{})["name"])
+		assert.Equal(t, "Books", roots[1].(map[string]interface{})["name"])
+
+		w, response = doJSON(t, http.MethodGet, "/categories?parent_id="+electronics, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		children := response["categories"].([]interface{})
+		require.Len(t, children, 1)
+		assert.Equal(t, laptops, children[0].(map[string]interface{})["id"])
+	})
+
+	t.Run("create category with unknown parent", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w, _ := doJSON(t, http.MethodPost, "/categories", map[string]interface{}{
+			"name":      "Orphan",
+			"parent_id": "00000000-0000-0000-0000-000000000001",
+		})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("changing categories requires authentication", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		electronics := createCategory(t, "Electronics", "")
+
+		for _, req := range []*http.Request{
+			httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(`{"name": "Anonymous"}`)),
+			httptest.NewRequest(http.MethodPut, "/categories/"+electronics, strings.NewReader(`{"name": "Anonymous"}`)),
+			httptest.NewRequest(http.MethodDelete, "/categories/"+electronics, nil),
+		} {
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer not-a-token")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, req.Method)
+		}
+
+		w, category := doJSON(t, http.MethodGet, "/categories/"+electronics, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Electronics", category["name"])
+	})
+
+	t.Run("move category below its own subcategory", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		electronics := createCategory(t, "Electronics", "")
+		laptops := createCategory(t, "Laptops", electronics)
+		gaming := createCategory(t, "Gaming", laptops)
+
+		w, _ := doJSON(t, http.MethodPut, "/categories/"+electronics, map[string]interface{}{
+			"name":      "Electronics",
+			"parent_id": gaming,
+		})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		// Moving a subcategory to the root is allowed
+		w, response := doJSON(t, http.MethodPut, "/categories/"+gaming, map[string]interface{}{"name": "Gaming"})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, response, "parent_id")
+	})
+
+	t.Run("concurrent moves cannot close a cycle", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		for round := 0; round < 10; round++ {
+			first := createCategory(t, fmt.Sprintf("First %d", round), "")
+			second := createCategory(t, fmt.Sprintf("Second %d", round), "")
+
+			// Move each category below the other at the same time: only one of the moves may succeed
+			var wg sync.WaitGroup
+			codes := make([]int, 2)
+			for i, move := range [][2]string{{first, second}, {second, first}} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					body, _ := json.Marshal(map[string]interface{}{"name": "Moved", "parent_id": move[1]})
+					req := httptest.NewRequest(http.MethodPut, "/categories/"+move[0], bytes.NewBuffer(body))
+					req.Header.Set("Content-Type", "application/json")
+					w := httptest.NewRecorder()
+					router.ServeHTTP(w, req)
+					codes[i] = w.Code
+				}()
+			}
+			wg.Wait()
+
+			assert.ElementsMatch(t, []int{http.StatusOK, http.StatusUnprocessableEntity}, codes, "round %d", round)
+		}
+	})
+
+	t.Run("delete category", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		electronics := createCategory(t, "Electronics", "")
+		laptops := createCategory(t, "Laptops", electronics)
+
+		// A category with subcategories is kept
+		w, _ := doJSON(t, http.MethodDelete, "/categories/"+electronics, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// A category with products is kept
+		w, _ = doJSON(t, http.MethodPost, "/products", map[string]interface{}{
+			"name":        "Laptop",
+			"price":       999.0,
+			"category_id": laptops,
+		})
+		require.Equal(t, http.StatusCreated, w.Code)
+		w, _ = doJSON(t, http.MethodDelete, "/categories/"+laptops, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		books := createCategory(t, "Books", "")
+		w, _ = doJSON(t, http.MethodDelete, "/categories/"+books, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = doJSON(t, http.MethodGet, "/categories/"+books, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("products with category and tags", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		electronics := createCategory(t, "Electronics", "")
+		laptops := createCategory(t, "Laptops", electronics)
+		books := createCategory(t, "Books", "")
+
+		createProduct := func(name, categoryID string, tags []string) string {
+			w, response := doJSON(t, http.MethodPost, "/products", map[string]interface{}{
+				"name":        name,
+				"price":       10.0,
+				"category_id": categoryID,
+				"tags":        tags,
+			})
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			return response["id"].(string)
+		}
+		laptop := createProduct("Laptop", laptops, []string{"Sale", "new", "sale"})
+		createProduct("Charger", electronics, []string{"accessories"})
+		createProduct("Novel", books, []string{"sale"})
+
+		w, response := doJSON(t, http.MethodGet, "/products/"+laptop, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, laptops, response["category_id"])
+		assert.Equal(t, []interface{}{"new", "sale"}, response["tags"])
+
+		listNames := func(target string) []string {
+			w, response := doJSON(t, http.MethodGet, target, nil)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var names []string
+			for _, product := range response["products"].([]interface{}) {
+				names = append(names, product.(map[string]interface{})["name"].(string))
+			}
+			return names
+		}
+
+		// The category filter includes products of subcategories
+		assert.ElementsMatch(t, []string{"Laptop", "Charger"}, listNames("/products?category_id="+electronics))
+		assert.ElementsMatch(t, []string{"Laptop"}, listNames("/products?category_id="+laptops))
+		assert.ElementsMatch(t, []string{"Laptop", "Novel"}, listNames("/products?tag=SALE"))
+		assert.ElementsMatch(t, []string{"Laptop"}, listNames(fmt.Sprintf("/products?category_id=%s&tag=sale", electronics)))
+
+		// The product.created event carries the category
+		var eventData []byte
+		err := testDB.DB.QueryRow(
+			"SELECT event_data FROM events WHERE event_type = 'product.created' AND event_data->>'product_id' = $1", laptop).Scan(&eventData)
+		require.NoError(t, err)
+		var msg sqspkg.ProductMessage
+		require.NoError(t, json.Unmarshal(eventData, &msg))
+		require.NotNil(t, msg.Category)
+		assert.Equal(t, laptops, msg.Category.ID)
+		assert.Equal(t, "Laptops", msg.Category.Name)
+
+		// Tags and category can be changed and cleared
+		w, response = doJSON(t, http.MethodPatch, "/products/"+laptop, map[string]interface{}{
+			"category_id": nil,
+			"tags":        []string{"clearance"},
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.NotContains(t, response, "category_id")
+		assert.Equal(t, []interface{}{"clearance"}, response["tags"])
+		assert.ElementsMatch(t, []string{"Novel"}, listNames("/products?tag=sale"))
+	})
+
+	t.Run("create product with unknown category", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w, _ := doJSON(t, http.MethodPost, "/products", map[string]interface{}{
+			"name":        "Laptop",
+			"price":       10.0,
+			"category_id": "00000000-0000-0000-0000-000000000001",
+		})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
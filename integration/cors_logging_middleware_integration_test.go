@@ -26,13 +26,13 @@ func TestCORSMiddleware_Integration(t *testing.T) {
 		// Set up repositories and services
 		productRepo := reposql.NewProductRepository(testDB.DB)
 		eventRepo := reposql.NewEventRepository(testDB.DB)
-		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
 
 		// Set up HTTP router with CORS middleware
 		router := gin.New()
-		productCtr := controller.NewProductController(productService)
+		productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
 		cfg := &config.Config{}
-		httpAPI.InitRouter(cfg, nil, router, productCtr)
+		httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
 
 		// Make a GET request to list products
 		req := httptest.NewRequest(http.MethodGet, "/products", nil)
@@ -43,7 +43,7 @@ func TestCORSMiddleware_Integration(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
 		assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
-		assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "Content-Type, Authorization, X-API-Key", w.Header().Get("Access-Control-Allow-Headers"))
 		assert.Equal(t, "86400", w.Header().Get("Access-Control-Max-Age"))
 	})
 
@@ -55,13 +55,13 @@ func TestCORSMiddleware_Integration(t *testing.T) {
 		// Set up repositories and services
 		productRepo := reposql.NewProductRepository(testDB.DB)
 		eventRepo := reposql.NewEventRepository(testDB.DB)
-		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
 
 		// Set up HTTP router with CORS middleware
 		router := gin.New()
-		productCtr := controller.NewProductController(productService)
+		productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
 		cfg := &config.Config{}
-		httpAPI.InitRouter(cfg, nil, router, productCtr)
+		httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
 
 		// Make an OPTIONS preflight request
 		req := httptest.NewRequest(http.MethodOptions, "/products", nil)
@@ -85,13 +85,13 @@ func TestCORSMiddleware_Integration(t *testing.T) {
 		// Set up repositories and services
 		productRepo := reposql.NewProductRepository(testDB.DB)
 		eventRepo := reposql.NewEventRepository(testDB.DB)
-		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
 
 		// Set up HTTP router with CORS middleware
 		router := gin.New()
-		productCtr := controller.NewProductController(productService)
+		productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
 		cfg := &config.Config{}
-		httpAPI.InitRouter(cfg, nil, router, productCtr)
+		httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
 
 		// Make a POST request to create a product
 		body := `{"name":"Test Product","description":"A test product","price":99.99}`
@@ -117,13 +117,13 @@ func TestLoggingMiddleware_Integration(t *testing.T) {
 		// Set up repositories and services
 		productRepo := reposql.NewProductRepository(testDB.DB)
 		eventRepo := reposql.NewEventRepository(testDB.DB)
-		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
 
 		// Set up HTTP router with Logger middleware
 		router := gin.New()
-		productCtr := controller.NewProductController(productService)
+		productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
 		cfg := &config.Config{}
-		httpAPI.InitRouter(cfg, nil, router, productCtr)
+		httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
 
 		// Make a GET request (logging happens in background)
 		req := httptest.NewRequest(http.MethodGet, "/products", nil)
@@ -143,13 +143,13 @@ func TestLoggingMiddleware_Integration(t *testing.T) {
 		// Set up repositories and services
 		productRepo := reposql.NewProductRepository(testDB.DB)
 		eventRepo := reposql.NewEventRepository(testDB.DB)
-		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
 
 		// Set up HTTP router with Logger middleware
 		router := gin.New()
-		productCtr := controller.NewProductController(productService)
+		productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
 		cfg := &config.Config{}
-		httpAPI.InitRouter(cfg, nil, router, productCtr)
+		httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
 
 		// Make a POST request to create a product
 		body := `{"name":"Test Product","description":"A test product","price":99.99}`
@@ -170,13 +170,13 @@ func TestLoggingMiddleware_Integration(t *testing.T) {
 		// Set up repositories and services
 		productRepo := reposql.NewProductRepository(testDB.DB)
 		eventRepo := reposql.NewEventRepository(testDB.DB)
-		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
 
 		// Set up HTTP router with Logger middleware
 		router := gin.New()
-		productCtr := controller.NewProductController(productService)
+		productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
 		cfg := &config.Config{}
-		httpAPI.InitRouter(cfg, nil, router, productCtr)
+		httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
 
 		// Make a request with invalid data to trigger an error
 		body := `{"invalid":"data"}`
@@ -0,0 +1,147 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iyhunko/microservices-with-sqs/internal/config"
+	httpAPI "github.com/iyhunko/microservices-with-sqs/internal/http"
+	"github.com/iyhunko/microservices-with-sqs/internal/http/controller"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventAPI_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	ctx := context.Background()
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	eventService := service.NewEventService(testDB.DB, eventRepo)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	eventCtr := controller.NewEventController(eventService, NewTestPageTokenSigner())
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, nil, nil, nil, nil, nil, eventCtr)
+
+	do := func(t *testing.T, method, target string, payload interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		var body bytes.Buffer
+		if payload != nil {
+			require.NoError(t, json.NewEncoder(&body).Encode(payload))
+		}
+		req := httptest.NewRequest(method, target, &body)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	// seed stores a processed, a dead and a pending event
+	seed := func(t *testing.T) (processed, dead, pending *model.Event) {
+		t.Helper()
+		testDB.TruncateTables(t)
+		processed = &model.Event{EventType: "product.created", EventData: []byte(`{"product_id":"1"}`)}
+		dead = &model.Event{EventType: "product.updated", EventData: []byte(`{"product_id":"2"}`)}
+		pending = &model.Event{EventType: "product.created", EventData: []byte(`{"product_id":"3"}`)}
+		require.NoError(t, eventRepo.CreateBatch(ctx, []*model.Event{processed, dead, pending}))
+		_, err := testDB.DB.Exec(`UPDATE events SET locked_by = 'seed' WHERE id IN ($1, $2)`, processed.ID, dead.ID)
+		require.NoError(t, err)
+		require.NoError(t, eventRepo.UpdateStatus(ctx, processed.ID, "seed", model.EventStatusProcessed))
+
+		lastError := "sqs: service unavailable"
+		dead.Status = model.EventStatusDead
+		dead.Attempts = 10
+		dead.LastError = &lastError
+		require.NoError(t, eventRepo.RecordFailure(ctx, dead, "seed"))
+		return processed, dead, pending
+	}
+
+	t.Run("list and get events", func(t *testing.T) {
+		_, dead, _ := seed(t)
+
+		w, response := do(t, http.MethodGet, "/admin/events", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, response["events"], 3)
+
+		w, response = do(t, http.MethodGet, "/admin/events?status=dead", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		events := response["events"].([]interface{})
+		require.Len(t, events, 1)
+		assert.Equal(t, dead.ID.String(), events[0].(map[string]interface{})["id"])
+
+		w, response = do(t, http.MethodGet, "/admin/events?event_type=product.created&limit=1", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, response["events"], 1)
+		assert.Equal(t, true, response["has_more"])
+		w, response = do(t, http.MethodGet, "/admin/events?event_type=product.created&limit=1&token="+response["next_page_token"].(string), nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, response["events"], 1)
+		assert.Equal(t, false, response["has_more"])
+
+		w, _ = do(t, http.MethodGet, "/admin/events?status=failed", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, event := do(t, http.MethodGet, "/admin/events/"+dead.ID.String(), nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "dead", event["status"])
+		assert.Equal(t, float64(10), event["attempts"])
+		assert.Equal(t, "sqs: service unavailable", event["last_error"])
+		assert.Equal(t, map[string]interface{}{"product_id": "2"}, event["event_data"])
+
+		w, _ = do(t, http.MethodGet, "/admin/events/00000000-0000-0000-0000-000000000001", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("requeue a dead event", func(t *testing.T) {
+		processed, dead, _ := seed(t)
+
+		w, event := do(t, http.MethodPost, "/admin/events/"+dead.ID.String()+"/requeue", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "pending", event["status"])
+		assert.Equal(t, float64(0), event["attempts"])
+
+		claimed, err := eventRepo.ClaimPending(ctx, "worker", time.Now(), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Len(t, claimed, 2)
+
+		var actor, action string
+		require.NoError(t, testDB.DB.QueryRow("SELECT actor, action FROM audit_log WHERE resource_type = 'event' AND resource_id = $1", dead.ID).
+			Scan(&actor, &action))
+		assert.Equal(t, TestUser.ID.String(), actor)
+		assert.Equal(t, "requeue", action)
+
+		w, _ = do(t, http.MethodPost, "/admin/events/"+processed.ID.String()+"/requeue", nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("requeue several events all or nothing", func(t *testing.T) {
+		processed, dead, _ := seed(t)
+
+		w, _ := do(t, http.MethodPost, "/admin/events/requeue", map[string]interface{}{"ids": []string{dead.ID.String(), processed.ID.String()}})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		found, err := eventRepo.FindByID(ctx, dead.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.EventStatusDead, found.(*model.Event).Status)
+
+		w, _ = do(t, http.MethodPost, "/admin/events/requeue", map[string]interface{}{"ids": []string{"not-a-uuid"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, response := do(t, http.MethodPost, "/admin/events/requeue", map[string]interface{}{"ids": []string{dead.ID.String()}})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, response["events"], 1)
+	})
+}
@@ -0,0 +1,77 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventWorker_AggregateOrdering_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	ctx := context.Background()
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	testDB.TruncateTables(t)
+
+	// Interleave the events of a few aggregates, as concurrent requests would
+	aggregates := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for i := 0; i < 20; i++ {
+		for _, aggregateID := range aggregates {
+			event := &model.Event{EventType: fmt.Sprintf("product.updated.%d", i), EventData: []byte(`{}`), AggregateID: aggregateID}
+			require.NoError(t, eventRepo.WithinTransaction(ctx, func(repo repository.Repository) error {
+				_, err := repo.Create(ctx, event)
+				return err
+			}))
+		}
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	retry := service.EventRetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.NewEventWorker(eventRepo, nil, 10*time.Millisecond, time.Minute, retry).Start(workerCtx)
+		}()
+	}
+
+	require.Eventually(t, func() bool {
+		var pending int
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM events WHERE status = 'pending'").Scan(&pending))
+		return pending == 0
+	}, 30*time.Second, 50*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	// Within every aggregate, the events were processed in sequence order
+	for _, aggregateID := range aggregates {
+		rows, err := testDB.DB.Query("SELECT event_type, processed_at FROM events WHERE aggregate_id = $1 ORDER BY sequence", aggregateID)
+		require.NoError(t, err)
+		var previous time.Time
+		i := 0
+		for rows.Next() {
+			var eventType string
+			var processedAt time.Time
+			require.NoError(t, rows.Scan(&eventType, &processedAt))
+			assert.Equal(t, fmt.Sprintf("product.updated.%d", i), eventType)
+			assert.True(t, processedAt.After(previous), "%s processed before the previous event", eventType)
+			previous = processedAt
+			i++
+		}
+		require.NoError(t, rows.Err())
+		rows.Close()
+		assert.Equal(t, 20, i)
+	}
+}
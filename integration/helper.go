@@ -11,14 +11,67 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
+	httpAPI "github.com/iyhunko/microservices-with-sqs/internal/http"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
 	_ "github.com/lib/pq"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 )
 
+// NewTestPageTokenSigner creates a page token signer with a fixed secret for API tests.
+func NewTestPageTokenSigner() *repository.PageTokenSigner {
+	return repository.NewPageTokenSigner("integration-test-secret", time.Hour)
+}
+
+// TestUser is the admin on whose behalf AuthenticateRequests sends requests. AuthenticateRequests stores it in the
+// database and TruncateTables keeps it there, since tokens of users that do not exist are rejected.
+var TestUser = &model.User{
+	ID:     uuid.MustParse("00000000-0000-0000-0000-0000000000aa"),
+	Email:  "tester@example.com",
+	Name:   "Tester",
+	Status: model.UserStatusActive,
+	Role:   model.UserRoleAdmin,
+}
+
+// AuthenticateRequests adds an access token of TestUser to every request of the router without an Authorization
+// header and returns the access control to pass to InitRouter, with the default policy file. It must be called
+// before InitRouter so that it runs before the authentication middleware.
+func AuthenticateRequests(t *testing.T, tdb *TestDB, router *gin.Engine) httpAPI.AccessControl {
+	t.Helper()
+
+	tokens := auth.NewTokenSigner("integration-test-jwt-secret", time.Hour)
+	token, _, err := tokens.Issue(TestUser)
+	if err != nil {
+		t.Fatalf("Could not issue access token: %s", err)
+	}
+	policy, err := auth.LoadPolicy("../rbac_policy.json")
+	if err != nil {
+		t.Fatalf("Could not load access control policy: %s", err)
+	}
+	tdb.storeTestUser(t)
+
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+	})
+	return httpAPI.AccessControl{
+		Verifier: tokens,
+		APIKeys:  service.NewAPIKeyService(tdb.DB, reposql.NewAPIKeyRepository(tdb.DB), policy),
+		Policy:   policy,
+		Users:    service.NewUserService(tdb.DB, reposql.NewUserRepository(tdb.DB), tokens),
+	}
+}
+
 // TestDB holds the test database connection and cleanup function.
 type TestDB struct {
 	DB       *sql.DB
@@ -134,7 +187,7 @@ func (tdb *TestDB) TruncateTables(t *testing.T) {
 	t.Helper()
 
 	ctx := context.Background()
-	tables := []string{"events", "products", "users"}
+	tables := []string{"audit_log", "idempotency_keys", "events", "inventory", "product_price_history", "product_tags", "products", "categories", "api_keys", "users"}
 
 	for _, table := range tables {
 		_, err := tdb.DB.ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s CASCADE", table))
@@ -142,4 +195,18 @@ func (tdb *TestDB) TruncateTables(t *testing.T) {
 			t.Fatalf("Could not truncate table %s: %s", table, err)
 		}
 	}
+	tdb.storeTestUser(t)
+}
+
+// storeTestUser stores TestUser unless it already exists.
+func (tdb *TestDB) storeTestUser(t *testing.T) {
+	t.Helper()
+
+	_, err := tdb.DB.ExecContext(context.Background(),
+		`INSERT INTO users (id, email, password, name, region, status, role) VALUES ($1, $2, '', $3, '', $4, $5)
+		 ON CONFLICT (id) DO NOTHING`,
+		TestUser.ID, TestUser.Email, TestUser.Name, TestUser.Status, TestUser.Role)
+	if err != nil {
+		t.Fatalf("Could not store test user: %s", err)
+	}
 }
@@ -0,0 +1,179 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iyhunko/microservices-with-sqs/internal/config"
+	httpAPI "github.com/iyhunko/microservices-with-sqs/internal/http"
+	"github.com/iyhunko/microservices-with-sqs/internal/http/controller"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventoryAPI_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	productRepo := reposql.NewProductRepository(testDB.DB)
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
+	inventoryService := service.NewInventoryService(testDB.DB, reposql.NewInventoryRepository(testDB.DB), 5)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
+	inventoryCtr := controller.NewInventoryController(inventoryService)
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, inventoryCtr, nil, nil, nil)
+
+	doJSON := func(t *testing.T, method, target string, payload interface{}) (int, controller.InventoryResponse) {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response controller.InventoryResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	createProduct := func(t *testing.T) string {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{"name": "Laptop", "price": 999.0})
+		req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response["id"].(string)
+	}
+
+	countEvents := func(t *testing.T, eventType string) int {
+		t.Helper()
+		var count int
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM events WHERE event_type = $1", eventType).Scan(&count))
+		return count
+	}
+
+	t.Run("new product has no stock", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		code, response := doJSON(t, http.MethodGet, "/products/"+productID+"/inventory", nil)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, productID, response.ProductID)
+		assert.Zero(t, response.QuantityOnHand)
+		assert.Zero(t, response.QuantityAvailable)
+	})
+
+	t.Run("adjust, reserve and release stock", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		code, response := doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/adjust", map[string]interface{}{"delta": 10})
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(10), response.QuantityOnHand)
+
+		code, response = doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/reserve", map[string]interface{}{"quantity": 4})
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(4), response.QuantityReserved)
+		assert.Equal(t, int64(6), response.QuantityAvailable)
+
+		// Reserved stock cannot be removed
+		code, _ = doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/adjust", map[string]interface{}{"delta": -7})
+		assert.Equal(t, http.StatusConflict, code)
+
+		code, _ = doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/release", map[string]interface{}{"quantity": 5})
+		assert.Equal(t, http.StatusConflict, code)
+
+		code, response = doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/release", map[string]interface{}{"quantity": 4})
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(10), response.QuantityAvailable)
+
+		assert.Equal(t, 3, countEvents(t, "inventory.adjusted"))
+		assert.Equal(t, 0, countEvents(t, "inventory.low_stock"))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		code, _ := doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/adjust", map[string]interface{}{"delta": 0})
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/reserve", map[string]interface{}{"quantity": -1})
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = doJSON(t, http.MethodPost, "/products/00000000-0000-0000-0000-000000000001/inventory/adjust", map[string]interface{}{"delta": 1})
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/adjust", map[string]interface{}{"delta": int64(math.MaxInt64)})
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("adjustments cannot overflow the stock on hand", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		code, _ := doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/adjust", map[string]interface{}{"delta": 1})
+		require.Equal(t, http.StatusOK, code)
+		_, err := testDB.DB.Exec("UPDATE inventory SET quantity_on_hand = $1 WHERE product_id = $2", int64(math.MaxInt64-10), productID)
+		require.NoError(t, err)
+
+		code, _ = doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/adjust", map[string]interface{}{"delta": 11})
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		var onHand int64
+		require.NoError(t, testDB.DB.QueryRow("SELECT quantity_on_hand FROM inventory WHERE product_id = $1", productID).Scan(&onHand))
+		assert.Equal(t, int64(math.MaxInt64-10), onHand)
+	})
+
+	t.Run("concurrent reservations do not oversell", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		code, _ := doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/adjust", map[string]interface{}{"delta": 10})
+		require.Equal(t, http.StatusOK, code)
+
+		const attempts = 25
+		codes := make(chan int, attempts)
+		var wg sync.WaitGroup
+		for range attempts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				code, _ := doJSON(t, http.MethodPost, "/products/"+productID+"/inventory/reserve", map[string]interface{}{"quantity": 1})
+				codes <- code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+
+		counts := map[int]int{}
+		for code := range codes {
+			counts[code]++
+		}
+		assert.Equal(t, 10, counts[http.StatusOK])
+		assert.Equal(t, attempts-10, counts[http.StatusConflict])
+
+		code, response := doJSON(t, http.MethodGet, "/products/"+productID+"/inventory", nil)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(10), response.QuantityReserved)
+		assert.Zero(t, response.QuantityAvailable)
+
+		// The available stock crossed the low stock threshold exactly once
+		assert.Equal(t, 11, countEvents(t, "inventory.adjusted"))
+		assert.Equal(t, 1, countEvents(t, "inventory.low_stock"))
+	})
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
 	sqspkg "github.com/iyhunko/microservices-with-sqs/internal/sqs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -46,7 +47,7 @@ func TestNotificationService_Integration(t *testing.T) {
 			Action:    "created",
 			ProductID: "123e4567-e89b-12d3-a456-426614174000",
 			Name:      "Test Product",
-			Price:     99.99,
+			Price:     model.Money{Amount: 9999, Currency: "USD"},
 		}
 		msgBody, err := json.Marshal(productMsg)
 		require.NoError(t, err)
@@ -108,7 +109,7 @@ func TestNotificationService_Integration(t *testing.T) {
 			Action:    "deleted",
 			ProductID: "123e4567-e89b-12d3-a456-426614174000",
 			Name:      "Deleted Product",
-			Price:     49.99,
+			Price:     model.Money{Amount: 4999, Currency: "USD"},
 		}
 		msgBody, err := json.Marshal(productMsg)
 		require.NoError(t, err)
@@ -217,7 +218,7 @@ func TestNotificationService_Integration(t *testing.T) {
 				Action:    "created",
 				ProductID: "123e4567-e89b-12d3-a456-42661417400" + string(rune('0'+i)),
 				Name:      "Product " + string(rune('A'+i)),
-				Price:     float64(10 * (i + 1)),
+				Price:     model.Money{Amount: int64(1000 * (i + 1)), Currency: "USD"},
 			}
 			msgBody, _ := json.Marshal(productMsg)
 			messageBody := string(msgBody)
@@ -2,19 +2,28 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
 	"github.com/iyhunko/microservices-with-sqs/internal/config"
 	httpAPI "github.com/iyhunko/microservices-with-sqs/internal/http"
 	"github.com/iyhunko/microservices-with-sqs/internal/http/controller"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
 	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
 	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	sqspkg "github.com/iyhunko/microservices-with-sqs/internal/sqs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -26,14 +35,14 @@ func TestProductAPI_CreateProduct_Integration(t *testing.T) {
 	// Set up repositories and services
 	productRepo := reposql.NewProductRepository(testDB.DB)
 	eventRepo := reposql.NewEventRepository(testDB.DB)
-	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
 
 	// Set up HTTP router
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	productCtr := controller.NewProductController(productService)
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
 	cfg := &config.Config{}
-	httpAPI.InitRouter(cfg, nil, router, productCtr)
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
 
 	t.Run("create product successfully", func(t *testing.T) {
 		testDB.TruncateTables(t)
@@ -60,7 +69,7 @@ func TestProductAPI_CreateProduct_Integration(t *testing.T) {
 		assert.NotEmpty(t, response["id"])
 		assert.Equal(t, "Test Laptop", response["name"])
 		assert.Equal(t, "High-performance laptop", response["description"])
-		assert.Equal(t, 1299.99, response["price"])
+		assert.Equal(t, map[string]interface{}{"amount": "1299.99", "currency": "USD"}, response["price"])
 		assert.NotEmpty(t, response["created_at"])
 		assert.NotEmpty(t, response["updated_at"])
 
@@ -73,6 +82,23 @@ func TestProductAPI_CreateProduct_Integration(t *testing.T) {
 		assert.NotNil(t, found)
 	})
 
+	t.Run("create product with an invalid access token", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		body, _ := json.Marshal(map[string]interface{}{"name": "Test Laptop", "price": 1299.99})
+		req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer not-a-token")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		var count int
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM products").Scan(&count))
+		assert.Zero(t, count)
+	})
+
 	t.Run("create product with invalid data", func(t *testing.T) {
 		testDB.TruncateTables(t)
 
@@ -108,6 +134,429 @@ func TestProductAPI_CreateProduct_Integration(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
+
+	t.Run("create product with money price", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		create := func(price interface{}) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(map[string]interface{}{"name": "Priced Product", "price": price})
+			req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := create(map[string]interface{}{"amount": "19.99", "currency": "EUR"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, map[string]interface{}{"amount": "19.99", "currency": "EUR"}, response["price"])
+
+		w = create(map[string]interface{}{"amount": "1500", "currency": "JPY"})
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		assert.Equal(t, http.StatusBadRequest, create(map[string]interface{}{"amount": "19.999", "currency": "USD"}).Code)
+		assert.Equal(t, http.StatusBadRequest, create(map[string]interface{}{"amount": "15.5", "currency": "JPY"}).Code)
+		assert.Equal(t, http.StatusBadRequest, create(map[string]interface{}{"amount": "10", "currency": "XYZ"}).Code)
+
+		// The largest amount the price column holds is accepted, anything larger is rejected
+		w = create(map[string]interface{}{"amount": "999999999999.999", "currency": "KWD"})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		w = create(map[string]interface{}{"amount": "1000000000000", "currency": "USD"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), model.ErrAmountTooLarge.Error())
+	})
+
+	t.Run("create product with idempotency key", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		create := func(key string, reqBody map[string]interface{}) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(reqBody)
+			req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		reqBody := map[string]interface{}{"name": "Keyboard", "description": "Mechanical", "price": 89.5}
+
+		first := create("create-keyboard", reqBody)
+		require.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+		// A retry with the same key and body returns the original response
+		replay := create("create-keyboard", reqBody)
+		require.Equal(t, http.StatusCreated, replay.Code)
+		assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+		assert.JSONEq(t, first.Body.String(), replay.Body.String())
+		assert.Equal(t, first.Header().Get("ETag"), replay.Header().Get("ETag"))
+
+		// The same key with a different body is rejected
+		reqBody["price"] = 99.5
+		mismatch := create("create-keyboard", reqBody)
+		assert.Equal(t, http.StatusUnprocessableEntity, mismatch.Code)
+
+		// Only one product and one outbox event were written
+		var products, events int
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM products").Scan(&products))
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM events").Scan(&events))
+		assert.Equal(t, 1, products)
+		assert.Equal(t, 1, events)
+
+		// Expired keys are cleaned up
+		deleted, err := reposql.NewIdempotencyKeyRepository(testDB.DB).DeleteExpired(context.Background(), time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+
+	t.Run("idempotency keys are scoped to the caller and expire", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		other := &model.User{ID: uuid.New(), Email: "other@example.com", Name: "Other", Status: model.UserStatusActive, Role: model.UserRoleAdmin}
+		_, err := testDB.DB.Exec(`INSERT INTO users (id, email, password, name, region, status, role) VALUES ($1, $2, '', $3, '', $4, $5)`,
+			other.ID, other.Email, other.Name, other.Status, other.Role)
+		require.NoError(t, err)
+		otherToken, _, err := auth.NewTokenSigner("integration-test-jwt-secret", time.Hour).Issue(other)
+		require.NoError(t, err)
+
+		create := func(token string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(map[string]interface{}{"name": "Mouse", "description": "Wireless", "price": 25})
+			req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", "create-mouse")
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		first := create("")
+		require.Equal(t, http.StatusCreated, first.Code)
+
+		// Another caller sending the same key gets a product of its own
+		second := create(otherToken)
+		require.Equal(t, http.StatusCreated, second.Code)
+		assert.Empty(t, second.Header().Get("Idempotent-Replayed"))
+		assert.NotEqual(t, first.Body.String(), second.Body.String())
+
+		// A key older than its TTL is not replayed even before the cleanup worker removes it
+		_, err = testDB.DB.Exec(`UPDATE idempotency_keys SET created_at = created_at - $1::interval`, "48 hours")
+		require.NoError(t, err)
+		third := create("")
+		require.Equal(t, http.StatusCreated, third.Code)
+		assert.Empty(t, third.Header().Get("Idempotent-Replayed"))
+
+		var products, keys int
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM products").Scan(&products))
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM idempotency_keys").Scan(&keys))
+		assert.Equal(t, 3, products)
+		assert.Equal(t, 2, keys)
+	})
+}
+
+func TestProductAPI_BatchProducts_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	// Set up repositories and services
+	productRepo := reposql.NewProductRepository(testDB.DB)
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
+
+	// Set up HTTP router
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
+
+	post := func(path string, reqBody interface{}) (*httptest.ResponseRecorder, controller.BatchResponse) {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response controller.BatchResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	count := func(query string) int {
+		var n int
+		require.NoError(t, testDB.DB.QueryRow(query).Scan(&n))
+		return n
+	}
+
+	t.Run("batch create all or nothing", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w, response := post("/products:batchCreate", map[string]interface{}{
+			"items": []map[string]interface{}{
+				{"name": "Keyboard", "price": 49.99},
+				{"name": "Broken", "price": -1},
+			},
+		})
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, http.StatusConflict, response.Results[0].Status)
+		assert.Equal(t, http.StatusBadRequest, response.Results[1].Status)
+		assert.Equal(t, 0, count("SELECT COUNT(*) FROM products"))
+
+		w, response = post("/products:batchCreate", map[string]interface{}{
+			"items": []map[string]interface{}{
+				{"name": "Keyboard", "price": 49.99},
+				{"name": "Mouse", "price": 19.99},
+			},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, response.Succeeded)
+		assert.Equal(t, "Mouse", response.Results[1].Product.Name)
+		assert.Equal(t, 2, count("SELECT COUNT(*) FROM products"))
+		assert.Equal(t, 2, count("SELECT COUNT(*) FROM events WHERE event_type = 'product.created'"))
+	})
+
+	t.Run("batch create best effort", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w, response := post("/products:batchCreate", map[string]interface{}{
+			"mode": "best_effort",
+			"items": []map[string]interface{}{
+				{"name": "Broken"},
+				{"name": "Mouse", "price": 19.99},
+			},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, response.Succeeded)
+		assert.Equal(t, 1, response.Failed)
+		assert.Equal(t, http.StatusBadRequest, response.Results[0].Status)
+		assert.Equal(t, http.StatusCreated, response.Results[1].Status)
+		assert.Equal(t, 1, count("SELECT COUNT(*) FROM products"))
+		assert.Equal(t, 1, count("SELECT COUNT(*) FROM events"))
+
+		// Items breaking a column constraint are rejected up front
+		testDB.TruncateTables(t)
+		w, response = post("/products:batchCreate", map[string]interface{}{
+			"mode": "best_effort",
+			"items": []map[string]interface{}{
+				{"name": strings.Repeat("x", 256), "price": 1},
+				{"name": "Null\u0000byte", "price": 1},
+				{"name": "Mouse", "price": 19.99},
+			},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusBadRequest, response.Results[0].Status)
+		assert.Equal(t, http.StatusBadRequest, response.Results[1].Status)
+		assert.Equal(t, http.StatusCreated, response.Results[2].Status)
+
+		// A row the database rejects is skipped without sinking the others
+		testDB.TruncateTables(t)
+		_, err := testDB.DB.Exec("ALTER TABLE products ADD CONSTRAINT test_rejected CHECK (name <> 'Rejected')")
+		require.NoError(t, err)
+		defer func() {
+			_, err := testDB.DB.Exec("ALTER TABLE products DROP CONSTRAINT test_rejected")
+			require.NoError(t, err)
+		}()
+		w, response = post("/products:batchCreate", map[string]interface{}{
+			"mode": "best_effort",
+			"items": []map[string]interface{}{
+				{"name": "Keyboard", "price": 49.99, "tags": []string{"input"}},
+				{"name": "Rejected", "price": 1},
+				{"name": "Mouse", "price": 19.99},
+			},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, response.Succeeded)
+		assert.Equal(t, http.StatusCreated, response.Results[0].Status)
+		assert.Equal(t, http.StatusInternalServerError, response.Results[1].Status)
+		assert.Equal(t, http.StatusCreated, response.Results[2].Status)
+		assert.Equal(t, 2, count("SELECT COUNT(*) FROM products"))
+		assert.Equal(t, 1, count("SELECT COUNT(*) FROM product_tags"))
+		assert.Equal(t, 2, count("SELECT COUNT(*) FROM events"))
+		assert.Equal(t, 2, count("SELECT COUNT(*) FROM audit_log"))
+	})
+
+	t.Run("batch delete", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		_, created := post("/products:batchCreate", map[string]interface{}{
+			"items": []map[string]interface{}{
+				{"name": "Keyboard", "price": 49.99},
+				{"name": "Mouse", "price": 19.99},
+			},
+		})
+		first, second := created.Results[0].ID, created.Results[1].ID
+		unknown := uuid.New().String()
+
+		// All or nothing: an unknown product rolls back the whole batch
+		w, response := post("/products:batchDelete", map[string]interface{}{"ids": []string{first, unknown}})
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, http.StatusConflict, response.Results[0].Status)
+		assert.Equal(t, http.StatusNotFound, response.Results[1].Status)
+		assert.Equal(t, 0, count("SELECT COUNT(*) FROM products WHERE deleted_at IS NOT NULL"))
+
+		// Best effort: known products are deleted and the rest is reported
+		w, response = post("/products:batchDelete", map[string]interface{}{
+			"mode": "best_effort",
+			"ids":  []string{first, unknown, "not-a-uuid", second},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, response.Succeeded)
+		assert.Equal(t, 2, response.Failed)
+		assert.Equal(t, http.StatusNoContent, response.Results[0].Status)
+		assert.Equal(t, http.StatusNotFound, response.Results[1].Status)
+		assert.Equal(t, http.StatusBadRequest, response.Results[2].Status)
+		assert.Equal(t, http.StatusNoContent, response.Results[3].Status)
+		assert.Equal(t, 2, count("SELECT COUNT(*) FROM products WHERE deleted_at IS NOT NULL"))
+		assert.Equal(t, 2, count("SELECT COUNT(*) FROM events WHERE event_type = 'product.deleted'"))
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		w, _ := post("/products:batchUpdate", map[string]interface{}{})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestProductAPI_ImportExport_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	// Set up repositories and services
+	productRepo := reposql.NewProductRepository(testDB.DB)
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
+
+	// Set up HTTP router
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
+
+	importProducts := func(contentType, body string) (*httptest.ResponseRecorder, controller.ImportProductsResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/products/import", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response controller.ImportProductsResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	exportProducts := func(format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/products/export?format="+format, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("import CSV with row errors", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		body := "name,description,price\n" +
+			"Keyboard,\"Mechanical, RGB\",49.99\n" +
+			",Missing name,10\n" +
+			"Mouse,,abc\n" +
+			"Monitor,4K,299\n"
+		w, response := importProducts("text/csv", body)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, response.Imported)
+		assert.Equal(t, 2, response.Failed)
+		require.Len(t, response.Errors, 2)
+		assert.Equal(t, 3, response.Errors[0].Line)
+		assert.Equal(t, 4, response.Errors[1].Line)
+
+		var events int
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM events WHERE event_type = 'product.created'").Scan(&events))
+		assert.Equal(t, 2, events)
+	})
+
+	t.Run("import NDJSON with row errors", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		body := `{"name": "Keyboard", "price": 49.99}` + "\n\n" +
+			`{"name": "Broken", "price": -1}` + "\n" +
+			`not json` + "\n"
+		w, response := importProducts("application/x-ndjson", body)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, response.Imported)
+		require.Len(t, response.Errors, 2)
+		assert.Equal(t, 3, response.Errors[0].Line)
+		assert.Equal(t, 4, response.Errors[1].Line)
+	})
+
+	t.Run("import reports rows with unknown categories", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		body := `{"name": "Keyboard", "price": 49.99}` + "\n" +
+			`{"name": "Mouse", "price": 19.99, "category_id": "` + uuid.New().String() + `"}` + "\n" +
+			`{"name": "Broken", "price": -1}` + "\n"
+		w, response := importProducts("application/x-ndjson", body)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, response.Imported)
+		assert.Equal(t, 2, response.Failed)
+		require.Len(t, response.Errors, 2)
+		assert.Equal(t, 2, response.Errors[0].Line)
+		assert.Equal(t, service.ErrCategoryNotFound.Error(), response.Errors[0].Error)
+		assert.Equal(t, 3, response.Errors[1].Line)
+
+		var products int
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM products").Scan(&products))
+		assert.Equal(t, 1, products)
+	})
+
+	t.Run("import requires a known format", func(t *testing.T) {
+		w, _ := importProducts("application/json", "{}")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, _ = importProducts("text/csv", "title,cost\n")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("export round trip", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		_, response := importProducts("text/csv", "name,description,price\nKeyboard,\"Mechanical, RGB\",49.99\nMouse,,19.99\n")
+		require.Equal(t, 2, response.Imported)
+
+		w := exportProducts("csv")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		csvExport := w.Body.String()
+		assert.Contains(t, csvExport, "id,name,description,price,currency,version,created_at,updated_at,deleted_at\n")
+		assert.Contains(t, csvExport, `"Mechanical, RGB"`)
+
+		w = exportProducts("ndjson")
+		require.Equal(t, http.StatusOK, w.Code)
+		lines := bytes.Split(bytes.TrimSpace(w.Body.Bytes()), []byte("\n"))
+		require.Len(t, lines, 2)
+		var first controller.ProductResponse
+		require.NoError(t, json.Unmarshal(lines[0], &first))
+		assert.Equal(t, "Keyboard", first.Name)
+
+		// The CSV export can be imported into another environment as is
+		testDB.TruncateTables(t)
+		_, response = importProducts("text/csv", csvExport)
+		assert.Equal(t, 2, response.Imported)
+		assert.Empty(t, response.Errors)
+	})
+
+	t.Run("export of an empty catalog", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w := exportProducts("csv")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "id,name,description,price,currency,version,created_at,updated_at,deleted_at\n", w.Body.String())
+
+		w = exportProducts("xml")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
 }
 
 func TestProductAPI_ListProducts_Integration(t *testing.T) {
@@ -116,13 +565,13 @@ func TestProductAPI_ListProducts_Integration(t *testing.T) {
 
 	productRepo := reposql.NewProductRepository(testDB.DB)
 	eventRepo := reposql.NewEventRepository(testDB.DB)
-	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	productCtr := controller.NewProductController(productService)
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
 	cfg := &config.Config{}
-	httpAPI.InitRouter(cfg, nil, router, productCtr)
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
 
 	t.Run("list products", func(t *testing.T) {
 		testDB.TruncateTables(t)
@@ -217,94 +666,786 @@ func TestProductAPI_ListProducts_Integration(t *testing.T) {
 		assert.Len(t, productsArray, 2)
 	})
 
-	t.Run("list products when empty", func(t *testing.T) {
+	t.Run("list products forward and backward", func(t *testing.T) {
 		testDB.TruncateTables(t)
 
-		req := httptest.NewRequest(http.MethodGet, "/products", nil)
-		w := httptest.NewRecorder()
+		for i := 1; i <= 5; i++ {
+			body, _ := json.Marshal(map[string]interface{}{"name": fmt.Sprintf("Product %d", i), "price": float64(i)})
+			req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusCreated, w.Code)
+		}
 
-		router.ServeHTTP(w, req)
+		list := func(t *testing.T, rawQuery string) controller.ListProductsResponse {
+			t.Helper()
 
-		assert.Equal(t, http.StatusOK, w.Code)
+			req := httptest.NewRequest(http.MethodGet, "/products?currency=USD&sort=price:asc&limit=2&"+rawQuery, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
 
-		var response map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &response)
-		require.NoError(t, err)
+			var response controller.ListProductsResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return response
+		}
+		names := func(response controller.ListProductsResponse) []string {
+			result := make([]string, 0, len(response.Products))
+			for _, p := range response.Products {
+				result = append(result, p.Name)
+			}
+			return result
+		}
 
-		productsArray := response["products"].([]interface{})
-		assert.Empty(t, productsArray)
+		first := list(t, "include_total=true")
+		assert.Equal(t, []string{"Product 1", "Product 2"}, names(first))
+		assert.True(t, first.HasMore)
+		assert.Empty(t, first.PrevPageToken)
+		require.NotNil(t, first.TotalCount)
+		assert.Equal(t, int64(5), *first.TotalCount)
+
+		second := list(t, "token="+url.QueryEscape(first.NextPageToken))
+		assert.Equal(t, []string{"Product 3", "Product 4"}, names(second))
+		assert.True(t, second.HasMore)
+		assert.Nil(t, second.TotalCount)
+
+		last := list(t, "token="+url.QueryEscape(second.NextPageToken))
+		assert.Equal(t, []string{"Product 5"}, names(last))
+		assert.False(t, last.HasMore)
+		assert.Empty(t, last.NextPageToken)
+		require.NotEmpty(t, last.PrevPageToken)
+
+		back := list(t, "token="+url.QueryEscape(last.PrevPageToken))
+		assert.Equal(t, []string{"Product 3", "Product 4"}, names(back))
+		assert.True(t, back.HasMore)
+
+		backToFirst := list(t, "token="+url.QueryEscape(back.PrevPageToken))
+		assert.Equal(t, []string{"Product 1", "Product 2"}, names(backToFirst))
+		assert.True(t, backToFirst.HasMore)
+		assert.Empty(t, backToFirst.PrevPageToken)
 	})
-}
 
-func TestProductAPI_DeleteProduct_Integration(t *testing.T) {
-	testDB := SetupTestDB(t)
-	defer testDB.Cleanup(t)
+	t.Run("list products with filters", func(t *testing.T) {
+		testDB.TruncateTables(t)
 
-	productRepo := reposql.NewProductRepository(testDB.DB)
-	eventRepo := reposql.NewEventRepository(testDB.DB)
-	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+		products := []struct {
+			name  string
+			price float64
+		}{
+			{"Laptop Pro", 1500},
+			{"Laptop Air", 900},
+			{"Gaming laptop", 1200},
+			{"Mouse 100% wireless", 25},
+			{"Keyboard", 60},
+		}
+		for _, p := range products {
+			body, _ := json.Marshal(map[string]interface{}{"name": p.name, "price": p.price})
+			req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusCreated, w.Code)
+		}
 
-	gin.SetMode(gin.TestMode)
-	router := gin.New()
-	productCtr := controller.NewProductController(productService)
-	cfg := &config.Config{}
-	httpAPI.InitRouter(cfg, nil, router, productCtr)
+		listNames := func(t *testing.T, rawQuery string) ([]string, string) {
+			t.Helper()
 
-	t.Run("delete product successfully", func(t *testing.T) {
-		testDB.TruncateTables(t)
+			req := httptest.NewRequest(http.MethodGet, "/products?"+rawQuery, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
 
-		// Create a product first
-		reqBody := map[string]interface{}{
-			"name":  "Product to Delete",
-			"price": 25.99,
+			var response controller.ListProductsResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			names := make([]string, 0, len(response.Products))
+			for _, p := range response.Products {
+				names = append(names, p.Name)
+			}
+			return names, response.NextPageToken
 		}
-		body, _ := json.Marshal(reqBody)
+
+		names, _ := listNames(t, "name_prefix=laptop")
+		assert.Equal(t, []string{"Laptop Air", "Laptop Pro"}, names)
+
+		names, _ = listNames(t, "name_contains=LAPTOP")
+		assert.Equal(t, []string{"Gaming laptop", "Laptop Air", "Laptop Pro"}, names)
+
+		// Wildcards in the search term are matched literally
+		names, _ = listNames(t, "name_contains="+url.QueryEscape("100%"))
+		assert.Equal(t, []string{"Mouse 100% wireless"}, names)
+		names, _ = listNames(t, "name_contains="+url.QueryEscape("%"))
+		assert.Equal(t, []string{"Mouse 100% wireless"}, names)
+
+		names, _ = listNames(t, "currency=USD&min_price=60&max_price=1200")
+		assert.Equal(t, []string{"Keyboard", "Gaming laptop", "Laptop Air"}, names)
+
+		future := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+		names, _ = listNames(t, "created_after="+future)
+		assert.Empty(t, names)
+		names, _ = listNames(t, "created_before="+future)
+		assert.Len(t, names, 5)
+
+		// The page token keeps working while the same filters are applied
+		names, token := listNames(t, "name_contains=laptop&limit=2")
+		assert.Equal(t, []string{"Gaming laptop", "Laptop Air"}, names)
+		require.NotEmpty(t, token)
+		names, _ = listNames(t, "name_contains=laptop&limit=2&token="+url.QueryEscape(token))
+		assert.Equal(t, []string{"Laptop Pro"}, names)
+
+		// Price bounds are amounts of the single currency given by the currency filter
+		body, _ := json.Marshal(map[string]interface{}{"name": "Euro tablet", "price": map[string]string{"amount": "1000.00", "currency": "EUR"}})
 		req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
-		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		names, _ = listNames(t, "currency=USD&min_price=60&max_price=1200")
+		assert.Equal(t, []string{"Keyboard", "Gaming laptop", "Laptop Air"}, names)
+		names, _ = listNames(t, "currency=EUR&min_price=999.99&max_price=1000")
+		assert.Equal(t, []string{"Euro tablet"}, names)
+		names, _ = listNames(t, "currency=EUR")
+		assert.Equal(t, []string{"Euro tablet"}, names)
+	})
 
-		var createResponse map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &createResponse)
-		require.NoError(t, err)
-		productID := createResponse["id"].(string)
+	t.Run("list products with sort order", func(t *testing.T) {
+		testDB.TruncateTables(t)
 
-		// Delete the product
-		req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/products/%s", productID), nil)
-		w = httptest.NewRecorder()
-		router.ServeHTTP(w, req)
+		products := []struct {
+			name  string
+			price float64
+		}{
+			{"Cherry", 30},
+			{"Apple", 10},
+			{"Banana", 20},
+			{"Date", 10},
+			{"Elderberry", 50},
+		}
+		for _, p := range products {
+			body, _ := json.Marshal(map[string]interface{}{"name": p.name, "price": p.price})
+			req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusCreated, w.Code)
+		}
 
-		assert.Equal(t, http.StatusOK, w.Code)
+		// listAll walks every page of the given sort order two products at a time
+		listAll := func(t *testing.T, sort string) []string {
+			t.Helper()
+
+			var names []string
+			token := ""
+			for range len(products) + 1 {
+				req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/products?limit=2&sort=%s&token=%s", sort, url.QueryEscape(token)), nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+				var response controller.ListProductsResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				for _, p := range response.Products {
+					names = append(names, p.Name)
+				}
+				if !response.HasMore {
+					break
+				}
+				token = response.NextPageToken
+			}
+			return names
+		}
 
-		var deleteResponse map[string]interface{}
-		err = json.Unmarshal(w.Body.Bytes(), &deleteResponse)
-		require.NoError(t, err)
-		assert.Equal(t, "product deleted successfully", deleteResponse["message"])
+		assert.Equal(t, []string{"Apple", "Banana", "Cherry", "Date", "Elderberry"}, listAll(t, "name"))
+		assert.Equal(t, []string{"Elderberry", "Date", "Cherry", "Banana", "Apple"}, listAll(t, "name:desc"))
 
-		// Verify product was deleted from database
-		id, err := uuid.Parse(productID)
-		require.NoError(t, err)
-		_, err = productRepo.FindByID(req.Context(), id)
-		assert.Error(t, err)
+		byPrice := listAll(t, "price:asc")
+		require.Len(t, byPrice, 5)
+		assert.ElementsMatch(t, []string{"Apple", "Date"}, byPrice[:2])
+		assert.Equal(t, []string{"Banana", "Cherry", "Elderberry"}, byPrice[2:])
+
+		assert.Equal(t, []string{"Elderberry", "Date", "Banana", "Apple", "Cherry"}, listAll(t, "created_at:desc"))
 	})
 
-	t.Run("delete non-existent product", func(t *testing.T) {
+	t.Run("list products with token of another sort order", func(t *testing.T) {
 		testDB.TruncateTables(t)
 
-		nonExistentID := uuid.New().String()
-		req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/products/%s", nonExistentID), nil)
-		w := httptest.NewRecorder()
+		for i := 1; i <= 3; i++ {
+			body, _ := json.Marshal(map[string]interface{}{"name": fmt.Sprintf("Product %d", i), "price": float64(i)})
+			req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusCreated, w.Code)
+		}
 
+		req := httptest.NewRequest(http.MethodGet, "/products?currency=USD&limit=1&sort=price:asc", nil)
+		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
 
-		assert.Equal(t, http.StatusNotFound, w.Code)
+		var response controller.ListProductsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.NotEmpty(t, response.NextPageToken)
+
+		req = httptest.NewRequest(http.MethodGet, "/products?currency=USD&limit=1&sort=price:desc&token="+url.QueryEscape(response.NextPageToken), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "sort order")
 	})
 
-	t.Run("delete product with invalid ID", func(t *testing.T) {
+	t.Run("list products with forged or expired token", func(t *testing.T) {
 		testDB.TruncateTables(t)
 
-		req := httptest.NewRequest(http.MethodDelete, "/products/invalid-uuid", nil)
+		forged := repository.Paginator{LastID: uuid.New(), LastCreatedAt: time.Now()}.
+			Encode(repository.NewPageTokenSigner("not-the-server-secret", time.Hour))
+		req := httptest.NewRequest(http.MethodGet, "/products?token="+url.QueryEscape(forged), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), repository.ErrPageTokenSignature.Error())
+
+		expiringRouter := gin.New()
+		expiringCtr := controller.NewProductController(productService, repository.NewPageTokenSigner("integration-test-secret", time.Nanosecond))
+		httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, expiringRouter), nil, expiringRouter, expiringCtr, nil, nil, nil, nil, nil)
+
+		body, _ := json.Marshal(map[string]interface{}{"name": "Product", "price": 1.0})
+		for range 2 {
+			req = httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w = httptest.NewRecorder()
+			expiringRouter.ServeHTTP(w, req)
+			require.Equal(t, http.StatusCreated, w.Code)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/products?limit=1", nil)
+		w = httptest.NewRecorder()
+		expiringRouter.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response controller.ListProductsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.NotEmpty(t, response.NextPageToken)
+
+		// Expiry has one second resolution
+		time.Sleep(1100 * time.Millisecond)
+
+		req = httptest.NewRequest(http.MethodGet, "/products?limit=1&token="+url.QueryEscape(response.NextPageToken), nil)
+		w = httptest.NewRecorder()
+		expiringRouter.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), repository.ErrPageTokenExpired.Error())
+	})
+
+	t.Run("list products with invalid filters", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		for _, rawQuery := range []string{
+			"currency=USD&min_price=abc",
+			"currency=USD&min_price=-1",
+			"currency=USD&min_price=10&max_price=5",
+			"currency=USD&min_price=1.999",
+			"min_price=10",
+			"max_price=10",
+			"sort=price",
+			"currency=JPY&min_price=1.5",
+			"currency=XYZ",
+			"created_after=yesterday",
+			"created_after=2024-02-01T00:00:00Z&created_before=2024-01-01T00:00:00Z",
+			"sort=description",
+			"currency=USD&sort=price:up",
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/products?"+rawQuery, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, rawQuery)
+		}
+	})
+
+	t.Run("list products when empty", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+
+		productsArray := response["products"].([]interface{})
+		assert.Empty(t, productsArray)
+	})
+}
+
+func TestProductAPI_GetProduct_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	productRepo := reposql.NewProductRepository(testDB.DB)
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
+
+	t.Run("get product successfully", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		// Create a product first
+		reqBody := map[string]interface{}{
+			"name":        "Product to Get",
+			"description": "Fetched by ID",
+			"price":       15.49,
+		}
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var createResponse map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &createResponse)
+		require.NoError(t, err)
+		productID := createResponse["id"].(string)
+
+		// Get the product
+		req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/products/%s", productID), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var getResponse map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &getResponse)
+		require.NoError(t, err)
+		assert.Equal(t, productID, getResponse["id"])
+		assert.Equal(t, "Product to Get", getResponse["name"])
+		assert.Equal(t, "Fetched by ID", getResponse["description"])
+		assert.Equal(t, map[string]interface{}{"amount": "15.49", "currency": "USD"}, getResponse["price"])
+	})
+
+	t.Run("get non-existent product", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		nonExistentID := uuid.New().String()
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/products/%s", nonExistentID), nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("get product with invalid ID", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/products/invalid-uuid", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestProductAPI_UpdateProduct_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	productRepo := reposql.NewProductRepository(testDB.DB)
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
+
+	createProduct := func(t *testing.T) string {
+		t.Helper()
+
+		reqBody := map[string]interface{}{
+			"name":        "Original Name",
+			"description": "Original description",
+			"price":       100.0,
+		}
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var createResponse map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &createResponse))
+		return createResponse["id"].(string)
+	}
+
+	t.Run("patch product price", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		body, _ := json.Marshal(map[string]interface{}{"price": 80.5})
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/products/%s", productID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, productID, response["id"])
+		assert.Equal(t, "Original Name", response["name"])
+		assert.Equal(t, map[string]interface{}{"amount": "80.50", "currency": "USD"}, response["price"])
+
+		// Verify the product.updated event carries old and new values
+		var eventData []byte
+		err := testDB.DB.QueryRowContext(req.Context(),
+			"SELECT event_data FROM events WHERE event_type = 'product.updated'").Scan(&eventData)
+		require.NoError(t, err)
+
+		var msg sqspkg.ProductMessage
+		require.NoError(t, json.Unmarshal(eventData, &msg))
+		assert.Equal(t, "updated", msg.Action)
+		assert.Equal(t, productID, msg.ProductID)
+		require.Contains(t, msg.Changes, "price")
+		assert.Equal(t, map[string]interface{}{"amount": "100.00", "currency": "USD"}, msg.Changes["price"].Old)
+		assert.Equal(t, map[string]interface{}{"amount": "80.50", "currency": "USD"}, msg.Changes["price"].New)
+		assert.NotContains(t, msg.Changes, "name")
+	})
+
+	t.Run("put product replaces all fields", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		body, _ := json.Marshal(map[string]interface{}{
+			"name":        "Replaced Name",
+			"description": "Replaced description",
+			"price":       120.0,
+		})
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/products/%s", productID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "Replaced Name", response["name"])
+		assert.Equal(t, "Replaced description", response["description"])
+		assert.Equal(t, map[string]interface{}{"amount": "120.00", "currency": "USD"}, response["price"])
+	})
+
+	t.Run("put product with missing fields", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		body, _ := json.Marshal(map[string]interface{}{"description": "Only description"})
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/products/%s", productID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("patch product with empty body", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/products/%s", productID), bytes.NewBufferString("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("conditional updates with If-Match", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		// Fetch the current ETag
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/products/%s", productID), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		assert.Equal(t, `"1"`, etag)
+
+		// First editor wins with the current ETag
+		body, _ := json.Marshal(map[string]interface{}{"name": "First Editor"})
+		req = httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/products/%s", productID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", etag)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+		// Second editor with the stale ETag is rejected
+		body, _ = json.Marshal(map[string]interface{}{"name": "Second Editor"})
+		req = httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/products/%s", productID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", etag)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+		// Stale delete is rejected as well
+		req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/products/%s", productID), nil)
+		req.Header.Set("If-Match", etag)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+		// The first editor's change is kept and only one update event was written
+		id, err := uuid.Parse(productID)
+		require.NoError(t, err)
+		found, err := productRepo.FindByID(req.Context(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "First Editor", found.(*model.Product).Name)
+		assert.Equal(t, int64(2), found.(*model.Product).Version)
+
+		var updateEvents int
+		err = testDB.DB.QueryRowContext(req.Context(),
+			"SELECT COUNT(*) FROM events WHERE event_type = 'product.updated'").Scan(&updateEvents)
+		require.NoError(t, err)
+		assert.Equal(t, 1, updateEvents)
+	})
+
+	t.Run("malformed If-Match", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		body, _ := json.Marshal(map[string]interface{}{"name": "Whatever"})
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/products/%s", productID), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "not-an-etag")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("concurrent writes without If-Match are not rejected", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		const editors = 10
+		codes := make([]int, editors)
+		var wg sync.WaitGroup
+		for i := range editors {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				body, _ := json.Marshal(map[string]interface{}{"name": fmt.Sprintf("Editor %d", i)})
+				req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/products/%s", productID), bytes.NewBuffer(body))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				codes[i] = w.Code
+			}()
+		}
+		wg.Wait()
+
+		for _, code := range codes {
+			assert.Equal(t, http.StatusOK, code)
+		}
+		id, err := uuid.Parse(productID)
+		require.NoError(t, err)
+		found, err := productRepo.FindByID(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1+editors), found.(*model.Product).Version)
+
+		// A delete racing an update either wins or is applied after it, and an update that loses to it is a 404
+		var deleteCode, updateCode int
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/products/%s", productID), nil))
+			deleteCode = w.Code
+		}()
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/products/%s", productID), bytes.NewBufferString(`{"name": "Late Editor"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			updateCode = w.Code
+		}()
+		wg.Wait()
+
+		assert.Equal(t, http.StatusOK, deleteCode)
+		assert.Contains(t, []int{http.StatusOK, http.StatusNotFound}, updateCode)
+	})
+
+	t.Run("patch non-existent product", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		body, _ := json.Marshal(map[string]interface{}{"name": "Ghost"})
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/products/%s", uuid.New()), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("price changes are recorded in the price history", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createProduct(t)
+
+		for _, payload := range []map[string]interface{}{
+			{"price": 80.0},
+			{"name": "Renamed"},
+			{"price": map[string]interface{}{"amount": "90", "currency": "EUR"}},
+		} {
+			body, _ := json.Marshal(payload)
+			req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/products/%s", productID), bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		}
+
+		// Newest first, one entry per page
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/products/%s/price-history?limit=1", productID), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var firstPage controller.ListPriceHistoryResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &firstPage))
+		require.Len(t, firstPage.PriceHistory, 1)
+		assert.Equal(t, model.Money{Amount: 8000, Currency: "USD"}, firstPage.PriceHistory[0].OldPrice)
+		assert.Equal(t, model.Money{Amount: 9000, Currency: "EUR"}, firstPage.PriceHistory[0].NewPrice)
+		assert.Nil(t, firstPage.PriceHistory[0].PercentChange)
+		require.True(t, firstPage.HasMore)
+
+		req = httptest.NewRequest(http.MethodGet,
+			fmt.Sprintf("/products/%s/price-history?limit=1&token=%s", productID, url.QueryEscape(firstPage.NextPageToken)), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var secondPage controller.ListPriceHistoryResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &secondPage))
+		require.Len(t, secondPage.PriceHistory, 1)
+		assert.Equal(t, model.Money{Amount: 10000, Currency: "USD"}, secondPage.PriceHistory[0].OldPrice)
+		require.NotNil(t, secondPage.PriceHistory[0].PercentChange)
+		assert.InDelta(t, -20.0, *secondPage.PriceHistory[0].PercentChange, 0.001)
+		assert.False(t, secondPage.HasMore)
+
+		// Each price change has its own event
+		var count int
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM events WHERE event_type = 'product.price_changed'").Scan(&count))
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("price history of a missing product", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/products/%s/price-history", uuid.New()), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestProductAPI_DeleteProduct_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	productRepo := reposql.NewProductRepository(testDB.DB)
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
+
+	t.Run("delete product successfully", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		// Create a product first
+		reqBody := map[string]interface{}{
+			"name":  "Product to Delete",
+			"price": 25.99,
+		}
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var createResponse map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &createResponse)
+		require.NoError(t, err)
+		productID := createResponse["id"].(string)
+
+		// Delete the product
+		req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/products/%s", productID), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var deleteResponse map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &deleteResponse)
+		require.NoError(t, err)
+		assert.Equal(t, "product deleted successfully", deleteResponse["message"])
+
+		// Verify product is hidden but kept in the database as soft-deleted
+		id, err := uuid.Parse(productID)
+		require.NoError(t, err)
+		_, err = productRepo.FindByID(req.Context(), id)
+		assert.Error(t, err)
+
+		deleted, err := productRepo.FindByIDIncludingDeleted(req.Context(), id)
+		require.NoError(t, err)
+		assert.NotNil(t, deleted.DeletedAt)
+
+		// Verify a second delete reports the product as gone
+		req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/products/%s", productID), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("delete non-existent product", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		nonExistentID := uuid.New().String()
+		req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/products/%s", nonExistentID), nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("delete product with invalid ID", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		req := httptest.NewRequest(http.MethodDelete, "/products/invalid-uuid", nil)
 		w := httptest.NewRecorder()
 
 		router.ServeHTTP(w, req)
@@ -312,3 +1453,174 @@ func TestProductAPI_DeleteProduct_Integration(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 }
+
+func TestProductAPI_RestoreProduct_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	productRepo := reposql.NewProductRepository(testDB.DB)
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+	productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
+	cfg := &config.Config{}
+	httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
+
+	createAndDelete := func(t *testing.T) string {
+		t.Helper()
+
+		body, _ := json.Marshal(map[string]interface{}{"name": "Restorable", "price": 10.0})
+		req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var createResponse map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &createResponse))
+		productID := createResponse["id"].(string)
+
+		req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/products/%s", productID), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		return productID
+	}
+
+	t.Run("deleted products are hidden from list unless requested", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createAndDelete(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var listResponse controller.ListProductsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listResponse))
+		assert.Empty(t, listResponse.Products)
+
+		req = httptest.NewRequest(http.MethodGet, "/products?include_deleted=true", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listResponse))
+		require.Len(t, listResponse.Products, 1)
+		assert.Equal(t, productID, listResponse.Products[0].ID)
+		assert.NotEmpty(t, listResponse.Products[0].DeletedAt)
+	})
+
+	t.Run("deleted products are only listed to authenticated callers", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		createAndDelete(t)
+
+		for _, target := range []string{"/products?include_deleted=true", "/products/export?include_deleted=true"} {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			req.Header.Set("Authorization", "Bearer not-a-token")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, target)
+		}
+
+		// Without the flag, the routes stay public
+		req := httptest.NewRequest(http.MethodGet, "/products?include_deleted=false", nil)
+		req.Header.Set("Authorization", "Bearer not-a-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("restore deleted product", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createAndDelete(t)
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/products/%s/restore", productID), nil)
+		req.Header.Set("If-Match", `"2"`)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+		var response controller.ProductResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Empty(t, response.DeletedAt)
+
+		// The product is visible again
+		req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/products/%s", productID), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// A product.restored event was stored in the outbox
+		var count int
+		err := testDB.DB.QueryRow(`SELECT COUNT(*) FROM events WHERE event_type = 'product.restored'`).Scan(&count)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("restore with stale If-Match", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createAndDelete(t)
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/products/%s/restore", productID), nil)
+		req.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("restore product that is not deleted", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		body, _ := json.Marshal(map[string]interface{}{"name": "Alive", "price": 10.0})
+		req := httptest.NewRequest(http.MethodPost, "/products", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var createResponse map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &createResponse))
+
+		req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/products/%s/restore", createResponse["id"]), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("restore non-existent product", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/products/%s/restore", uuid.New()), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("purge removes products deleted before the cut-off", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		productID := createAndDelete(t)
+		id, err := uuid.Parse(productID)
+		require.NoError(t, err)
+
+		// Nothing is old enough yet
+		purged, err := productRepo.PurgeDeleted(context.Background(), time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+
+		purged, err = productRepo.PurgeDeleted(context.Background(), time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		_, err = productRepo.FindByIDIncludingDeleted(context.Background(), id)
+		assert.Error(t, err)
+	})
+}
@@ -46,13 +46,13 @@ func TestRecoveryMiddleware_Integration(t *testing.T) {
 		// Set up repositories and services
 		productRepo := reposql.NewProductRepository(testDB.DB)
 		eventRepo := reposql.NewEventRepository(testDB.DB)
-		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil)
+		productService := service.NewProductService(testDB.DB, productRepo, eventRepo, nil, config.DefaultIdempotencyKeyTTL)
 
 		// Set up HTTP router with recovery middleware
 		router := gin.New()
-		productCtr := controller.NewProductController(productService)
+		productCtr := controller.NewProductController(productService, NewTestPageTokenSigner())
 		cfg := &config.Config{}
-		httpAPI.InitRouter(cfg, nil, router, productCtr)
+		httpAPI.InitRouter(cfg, AuthenticateRequests(t, testDB, router), nil, router, productCtr, nil, nil, nil, nil, nil)
 
 		// Normal request should work
 		req := httptest.NewRequest(http.MethodGet, "/products", nil)
@@ -4,7 +4,10 @@ package integration
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iyhunko/microservices-with-sqs/internal/model"
@@ -30,7 +33,7 @@ func TestProductRepository_CreateWithTransaction_Integration(t *testing.T) {
 			product := &model.Product{
 				Name:        "Test Product",
 				Description: "Test Description",
-				Price:       99.99,
+				Price:       model.Money{Amount: 9999, Currency: "USD"},
 			}
 
 			result, err := repo.Create(ctx, product)
@@ -60,7 +63,7 @@ func TestProductRepository_CreateWithTransaction_Integration(t *testing.T) {
 			product := &model.Product{
 				Name:        "Test Product to Rollback",
 				Description: "Should not be committed",
-				Price:       49.99,
+				Price:       model.Money{Amount: 4999, Currency: "USD"},
 			}
 
 			result, err := repo.Create(ctx, product)
@@ -93,7 +96,7 @@ func TestProductRepository_CreateWithTransaction_Integration(t *testing.T) {
 			product1 := &model.Product{
 				Name:        "Product 1",
 				Description: "First product",
-				Price:       10.99,
+				Price:       model.Money{Amount: 1099, Currency: "USD"},
 			}
 			result1, err := repo.Create(ctx, product1)
 			if err != nil {
@@ -105,7 +108,7 @@ func TestProductRepository_CreateWithTransaction_Integration(t *testing.T) {
 			product2 := &model.Product{
 				Name:        "Product 2",
 				Description: "Second product",
-				Price:       20.99,
+				Price:       model.Money{Amount: 2099, Currency: "USD"},
 			}
 			result2, err := repo.Create(ctx, product2)
 			if err != nil {
@@ -135,7 +138,7 @@ func TestProductRepository_CreateWithTransaction_Integration(t *testing.T) {
 		product := &model.Product{
 			Name:        "Product to Delete",
 			Description: "Will be deleted in transaction",
-			Price:       30.99,
+			Price:       model.Money{Amount: 3099, Currency: "USD"},
 		}
 		result, err := productRepo.Create(ctx, product)
 		require.NoError(t, err)
@@ -194,6 +197,274 @@ func TestEventRepository_CreateWithTransaction_Integration(t *testing.T) {
 	})
 }
 
+func TestEventRepository_ClaimPending_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	ctx := context.Background()
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+
+	createPending := func(t *testing.T, count int) {
+		t.Helper()
+		events := make([]*model.Event, count)
+		for i := range events {
+			events[i] = &model.Event{EventType: "test.event", EventData: []byte(`{}`)}
+		}
+		require.NoError(t, eventRepo.CreateBatch(ctx, events))
+	}
+
+	t.Run("concurrent workers claim every event once", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		createPending(t, 200)
+
+		var mu sync.Mutex
+		claimed := make(map[uuid.UUID]int)
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(workerID string) {
+				defer wg.Done()
+				for {
+					events, err := eventRepo.ClaimPending(ctx, workerID, time.Now(), time.Minute, 10)
+					if !assert.NoError(t, err) || len(events) == 0 {
+						return
+					}
+					mu.Lock()
+					for _, event := range events {
+						claimed[event.ID]++
+					}
+					mu.Unlock()
+				}
+			}(fmt.Sprintf("worker-%d", w))
+		}
+		wg.Wait()
+
+		assert.Len(t, claimed, 200)
+		for id, times := range claimed {
+			assert.Equal(t, 1, times, id.String())
+		}
+	})
+
+	t.Run("expired leases are claimed again", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		createPending(t, 2)
+		now := time.Now()
+
+		crashed, err := eventRepo.ClaimPending(ctx, "crashed", now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, crashed, 2)
+		require.NoError(t, eventRepo.UpdateStatus(ctx, crashed[0].ID, "crashed", model.EventStatusProcessed))
+
+		events, err := eventRepo.ClaimPending(ctx, "other", now.Add(30*time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+
+		events, err = eventRepo.ClaimPending(ctx, "other", now.Add(2*time.Minute), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, crashed[1].ID, events[0].ID)
+		assert.Equal(t, "other", *events[0].LockedBy)
+	})
+
+	t.Run("a worker whose lease was taken over cannot store an outcome", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		createPending(t, 1)
+		now := time.Now()
+
+		slow, err := eventRepo.ClaimPending(ctx, "slow", now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, slow, 1)
+
+		events, err := eventRepo.ClaimPending(ctx, "other", now.Add(2*time.Minute), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+
+		require.ErrorIs(t, eventRepo.UpdateStatus(ctx, slow[0].ID, "slow", model.EventStatusProcessed), repository.ErrLeaseLost)
+		lastError := "connection refused"
+		slow[0].Status = model.EventStatusDead
+		slow[0].Attempts = 1
+		slow[0].LastError = &lastError
+		require.ErrorIs(t, eventRepo.RecordFailure(ctx, slow[0], "slow"), repository.ErrLeaseLost)
+
+		found, err := eventRepo.FindByID(ctx, slow[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.EventStatusPending, found.(*model.Event).Status)
+		assert.Equal(t, 0, found.(*model.Event).Attempts)
+		assert.Equal(t, "other", *found.(*model.Event).LockedBy)
+
+		require.NoError(t, eventRepo.UpdateStatus(ctx, slow[0].ID, "other", model.EventStatusProcessed))
+	})
+
+	t.Run("failed events are claimed again at their next attempt", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		createPending(t, 1)
+		now := time.Now()
+
+		events, err := eventRepo.ClaimPending(ctx, "worker", now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+
+		failed := events[0]
+		lastError := "connection refused"
+		nextAttemptAt := now.Add(10 * time.Second)
+		failed.Attempts = 1
+		failed.LastError = &lastError
+		failed.NextAttemptAt = &nextAttemptAt
+		require.NoError(t, eventRepo.RecordFailure(ctx, failed, "worker"))
+
+		events, err = eventRepo.ClaimPending(ctx, "worker", now.Add(5*time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+
+		events, err = eventRepo.ClaimPending(ctx, "worker", now.Add(11*time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, 1, events[0].Attempts)
+		assert.Equal(t, lastError, *events[0].LastError)
+
+		events[0].Status = model.EventStatusDead
+		events[0].Attempts = 2
+		events[0].NextAttemptAt = nil
+		require.NoError(t, eventRepo.RecordFailure(ctx, events[0], "worker"))
+
+		found, err := eventRepo.FindByID(ctx, failed.ID)
+		require.NoError(t, err)
+		dead := found.(*model.Event)
+		assert.Equal(t, model.EventStatusDead, dead.Status)
+		assert.Equal(t, 2, dead.Attempts)
+		assert.NotNil(t, dead.ProcessedAt)
+		assert.Nil(t, dead.LockedBy)
+	})
+}
+
+func TestEventRepository_AggregateOrdering_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	ctx := context.Background()
+	eventRepo := reposql.NewEventRepository(testDB.DB)
+
+	// createEvents stores one event of every given type about the aggregate, each in a transaction of its own
+	createEvents := func(t *testing.T, aggregateID uuid.UUID, eventTypes ...string) []*model.Event {
+		t.Helper()
+		events := make([]*model.Event, 0, len(eventTypes))
+		for _, eventType := range eventTypes {
+			event := &model.Event{EventType: eventType, EventData: []byte(`{}`), AggregateID: aggregateID}
+			require.NoError(t, eventRepo.WithinTransaction(ctx, func(repo repository.Repository) error {
+				_, err := repo.Create(ctx, event)
+				return err
+			}))
+			events = append(events, event)
+		}
+		return events
+	}
+	claimIDs := func(t *testing.T, now time.Time) []uuid.UUID {
+		t.Helper()
+		events, err := eventRepo.ClaimPending(ctx, "worker", now, time.Minute, 10)
+		require.NoError(t, err)
+		ids := make([]uuid.UUID, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+
+	t.Run("events of an aggregate are claimed one at a time in sequence order", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		product, other := uuid.New(), uuid.New()
+		events := createEvents(t, product, "product.created", "product.updated", "product.deleted")
+		otherEvents := createEvents(t, other, "product.created")
+
+		// The clock of the creating instance does not matter: created_at is not the publishing order
+		_, err := testDB.DB.Exec("UPDATE events SET created_at = created_at - INTERVAL '1 hour' WHERE id = $1", events[2].ID)
+		require.NoError(t, err)
+
+		now := time.Now()
+		assert.Equal(t, []uuid.UUID{events[0].ID, otherEvents[0].ID}, claimIDs(t, now))
+		assert.Empty(t, claimIDs(t, now), "later events wait while the first one is leased")
+
+		require.NoError(t, eventRepo.UpdateStatus(ctx, events[0].ID, "worker", model.EventStatusProcessed))
+		assert.Equal(t, []uuid.UUID{events[1].ID}, claimIDs(t, now))
+		require.NoError(t, eventRepo.UpdateStatus(ctx, events[1].ID, "worker", model.EventStatusProcessed))
+		assert.Equal(t, []uuid.UUID{events[2].ID}, claimIDs(t, now))
+
+		var sequences []int64
+		for _, event := range events {
+			found, err := eventRepo.FindByID(ctx, event.ID)
+			require.NoError(t, err)
+			assert.Equal(t, product, found.(*model.Event).AggregateID)
+			sequences = append(sequences, found.(*model.Event).Sequence)
+		}
+		assert.IsIncreasing(t, sequences)
+	})
+
+	t.Run("a failed event holds back its aggregate until it is dead", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		events := createEvents(t, uuid.New(), "product.created", "product.updated")
+		now := time.Now()
+
+		claimed, err := eventRepo.ClaimPending(ctx, "worker", now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		failed := claimed[0]
+		lastError := "connection refused"
+		nextAttemptAt := now.Add(10 * time.Second)
+		failed.Attempts = 1
+		failed.LastError = &lastError
+		failed.NextAttemptAt = &nextAttemptAt
+		require.NoError(t, eventRepo.RecordFailure(ctx, failed, "worker"))
+
+		assert.Empty(t, claimIDs(t, now), "the later event waits for the retry")
+		assert.Equal(t, []uuid.UUID{events[0].ID}, claimIDs(t, now.Add(11*time.Second)))
+
+		failed.Status = model.EventStatusDead
+		failed.Attempts = 2
+		failed.NextAttemptAt = nil
+		require.NoError(t, eventRepo.RecordFailure(ctx, failed, "worker"))
+		assert.Equal(t, []uuid.UUID{events[1].ID}, claimIDs(t, now.Add(11*time.Second)))
+	})
+
+	t.Run("concurrent transactions of an aggregate are sequenced in commit order", func(t *testing.T) {
+		testDB.TruncateTables(t)
+		aggregateID := uuid.New()
+
+		first, err := testDB.DB.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer first.Rollback()
+		firstEvent := &model.Event{EventType: "product.updated", EventData: []byte(`{}`), AggregateID: aggregateID}
+		_, err = reposql.NewEventRepositoryWithTx(testDB.DB, first).Create(ctx, firstEvent)
+		require.NoError(t, err)
+
+		// The second transaction waits for the first one to end before it gets a sequence
+		secondEvent := &model.Event{EventType: "product.deleted", EventData: []byte(`{}`), AggregateID: aggregateID}
+		done := make(chan error, 1)
+		go func() {
+			done <- eventRepo.WithinTransaction(ctx, func(repo repository.Repository) error {
+				_, err := repo.Create(ctx, secondEvent)
+				return err
+			})
+		}()
+		select {
+		case err := <-done:
+			t.Fatalf("second transaction was not held back: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		// Meanwhile the events of other aggregates are created freely
+		createEvents(t, uuid.New(), "product.created")
+
+		require.NoError(t, first.Commit())
+		require.NoError(t, <-done)
+
+		var firstSequence, secondSequence int64
+		require.NoError(t, testDB.DB.QueryRow("SELECT sequence FROM events WHERE id = $1", firstEvent.ID).Scan(&firstSequence))
+		require.NoError(t, testDB.DB.QueryRow("SELECT sequence FROM events WHERE id = $1", secondEvent.ID).Scan(&secondSequence))
+		assert.Less(t, firstSequence, secondSequence)
+		assert.Contains(t, claimIDs(t, time.Now()), firstEvent.ID)
+		assert.NotContains(t, claimIDs(t, time.Now()), secondEvent.ID)
+	})
+}
+
 func TestRepositoryTransactions_ComplexScenarios_Integration(t *testing.T) {
 	testDB := SetupTestDB(t)
 	defer testDB.Cleanup(t)
@@ -212,7 +483,7 @@ func TestRepositoryTransactions_ComplexScenarios_Integration(t *testing.T) {
 				product := &model.Product{
 					Name:        "Batch Product " + string(rune('A'+i-1)),
 					Description: "Batch created product",
-					Price:       float64(i * 10),
+					Price:       model.Money{Amount: int64(i * 1000), Currency: "USD"},
 				}
 
 				result, err := txRepo.Create(ctx, product)
@@ -246,7 +517,7 @@ func TestRepositoryTransactions_ComplexScenarios_Integration(t *testing.T) {
 			// Create first product
 			product1 := &model.Product{
 				Name:  "Product 1",
-				Price: 10.0,
+				Price: model.Money{Amount: 1000, Currency: "USD"},
 			}
 			result1, err := txRepo.Create(ctx, product1)
 			if err != nil {
@@ -257,7 +528,7 @@ func TestRepositoryTransactions_ComplexScenarios_Integration(t *testing.T) {
 			// Create second product
 			product2 := &model.Product{
 				Name:  "Product 2",
-				Price: 20.0,
+				Price: model.Money{Amount: 2000, Currency: "USD"},
 			}
 			result2, err := txRepo.Create(ctx, product2)
 			if err != nil {
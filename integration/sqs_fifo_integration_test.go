@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqspkg "github.com/iyhunko/microservices-with-sqs/internal/sqs"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SetupTestSQS starts LocalStack with the queues of local/sqs/init-localstack.sh and returns a client for it.
+// The container is purged when the test ends.
+func SetupTestSQS(t *testing.T) *sqs.Client {
+	t.Helper()
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = 120 * time.Second
+
+	initScript, err := filepath.Abs("../local/sqs/init-localstack.sh")
+	require.NoError(t, err)
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "localstack/localstack",
+		Tag:        "latest",
+		Env:        []string{"SERVICES=sqs"},
+		Mounts:     []string{initScript + ":/etc/localstack/init/ready.d/init-localstack.sh"},
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		t.Fatalf("Could not start resource: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := pool.Purge(resource); err != nil {
+			t.Errorf("Could not purge resource: %s", err)
+		}
+	})
+	if err := resource.Expire(120); err != nil {
+		t.Fatalf("Could not set expiration: %s", err)
+	}
+
+	client, err := sqspkg.NewClient(context.Background(), "us-east-1", "http://"+resource.GetHostPort("4566/tcp"))
+	require.NoError(t, err)
+
+	// Wait for the init script to create the queues
+	if err := pool.Retry(func() error {
+		_, err := client.GetQueueUrl(context.Background(), &sqs.GetQueueUrlInput{QueueName: aws.String("test-queue.fifo")})
+		return err
+	}); err != nil {
+		t.Fatalf("Could not find the queues: %s", err)
+	}
+
+	return client
+}
+
+func TestPublisher_FIFOQueue_Integration(t *testing.T) {
+	client := SetupTestSQS(t)
+	ctx := context.Background()
+
+	queue, err := client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String("test-queue.fifo")})
+	require.NoError(t, err)
+	queueURL := aws.ToString(queue.QueueUrl)
+
+	// The queue is detected as a FIFO queue from its URL
+	publisher := sqspkg.NewPublisher(client, queueURL, false)
+	publish := func(action, eventID string) {
+		t.Helper()
+		msg := sqspkg.ProductMessage{Action: action, ProductID: "product-1", Name: "Laptop"}
+		require.NoError(t, publisher.PublishProductMessage(ctx, msg, msg.ProductID, eventID))
+	}
+	publish("created", "event-1")
+	publish("updated", "event-2")
+	publish("created", "event-1") // published again after a crash
+	publish("deleted", "event-3")
+
+	var actions []string
+	deadline := time.Now().Add(30 * time.Second)
+	for len(actions) < 3 && time.Now().Before(deadline) {
+		result, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queueURL),
+			MaxNumberOfMessages: 10,
+			WaitTimeSeconds:     1,
+		})
+		require.NoError(t, err)
+		for _, message := range result.Messages {
+			var msg sqspkg.ProductMessage
+			require.NoError(t, json.Unmarshal([]byte(aws.ToString(message.Body)), &msg))
+			actions = append(actions, msg.Action)
+			_, err := client.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: aws.String(queueURL), ReceiptHandle: message.ReceiptHandle})
+			require.NoError(t, err)
+		}
+	}
+
+	assert.Equal(t, []string{"created", "updated", "deleted"}, actions)
+
+	// Nothing else is left: the duplicate was dropped
+	result, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String(queueURL), WaitTimeSeconds: 1})
+	require.NoError(t, err)
+	assert.Empty(t, result.Messages)
+}
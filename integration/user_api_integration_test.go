@@ -0,0 +1,167 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
+	"github.com/iyhunko/microservices-with-sqs/internal/config"
+	httpAPI "github.com/iyhunko/microservices-with-sqs/internal/http"
+	"github.com/iyhunko/microservices-with-sqs/internal/http/controller"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAPI_Integration(t *testing.T) {
+	testDB := SetupTestDB(t)
+	defer testDB.Cleanup(t)
+
+	tokens := auth.NewTokenSigner("test-jwt-secret", time.Hour)
+	userService := service.NewUserService(testDB.DB, reposql.NewUserRepository(testDB.DB), tokens)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	userCtr := controller.NewUserController(userService)
+	cfg := &config.Config{}
+	policy, err := auth.LoadPolicy("../rbac_policy.json")
+	require.NoError(t, err)
+	apiKeyService := service.NewAPIKeyService(testDB.DB, reposql.NewAPIKeyRepository(testDB.DB), policy)
+	access := httpAPI.AccessControl{Verifier: tokens, APIKeys: apiKeyService, Policy: policy, Users: userService}
+	httpAPI.InitRouter(cfg, access, userCtr, router, nil, nil, nil, nil, nil, nil)
+
+	do := func(t *testing.T, method, target, token string, payload interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+	doJSON := func(t *testing.T, target string, payload interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		return do(t, http.MethodPost, target, "", payload)
+	}
+	login := func(t *testing.T, email, password string) string {
+		t.Helper()
+		w, response := doJSON(t, "/auth/login", map[string]interface{}{"email": email, "password": password})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return response["access_token"].(string)
+	}
+
+	register := map[string]interface{}{"email": "alice@example.com", "password": "correct horse", "name": "Alice", "region": "EU"}
+
+	t.Run("register and log in", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w, user := doJSON(t, "/users/register", register)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, "alice@example.com", user["email"])
+		assert.Equal(t, "viewer", user["role"])
+		assert.NotContains(t, user, "password")
+
+		// The password is stored as a bcrypt hash
+		var stored string
+		require.NoError(t, testDB.DB.QueryRow("SELECT password FROM users WHERE email = $1", "alice@example.com").Scan(&stored))
+		assert.NotEqual(t, "correct horse", stored)
+		assert.Regexp(t, `^\$2[aby]\$`, stored)
+
+		// Registration stores a user.registered event in the outbox
+		var events int
+		require.NoError(t, testDB.DB.QueryRow("SELECT COUNT(*) FROM events WHERE event_type = 'user.registered'").Scan(&events))
+		assert.Equal(t, 1, events)
+
+		w, session := doJSON(t, "/auth/login", map[string]interface{}{"email": "Alice@Example.com", "password": "correct horse"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "Bearer", session["token_type"])
+		claims, err := tokens.Verify(session["access_token"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, user["id"], claims.Subject)
+	})
+
+	t.Run("duplicate email", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w, _ := doJSON(t, "/users/register", register)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		duplicate := map[string]interface{}{"email": "ALICE@example.com", "password": "another secret", "name": "Alice 2"}
+		w, _ = doJSON(t, "/users/register", duplicate)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("invalid registration", func(t *testing.T) {
+		w, _ := doJSON(t, "/users/register", map[string]interface{}{"email": "not-an-email", "password": "correct horse", "name": "Alice"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, _ = doJSON(t, "/users/register", map[string]interface{}{"email": "bob@example.com", "password": "short", "name": "Bob"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w, _ := doJSON(t, "/users/register", register)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		w, _ = doJSON(t, "/auth/login", map[string]interface{}{"email": "alice@example.com", "password": "wrong password"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w, _ = doJSON(t, "/auth/login", map[string]interface{}{"email": "nobody@example.com", "password": "correct horse"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("role and status changes by an admin", func(t *testing.T) {
+		testDB.TruncateTables(t)
+
+		w, alice := doJSON(t, "/users/register", register)
+		require.Equal(t, http.StatusCreated, w.Code)
+		w, bob := doJSON(t, "/users/register", map[string]interface{}{"email": "bob@example.com", "password": "battery staple", "name": "Bob"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		aliceToken := login(t, "alice@example.com", "correct horse")
+		bobToken := login(t, "bob@example.com", "battery staple")
+
+		// Viewers may not manage users
+		w, _ = do(t, http.MethodGet, "/users/"+bob["id"].(string), aliceToken, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// Promote Alice directly in the database, as for the first admin; her existing token picks it up
+		_, err := testDB.DB.Exec("UPDATE users SET role = 'admin' WHERE email = 'alice@example.com'")
+		require.NoError(t, err)
+
+		w, _ = do(t, http.MethodPatch, "/users/"+bob["id"].(string), aliceToken, map[string]interface{}{"role": "owner"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, updated := do(t, http.MethodPatch, "/users/"+bob["id"].(string), aliceToken, map[string]interface{}{"status": "suspended"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "suspended", updated["status"])
+		assert.Equal(t, "viewer", updated["role"])
+
+		var actor string
+		require.NoError(t, testDB.DB.QueryRow("SELECT actor FROM audit_log WHERE resource_type = 'user' AND resource_id = $1", bob["id"]).Scan(&actor))
+		assert.Equal(t, alice["id"], actor)
+
+		// Suspended users can neither log in nor use their existing tokens
+		w, _ = doJSON(t, "/auth/login", map[string]interface{}{"email": "bob@example.com", "password": "battery staple"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = do(t, http.MethodGet, "/users/"+alice["id"].(string), bobToken, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w, _ = do(t, http.MethodGet, "/users/00000000-0000-0000-0000-000000000001", aliceToken, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
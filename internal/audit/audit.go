@@ -0,0 +1,61 @@
+// Package audit describes changes made through the API for the audit log.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+)
+
+// AnonymousActor is recorded as the actor of changes made without an authenticated user.
+const AnonymousActor = "anonymous"
+
+// Audit actions recorded for changed resources.
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionRequeue = "requeue"
+)
+
+// Metadata describes who made a change and through which request.
+type Metadata struct {
+	Actor     string
+	RequestID string
+	ClientIP  string
+}
+
+type metadataKey struct{}
+
+// WithMetadata returns a copy of ctx carrying the given audit metadata.
+func WithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// FromContext returns the audit metadata carried by ctx. Changes without a known actor are made by AnonymousActor.
+func FromContext(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(metadataKey{}).(Metadata)
+	if metadata.Actor == "" {
+		metadata.Actor = AnonymousActor
+	}
+	return metadata
+}
+
+// NewEntry builds an audit log entry for a change of a resource made in the request described by ctx.
+// before and after are JSON snapshots of the resource, left empty when the resource did not exist before or after the change.
+func NewEntry(ctx context.Context, action, resourceType string, resourceID uuid.UUID, before, after json.RawMessage) *model.AuditLogEntry {
+	metadata := FromContext(ctx)
+	return &model.AuditLogEntry{
+		Actor:        metadata.Actor,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Before:       before,
+		After:        after,
+		RequestID:    metadata.RequestID,
+		ClientIP:     metadata.ClientIP,
+	}
+}
@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Run("defaults to the anonymous actor", func(t *testing.T) {
+		assert.Equal(t, Metadata{Actor: AnonymousActor}, FromContext(context.Background()))
+	})
+
+	t.Run("returns the stored metadata", func(t *testing.T) {
+		metadata := Metadata{Actor: "alice", RequestID: "req-1", ClientIP: "203.0.113.7"}
+		assert.Equal(t, metadata, FromContext(WithMetadata(context.Background(), metadata)))
+	})
+}
+
+func TestNewEntry(t *testing.T) {
+	ctx := WithMetadata(context.Background(), Metadata{RequestID: "req-1", ClientIP: "203.0.113.7"})
+	resourceID := uuid.New()
+
+	entry := NewEntry(ctx, ActionCreate, "product", resourceID, nil, json.RawMessage(`{"name":"Keyboard"}`))
+
+	assert.Equal(t, AnonymousActor, entry.Actor)
+	assert.Equal(t, ActionCreate, entry.Action)
+	assert.Equal(t, "product", entry.ResourceType)
+	assert.Equal(t, resourceID, entry.ResourceID)
+	assert.Nil(t, entry.Before)
+	assert.JSONEq(t, `{"name":"Keyboard"}`, string(entry.After))
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, "203.0.113.7", entry.ClientIP)
+}
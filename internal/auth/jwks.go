@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Verifier verifies access tokens and returns their claims.
+type Verifier interface {
+	Verify(token string) (*Claims, error)
+}
+
+// Verifiers is a Verifier that accepts a token verified by any of its verifiers.
+type Verifiers []Verifier
+
+// Verify returns the claims of the first verifier that accepts the token. A token that was rejected by all
+// verifiers is reported as ErrTokenExpired if one of them found it expired and as ErrInvalidToken otherwise.
+func (vs Verifiers) Verify(token string) (*Claims, error) {
+	err := ErrInvalidToken
+	for _, v := range vs {
+		claims, verifyErr := v.Verify(token)
+		if verifyErr == nil {
+			return claims, nil
+		}
+		if errors.Is(verifyErr, ErrTokenExpired) {
+			err = verifyErr
+		}
+	}
+	return nil, err
+}
+
+// ExternalSubjectPrefix starts the subject of every token verified by a KeySet, so that users of an external
+// identity provider are never mistaken for users of this service with the same ID.
+const ExternalSubjectPrefix = "external:"
+
+// jsonWebKey is an RSA public key of a JSON Web Key Set (RFC 7517).
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// jwtHeader is the JOSE header of a JSON Web Token.
+type jwtHeader struct {
+	Alg   string `json:"alg"`
+	KeyID string `json:"kid"`
+}
+
+// registeredClaims are the claims of a token that tell whom it was issued by and for.
+type registeredClaims struct {
+	Issuer   string   `json:"iss"`
+	Audience audience `json:"aud"`
+}
+
+// audience is the aud claim of a token, which holds either a single value or a list of them.
+type audience []string
+
+// UnmarshalJSON accepts both forms of the aud claim.
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// KeySet verifies JSON Web Tokens signed with RSA-SHA256 (RS256) by the keys of a JSON Web Key Set,
+// e.g. tokens issued by an external identity provider. Only tokens of the expected issuer and audience are
+// accepted, so that tokens the provider signs for other applications are not credentials of this service.
+type KeySet struct {
+	keys     map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// LoadJWKS reads a JSON Web Key Set from a local file. Only RSA signing keys are used; other keys are skipped.
+func LoadJWKS(path, issuer, audience string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	return ParseJWKS(data, issuer, audience)
+}
+
+// ParseJWKS parses a JSON Web Key Set whose keys verify tokens of the given issuer and audience.
+// Only RSA signing keys are used; other keys are skipped.
+func ParseJWKS(data []byte, issuer, audience string) (*KeySet, error) {
+	if issuer == "" || audience == "" {
+		return nil, errors.New("JWKS tokens require an expected issuer and audience")
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") || (jwk.Alg != "" && jwk.Alg != "RS256") {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %q: %w", jwk.KeyID, err)
+		}
+		keys[jwk.KeyID] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no RSA signing keys")
+	}
+
+	return &KeySet{keys: keys, issuer: issuer, audience: audience, now: time.Now}, nil
+}
+
+// Verify checks the RS256 signature, expiry, issuer and audience of an access token and returns its claims with
+// the subject prefixed by ExternalSubjectPrefix. The signing key is looked up by the kid header; a token without
+// kid is accepted only when the set holds a single key.
+func (ks *KeySet) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "RS256" {
+		return nil, ErrInvalidToken
+	}
+	key, ok := ks.keys[header.KeyID]
+	if !ok && header.KeyID == "" && len(ks.keys) == 1 {
+		for _, k := range ks.keys {
+			key, ok = k, true
+		}
+	}
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	var registered registeredClaims
+	if err := decodeSegment(parts[1], &registered); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if registered.Issuer != ks.issuer || !slices.Contains(registered.Audience, ks.audience) {
+		return nil, ErrInvalidToken
+	}
+	if ks.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	claims.Subject = ExternalSubjectPrefix + claims.Subject
+	claims.External = true
+
+	return &claims, nil
+}
+
+func (jwk jsonWebKey) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token into v.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
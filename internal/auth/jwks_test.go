@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+const (
+	testIssuer   = "https://idp.example.com/"
+	testAudience = "product-service"
+)
+
+// providerClaims are the claims of a token of an identity provider, including whom it was issued by and for.
+type providerClaims struct {
+	Claims
+	Issuer   string      `json:"iss,omitempty"`
+	Audience interface{} `json:"aud,omitempty"`
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, header map[string]string, claims providerClaims) string {
+	t.Helper()
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func jwksOf(keys map[string]*rsa.PrivateKey) []byte {
+	var jwks []string
+	for kid, key := range keys {
+		jwks = append(jwks, fmt.Sprintf(`{"kty":"RSA","kid":%q,"use":"sig","alg":"RS256","n":%q,"e":%q}`, kid,
+			base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())))
+	}
+	data := `{"keys":[{"kty":"oct","kid":"hmac","k":"c2VjcmV0"}`
+	for _, jwk := range jwks {
+		data += "," + jwk
+	}
+	return []byte(data + "]}")
+}
+
+func TestKeySet(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	keys, err := ParseJWKS(jwksOf(map[string]*rsa.PrivateKey{"key-1": key}), testIssuer, testAudience)
+	require.NoError(t, err)
+	keys.now = func() time.Time { return now }
+
+	claims := providerClaims{
+		Claims:   Claims{Subject: "user-1", Email: "alice@example.com", Role: model.UserRoleAdmin, ExpiresAt: now.Add(time.Hour).Unix()},
+		Issuer:   testIssuer,
+		Audience: testAudience,
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		token := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-1"}, claims)
+
+		verified, err := keys.Verify(token)
+		require.NoError(t, err)
+		assert.Equal(t, ExternalSubjectPrefix+"user-1", verified.Subject)
+		assert.Equal(t, model.UserRoleAdmin, verified.Role)
+		assert.True(t, verified.External)
+	})
+
+	t.Run("audience list", func(t *testing.T) {
+		listed := claims
+		listed.Audience = []string{"other-service", testAudience}
+		token := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-1"}, listed)
+
+		_, err := keys.Verify(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("other issuer", func(t *testing.T) {
+		other := claims
+		other.Issuer = "https://other.example.com/"
+		token := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-1"}, other)
+
+		_, err := keys.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("other audience", func(t *testing.T) {
+		other := claims
+		other.Audience = []string{"other-service"}
+		token := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-1"}, other)
+
+		_, err := keys.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("missing audience", func(t *testing.T) {
+		missing := claims
+		missing.Audience = nil
+		token := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-1"}, missing)
+
+		_, err := keys.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("token without kid and a single key", func(t *testing.T) {
+		token := signRS256(t, key, map[string]string{"alg": "RS256"}, claims)
+
+		_, err := keys.Verify(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown kid", func(t *testing.T) {
+		token := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-2"}, claims)
+
+		_, err := keys.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		token := signRS256(t, other, map[string]string{"alg": "RS256", "kid": "key-1"}, claims)
+
+		_, err := keys.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		expired := claims
+		expired.ExpiresAt = now.Unix()
+		token := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-1"}, expired)
+
+		_, err := keys.Verify(token)
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+
+	t.Run("HS256 token", func(t *testing.T) {
+		token, _, err := NewTokenSigner("secret", time.Hour).Issue(&model.User{ID: uuid.New()})
+		require.NoError(t, err)
+
+		_, err = keys.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("no RSA keys", func(t *testing.T) {
+		_, err := ParseJWKS([]byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`), testIssuer, testAudience)
+		assert.Error(t, err)
+	})
+
+	t.Run("no expected issuer", func(t *testing.T) {
+		_, err := ParseJWKS(jwksOf(map[string]*rsa.PrivateKey{"key-1": key}), "", testAudience)
+		assert.Error(t, err)
+	})
+}
+
+func TestVerifiers(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keys, err := ParseJWKS(jwksOf(map[string]*rsa.PrivateKey{"key-1": key}), testIssuer, testAudience)
+	require.NoError(t, err)
+	signer := NewTokenSigner("secret", time.Hour)
+	verifier := Verifiers{signer, keys}
+
+	t.Run("accepts tokens of either verifier", func(t *testing.T) {
+		hs256, _, err := signer.Issue(&model.User{ID: uuid.New()})
+		require.NoError(t, err)
+		rs256 := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-1"},
+			providerClaims{Claims: Claims{Subject: "user-1", ExpiresAt: time.Now().Add(time.Hour).Unix()}, Issuer: testIssuer, Audience: testAudience})
+
+		_, err = verifier.Verify(hs256)
+		assert.NoError(t, err)
+		_, err = verifier.Verify(rs256)
+		assert.NoError(t, err)
+	})
+
+	t.Run("reports expired tokens", func(t *testing.T) {
+		token := signRS256(t, key, map[string]string{"alg": "RS256", "kid": "key-1"},
+			providerClaims{Claims: Claims{Subject: "user-1", ExpiresAt: time.Now().Add(-time.Minute).Unix()}, Issuer: testIssuer, Audience: testAudience})
+
+		_, err := verifier.Verify(token)
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+
+	t.Run("rejects unknown tokens", func(t *testing.T) {
+		_, err := verifier.Verify("not.a.token")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Permissions granted to roles by a Policy.
+const (
+	PermissionProductsWrite   = "products:write"
+	PermissionCategoriesWrite = "categories:write"
+	PermissionInventoryWrite  = "inventory:write"
+	PermissionAuditRead       = "audit:read"
+	PermissionUsersRead       = "users:read"
+	PermissionUsersWrite      = "users:write"
+	PermissionEventsRead      = "events:read"
+	PermissionEventsWrite     = "events:write"
+	PermissionAPIKeysWrite    = "api-keys:write"
+
+	// PermissionAll grants every permission.
+	PermissionAll = "*"
+)
+
+// knownPermissions are the permissions a policy file may grant.
+var knownPermissions = map[string]bool{
+	PermissionProductsWrite:   true,
+	PermissionCategoriesWrite: true,
+	PermissionInventoryWrite:  true,
+	PermissionAuditRead:       true,
+	PermissionUsersRead:       true,
+	PermissionUsersWrite:      true,
+	PermissionEventsRead:      true,
+	PermissionEventsWrite:     true,
+	PermissionAPIKeysWrite:    true,
+	PermissionAll:             true,
+}
+
+// IsPermission reports whether permission is one of the individual permissions, i.e. known and not PermissionAll.
+func IsPermission(permission string) bool {
+	return knownPermissions[permission] && permission != PermissionAll
+}
+
+// Policy maps roles to the permissions they are granted. Roles that are not in the policy have no permissions.
+type Policy struct {
+	roles map[string]map[string]bool
+}
+
+// LoadPolicy reads a policy from a JSON file of the form {"roles": {"<role>": ["<permission>", ...]}}.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses a JSON policy. Unknown permissions are rejected so that typos do not silently deny access.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var file struct {
+		Roles map[string][]string `json:"roles"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode policy: %w", err)
+	}
+
+	roles := make(map[string]map[string]bool, len(file.Roles))
+	for role, permissions := range file.Roles {
+		granted := make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			if !knownPermissions[permission] {
+				return nil, fmt.Errorf("unknown permission %q for role %q", permission, role)
+			}
+			granted[permission] = true
+		}
+		roles[role] = granted
+	}
+
+	return &Policy{roles: roles}, nil
+}
+
+// Allows reports whether the role is granted the permission.
+func (p *Policy) Allows(role, permission string) bool {
+	granted := p.roles[role]
+	return granted[permission] || granted[PermissionAll]
+}
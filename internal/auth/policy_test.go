@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`{"roles": {"admin": ["*"], "editor": ["products:write"], "viewer": []}}`))
+	require.NoError(t, err)
+
+	assert.True(t, policy.Allows("admin", PermissionUsersWrite))
+	assert.True(t, policy.Allows("editor", PermissionProductsWrite))
+	assert.False(t, policy.Allows("editor", PermissionAuditRead))
+	assert.False(t, policy.Allows("viewer", PermissionProductsWrite))
+	assert.False(t, policy.Allows("unknown", PermissionProductsWrite))
+	assert.False(t, policy.Allows("", PermissionProductsWrite))
+}
+
+func TestParsePolicy_UnknownPermission(t *testing.T) {
+	_, err := ParsePolicy([]byte(`{"roles": {"editor": ["product:write"]}}`))
+	assert.ErrorContains(t, err, "product:write")
+}
+
+func TestLoadPolicy_DefaultFile(t *testing.T) {
+	policy, err := LoadPolicy("../../rbac_policy.json")
+	require.NoError(t, err)
+
+	assert.True(t, policy.Allows(model.UserRoleAdmin, PermissionAuditRead))
+	assert.True(t, policy.Allows(model.UserRoleEditor, PermissionProductsWrite))
+	assert.True(t, policy.Allows(model.UserRoleEditor, PermissionCategoriesWrite))
+	assert.False(t, policy.Allows(model.UserRoleEditor, PermissionUsersWrite))
+	assert.False(t, policy.Allows(model.UserRoleViewer, PermissionProductsWrite))
+	assert.False(t, policy.Allows(model.UserRoleViewer, PermissionCategoriesWrite))
+}
+
+func TestIsPermission(t *testing.T) {
+	assert.True(t, IsPermission(PermissionProductsWrite))
+	assert.False(t, IsPermission(PermissionAll))
+	assert.False(t, IsPermission("products:delete"))
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenSigner(t *testing.T) {
+	user := &model.User{ID: uuid.New(), Email: "alice@example.com", Role: model.UserRoleViewer}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	signer := NewTokenSigner("secret", time.Hour)
+	signer.now = func() time.Time { return now }
+
+	t.Run("round trip", func(t *testing.T) {
+		token, issued, err := signer.Issue(user)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(time.Hour).Unix(), issued.ExpiresAt)
+
+		claims, err := signer.Verify(token)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID.String(), claims.Subject)
+		assert.Equal(t, "alice@example.com", claims.Email)
+		assert.Equal(t, model.UserRoleViewer, claims.Role)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		token, _, err := signer.Issue(user)
+		require.NoError(t, err)
+
+		later := NewTokenSigner("secret", time.Hour)
+		later.now = func() time.Time { return now.Add(time.Hour) }
+		_, err = later.Verify(token)
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+
+	t.Run("other secret", func(t *testing.T) {
+		token, _, err := signer.Issue(user)
+		require.NoError(t, err)
+
+		_, err = NewTokenSigner("other", time.Hour).Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("tampered claims", func(t *testing.T) {
+		token, _, err := signer.Issue(user)
+		require.NoError(t, err)
+
+		parts := strings.Split(token, ".")
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"x","role":"admin","exp":9999999999}`))
+		_, err = signer.Verify(strings.Join(parts, "."))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("unsigned token", func(t *testing.T) {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+		payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"x","exp":9999999999}`))
+		_, err := signer.Verify(header + "." + payload + ".")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
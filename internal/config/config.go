@@ -6,6 +6,8 @@ import (
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -55,6 +57,106 @@ const (
 
 	// SQSQueueURLEnv is the environment variable for SQS queue URL.
 	SQSQueueURLEnv = "SQS_QUEUE_URL"
+
+	// SQSFIFOQueueEnv is the environment variable for whether the SQS queue is a FIFO queue. Queues whose URL
+	// ends in ".fifo" are FIFO queues regardless.
+	SQSFIFOQueueEnv = "SQS_FIFO_QUEUE"
+
+	// ProductPurgeRetentionEnv is the environment variable for how long soft-deleted products are kept before purging.
+	ProductPurgeRetentionEnv = "PRODUCT_PURGE_RETENTION"
+
+	// ProductPurgeIntervalEnv is the environment variable for how often soft-deleted products are purged.
+	ProductPurgeIntervalEnv = "PRODUCT_PURGE_INTERVAL"
+
+	// IdempotencyKeyTTLEnv is the environment variable for how long idempotency keys are remembered.
+	IdempotencyKeyTTLEnv = "IDEMPOTENCY_KEY_TTL"
+
+	// IdempotencyCleanupIntervalEnv is the environment variable for how often expired idempotency keys are removed.
+	IdempotencyCleanupIntervalEnv = "IDEMPOTENCY_CLEANUP_INTERVAL"
+
+	// PageTokenSecretEnv is the environment variable for the secret used to sign pagination tokens.
+	PageTokenSecretEnv = "PAGE_TOKEN_SECRET"
+
+	// PageTokenTTLEnv is the environment variable for how long pagination tokens stay valid; 0 disables expiry.
+	PageTokenTTLEnv = "PAGE_TOKEN_TTL"
+
+	// InventoryLowStockThresholdEnv is the environment variable for the available stock at or below which
+	// an inventory.low_stock event is emitted.
+	InventoryLowStockThresholdEnv = "INVENTORY_LOW_STOCK_THRESHOLD"
+
+	// JWTSecretEnv is the environment variable for the secret used to sign access tokens.
+	JWTSecretEnv = "JWT_SECRET"
+
+	// JWTTTLEnv is the environment variable for how long access tokens stay valid.
+	JWTTTLEnv = "JWT_TTL"
+
+	// JWTJWKSFileEnv is the environment variable for the path of a JSON Web Key Set file whose RSA keys
+	// verify RS256 access tokens in addition to the tokens signed with JWTSecretEnv.
+	JWTJWKSFileEnv = "JWT_JWKS_FILE"
+
+	// JWTIssuerEnv is the environment variable for the issuer (iss claim) that RS256 access tokens must carry.
+	JWTIssuerEnv = "JWT_ISSUER"
+
+	// JWTAudienceEnv is the environment variable for the audience (aud claim) that RS256 access tokens must carry.
+	JWTAudienceEnv = "JWT_AUDIENCE"
+
+	// AuthPublicRoutesEnv is the environment variable for a comma-separated list of "<METHOD> <route>" entries
+	// that are served without an access token.
+	AuthPublicRoutesEnv = "AUTH_PUBLIC_ROUTES"
+
+	// RBACPolicyFileEnv is the environment variable for the path of the JSON file mapping roles to permissions.
+	RBACPolicyFileEnv = "RBAC_POLICY_FILE"
+
+	// EventLeaseDurationEnv is the environment variable for how long an event worker holds the pending events
+	// it claimed before other workers may claim them again.
+	EventLeaseDurationEnv = "EVENT_LEASE_DURATION"
+
+	// EventMaxAttemptsEnv is the environment variable for how many times publishing an event is attempted
+	// before it is marked dead.
+	EventMaxAttemptsEnv = "EVENT_MAX_ATTEMPTS"
+
+	// EventRetryBaseDelayEnv is the environment variable for the delay before the first retry of an event,
+	// which doubles with every further attempt.
+	EventRetryBaseDelayEnv = "EVENT_RETRY_BASE_DELAY"
+
+	// EventRetryMaxDelayEnv is the environment variable for the longest delay between retries of an event.
+	EventRetryMaxDelayEnv = "EVENT_RETRY_MAX_DELAY"
+
+	// DefaultPageTokenTTL is the default lifetime of pagination tokens.
+	DefaultPageTokenTTL = 24 * time.Hour
+
+	// DefaultProductPurgeRetention is the default retention period for soft-deleted products.
+	DefaultProductPurgeRetention = 30 * 24 * time.Hour
+
+	// DefaultProductPurgeInterval is the default interval between purge runs.
+	DefaultProductPurgeInterval = time.Hour
+
+	// DefaultIdempotencyKeyTTL is the default lifetime of idempotency keys.
+	DefaultIdempotencyKeyTTL = 24 * time.Hour
+
+	// DefaultIdempotencyCleanupInterval is the default interval between idempotency key cleanup runs.
+	DefaultIdempotencyCleanupInterval = time.Hour
+
+	// DefaultInventoryLowStockThreshold is the default low stock threshold.
+	DefaultInventoryLowStockThreshold = 10
+
+	// DefaultJWTTTL is the default lifetime of access tokens.
+	DefaultJWTTTL = time.Hour
+
+	// DefaultRBACPolicyFile is the default path of the access control policy file.
+	DefaultRBACPolicyFile = "rbac_policy.json"
+
+	// DefaultEventLeaseDuration is the default lease of the pending events claimed by an event worker.
+	DefaultEventLeaseDuration = time.Minute
+
+	// DefaultEventMaxAttempts is the default number of attempts to publish an event.
+	DefaultEventMaxAttempts = 10
+
+	// DefaultEventRetryBaseDelay is the default delay before the first retry of an event.
+	DefaultEventRetryBaseDelay = 2 * time.Second
+
+	// DefaultEventRetryMaxDelay is the default longest delay between retries of an event.
+	DefaultEventRetryMaxDelay = 15 * time.Minute
 )
 
 var (
@@ -64,18 +166,62 @@ var (
 
 // Config represents the application configuration.
 type Config struct {
-	DebugMode     bool
-	Database      DB
-	HTTPServer    Server
-	MetricsServer Server
-	AWS           AWSConfig
+	DebugMode       bool
+	Database        DB
+	HTTPServer      Server
+	MetricsServer   Server
+	AWS             AWSConfig
+	ProductPurge    PurgeConfig
+	IdempotencyKeys PurgeConfig
+	Pagination      PaginationConfig
+	Inventory       InventoryConfig
+	Auth            AuthConfig
+	Events          EventsConfig
+}
+
+// EventsConfig represents settings of the workers publishing the events of the outbox table.
+type EventsConfig struct {
+	LeaseDuration  time.Duration
+	MaxAttempts    int64
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// AuthConfig represents settings of the access tokens issued on login, of the routes that require them
+// and of the policy granting permissions to roles.
+type AuthConfig struct {
+	JWTSecret    string
+	TokenTTL     time.Duration
+	JWKSFile     string
+	Issuer       string
+	Audience     string
+	PublicRoutes []string
+	PolicyFile   string
+}
+
+// InventoryConfig represents settings of the stock level tracking.
+type InventoryConfig struct {
+	LowStockThreshold int64
+}
+
+// PaginationConfig represents settings of the signed pagination tokens.
+type PaginationConfig struct {
+	TokenSecret string
+	TokenTTL    time.Duration
+}
+
+// PurgeConfig represents settings of a background job that hard-deletes rows older than a retention period.
+type PurgeConfig struct {
+	Retention time.Duration
+	Interval  time.Duration
 }
 
 // AWSConfig represents AWS-specific configuration settings.
 type AWSConfig struct {
-	Region      string
-	Endpoint    string
-	SQSQueueURL string
+	Region       string
+	Endpoint     string
+	SQSQueueURL  string
+	SQSFIFOQueue bool
 }
 
 // DB represents database configuration settings.
@@ -140,6 +286,40 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid port number: %w", err)
 	}
 
+	// Validate purge settings
+	if c.ProductPurge.Retention <= 0 || c.ProductPurge.Interval <= 0 {
+		return fmt.Errorf("%s and %s must be positive durations", ProductPurgeRetentionEnv, ProductPurgeIntervalEnv)
+	}
+	if c.IdempotencyKeys.Retention <= 0 || c.IdempotencyKeys.Interval <= 0 {
+		return fmt.Errorf("%s and %s must be positive durations", IdempotencyKeyTTLEnv, IdempotencyCleanupIntervalEnv)
+	}
+
+	// Validate pagination settings
+	if err := allNonEmpty(map[string]string{
+		PageTokenSecretEnv: c.Pagination.TokenSecret,
+	}); err != nil {
+		return fmt.Errorf("pagination configuration incomplete: %w", err)
+	}
+	if c.Pagination.TokenTTL < 0 {
+		return fmt.Errorf("%s must not be negative", PageTokenTTLEnv)
+	}
+
+	// Validate inventory settings
+	if c.Inventory.LowStockThreshold < 0 {
+		return fmt.Errorf("%s must not be negative", InventoryLowStockThresholdEnv)
+	}
+
+	// Validate event worker settings
+	if c.Events.LeaseDuration <= 0 {
+		return fmt.Errorf("%s must be a positive duration", EventLeaseDurationEnv)
+	}
+	if c.Events.MaxAttempts < 1 {
+		return fmt.Errorf("%s must be at least 1", EventMaxAttemptsEnv)
+	}
+	if c.Events.RetryBaseDelay <= 0 || c.Events.RetryMaxDelay < c.Events.RetryBaseDelay {
+		return fmt.Errorf("%s must be a positive duration not above %s", EventRetryBaseDelayEnv, EventRetryMaxDelayEnv)
+	}
+
 	// Validate AWS configuration
 	if err := allNonEmpty(map[string]string{
 		SQSQueueURLEnv: c.AWS.SQSQueueURL,
@@ -147,6 +327,29 @@ func (c *Config) validate() error {
 		return fmt.Errorf("AWS configuration incomplete: %w", err)
 	}
 
+	// Validate authentication settings
+	if err := allNonEmpty(map[string]string{
+		JWTSecretEnv: c.Auth.JWTSecret,
+	}); err != nil {
+		return fmt.Errorf("authentication configuration incomplete: %w", err)
+	}
+	if c.Auth.TokenTTL <= 0 {
+		return fmt.Errorf("%s must be a positive duration", JWTTTLEnv)
+	}
+	if c.Auth.JWKSFile != "" {
+		if err := allNonEmpty(map[string]string{
+			JWTIssuerEnv:   c.Auth.Issuer,
+			JWTAudienceEnv: c.Auth.Audience,
+		}); err != nil {
+			return fmt.Errorf("%s requires the expected issuer and audience: %w", JWTJWKSFileEnv, err)
+		}
+	}
+	for _, route := range c.Auth.PublicRoutes {
+		if fields := strings.Fields(route); len(fields) != 2 || !strings.HasPrefix(fields[1], "/") {
+			return fmt.Errorf("invalid route %q in %s, expected \"<METHOD> <route>\"", route, AuthPublicRoutesEnv)
+		}
+	}
+
 	return nil
 }
 
@@ -157,6 +360,38 @@ func getEnvAsBool(name string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvAsDuration(name string, defaultValue time.Duration) time.Duration {
+	if val, err := time.ParseDuration(os.Getenv(name)); err == nil {
+		return val
+	}
+	return defaultValue
+}
+
+func getEnvAsInt(name string, defaultValue int64) int64 {
+	if val, err := strconv.ParseInt(os.Getenv(name), 10, 64); err == nil {
+		return val
+	}
+	return defaultValue
+}
+
+func getEnv(name, defaultValue string) string {
+	if val := os.Getenv(name); val != "" {
+		return val
+	}
+	return defaultValue
+}
+
+// getEnvAsList splits a comma-separated environment variable into its trimmed, non-empty items.
+func getEnvAsList(name string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // ApplyEnvFile loads environment variables from the specified .env files.
 func ApplyEnvFile(files ...string) error {
 	err := godotenv.Load(files...)
@@ -194,9 +429,40 @@ func LoadFromEnv() (*Config, error) {
 			Port: os.Getenv(MetricsServerPortEnv),
 		},
 		AWS: AWSConfig{
-			Region:      os.Getenv(AWSRegionEnv),
-			Endpoint:    os.Getenv(AWSEndpointEnv),
-			SQSQueueURL: os.Getenv(SQSQueueURLEnv),
+			Region:       os.Getenv(AWSRegionEnv),
+			Endpoint:     os.Getenv(AWSEndpointEnv),
+			SQSQueueURL:  os.Getenv(SQSQueueURLEnv),
+			SQSFIFOQueue: getEnvAsBool(SQSFIFOQueueEnv, false),
+		},
+		ProductPurge: PurgeConfig{
+			Retention: getEnvAsDuration(ProductPurgeRetentionEnv, DefaultProductPurgeRetention),
+			Interval:  getEnvAsDuration(ProductPurgeIntervalEnv, DefaultProductPurgeInterval),
+		},
+		IdempotencyKeys: PurgeConfig{
+			Retention: getEnvAsDuration(IdempotencyKeyTTLEnv, DefaultIdempotencyKeyTTL),
+			Interval:  getEnvAsDuration(IdempotencyCleanupIntervalEnv, DefaultIdempotencyCleanupInterval),
+		},
+		Pagination: PaginationConfig{
+			TokenSecret: os.Getenv(PageTokenSecretEnv),
+			TokenTTL:    getEnvAsDuration(PageTokenTTLEnv, DefaultPageTokenTTL),
+		},
+		Inventory: InventoryConfig{
+			LowStockThreshold: getEnvAsInt(InventoryLowStockThresholdEnv, DefaultInventoryLowStockThreshold),
+		},
+		Auth: AuthConfig{
+			JWTSecret:    os.Getenv(JWTSecretEnv),
+			TokenTTL:     getEnvAsDuration(JWTTTLEnv, DefaultJWTTTL),
+			JWKSFile:     os.Getenv(JWTJWKSFileEnv),
+			Issuer:       os.Getenv(JWTIssuerEnv),
+			Audience:     os.Getenv(JWTAudienceEnv),
+			PublicRoutes: getEnvAsList(AuthPublicRoutesEnv),
+			PolicyFile:   getEnv(RBACPolicyFileEnv, DefaultRBACPolicyFile),
+		},
+		Events: EventsConfig{
+			LeaseDuration:  getEnvAsDuration(EventLeaseDurationEnv, DefaultEventLeaseDuration),
+			MaxAttempts:    getEnvAsInt(EventMaxAttemptsEnv, DefaultEventMaxAttempts),
+			RetryBaseDelay: getEnvAsDuration(EventRetryBaseDelayEnv, DefaultEventRetryBaseDelay),
+			RetryMaxDelay:  getEnvAsDuration(EventRetryMaxDelayEnv, DefaultEventRetryMaxDelay),
 		},
 	}
 
@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/iyhunko/microservices-with-sqs/internal/config"
 	"github.com/stretchr/testify/assert"
@@ -18,6 +19,10 @@ func TestLoadFromEnv(t *testing.T) {
 	t.Setenv(config.HTTPServerPortEnv, "8080")
 	t.Setenv(config.MetricsServerPortEnv, "9090")
 	t.Setenv(config.SQSQueueURLEnv, "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue")
+	t.Setenv(config.PageTokenSecretEnv, "test-secret")
+	t.Setenv(config.PageTokenTTLEnv, "1h")
+	t.Setenv(config.JWTSecretEnv, "jwt-secret")
+	t.Setenv(config.AuthPublicRoutesEnv, "POST /products/:id/inventory/reserve, POST /products/:id/inventory/release,")
 
 	conf, err := config.LoadFromEnv()
 	require.NoError(t, err, "loading config should not return error")
@@ -31,6 +36,63 @@ func TestLoadFromEnv(t *testing.T) {
 	assert.Equal(t, "8080", conf.HTTPServer.Port, "HTTP Server Port should be '8080'")
 	assert.Equal(t, "9090", conf.MetricsServer.Port, "Metrics Server Port should be '9090'")
 	assert.Equal(t, "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue", conf.AWS.SQSQueueURL, "SQS Queue URL should be set")
+	assert.False(t, conf.AWS.SQSFIFOQueue, "SQS FIFO queue should default to false")
+	assert.Equal(t, config.DefaultProductPurgeRetention, conf.ProductPurge.Retention, "Purge retention should default")
+	assert.Equal(t, config.DefaultProductPurgeInterval, conf.ProductPurge.Interval, "Purge interval should default")
+	assert.Equal(t, "test-secret", conf.Pagination.TokenSecret, "Page token secret should be set")
+	assert.Equal(t, time.Hour, conf.Pagination.TokenTTL, "Page token TTL should be set")
+	assert.Equal(t, int64(config.DefaultInventoryLowStockThreshold), conf.Inventory.LowStockThreshold, "Low stock threshold should default")
+	assert.Equal(t, "jwt-secret", conf.Auth.JWTSecret, "JWT secret should be set")
+	assert.Equal(t, config.DefaultJWTTTL, conf.Auth.TokenTTL, "JWT TTL should default")
+	assert.Equal(t, []string{"POST /products/:id/inventory/reserve", "POST /products/:id/inventory/release"},
+		conf.Auth.PublicRoutes, "Public routes should be split")
+	assert.Equal(t, config.DefaultRBACPolicyFile, conf.Auth.PolicyFile, "Policy file should default")
+	assert.Equal(t, config.DefaultEventLeaseDuration, conf.Events.LeaseDuration, "Event lease should default")
+	assert.Equal(t, int64(config.DefaultEventMaxAttempts), conf.Events.MaxAttempts, "Event max attempts should default")
+	assert.Equal(t, config.DefaultEventRetryBaseDelay, conf.Events.RetryBaseDelay, "Event retry base delay should default")
+	assert.Equal(t, config.DefaultEventRetryMaxDelay, conf.Events.RetryMaxDelay, "Event retry max delay should default")
+}
+
+func TestGetEnvAsDuration(t *testing.T) {
+	tests := []struct {
+		name         string
+		envValue     string
+		defaultValue time.Duration
+		want         time.Duration
+	}{
+		{"GetEnvAsDuration_Valid", "90m", time.Hour, 90 * time.Minute},
+		{"GetEnvAsDuration_Invalid", "invalid", time.Hour, time.Hour},
+		{"GetEnvAsDuration_Empty", "", time.Minute, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_ENV", tt.envValue)
+			got := config.GetEnvAsDuration("TEST_ENV", tt.defaultValue)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetEnvAsInt(t *testing.T) {
+	tests := []struct {
+		name         string
+		envValue     string
+		defaultValue int64
+		want         int64
+	}{
+		{"GetEnvAsInt_Valid", "25", 10, 25},
+		{"GetEnvAsInt_Invalid", "invalid", 10, 10},
+		{"GetEnvAsInt_Empty", "", 5, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_ENV", tt.envValue)
+			got := config.GetEnvAsInt("TEST_ENV", tt.defaultValue)
+			assert.Equal(t, tt.want, got)
+		})
+	}
 }
 
 func TestGetEnvAsBool(t *testing.T) {
@@ -110,6 +172,7 @@ func TestLoadFromEnv_MissingSQSQueueURL(t *testing.T) {
 	t.Setenv(config.DBPortEnv, "5432")
 	t.Setenv(config.HTTPServerPortEnv, "8080")
 	t.Setenv(config.MetricsServerPortEnv, "9090")
+	t.Setenv(config.PageTokenSecretEnv, "test-secret")
 	// Intentionally not setting SQSQueueURLEnv
 
 	conf, err := config.LoadFromEnv()
@@ -117,3 +180,97 @@ func TestLoadFromEnv_MissingSQSQueueURL(t *testing.T) {
 	assert.Nil(t, conf, "config should be nil when validation fails")
 	assert.Contains(t, err.Error(), "AWS configuration incomplete", "error should mention AWS configuration")
 }
+
+func TestLoadFromEnv_MissingPageTokenSecret(t *testing.T) {
+	t.Setenv(config.DBHostEnv, "localhost")
+	t.Setenv(config.DBUserEnv, "user")
+	t.Setenv(config.DBNameEnv, "testdb")
+	t.Setenv(config.DBPortEnv, "5432")
+	t.Setenv(config.HTTPServerPortEnv, "8080")
+	t.Setenv(config.MetricsServerPortEnv, "9090")
+	t.Setenv(config.SQSQueueURLEnv, "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue")
+	// Intentionally not setting PageTokenSecretEnv
+
+	conf, err := config.LoadFromEnv()
+	require.Error(t, err, "loading config should return error when page token secret is missing")
+	assert.Nil(t, conf, "config should be nil when validation fails")
+	assert.ErrorIs(t, err, config.ErrMissingConfig)
+	assert.Contains(t, err.Error(), config.PageTokenSecretEnv, "error should mention the missing key")
+}
+
+func TestLoadFromEnv_MissingJWTSecret(t *testing.T) {
+	t.Setenv(config.DBHostEnv, "localhost")
+	t.Setenv(config.DBUserEnv, "user")
+	t.Setenv(config.DBNameEnv, "testdb")
+	t.Setenv(config.DBPortEnv, "5432")
+	t.Setenv(config.HTTPServerPortEnv, "8080")
+	t.Setenv(config.MetricsServerPortEnv, "9090")
+	t.Setenv(config.SQSQueueURLEnv, "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue")
+	t.Setenv(config.PageTokenSecretEnv, "test-secret")
+	t.Setenv(config.JWTSecretEnv, "")
+	// Intentionally leaving JWTSecretEnv empty
+
+	conf, err := config.LoadFromEnv()
+	require.Error(t, err, "loading config should return error when the JWT secret is missing")
+	assert.Nil(t, conf, "config should be nil when validation fails")
+	assert.ErrorIs(t, err, config.ErrMissingConfig)
+	assert.Contains(t, err.Error(), config.JWTSecretEnv, "error should mention the missing key")
+}
+
+func TestLoadFromEnv_InvalidPublicRoute(t *testing.T) {
+	t.Setenv(config.DBHostEnv, "localhost")
+	t.Setenv(config.DBUserEnv, "user")
+	t.Setenv(config.DBNameEnv, "testdb")
+	t.Setenv(config.DBPortEnv, "5432")
+	t.Setenv(config.HTTPServerPortEnv, "8080")
+	t.Setenv(config.MetricsServerPortEnv, "9090")
+	t.Setenv(config.SQSQueueURLEnv, "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue")
+	t.Setenv(config.PageTokenSecretEnv, "test-secret")
+	t.Setenv(config.JWTSecretEnv, "jwt-secret")
+	t.Setenv(config.AuthPublicRoutesEnv, "/products")
+
+	conf, err := config.LoadFromEnv()
+	require.Error(t, err, "loading config should return error when a public route has no method")
+	assert.Nil(t, conf, "config should be nil when validation fails")
+	assert.Contains(t, err.Error(), config.AuthPublicRoutesEnv, "error should mention the invalid key")
+}
+
+func TestLoadFromEnv_InvalidEventRetryDelays(t *testing.T) {
+	t.Setenv(config.DBHostEnv, "localhost")
+	t.Setenv(config.DBUserEnv, "user")
+	t.Setenv(config.DBNameEnv, "testdb")
+	t.Setenv(config.DBPortEnv, "5432")
+	t.Setenv(config.HTTPServerPortEnv, "8080")
+	t.Setenv(config.MetricsServerPortEnv, "9090")
+	t.Setenv(config.SQSQueueURLEnv, "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue")
+	t.Setenv(config.PageTokenSecretEnv, "test-secret")
+	t.Setenv(config.JWTSecretEnv, "jwt-secret")
+	t.Setenv(config.EventRetryBaseDelayEnv, "1h")
+	t.Setenv(config.EventRetryMaxDelayEnv, "1m")
+
+	conf, err := config.LoadFromEnv()
+	require.Error(t, err, "loading config should return error when the base delay exceeds the max delay")
+	assert.Nil(t, conf, "config should be nil when validation fails")
+	assert.Contains(t, err.Error(), config.EventRetryBaseDelayEnv, "error should mention the invalid key")
+}
+
+func TestLoadFromEnv_JWKSWithoutIssuer(t *testing.T) {
+	t.Setenv(config.DBHostEnv, "localhost")
+	t.Setenv(config.DBUserEnv, "user")
+	t.Setenv(config.DBNameEnv, "testdb")
+	t.Setenv(config.DBPortEnv, "5432")
+	t.Setenv(config.HTTPServerPortEnv, "8080")
+	t.Setenv(config.MetricsServerPortEnv, "9090")
+	t.Setenv(config.SQSQueueURLEnv, "https://sqs.us-east-1.amazonaws.com/123456789012/test-queue")
+	t.Setenv(config.PageTokenSecretEnv, "test-secret")
+	t.Setenv(config.JWTSecretEnv, "jwt-secret")
+	t.Setenv(config.JWTJWKSFileEnv, "jwks.json")
+	t.Setenv(config.JWTIssuerEnv, "")
+	t.Setenv(config.JWTAudienceEnv, "product-service")
+
+	conf, err := config.LoadFromEnv()
+	require.Error(t, err, "loading config should return error when JWKS tokens have no expected issuer")
+	assert.Nil(t, conf, "config should be nil when validation fails")
+	assert.ErrorIs(t, err, config.ErrMissingConfig)
+	assert.Contains(t, err.Error(), config.JWTIssuerEnv, "error should mention the missing key")
+}
@@ -1,9 +1,19 @@
 package config
 
+import "time"
+
 func GetEnvAsBool(key string, defaultValue bool) bool {
 	return getEnvAsBool(key, defaultValue)
 }
 
+func GetEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	return getEnvAsDuration(key, defaultValue)
+}
+
+func GetEnvAsInt(key string, defaultValue int64) int64 {
+	return getEnvAsInt(key, defaultValue)
+}
+
 func AllNonEmpty(keyValues map[string]string) error {
 	return allNonEmpty(keyValues)
 }
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/http/middleware"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+)
+
+// APIKeyController handles HTTP requests for managing the API keys of the authenticated user.
+type APIKeyController struct {
+	apiKeyService *service.APIKeyService
+}
+
+// NewAPIKeyController creates a new APIKeyController with the given API key service.
+func NewAPIKeyController(apiKeyService *service.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService: apiKeyService}
+}
+
+// CreateAPIKeyRequest represents the request body for creating an API key. Keys without an expiry time
+// are valid until they are revoked.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=255"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyResponse represents the response body for an API key. The key itself is only included
+// in the response to its creation.
+type APIKeyResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Key        string   `json:"key,omitempty"`
+	KeyPrefix  string   `json:"key_prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	RevokedAt  *string  `json:"revoked_at"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// ListAPIKeysResponse represents the response body for listing API keys.
+type ListAPIKeysResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
+
+// CreateAPIKey handles the HTTP POST request for creating an API key of the authenticated user.
+func (kc *APIKeyController) CreateAPIKey(c *gin.Context) {
+	caller, ok := apiKeyCaller(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, plaintext, err := kc.apiKeyService.CreateAPIKey(c.Request.Context(), service.CreateAPIKeyInput{
+		OwnerID:   caller.id,
+		OwnerRole: caller.role,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		var notFoundErr *repository.NotFoundError
+		switch {
+		case errors.Is(err, service.ErrScopeNotAllowed), errors.Is(err, service.ErrExpiryInPast):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.As(err, &notFoundErr):
+			c.JSON(http.StatusForbidden, gin.H{"error": "API keys can only be created by registered users"})
+		default:
+			slog.Error("failed to create API key", slog.Any("err", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create API key"})
+		}
+		return
+	}
+
+	response := toAPIKeyResponse(key)
+	response.Key = plaintext
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListAPIKeys handles the HTTP GET request for listing the API keys of the authenticated user, newest first.
+func (kc *APIKeyController) ListAPIKeys(c *gin.Context) {
+	caller, ok := apiKeyCaller(c)
+	if !ok {
+		return
+	}
+
+	keys, err := kc.apiKeyService.ListAPIKeys(c.Request.Context(), caller.id)
+	if err != nil {
+		slog.Error("failed to list API keys", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list API keys"})
+		return
+	}
+
+	response := ListAPIKeysResponse{APIKeys: make([]APIKeyResponse, 0, len(keys))}
+	for _, key := range keys {
+		response.APIKeys = append(response.APIKeys, toAPIKeyResponse(key))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeAPIKey handles the HTTP DELETE request for revoking an API key. Revoked keys stay listed.
+func (kc *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	caller, ok := apiKeyCaller(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+
+	if err := kc.apiKeyService.RevokeAPIKey(c.Request.Context(), id, caller.id, caller.role); err != nil {
+		var notFoundErr *repository.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		slog.Error("failed to revoke API key", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke API key"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type apiKeyOwner struct {
+	id   uuid.UUID
+	role string
+}
+
+// apiKeyCaller returns the authenticated user managing API keys, or responds with an error. Only users
+// of this service can own keys, not users of an external identity provider.
+func apiKeyCaller(c *gin.Context) (apiKeyOwner, bool) {
+	claims, ok := middleware.AuthUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return apiKeyOwner{}, false
+	}
+	id, err := uuid.Parse(claims.Subject)
+	if claims.External || err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys can only be managed by registered users"})
+		return apiKeyOwner{}, false
+	}
+	return apiKeyOwner{id: id, role: claims.Role}, true
+}
+
+func toAPIKeyResponse(key *model.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID.String(),
+		Name:       key.Name,
+		KeyPrefix:  key.KeyPrefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  formatOptionalTime(key.ExpiresAt),
+		LastUsedAt: formatOptionalTime(key.LastUsedAt),
+		RevokedAt:  formatOptionalTime(key.RevokedAt),
+		CreatedAt:  key.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format("2006-01-02T15:04:05Z07:00")
+	return &formatted
+}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+)
+
+// AuditController handles HTTP requests for reading the audit log.
+type AuditController struct {
+	auditService *service.AuditService
+	pageTokens   *repository.PageTokenSigner
+}
+
+// NewAuditController creates a new AuditController with the given audit service and page token signer.
+func NewAuditController(auditService *service.AuditService, pageTokens *repository.PageTokenSigner) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+		pageTokens:   pageTokens,
+	}
+}
+
+// ListAuditLogRequest represents the query parameters for listing audit log entries.
+type ListAuditLogRequest struct {
+	Limit         int32      `form:"limit"`
+	Token         string     `form:"token"`
+	Actor         string     `form:"actor"`
+	Action        string     `form:"action"`
+	ResourceType  string     `form:"resource_type"`
+	ResourceID    string     `form:"resource_id" binding:"omitempty,uuid"`
+	CreatedAfter  *time.Time `form:"created_after"`
+	CreatedBefore *time.Time `form:"created_before"`
+}
+
+// AuditLogEntryResponse represents one audit log entry. Before is omitted for creations and after for deletions.
+type AuditLogEntryResponse struct {
+	ID           string          `json:"id"`
+	Actor        string          `json:"actor"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	RequestID    string          `json:"request_id,omitempty"`
+	ClientIP     string          `json:"client_ip,omitempty"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+// ListAuditLogResponse represents the response body for listing audit log entries.
+type ListAuditLogResponse struct {
+	Entries       []AuditLogEntryResponse `json:"entries"`
+	NextPageToken string                  `json:"next_page_token,omitempty"`
+	PrevPageToken string                  `json:"prev_page_token,omitempty"`
+	HasMore       bool                    `json:"has_more"`
+}
+
+// ListAuditLog handles the HTTP GET request for listing audit log entries with filters and pagination, newest first.
+func (ac *AuditController) ListAuditLog(c *gin.Context) {
+	var req ListAuditLogRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := req.toQuery()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := query.ApplyPagination(req.Limit, req.Token, ac.pageTokens); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := ac.auditService.ListAuditLog(c.Request.Context(), *query)
+	if err != nil {
+		slog.Error("failed to list audit log", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list audit log"})
+		return
+	}
+
+	response := ListAuditLogResponse{
+		Entries: make([]AuditLogEntryResponse, 0, len(page.Entries)),
+		HasMore: page.HasNext,
+	}
+	for _, entry := range page.Entries {
+		response.Entries = append(response.Entries, toAuditLogEntryResponse(entry))
+	}
+
+	// Generate page tokens pointing after the last and before the first entry
+	if len(page.Entries) > 0 {
+		if page.HasNext {
+			next := auditLogPaginator(page.Entries[len(page.Entries)-1], query.Sort)
+			response.NextPageToken = next.Encode(ac.pageTokens)
+		}
+		if page.HasPrev {
+			prev := auditLogPaginator(page.Entries[0], query.Sort)
+			prev.Backward = true
+			response.PrevPageToken = prev.Encode(ac.pageTokens)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// toQuery validates the filters and translates them into a repository query sorted newest first.
+func (req ListAuditLogRequest) toQuery() (*repository.Query, error) {
+	if req.CreatedAfter != nil && req.CreatedBefore != nil && !req.CreatedAfter.Before(*req.CreatedBefore) {
+		return nil, errors.New("created_after must be before created_before")
+	}
+
+	query := repository.NewQuery()
+	query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortDesc}
+	if req.Actor != "" {
+		query.With(repository.ActorField, req.Actor)
+	}
+	if req.Action != "" {
+		query.With(repository.ActionField, req.Action)
+	}
+	if req.ResourceType != "" {
+		query.With(repository.ResourceTypeField, req.ResourceType)
+	}
+	if req.ResourceID != "" {
+		query.With(repository.ResourceIDField, req.ResourceID)
+	}
+	if req.CreatedAfter != nil {
+		query.With(repository.CreatedAfterField, req.CreatedAfter.Format(time.RFC3339Nano))
+	}
+	if req.CreatedBefore != nil {
+		query.With(repository.CreatedBeforeField, req.CreatedBefore.Format(time.RFC3339Nano))
+	}
+	return query, nil
+}
+
+// auditLogPaginator returns a cursor positioned at the given audit log entry.
+func auditLogPaginator(entry *model.AuditLogEntry, sort repository.Sort) repository.Paginator {
+	return repository.Paginator{
+		LastID:        entry.ID,
+		LastCreatedAt: entry.CreatedAt,
+		Sort:          sort,
+	}
+}
+
+func toAuditLogEntryResponse(entry *model.AuditLogEntry) AuditLogEntryResponse {
+	return AuditLogEntryResponse{
+		ID:           entry.ID.String(),
+		Actor:        entry.Actor,
+		Action:       entry.Action,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID.String(),
+		Before:       entry.Before,
+		After:        entry.After,
+		RequestID:    entry.RequestID,
+		ClientIP:     entry.ClientIP,
+		CreatedAt:    entry.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+)
+
+// CategoryController handles HTTP requests for category operations.
+type CategoryController struct {
+	categoryService *service.CategoryService
+	pageTokens      *repository.PageTokenSigner
+}
+
+// NewCategoryController creates a new CategoryController with the given category service and page token signer.
+func NewCategoryController(categoryService *service.CategoryService, pageTokens *repository.PageTokenSigner) *CategoryController {
+	return &CategoryController{
+		categoryService: categoryService,
+		pageTokens:      pageTokens,
+	}
+}
+
+// CategoryRequest represents the request body for creating or replacing a category.
+// A category without a parent_id is a root category.
+type CategoryRequest struct {
+	Name     string     `json:"name" binding:"required,max=255"`
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+// CategoryResponse represents the response body for a category.
+type CategoryResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ParentID  string `json:"parent_id,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ListCategoriesRequest represents the query parameters for listing categories.
+// parent_id restricts the list to the direct children of a category, or to root categories when set to "root".
+type ListCategoriesRequest struct {
+	Limit    int32  `form:"limit"`
+	Token    string `form:"token"`
+	ParentID string `form:"parent_id"`
+}
+
+// ListCategoriesResponse represents the response body for listing categories.
+type ListCategoriesResponse struct {
+	Categories    []CategoryResponse `json:"categories"`
+	NextPageToken string             `json:"next_page_token,omitempty"`
+	PrevPageToken string             `json:"prev_page_token,omitempty"`
+	HasMore       bool               `json:"has_more"`
+}
+
+// CreateCategory handles the HTTP POST request for creating a new category.
+func (cc *CategoryController) CreateCategory(c *gin.Context) {
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := cc.categoryService.CreateCategory(c.Request.Context(), req.Name, req.ParentID)
+	if err != nil {
+		respondCategoryError(c, "create", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, toCategoryResponse(category))
+}
+
+// GetCategory handles the HTTP GET request for retrieving a single category by ID.
+func (cc *CategoryController) GetCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	category, err := cc.categoryService.GetCategory(c.Request.Context(), id)
+	if err != nil {
+		respondCategoryError(c, "get", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toCategoryResponse(category))
+}
+
+// ListCategories handles the HTTP GET request for listing categories with pagination, oldest first.
+func (cc *CategoryController) ListCategories(c *gin.Context) {
+	var req ListCategoriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := repository.NewQuery()
+	query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortAsc}
+	switch {
+	case req.ParentID == "root":
+		query.With(repository.ParentIDField, string(repository.Empty))
+	case req.ParentID != "":
+		if _, err := uuid.Parse(req.ParentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parent_id must be a category ID or root"})
+			return
+		}
+		query.With(repository.ParentIDField, req.ParentID)
+	}
+
+	if err := query.ApplyPagination(req.Limit, req.Token, cc.pageTokens); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := cc.categoryService.ListCategories(c.Request.Context(), *query)
+	if err != nil {
+		slog.Error("failed to list categories", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list categories"})
+		return
+	}
+
+	response := ListCategoriesResponse{
+		Categories: make([]CategoryResponse, 0, len(page.Categories)),
+		HasMore:    page.HasNext,
+	}
+	for _, category := range page.Categories {
+		response.Categories = append(response.Categories, toCategoryResponse(category))
+	}
+
+	// Generate page tokens pointing after the last and before the first category
+	if len(page.Categories) > 0 {
+		if page.HasNext {
+			next := categoryPaginator(page.Categories[len(page.Categories)-1], query.Sort)
+			response.NextPageToken = next.Encode(cc.pageTokens)
+		}
+		if page.HasPrev {
+			prev := categoryPaginator(page.Categories[0], query.Sort)
+			prev.Backward = true
+			response.PrevPageToken = prev.Encode(cc.pageTokens)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// categoryPaginator returns a cursor positioned at the given category.
+func categoryPaginator(category *model.Category, sort repository.Sort) repository.Paginator {
+	return repository.Paginator{
+		LastID:        category.ID,
+		LastCreatedAt: category.CreatedAt,
+		Sort:          sort,
+	}
+}
+
+// ReplaceCategory handles the HTTP PUT request for renaming a category and moving it in the tree.
+func (cc *CategoryController) ReplaceCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := cc.categoryService.UpdateCategory(c.Request.Context(), id, req.Name, req.ParentID)
+	if err != nil {
+		respondCategoryError(c, "update", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toCategoryResponse(category))
+}
+
+// DeleteCategory handles the HTTP DELETE request for deleting a category that has no subcategories or products.
+func (cc *CategoryController) DeleteCategory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+
+	if err := cc.categoryService.DeleteCategory(c.Request.Context(), id); err != nil {
+		respondCategoryError(c, "delete", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "category deleted successfully"})
+}
+
+// respondCategoryError maps errors returned by CategoryService to HTTP responses.
+func respondCategoryError(c *gin.Context, action string, err error) {
+	var notFoundErr *repository.NotFoundError
+	switch {
+	case errors.As(err, &notFoundErr) && notFoundErr.Resource == "parent category":
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": notFoundErr.Error()})
+	case errors.As(err, &notFoundErr):
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+	case errors.Is(err, service.ErrCategoryCycle):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrStillReferenced):
+		c.JSON(http.StatusConflict, gin.H{"error": "category still has subcategories or products"})
+	default:
+		slog.Error("failed to "+action+" category", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action + " category"})
+	}
+}
+
+func toCategoryResponse(category *model.Category) CategoryResponse {
+	response := CategoryResponse{
+		ID:        category.ID.String(),
+		Name:      category.Name,
+		CreatedAt: category.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: category.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if category.ParentID != nil {
+		response.ParentID = category.ParentID.String()
+	}
+	return response
+}
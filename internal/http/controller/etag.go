@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// errInvalidIfMatch is returned when the If-Match header is not a strong ETag produced by this API.
+	errInvalidIfMatch = errors.New("invalid If-Match header")
+)
+
+// setETag writes the resource version as a strong ETag response header.
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// parseIfMatch reads the expected resource version from the If-Match header.
+// It returns nil when the header is absent or "*", meaning any version is accepted.
+func parseIfMatch(c *gin.Context) (*int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil //nolint:nilnil // no precondition requested
+	}
+
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+
+	return &version, nil
+}
@@ -0,0 +1,259 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+)
+
+// EventController handles the admin HTTP requests for inspecting and replaying the events of the outbox table.
+type EventController struct {
+	eventService *service.EventService
+	pageTokens   *repository.PageTokenSigner
+}
+
+// NewEventController creates a new EventController with the given event service and page token signer.
+func NewEventController(eventService *service.EventService, pageTokens *repository.PageTokenSigner) *EventController {
+	return &EventController{
+		eventService: eventService,
+		pageTokens:   pageTokens,
+	}
+}
+
+// ListEventsRequest represents the query parameters for listing events.
+type ListEventsRequest struct {
+	Limit         int32      `form:"limit"`
+	Token         string     `form:"token"`
+	Status        string     `form:"status" binding:"omitempty,oneof=pending processed dead"`
+	EventType     string     `form:"event_type"`
+	CreatedAfter  *time.Time `form:"created_after"`
+	CreatedBefore *time.Time `form:"created_before"`
+}
+
+// RequeueEventsRequest represents the request body for requeueing several events at once.
+type RequeueEventsRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100,dive,uuid"`
+}
+
+// EventResponse represents one event of the outbox table.
+type EventResponse struct {
+	ID            string          `json:"id"`
+	Sequence      int64           `json:"sequence"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	EventData     json.RawMessage `json:"event_data"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     *string         `json:"last_error"`
+	NextAttemptAt *string         `json:"next_attempt_at"`
+	LockedBy      *string         `json:"locked_by"`
+	LockedUntil   *string         `json:"locked_until"`
+	CreatedAt     string          `json:"created_at"`
+	ProcessedAt   *string         `json:"processed_at"`
+}
+
+// ListEventsResponse represents the response body for listing events.
+type ListEventsResponse struct {
+	Events        []EventResponse `json:"events"`
+	NextPageToken string          `json:"next_page_token,omitempty"`
+	PrevPageToken string          `json:"prev_page_token,omitempty"`
+	HasMore       bool            `json:"has_more"`
+}
+
+// RequeueEventsResponse represents the response body for requeueing events.
+type RequeueEventsResponse struct {
+	Events []EventResponse `json:"events"`
+}
+
+// ListEvents handles the HTTP GET request for listing events with filters and pagination, newest first.
+func (ec *EventController) ListEvents(c *gin.Context) {
+	var req ListEventsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := req.toQuery()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := query.ApplyPagination(req.Limit, req.Token, ec.pageTokens); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := ec.eventService.ListEvents(c.Request.Context(), *query)
+	if err != nil {
+		slog.Error("failed to list events", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list events"})
+		return
+	}
+
+	response := ListEventsResponse{
+		Events:  make([]EventResponse, 0, len(page.Events)),
+		HasMore: page.HasNext,
+	}
+	for _, event := range page.Events {
+		response.Events = append(response.Events, toEventResponse(event))
+	}
+
+	// Generate page tokens pointing after the last and before the first event
+	if len(page.Events) > 0 {
+		if page.HasNext {
+			next := eventPaginator(page.Events[len(page.Events)-1], query.Sort)
+			response.NextPageToken = next.Encode(ec.pageTokens)
+		}
+		if page.HasPrev {
+			prev := eventPaginator(page.Events[0], query.Sort)
+			prev.Backward = true
+			response.PrevPageToken = prev.Encode(ec.pageTokens)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetEvent handles the HTTP GET request for retrieving an event by ID.
+func (ec *EventController) GetEvent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	event, err := ec.eventService.GetEvent(c.Request.Context(), id)
+	if err != nil {
+		var notFoundErr *repository.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			return
+		}
+		slog.Error("failed to get event", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, toEventResponse(event))
+}
+
+// RequeueEvent handles the HTTP POST request for requeueing a failed or dead event.
+func (ec *EventController) RequeueEvent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	events, ok := ec.requeue(c, []uuid.UUID{id})
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, toEventResponse(events[0]))
+}
+
+// RequeueEvents handles the HTTP POST request for requeueing several failed or dead events at once.
+// Either all of the events are requeued or none.
+func (ec *EventController) RequeueEvents(c *gin.Context) {
+	var req RequeueEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The IDs are validated by the binding
+	ids := make([]uuid.UUID, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		ids = append(ids, uuid.MustParse(id))
+	}
+
+	events, ok := ec.requeue(c, ids)
+	if !ok {
+		return
+	}
+
+	response := RequeueEventsResponse{Events: make([]EventResponse, 0, len(events))}
+	for _, event := range events {
+		response.Events = append(response.Events, toEventResponse(event))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// requeue requeues the events, or responds with an error.
+func (ec *EventController) requeue(c *gin.Context, ids []uuid.UUID) ([]*model.Event, bool) {
+	events, err := ec.eventService.RequeueEvents(c.Request.Context(), ids)
+	if err != nil {
+		var notFoundErr *repository.NotFoundError
+		switch {
+		case errors.As(err, &notFoundErr):
+			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		case errors.Is(err, service.ErrEventNotRequeueable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			slog.Error("failed to requeue events", slog.Any("err", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to requeue events"})
+		}
+		return nil, false
+	}
+	return events, true
+}
+
+// toQuery validates the filters and translates them into a repository query sorted newest first.
+func (req ListEventsRequest) toQuery() (*repository.Query, error) {
+	if req.CreatedAfter != nil && req.CreatedBefore != nil && !req.CreatedAfter.Before(*req.CreatedBefore) {
+		return nil, errors.New("created_after must be before created_before")
+	}
+
+	query := repository.NewQuery()
+	query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortDesc}
+	if req.Status != "" {
+		query.With(repository.StatusField, req.Status)
+	}
+	if req.EventType != "" {
+		query.With(repository.EventTypeField, req.EventType)
+	}
+	if req.CreatedAfter != nil {
+		query.With(repository.CreatedAfterField, req.CreatedAfter.Format(time.RFC3339Nano))
+	}
+	if req.CreatedBefore != nil {
+		query.With(repository.CreatedBeforeField, req.CreatedBefore.Format(time.RFC3339Nano))
+	}
+	return query, nil
+}
+
+// eventPaginator returns a cursor positioned at the given event.
+func eventPaginator(event *model.Event, sort repository.Sort) repository.Paginator {
+	return repository.Paginator{
+		LastID:        event.ID,
+		LastCreatedAt: event.CreatedAt,
+		Sort:          sort,
+	}
+}
+
+func toEventResponse(event *model.Event) EventResponse {
+	return EventResponse{
+		ID:            event.ID.String(),
+		Sequence:      event.Sequence,
+		AggregateID:   event.AggregateID.String(),
+		EventType:     event.EventType,
+		EventData:     event.EventData,
+		Status:        string(event.Status),
+		Attempts:      event.Attempts,
+		LastError:     event.LastError,
+		NextAttemptAt: formatOptionalTime(event.NextAttemptAt),
+		LockedBy:      event.LockedBy,
+		LockedUntil:   formatOptionalTime(event.LockedUntil),
+		CreatedAt:     event.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ProcessedAt:   formatOptionalTime(event.ProcessedAt),
+	}
+}
@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+)
+
+// InventoryController handles HTTP requests for the stock of products.
+type InventoryController struct {
+	inventoryService *service.InventoryService
+}
+
+// NewInventoryController creates a new InventoryController with the given inventory service.
+func NewInventoryController(inventoryService *service.InventoryService) *InventoryController {
+	return &InventoryController{inventoryService: inventoryService}
+}
+
+// AdjustStockRequest represents the request body for adding stock on hand, or removing it with a negative delta.
+// A single adjustment moves at most a trillion units, well within the BIGINT stock columns.
+type AdjustStockRequest struct {
+	Delta int64 `json:"delta" binding:"required,min=-1000000000000,max=1000000000000"`
+}
+
+// StockQuantityRequest represents the request body for reserving or releasing stock.
+type StockQuantityRequest struct {
+	Quantity int64 `json:"quantity" binding:"required,gt=0"`
+}
+
+// InventoryResponse represents the response body for the stock of a product.
+type InventoryResponse struct {
+	ProductID         string `json:"product_id"`
+	QuantityOnHand    int64  `json:"quantity_on_hand"`
+	QuantityReserved  int64  `json:"quantity_reserved"`
+	QuantityAvailable int64  `json:"quantity_available"`
+	UpdatedAt         string `json:"updated_at,omitempty"`
+}
+
+// GetInventory handles the HTTP GET request for the stock of a product.
+func (ic *InventoryController) GetInventory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	inventory, err := ic.inventoryService.GetInventory(c.Request.Context(), id)
+	if err != nil {
+		respondInventoryError(c, id, "get", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toInventoryResponse(inventory))
+}
+
+// AdjustStock handles the HTTP POST request for changing the stock on hand of a product.
+func (ic *InventoryController) AdjustStock(c *gin.Context) {
+	var req AdjustStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ic.changeStock(c, "adjust", func(ctx context.Context, id uuid.UUID) (*model.Inventory, error) {
+		return ic.inventoryService.AdjustStock(ctx, id, req.Delta)
+	})
+}
+
+// ReserveStock handles the HTTP POST request for reserving available stock of a product.
+func (ic *InventoryController) ReserveStock(c *gin.Context) {
+	var req StockQuantityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ic.changeStock(c, "reserve", func(ctx context.Context, id uuid.UUID) (*model.Inventory, error) {
+		return ic.inventoryService.ReserveStock(ctx, id, req.Quantity)
+	})
+}
+
+// ReleaseStock handles the HTTP POST request for releasing reserved stock of a product.
+func (ic *InventoryController) ReleaseStock(c *gin.Context) {
+	var req StockQuantityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ic.changeStock(c, "release", func(ctx context.Context, id uuid.UUID) (*model.Inventory, error) {
+		return ic.inventoryService.ReleaseStock(ctx, id, req.Quantity)
+	})
+}
+
+func (ic *InventoryController) changeStock(c *gin.Context, action string,
+	change func(ctx context.Context, id uuid.UUID) (*model.Inventory, error),
+) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	inventory, err := change(c.Request.Context(), id)
+	if err != nil {
+		respondInventoryError(c, id, action, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toInventoryResponse(inventory))
+}
+
+// respondInventoryError maps errors returned by InventoryService to HTTP responses.
+func respondInventoryError(c *gin.Context, id uuid.UUID, action string, err error) {
+	var notFoundErr *repository.NotFoundError
+	switch {
+	case errors.As(err, &notFoundErr):
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+	case errors.Is(err, service.ErrInsufficientStock), errors.Is(err, service.ErrReleaseExceedsReserved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrStockOutOfRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		slog.Error("failed to "+action+" inventory", slog.String("product_id", id.String()), slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action + " inventory"})
+	}
+}
+
+func toInventoryResponse(inventory *model.Inventory) InventoryResponse {
+	response := InventoryResponse{
+		ProductID:         inventory.ProductID.String(),
+		QuantityOnHand:    inventory.QuantityOnHand,
+		QuantityReserved:  inventory.QuantityReserved,
+		QuantityAvailable: inventory.Available(),
+	}
+	if !inventory.UpdatedAt.IsZero() {
+		response.UpdatedAt = inventory.UpdatedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return response
+}
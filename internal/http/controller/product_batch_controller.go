@@ -0,0 +1,274 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+)
+
+const (
+	// batchModeAtomic applies a batch only if every item succeeds.
+	batchModeAtomic = "atomic"
+	// batchModeBestEffort applies the items that succeed and reports the others.
+	batchModeBestEffort = "best_effort"
+	// maxBatchItems limits the number of items accepted by a single batch request.
+	maxBatchItems = 1000
+)
+
+var errBatchAborted = errors.New("not applied because another item in the atomic batch failed")
+
+// BatchCreateProductsRequest represents the request body for creating products in bulk.
+type BatchCreateProductsRequest struct {
+	Mode  string                 `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Items []CreateProductRequest `json:"items" binding:"required,min=1,max=1000"`
+}
+
+// BatchDeleteProductsRequest represents the request body for deleting products in bulk.
+type BatchDeleteProductsRequest struct {
+	Mode string   `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	IDs  []string `json:"ids" binding:"required,min=1,max=1000"`
+}
+
+// BatchItemResult reports the outcome of a single batch item using an HTTP status code.
+type BatchItemResult struct {
+	Index   int              `json:"index"`
+	Status  int              `json:"status"`
+	ID      string           `json:"id,omitempty"`
+	Product *ProductResponse `json:"product,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// BatchResponse represents the response body of a batch request.
+type BatchResponse struct {
+	Results   []BatchItemResult `json:"results"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+}
+
+// ProductAction handles custom-method requests on the product collection such as POST /products:batchCreate.
+func (pc *ProductController) ProductAction(c *gin.Context) {
+	switch c.Param("action") {
+	case ":batchCreate":
+		pc.BatchCreateProducts(c)
+	case ":batchDelete":
+		pc.BatchDeleteProducts(c)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown product action"})
+	}
+}
+
+// BatchCreateProducts handles the HTTP POST request for creating up to maxBatchItems products in one transaction.
+// Invalid items fail the whole batch in atomic mode and are skipped in best-effort mode, as are items the
+// database rejects, e.g. for a category that was deleted in the meantime.
+func (pc *ProductController) BatchCreateProducts(c *gin.Context) {
+	var req BatchCreateProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := make([]BatchItemResult, len(req.Items))
+	inputs := make([]service.ProductInput, 0, len(req.Items))
+	positions := make([]int, 0, len(req.Items))
+	for i := range req.Items {
+		results[i].Index = i
+		if err := validateCreateProductRequest(&req.Items[i]); err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+			continue
+		}
+		inputs = append(inputs, req.Items[i].toInput())
+		positions = append(positions, i)
+	}
+
+	missing, err := pc.missingCategories(c.Request.Context(), inputs)
+	if err != nil {
+		slog.Error("failed to look up categories", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create products"})
+		return
+	}
+	if len(missing) > 0 {
+		valid := 0
+		for j, input := range inputs {
+			if input.CategoryID != nil && missing[*input.CategoryID] {
+				results[positions[j]].Status = http.StatusUnprocessableEntity
+				results[positions[j]].Error = service.ErrCategoryNotFound.Error()
+				continue
+			}
+			inputs[valid], positions[valid] = input, positions[j]
+			valid++
+		}
+		inputs, positions = inputs[:valid], positions[:valid]
+	}
+
+	if len(inputs) < len(req.Items) && req.Mode != batchModeBestEffort {
+		respondBatch(c, http.StatusUnprocessableEntity, abortBatch(results))
+		return
+	}
+
+	if len(inputs) > 0 {
+		created, itemErrs, err := pc.productService.CreateProducts(c.Request.Context(), inputs, req.Mode != batchModeBestEffort)
+		if errors.Is(err, service.ErrCategoryNotFound) {
+			// A category was deleted after it was looked up
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			slog.Error("failed to create products", slog.Int("count", len(inputs)), slog.Any("err", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create products"})
+			return
+		}
+		for i, product := range created {
+			switch {
+			case errors.Is(itemErrs[i], service.ErrCategoryNotFound):
+				results[positions[i]].Status = http.StatusUnprocessableEntity
+				results[positions[i]].Error = itemErrs[i].Error()
+			case itemErrs[i] != nil:
+				slog.Error("failed to create product of batch", slog.Int("index", positions[i]), slog.Any("err", itemErrs[i]))
+				results[positions[i]].Status = http.StatusInternalServerError
+				results[positions[i]].Error = "failed to create product"
+			default:
+				response := toProductResponse(product)
+				results[positions[i]] = BatchItemResult{
+					Index:   positions[i],
+					Status:  http.StatusCreated,
+					ID:      response.ID,
+					Product: &response,
+				}
+			}
+		}
+	}
+
+	respondBatch(c, http.StatusOK, results)
+}
+
+// BatchDeleteProducts handles the HTTP POST request for soft-deleting up to maxBatchItems products in one transaction.
+// Unknown or already deleted products fail the whole batch in atomic mode and are skipped in best-effort mode.
+func (pc *ProductController) BatchDeleteProducts(c *gin.Context) {
+	var req BatchDeleteProductsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := make([]BatchItemResult, len(req.IDs))
+	itemIDs := make([]uuid.UUID, len(req.IDs))
+	ids := make([]uuid.UUID, 0, len(req.IDs))
+	seen := make(map[uuid.UUID]bool, len(req.IDs))
+	for i, rawID := range req.IDs {
+		results[i] = BatchItemResult{Index: i, ID: rawID}
+		id, err := uuid.Parse(rawID)
+		switch {
+		case err != nil:
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = "invalid product ID"
+		case seen[id]:
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = "duplicate product ID in batch"
+		default:
+			seen[id] = true
+			itemIDs[i] = id
+			ids = append(ids, id)
+		}
+	}
+
+	atomic := req.Mode != batchModeBestEffort
+	if len(ids) < len(req.IDs) && atomic {
+		respondBatch(c, http.StatusUnprocessableEntity, abortBatch(results))
+		return
+	}
+
+	deleted := make(map[uuid.UUID]bool, len(ids))
+	if len(ids) > 0 {
+		products, err := pc.productService.DeleteProducts(c.Request.Context(), ids, atomic)
+		var notFoundErr *service.BatchNotFoundError
+		if errors.As(err, &notFoundErr) {
+			missing := make(map[uuid.UUID]bool, len(notFoundErr.IDs))
+			for _, id := range notFoundErr.IDs {
+				missing[id] = true
+			}
+			for i := range results {
+				if missing[itemIDs[i]] {
+					results[i].Status = http.StatusNotFound
+					results[i].Error = "product not found"
+				}
+			}
+			respondBatch(c, http.StatusUnprocessableEntity, abortBatch(results))
+			return
+		}
+		if err != nil {
+			slog.Error("failed to delete products", slog.Int("count", len(ids)), slog.Any("err", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete products"})
+			return
+		}
+		for _, product := range products {
+			deleted[product.ID] = true
+		}
+	}
+
+	for i := range results {
+		if results[i].Status != 0 {
+			continue
+		}
+		if deleted[itemIDs[i]] {
+			results[i].Status = http.StatusNoContent
+		} else {
+			results[i].Status = http.StatusNotFound
+			results[i].Error = "product not found"
+		}
+	}
+
+	respondBatch(c, http.StatusOK, results)
+}
+
+// missingCategories returns the IDs of the categories referenced by the inputs that do not exist.
+func (pc *ProductController) missingCategories(ctx context.Context, inputs []service.ProductInput) (map[uuid.UUID]bool, error) {
+	ids := make([]uuid.UUID, 0, len(inputs))
+	for _, input := range inputs {
+		if input.CategoryID != nil {
+			ids = append(ids, *input.CategoryID)
+		}
+	}
+
+	categories, err := pc.productService.FindCategories(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		if _, ok := categories[id]; !ok {
+			missing[id] = true
+		}
+	}
+	return missing, nil
+}
+
+// abortBatch marks every item that has not failed on its own as not applied.
+func abortBatch(results []BatchItemResult) []BatchItemResult {
+	for i := range results {
+		if results[i].Status == 0 {
+			results[i].Status = http.StatusConflict
+			results[i].Error = errBatchAborted.Error()
+		}
+	}
+	return results
+}
+
+// respondBatch writes the per-item results together with the success and failure counts.
+func respondBatch(c *gin.Context, status int, results []BatchItemResult) {
+	response := BatchResponse{Results: results}
+	for _, result := range results {
+		if result.Status < http.StatusBadRequest {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	c.JSON(status, response)
+}
@@ -1,42 +1,143 @@
 package controller
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"github.com/iyhunko/microservices-with-sqs/internal/model"
 	"github.com/iyhunko/microservices-with-sqs/internal/repository"
 	"github.com/iyhunko/microservices-with-sqs/internal/service"
 )
 
+var (
+	// errNonPositivePrice is returned when a product price is zero or negative.
+	errNonPositivePrice = errors.New("price must be greater than zero")
+	// errCurrencyRequired is returned when products are filtered or sorted by price without a currency filter.
+	errCurrencyRequired = errors.New("currency is required when filtering or sorting by price")
+	// errInvalidText is returned when a text field is not valid UTF-8 or contains NUL characters.
+	errInvalidText = errors.New("text fields must be valid UTF-8 without NUL characters")
+)
+
+const (
+	// idempotencyKeyHeader carries a client-chosen key that makes retried create requests safe.
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks responses answered from a stored idempotency key.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength matches the size of the idempotency_keys.idempotency_key column.
+	maxIdempotencyKeyLength = 255
+)
+
 // ProductController handles HTTP requests for product operations.
 type ProductController struct {
 	productService *service.ProductService
+	pageTokens     *repository.PageTokenSigner
 }
 
-// NewProductController creates a new ProductController with the given product service.
-func NewProductController(productService *service.ProductService) *ProductController {
+// NewProductController creates a new ProductController with the given product service and page token signer.
+func NewProductController(productService *service.ProductService, pageTokens *repository.PageTokenSigner) *ProductController {
 	return &ProductController{
 		productService: productService,
+		pageTokens:     pageTokens,
 	}
 }
 
 // CreateProductRequest represents the request body for creating a product.
 type CreateProductRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
+	Name        string       `json:"name" binding:"required,max=255"`
+	Description string       `json:"description"`
+	Price       *model.Money `json:"price" binding:"required"`
+	CategoryID  *uuid.UUID   `json:"category_id"`
+	Tags        []string     `json:"tags"`
+}
+
+// validate checks the rules of CreateProductRequest that binding tags cannot express and normalizes the tags.
+func (req *CreateProductRequest) validate() error {
+	if req.Price != nil && !req.Price.IsPositive() {
+		return errNonPositivePrice
+	}
+	if err := checkStorableText(append([]string{req.Name, req.Description}, req.Tags...)...); err != nil {
+		return err
+	}
+	tags, err := model.NormalizeTags(req.Tags)
+	if err != nil {
+		return err
+	}
+	req.Tags = tags
+	return nil
+}
+
+// checkStorableText rejects text that the database cannot store, i.e. invalid UTF-8 or NUL characters.
+func checkStorableText(values ...string) error {
+	for _, value := range values {
+		if !utf8.ValidString(value) || strings.ContainsRune(value, 0) {
+			return errInvalidText
+		}
+	}
+	return nil
+}
+
+// toInput returns the product described by a validated request.
+func (req *CreateProductRequest) toInput() service.ProductInput {
+	return service.ProductInput{
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       *req.Price,
+		CategoryID:  req.CategoryID,
+		Tags:        req.Tags,
+	}
+}
+
+// validateCreateProductRequest applies the binding rules and validate to a request that was decoded without binding.
+func validateCreateProductRequest(req *CreateProductRequest) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return err
+	}
+	return req.validate()
+}
+
+// UpdateProductRequest represents the request body for partially updating a product.
+// A null category_id removes the product from its category.
+type UpdateProductRequest struct {
+	Name        *string      `json:"name" binding:"omitempty,min=1,max=255"`
+	Description *string      `json:"description"`
+	Price       *model.Money `json:"price"`
+	CategoryID  nullableUUID `json:"category_id"`
+	Tags        *[]string    `json:"tags"`
+}
+
+// nullableUUID is an optional UUID field of a request body that tells apart a missing field from an explicit null.
+type nullableUUID struct {
+	Set   bool
+	Value *uuid.UUID
+}
+
+// UnmarshalJSON records that the field was sent, with a nil Value for null.
+func (n *nullableUUID) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	return json.Unmarshal(data, &n.Value)
 }
 
 // ProductResponse represents the response body for a product.
 type ProductResponse struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	CreatedAt   string  `json:"created_at"`
-	UpdatedAt   string  `json:"updated_at"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       model.Money `json:"price"`
+	CategoryID  string      `json:"category_id,omitempty"`
+	Tags        []string    `json:"tags"`
+	Version     int64       `json:"version"`
+	CreatedAt   string      `json:"created_at"`
+	UpdatedAt   string      `json:"updated_at"`
+	DeletedAt   string      `json:"deleted_at,omitempty"`
 }
 
 // CreateProduct handles the HTTP POST request for creating a new product.
@@ -46,16 +147,157 @@ func (pc *ProductController) CreateProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	createdProduct, err := pc.productService.CreateProduct(c.Request.Context(), req.Name, req.Description, req.Price)
+	idempotencyKey := c.GetHeader(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must not be longer than 255 characters"})
+		return
+	}
+
+	var (
+		createdProduct *model.Product
+		replayed       bool
+		err            error
+	)
+	if idempotencyKey != "" {
+		createdProduct, replayed, err = pc.productService.CreateProductIdempotent(c.Request.Context(), idempotencyKey, req.toInput())
+	} else {
+		createdProduct, err = pc.productService.CreateProduct(c.Request.Context(), req.toInput())
+	}
 	if err != nil {
+		if errors.Is(err, service.ErrIdempotencyKeyReused) || errors.Is(err, service.ErrCategoryNotFound) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Error("failed to create product", slog.Any("err", err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create product"})
 		return
 	}
 
+	if replayed {
+		c.Header(idempotentReplayedHeader, "true")
+	}
+	setETag(c, createdProduct.Version)
 	c.JSON(http.StatusCreated, toProductResponse(createdProduct))
 }
 
+// GetProduct handles the HTTP GET request for retrieving a single product by ID.
+func (pc *ProductController) GetProduct(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	product, err := pc.productService.GetProduct(c.Request.Context(), id)
+	if err != nil {
+		respondProductError(c, id, "get", err)
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, toProductResponse(product))
+}
+
+// ReplaceProduct handles the HTTP PUT request for replacing all mutable fields of a product.
+func (pc *ProductController) ReplaceProduct(c *gin.Context) {
+	var req CreateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pc.updateProduct(c, service.ProductUpdate{
+		Name:          &req.Name,
+		Description:   &req.Description,
+		Price:         req.Price,
+		CategoryID:    req.CategoryID,
+		ClearCategory: req.CategoryID == nil,
+		Tags:          &req.Tags,
+	})
+}
+
+// UpdateProduct handles the HTTP PATCH request for changing selected fields of a product.
+func (pc *ProductController) UpdateProduct(c *gin.Context) {
+	var req UpdateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name == nil && req.Description == nil && req.Price == nil && !req.CategoryID.Set && req.Tags == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one field must be provided"})
+		return
+	}
+	if req.Price != nil && !req.Price.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errNonPositivePrice.Error()})
+		return
+	}
+	var texts []string
+	for _, value := range []*string{req.Name, req.Description} {
+		if value != nil {
+			texts = append(texts, *value)
+		}
+	}
+	if req.Tags != nil {
+		texts = append(texts, *req.Tags...)
+	}
+	if err := checkStorableText(texts...); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Tags != nil {
+		tags, err := model.NormalizeTags(*req.Tags)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Tags = &tags
+	}
+
+	pc.updateProduct(c, service.ProductUpdate{
+		Name:          req.Name,
+		Description:   req.Description,
+		Price:         req.Price,
+		CategoryID:    req.CategoryID.Value,
+		ClearCategory: req.CategoryID.Set && req.CategoryID.Value == nil,
+		Tags:          req.Tags,
+	})
+}
+
+func (pc *ProductController) updateProduct(c *gin.Context, update service.ProductUpdate) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updatedProduct, err := pc.productService.UpdateProduct(c.Request.Context(), id, update, expectedVersion)
+	if err != nil {
+		respondProductError(c, id, "update", err)
+		return
+	}
+
+	setETag(c, updatedProduct.Version)
+	c.JSON(http.StatusOK, toProductResponse(updatedProduct))
+}
+
 // DeleteProduct handles the HTTP DELETE request for deleting a product by ID.
 func (pc *ProductController) DeleteProduct(c *gin.Context) {
 	idParam := c.Param("id")
@@ -65,24 +307,73 @@ func (pc *ProductController) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	if err := pc.productService.DeleteProduct(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+	expectedVersion, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := pc.productService.DeleteProduct(c.Request.Context(), id, expectedVersion); err != nil {
+		respondProductError(c, id, "delete", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "product deleted successfully"})
 }
 
+// RestoreProduct handles the HTTP POST request for restoring a soft-deleted product by ID.
+func (pc *ProductController) RestoreProduct(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	restoredProduct, err := pc.productService.RestoreProduct(c.Request.Context(), id, expectedVersion)
+	if err != nil {
+		respondProductError(c, id, "restore", err)
+		return
+	}
+
+	setETag(c, restoredProduct.Version)
+	c.JSON(http.StatusOK, toProductResponse(restoredProduct))
+}
+
 // ListProductsRequest represents the query parameters for listing products.
 type ListProductsRequest struct {
-	Limit int32  `form:"limit"`
-	Token string `form:"token"`
+	Limit          int32      `form:"limit"`
+	Token          string     `form:"token"`
+	IncludeDeleted bool       `form:"include_deleted"`
+	NamePrefix     string     `form:"name_prefix"`
+	NameContains   string     `form:"name_contains"`
+	MinPrice       string     `form:"min_price"`
+	MaxPrice       string     `form:"max_price"`
+	Currency       string     `form:"currency"`
+	CreatedAfter   *time.Time `form:"created_after"`
+	CreatedBefore  *time.Time `form:"created_before"`
+	CategoryID     string     `form:"category_id" binding:"omitempty,uuid"`
+	Tag            string     `form:"tag"`
+	Sort           string     `form:"sort"`
+	IncludeTotal   bool       `form:"include_total"`
 }
 
+// productSortFields lists the fields products can be sorted by.
+var productSortFields = []repository.QueryField{repository.CreatedAtField, repository.PriceField, repository.NameField}
+
 // ListProductsResponse represents the response body for listing products.
 type ListProductsResponse struct {
 	Products      []ProductResponse `json:"products"`
 	NextPageToken string            `json:"next_page_token,omitempty"`
+	PrevPageToken string            `json:"prev_page_token,omitempty"`
+	HasMore       bool              `json:"has_more"`
+	TotalCount    *int64            `json:"total_count,omitempty"`
 }
 
 // ListProducts handles the HTTP GET request for listing products with pagination.
@@ -93,47 +384,192 @@ func (pc *ProductController) ListProducts(c *gin.Context) {
 		return
 	}
 
-	query := repository.NewQuery()
-	if err := query.ApplyPagination(req.Limit, req.Token); err != nil {
+	query, err := req.toQuery()
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	products, err := pc.productService.ListProducts(c.Request.Context(), *query)
+	if err = query.ApplyPagination(req.Limit, req.Token, pc.pageTokens); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := pc.productService.ListProducts(c.Request.Context(), *query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list products"})
 		return
 	}
 
-	productResponses := make([]ProductResponse, 0, len(products))
-	for _, product := range products {
+	productResponses := make([]ProductResponse, 0, len(page.Products))
+	for _, product := range page.Products {
 		productResponses = append(productResponses, toProductResponse(product))
 	}
 
 	response := ListProductsResponse{
-		Products: productResponses,
+		Products:   productResponses,
+		HasMore:    page.HasNext,
+		TotalCount: page.TotalCount,
 	}
 
-	// Generate next page token if we have results
-	if len(products) > 0 {
-		lastProduct := products[len(products)-1]
-		paginator := repository.Paginator{
-			LastID:        lastProduct.ID,
-			LastCreatedAt: lastProduct.CreatedAt,
+	// Generate page tokens pointing after the last and before the first product
+	if len(page.Products) > 0 {
+		if page.HasNext {
+			next := productPaginator(page.Products[len(page.Products)-1], query.Sort)
+			response.NextPageToken = next.Encode(pc.pageTokens)
+		}
+		if page.HasPrev {
+			prev := productPaginator(page.Products[0], query.Sort)
+			prev.Backward = true
+			response.PrevPageToken = prev.Encode(pc.pageTokens)
 		}
-		response.NextPageToken = paginator.Encode()
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// productPaginator returns a cursor positioned at the given product for the given sort order.
+func productPaginator(product *model.Product, sort repository.Sort) repository.Paginator {
+	return repository.Paginator{
+		LastID:        product.ID,
+		LastCreatedAt: product.CreatedAt,
+		Sort:          sort,
+		LastValue:     productSortKey(product, sort.Field),
+	}
+}
+
+// toQuery validates the filters and translates them into a repository query.
+func (req ListProductsRequest) toQuery() (*repository.Query, error) {
+	sort, err := repository.ParseSort(req.Sort, productSortFields...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prices of different currencies are not comparable, so price bounds and the price order need one currency
+	if req.Currency == "" && (req.MinPrice != "" || req.MaxPrice != "" || sort.Field == repository.PriceField) {
+		return nil, errCurrencyRequired
+	}
+
+	minPrice, err := parsePriceBound("min_price", req.MinPrice, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	maxPrice, err := parsePriceBound("max_price", req.MaxPrice, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if minPrice != nil && maxPrice != nil && minPrice.Amount > maxPrice.Amount {
+		return nil, errors.New("min_price must not be greater than max_price")
+	}
+	if req.CreatedAfter != nil && req.CreatedBefore != nil && !req.CreatedAfter.Before(*req.CreatedBefore) {
+		return nil, errors.New("created_after must be before created_before")
+	}
+
+	query := repository.NewQuery()
+	query.Sort = sort
+	query.IncludeTotal = req.IncludeTotal
+	if req.IncludeDeleted {
+		query.With(repository.IncludeDeletedField, "true")
+	}
+	if req.NamePrefix != "" {
+		query.With(repository.NamePrefixField, req.NamePrefix)
+	}
+	if req.NameContains != "" {
+		query.With(repository.NameContainsField, req.NameContains)
+	}
+	if req.Currency != "" {
+		if _, err := model.CurrencyExponent(req.Currency); err != nil {
+			return nil, err
+		}
+		query.With(repository.CurrencyField, req.Currency)
+	}
+	if minPrice != nil {
+		query.With(repository.MinPriceField, minPrice.Decimal())
+	}
+	if maxPrice != nil {
+		query.With(repository.MaxPriceField, maxPrice.Decimal())
+	}
+	if req.CreatedAfter != nil {
+		query.With(repository.CreatedAfterField, req.CreatedAfter.Format(time.RFC3339Nano))
+	}
+	if req.CreatedBefore != nil {
+		query.With(repository.CreatedBeforeField, req.CreatedBefore.Format(time.RFC3339Nano))
+	}
+	if req.CategoryID != "" {
+		query.With(repository.CategoryIDField, req.CategoryID)
+	}
+	if tag := strings.ToLower(strings.TrimSpace(req.Tag)); tag != "" {
+		query.With(repository.TagField, tag)
+	}
+
+	return query, nil
+}
+
+// parsePriceBound parses the value of the price bound query parameter param as an exact amount of the currency.
+// An empty value returns nil.
+func parsePriceBound(param, value, currency string) (*model.Money, error) {
+	if value == "" {
+		return nil, nil
+	}
+	price, err := model.ParseMoney(value, currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", param, err)
+	}
+	if price.Amount < 0 {
+		return nil, fmt.Errorf("%s must not be negative", param)
+	}
+	return &price, nil
+}
+
+// productSortKey returns the value of the sort field for page tokens when it is not created_at.
+func productSortKey(product *model.Product, field repository.QueryField) string {
+	switch field {
+	case repository.PriceField:
+		return product.Price.Decimal()
+	case repository.NameField:
+		return product.Name
+	default:
+		return ""
+	}
+}
+
+// respondProductError maps errors returned by ProductService to HTTP responses.
+func respondProductError(c *gin.Context, id uuid.UUID, action string, err error) {
+	var notFoundErr *repository.NotFoundError
+	switch {
+	case errors.As(err, &notFoundErr):
+		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+	case errors.Is(err, repository.ErrVersionConflict):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "product version does not match If-Match"})
+	case errors.Is(err, service.ErrProductNotDeleted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrCategoryNotFound):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		slog.Error("failed to "+action+" product", slog.String("product_id", id.String()), slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action + " product"})
+	}
+}
+
 func toProductResponse(product *model.Product) ProductResponse {
-	return ProductResponse{
+	response := ProductResponse{
 		ID:          product.ID.String(),
 		Name:        product.Name,
 		Description: product.Description,
 		Price:       product.Price,
+		Tags:        product.Tags,
+		Version:     product.Version,
 		CreatedAt:   product.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:   product.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
+	if product.CategoryID != nil {
+		response.CategoryID = product.CategoryID.String()
+	}
+	if response.Tags == nil {
+		response.Tags = []string{}
+	}
+	if product.DeletedAt != nil {
+		response.DeletedAt = product.DeletedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return response
 }
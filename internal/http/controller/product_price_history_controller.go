@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+)
+
+// ListPriceHistoryRequest represents the query parameters for listing the price changes of a product.
+type ListPriceHistoryRequest struct {
+	Limit int32  `form:"limit"`
+	Token string `form:"token"`
+}
+
+// PriceHistoryEntryResponse represents one price change of a product.
+type PriceHistoryEntryResponse struct {
+	OldPrice      model.Money `json:"old_price"`
+	NewPrice      model.Money `json:"new_price"`
+	PercentChange *float64    `json:"percent_change,omitempty"`
+	ChangedAt     string      `json:"changed_at"`
+}
+
+// ListPriceHistoryResponse represents the response body for listing the price changes of a product.
+type ListPriceHistoryResponse struct {
+	PriceHistory  []PriceHistoryEntryResponse `json:"price_history"`
+	NextPageToken string                      `json:"next_page_token,omitempty"`
+	PrevPageToken string                      `json:"prev_page_token,omitempty"`
+	HasMore       bool                        `json:"has_more"`
+}
+
+// ListPriceHistory handles the HTTP GET request for listing the price changes of a product with pagination, newest first.
+func (pc *ProductController) ListPriceHistory(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	var req ListPriceHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := repository.NewQuery()
+	query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortDesc}
+	if err := query.ApplyPagination(req.Limit, req.Token, pc.pageTokens); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := pc.productService.ListPriceHistory(c.Request.Context(), id, *query)
+	if err != nil {
+		respondProductError(c, id, "list price history of", err)
+		return
+	}
+
+	response := ListPriceHistoryResponse{
+		PriceHistory: make([]PriceHistoryEntryResponse, 0, len(page.Entries)),
+		HasMore:      page.HasNext,
+	}
+	for _, entry := range page.Entries {
+		response.PriceHistory = append(response.PriceHistory, PriceHistoryEntryResponse{
+			OldPrice:      entry.OldPrice,
+			NewPrice:      entry.NewPrice,
+			PercentChange: entry.PercentChange(),
+			ChangedAt:     entry.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	// Generate page tokens pointing after the last and before the first entry
+	if len(page.Entries) > 0 {
+		if page.HasNext {
+			next := priceHistoryPaginator(page.Entries[len(page.Entries)-1], query.Sort)
+			response.NextPageToken = next.Encode(pc.pageTokens)
+		}
+		if page.HasPrev {
+			prev := priceHistoryPaginator(page.Entries[0], query.Sort)
+			prev.Backward = true
+			response.PrevPageToken = prev.Encode(pc.pageTokens)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// priceHistoryPaginator returns a cursor positioned at the given price history entry.
+func priceHistoryPaginator(entry *model.PriceHistoryEntry, sort repository.Sort) repository.Paginator {
+	return repository.Paginator{
+		LastID:        entry.ID,
+		LastCreatedAt: entry.CreatedAt,
+		Sort:          sort,
+	}
+}
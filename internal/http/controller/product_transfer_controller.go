@@ -0,0 +1,461 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+)
+
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+	// importBatchSize is the number of valid rows created per transaction during an import.
+	importBatchSize = 500
+	// maxNDJSONLineSize bounds a single NDJSON line read during an import.
+	maxNDJSONLineSize = 1 << 20
+	// csvTagSeparator separates the tags of a product within the tags column of a CSV file.
+	csvTagSeparator = "|"
+)
+
+// productCSVColumns are the columns written by the CSV export.
+// The import reads name, description, price, currency, category_id and tags.
+var productCSVColumns = []string{
+	"id", "name", "description", "price", "currency", "category_id", "tags", "version", "created_at", "updated_at", "deleted_at",
+}
+
+// ExportProductsRequest represents the query parameters for exporting products.
+// The filters match those of ListProductsRequest.
+type ExportProductsRequest struct {
+	Format         string     `form:"format" binding:"omitempty,oneof=csv ndjson"`
+	IncludeDeleted bool       `form:"include_deleted"`
+	NamePrefix     string     `form:"name_prefix"`
+	NameContains   string     `form:"name_contains"`
+	MinPrice       string     `form:"min_price"`
+	MaxPrice       string     `form:"max_price"`
+	Currency       string     `form:"currency"`
+	CreatedAfter   *time.Time `form:"created_after"`
+	CreatedBefore  *time.Time `form:"created_before"`
+	CategoryID     string     `form:"category_id" binding:"omitempty,uuid"`
+	Tag            string     `form:"tag"`
+}
+
+// ImportRowError describes an input row that was not imported.
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportProductsResponse represents the response body for importing products.
+type ImportProductsResponse struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportProductsErrorResponse represents the response body of an import that stopped because a batch of rows
+// could not be stored. The counts and errors cover the rows before the batch, which stay imported; the batch
+// spans the lines FailedFromLine to FailedToLine, and neither it nor the rows after it were imported.
+type ImportProductsErrorResponse struct {
+	Error string `json:"error"`
+	ImportProductsResponse
+	FailedFromLine int `json:"failed_from_line"`
+	FailedToLine   int `json:"failed_to_line"`
+}
+
+// ExportProducts handles the HTTP GET request for streaming all matching products as CSV or NDJSON in creation order.
+func (pc *ProductController) ExportProducts(c *gin.Context) {
+	var req ExportProductsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := ListProductsRequest{
+		IncludeDeleted: req.IncludeDeleted,
+		NamePrefix:     req.NamePrefix,
+		NameContains:   req.NameContains,
+		MinPrice:       req.MinPrice,
+		MaxPrice:       req.MaxPrice,
+		Currency:       req.Currency,
+		CreatedAfter:   req.CreatedAfter,
+		CreatedBefore:  req.CreatedBefore,
+		CategoryID:     req.CategoryID,
+		Tag:            req.Tag,
+	}.toQuery()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := req.Format
+	if format == "" {
+		format = formatCSV
+	}
+
+	// The response starts with the first page so that a failing first query can still be reported as an error
+	var encoder productEncoder
+	start := func() {
+		if format == formatNDJSON {
+			c.Header("Content-Type", "application/x-ndjson")
+			encoder = &ndjsonProductEncoder{encoder: json.NewEncoder(c.Writer)}
+		} else {
+			c.Header("Content-Type", "text/csv")
+			encoder = newCSVProductEncoder(c.Writer)
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+		c.Status(http.StatusOK)
+	}
+
+	err = pc.productService.ExportProducts(c.Request.Context(), *query, func(products []*model.Product) error {
+		if encoder == nil {
+			start()
+		}
+		for _, product := range products {
+			if err := encoder.encode(product); err != nil {
+				return err
+			}
+		}
+		if err := encoder.flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err == nil && encoder == nil {
+		start()
+		err = encoder.flush()
+	}
+	if err != nil {
+		slog.Error("failed to export products", slog.Any("err", err))
+		if encoder == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export products"})
+		}
+		// Otherwise the status is already sent, so the client sees a truncated stream
+		c.Abort()
+	}
+}
+
+// ImportProducts handles the HTTP POST request for importing products from a CSV or NDJSON body.
+// Every row is validated like CreateProductRequest; valid rows are created with their outbox events
+// in transactions of importBatchSize rows and invalid rows, including rows whose category does not exist,
+// are reported by line number.
+func (pc *ProductController) ImportProducts(c *gin.Context) {
+	format, err := importFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var decoder productDecoder
+	if format == formatNDJSON {
+		decoder = newNDJSONProductDecoder(c.Request.Body)
+	} else {
+		decoder, err = newCSVProductDecoder(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	response := ImportProductsResponse{Errors: []ImportRowError{}}
+	batch := make([]service.ProductInput, 0, importBatchSize)
+	lines := make([]int, 0, importBatchSize)
+	createBatch := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		// Rows the database rejects, e.g. because their category does not exist or was deleted meanwhile,
+		// are reported like invalid rows instead of failing the batch
+		_, itemErrs, err := pc.productService.CreateProducts(c.Request.Context(), batch, false)
+		if err != nil {
+			return err
+		}
+		for i, itemErr := range itemErrs {
+			switch {
+			case errors.Is(itemErr, service.ErrCategoryNotFound):
+				response.Failed++
+				response.Errors = append(response.Errors, ImportRowError{Line: lines[i], Error: itemErr.Error()})
+			case itemErr != nil:
+				slog.Error("failed to import product", slog.Int("line", lines[i]), slog.Any("err", itemErr))
+				response.Failed++
+				response.Errors = append(response.Errors, ImportRowError{Line: lines[i], Error: "failed to create product"})
+			default:
+				response.Imported++
+			}
+		}
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	for {
+		row, err := decoder.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "imported": response.Imported})
+			return
+		}
+
+		if row.err == nil {
+			row.err = validateCreateProductRequest(&row.product)
+		}
+		if row.err != nil {
+			response.Failed++
+			response.Errors = append(response.Errors, ImportRowError{Line: row.line, Error: row.err.Error()})
+			continue
+		}
+
+		batch = append(batch, row.product.toInput())
+		lines = append(lines, row.line)
+		if len(batch) == importBatchSize {
+			if err := createBatch(); err != nil {
+				respondImportError(c, err, response, lines)
+				return
+			}
+		}
+	}
+
+	if err := createBatch(); err != nil {
+		respondImportError(c, err, response, lines)
+		return
+	}
+
+	sortImportErrors(response.Errors)
+	c.JSON(http.StatusOK, response)
+}
+
+// sortImportErrors orders row errors by line, since rows rejected by the database are reported after the
+// invalid rows read while their batch was filled.
+func sortImportErrors(errs []ImportRowError) {
+	slices.SortStableFunc(errs, func(a, b ImportRowError) int {
+		return a.Line - b.Line
+	})
+}
+
+// importFormat picks the import format from the format query parameter or the Content-Type header.
+func importFormat(c *gin.Context) (string, error) {
+	if format := c.Query("format"); format != "" {
+		if format != formatCSV && format != formatNDJSON {
+			return "", errors.New("format must be csv or ndjson")
+		}
+		return format, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return formatCSV, nil
+	case "application/x-ndjson", "application/ndjson":
+		return formatNDJSON, nil
+	default:
+		return "", errors.New("set format to csv or ndjson, or send a text/csv or application/x-ndjson body")
+	}
+}
+
+// respondImportError reports a failure to store the import batch of the given lines along with the outcome
+// of the rows before it.
+func respondImportError(c *gin.Context, err error, response ImportProductsResponse, lines []int) {
+	slog.Error("failed to import products", slog.Int("imported", response.Imported), slog.Int("from_line", lines[0]), slog.Any("err", err))
+	sortImportErrors(response.Errors)
+	c.JSON(http.StatusInternalServerError, ImportProductsErrorResponse{
+		Error:                  "failed to import products",
+		ImportProductsResponse: response,
+		FailedFromLine:         lines[0],
+		FailedToLine:           lines[len(lines)-1],
+	})
+}
+
+// productEncoder writes products in an export format.
+type productEncoder interface {
+	encode(product *model.Product) error
+	flush() error
+}
+
+// csvProductEncoder writes products as CSV rows under a productCSVColumns header.
+type csvProductEncoder struct {
+	writer *csv.Writer
+}
+
+func newCSVProductEncoder(w io.Writer) *csvProductEncoder {
+	writer := csv.NewWriter(w)
+	// A write error is kept by the csv.Writer and reported on flush
+	_ = writer.Write(productCSVColumns)
+	return &csvProductEncoder{writer: writer}
+}
+
+func (e *csvProductEncoder) encode(product *model.Product) error {
+	response := toProductResponse(product)
+	return e.writer.Write([]string{
+		response.ID,
+		response.Name,
+		response.Description,
+		response.Price.Decimal(),
+		response.Price.Currency,
+		response.CategoryID,
+		strings.Join(response.Tags, csvTagSeparator),
+		strconv.FormatInt(response.Version, 10),
+		response.CreatedAt,
+		response.UpdatedAt,
+		response.DeletedAt,
+	})
+}
+
+func (e *csvProductEncoder) flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// ndjsonProductEncoder writes products as one ProductResponse JSON object per line.
+type ndjsonProductEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonProductEncoder) encode(product *model.Product) error {
+	return e.encoder.Encode(toProductResponse(product))
+}
+
+func (e *ndjsonProductEncoder) flush() error {
+	return nil
+}
+
+// importRow is a decoded input row. err is set when the row could not be decoded.
+type importRow struct {
+	line    int
+	product CreateProductRequest
+	err     error
+}
+
+// productDecoder reads rows in an import format. next returns io.EOF at the end of the input
+// and any other error when the input cannot be read any further.
+type productDecoder interface {
+	next() (importRow, error)
+}
+
+// csvProductDecoder reads products from CSV with a header row naming the name, description, price, currency,
+// category_id and tags columns, where only name and price are required. The currency defaults to model.DefaultCurrency
+// and the tags are separated by csvTagSeparator.
+// Other columns, such as those written by the export, are ignored.
+type csvProductDecoder struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVProductDecoder(r io.Reader) (*csvProductDecoder, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[column] = i
+	}
+	for _, required := range []string{"name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must contain a %q column", required)
+		}
+	}
+
+	return &csvProductDecoder{reader: reader, columns: columns}, nil
+}
+
+func (d *csvProductDecoder) next() (importRow, error) {
+	record, err := d.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return importRow{line: parseErr.Line, err: err}, nil
+		}
+		return importRow{}, err
+	}
+
+	line, _ := d.reader.FieldPos(0)
+	row := importRow{line: line}
+	row.product.Name = d.field(record, "name")
+	row.product.Description = d.field(record, "description")
+	if price := d.field(record, "price"); price != "" {
+		currency := d.field(record, "currency")
+		if currency == "" {
+			currency = model.DefaultCurrency
+		}
+		money, err := model.ParseMoney(price, currency)
+		if err != nil {
+			row.err = fmt.Errorf("invalid price: %w", err)
+		}
+		row.product.Price = &money
+	}
+	if categoryID := d.field(record, "category_id"); categoryID != "" && row.err == nil {
+		id, err := uuid.Parse(categoryID)
+		if err != nil {
+			row.err = fmt.Errorf("invalid category_id: %w", err)
+		}
+		row.product.CategoryID = &id
+	}
+	if tags := d.field(record, "tags"); tags != "" {
+		row.product.Tags = strings.Split(tags, csvTagSeparator)
+	}
+	return row, nil
+}
+
+// field returns the value of the named column, or an empty string if the record is too short or the column is absent.
+func (d *csvProductDecoder) field(record []string, column string) string {
+	i, ok := d.columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+// ndjsonProductDecoder reads products from newline-delimited JSON objects, skipping blank lines.
+type ndjsonProductDecoder struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONProductDecoder(r io.Reader) *ndjsonProductDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+	return &ndjsonProductDecoder{scanner: scanner}
+}
+
+func (d *ndjsonProductDecoder) next() (importRow, error) {
+	for d.scanner.Scan() {
+		d.line++
+		data := bytes.TrimSpace(d.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := importRow{line: d.line}
+		if err := json.Unmarshal(data, &row.product); err != nil {
+			row.err = fmt.Errorf("invalid JSON: %w", err)
+		}
+		return row, nil
+	}
+
+	if err := d.scanner.Err(); err != nil {
+		return importRow{}, fmt.Errorf("failed to read line %d: %w", d.line+1, err)
+	}
+	return importRow{}, io.EOF
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+)
+
+// UserController handles HTTP requests for registering users, logging them in and managing their roles.
+type UserController struct {
+	userService *service.UserService
+}
+
+// NewUserController creates a new UserController with the given user service.
+func NewUserController(userService *service.UserService) *UserController {
+	return &UserController{userService: userService}
+}
+
+// RegisterRequest represents the request body for registering a user.
+// Passwords are limited to 72 bytes, the most bcrypt takes into account.
+type RegisterRequest struct {
+	Email    string `json:"email" binding:"required,email,max=255"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+	Name     string `json:"name" binding:"required,max=255"`
+	Region   string `json:"region" binding:"max=255"`
+}
+
+// UserResponse represents the response body for a user. The password hash is never included.
+type UserResponse struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	Region    string `json:"region"`
+	Status    string `json:"status"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+// UpdateUserRequest represents the request body for changing the role or status of a user.
+type UpdateUserRequest struct {
+	Role   *string `json:"role" binding:"omitempty,oneof=admin editor viewer"`
+	Status *string `json:"status" binding:"omitempty,oneof=active suspended"`
+}
+
+// LoginRequest represents the request body for logging in.
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResponse represents the response body for a successful login.
+type LoginResponse struct {
+	AccessToken string       `json:"access_token"`
+	TokenType   string       `json:"token_type"`
+	ExpiresAt   string       `json:"expires_at"`
+	User        UserResponse `json:"user"`
+}
+
+// Register handles the HTTP POST request for registering a new user.
+func (uc *UserController) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := uc.userService.Register(c.Request.Context(), service.RegisterInput{
+		Email:    req.Email,
+		Password: req.Password,
+		Name:     req.Name,
+		Region:   req.Region,
+	})
+	if err != nil {
+		var uniqueErr *repository.UniqueConstraintError
+		if errors.As(err, &uniqueErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": "email is already registered"})
+			return
+		}
+		slog.Error("failed to register user", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register user"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, toUserResponse(user))
+}
+
+// Login handles the HTTP POST request for exchanging an email and password for an access token.
+func (uc *UserController) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := uc.userService.Login(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrUserSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Error("failed to log in user", slog.Any("err", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		AccessToken: result.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresAt:   result.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z07:00"),
+		User:        toUserResponse(result.User),
+	})
+}
+
+// GetUser handles the HTTP GET request for retrieving a user by ID.
+func (uc *UserController) GetUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	user, err := uc.userService.GetUser(c.Request.Context(), id)
+	if err != nil {
+		respondUserError(c, "get", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toUserResponse(user))
+}
+
+// UpdateUser handles the HTTP PATCH request for changing the role or status of a user.
+func (uc *UserController) UpdateUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := uc.userService.UpdateUser(c.Request.Context(), id, service.UpdateUserInput{
+		Role:   req.Role,
+		Status: req.Status,
+	})
+	if err != nil {
+		respondUserError(c, "update", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, toUserResponse(user))
+}
+
+func respondUserError(c *gin.Context, action string, err error) {
+	var notFoundErr *repository.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	slog.Error("failed to "+action+" user", slog.Any("err", err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to " + action + " user"})
+}
+
+func toUserResponse(user *model.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID.String(),
+		Email:     user.Email,
+		Name:      user.Name,
+		Region:    user.Region,
+		Status:    user.Status,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
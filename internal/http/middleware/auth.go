@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+)
+
+const (
+	// AuthUserKey is the gin context key under which Authenticate stores the claims of the authenticated user.
+	AuthUserKey = "auth_user"
+	// APIKeyHeader is the header carrying the API key of service-to-service callers.
+	APIKeyHeader = "X-API-Key"
+)
+
+// APIKeyVerifier verifies the API keys sent in the X-API-Key header and returns the claims of their owners.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (*auth.Claims, error)
+}
+
+// Authenticate is a middleware that requires a valid access token in the "Authorization: Bearer <token>" header,
+// or an API key in the X-API-Key header, and stores the claims of the authenticated user in the gin context
+// and as the actor of the audit log. Requests to public routes, given as "<METHOD> <route>"
+// (e.g. "POST /products/:id/inventory/reserve"), are passed through without credentials.
+func Authenticate(verifier auth.Verifier, apiKeys APIKeyVerifier, publicRoutes []string) gin.HandlerFunc {
+	public := make(map[string]bool, len(publicRoutes))
+	for _, route := range publicRoutes {
+		public[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if public[c.Request.Method+" "+c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		var claims *auth.Claims
+		if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+			var err error
+			claims, err = apiKeys.VerifyAPIKey(c.Request.Context(), apiKey)
+			switch {
+			case errors.Is(err, auth.ErrTokenExpired):
+				abortUnauthorized(c, "API key expired")
+				return
+			case errors.Is(err, auth.ErrInvalidToken):
+				abortUnauthorized(c, "invalid API key")
+				return
+			case err != nil:
+				slog.Error("failed to verify API key", slog.Any("error", err))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+				return
+			}
+		} else {
+			token, ok := bearerToken(c.GetHeader("Authorization"))
+			if !ok {
+				abortUnauthorized(c, "missing bearer token or API key")
+				return
+			}
+
+			var err error
+			claims, err = verifier.Verify(token)
+			if errors.Is(err, auth.ErrTokenExpired) {
+				abortUnauthorized(c, "access token expired")
+				return
+			}
+			if err != nil {
+				abortUnauthorized(c, "invalid access token")
+				return
+			}
+		}
+
+		c.Set(AuthUserKey, claims)
+
+		metadata := audit.FromContext(c.Request.Context())
+		metadata.Actor = claims.Subject
+		c.Request = c.Request.WithContext(audit.WithMetadata(c.Request.Context(), metadata))
+
+		c.Next()
+	}
+}
+
+// UserFinder looks up the stored users that Authorize checks the role and status of.
+type UserFinder interface {
+	GetUser(ctx context.Context, id uuid.UUID) (*model.User, error)
+}
+
+// Authorize is a middleware that lets a user authenticated by Authenticate through only if the policy grants
+// its role the permission and, for callers using an API key, the permission is one of the scopes of the key.
+// Suspended users are denied with 403 Forbidden. The role and status are read from the stored user so that
+// changes take effect immediately, and tokens and API keys of a user that no longer exists are rejected with
+// 401 Unauthorized. Users of an external identity provider are not users of this service and keep the role of
+// their token. Requests to public routes, which Authenticate passes through without a user, are not checked.
+func Authorize(policy *auth.Policy, users UserFinder, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := AuthUser(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if !claims.External {
+			id, err := uuid.Parse(claims.Subject)
+			if err != nil {
+				abortUnauthorized(c, "user no longer exists")
+				return
+			}
+
+			user, err := users.GetUser(c.Request.Context(), id)
+			var notFoundErr *repository.NotFoundError
+			switch {
+			case errors.As(err, &notFoundErr):
+				abortUnauthorized(c, "user no longer exists")
+				return
+			case err != nil:
+				slog.Error("failed to look up authenticated user", slog.String("user_id", claims.Subject), slog.Any("error", err))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+				return
+			case user.Status == model.UserStatusSuspended:
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user is suspended"})
+				return
+			default:
+				claims.Role = user.Role
+			}
+		}
+
+		if !policy.Allows(claims.Role, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		if claims.APIKeyID != "" && !slices.Contains(claims.Scopes, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key is missing the " + permission + " scope"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// WhenQueryFlag runs handler, e.g. Authenticate or Authorize, only for requests setting the boolean query parameter
+// param to true, so that an option of an otherwise public route can require authentication and a permission.
+// Other requests, including those with an invalid value that the route rejects itself, are passed through.
+func WhenQueryFlag(param string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if enabled, err := strconv.ParseBool(c.Query(param)); err != nil || !enabled {
+			c.Next()
+			return
+		}
+		handler(c)
+	}
+}
+
+// AuthUser returns the claims of the user authenticated by Authenticate, if any.
+func AuthUser(c *gin.Context) (*auth.Claims, bool) {
+	value, ok := c.Get(AuthUserKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*auth.Claims)
+	return claims, ok
+}
+
+// bearerToken extracts the token of an Authorization header using the Bearer scheme.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
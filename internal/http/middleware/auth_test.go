@@ -0,0 +1,315 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPIKeys is an APIKeyVerifier serving the claims of API keys from a map. The key "sk_expired" is expired.
+type fakeAPIKeys map[string]*auth.Claims
+
+func (f fakeAPIKeys) VerifyAPIKey(_ context.Context, key string) (*auth.Claims, error) {
+	if key == "sk_expired" {
+		return nil, auth.ErrTokenExpired
+	}
+	claims, ok := f[key]
+	if !ok {
+		return nil, auth.ErrInvalidToken
+	}
+	verified := *claims
+	return &verified, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	signer := auth.NewTokenSigner("secret", time.Hour)
+	user := &model.User{ID: uuid.New(), Email: "alice@example.com", Role: model.UserRoleViewer}
+	token, _, err := signer.Issue(user)
+	require.NoError(t, err)
+	apiKeys := fakeAPIKeys{"sk_valid": {Subject: user.ID.String(), APIKeyID: uuid.NewString()}}
+
+	router := gin.New()
+	authenticated := Authenticate(signer, apiKeys, []string{"POST /public/:id"})
+	handler := func(c *gin.Context) {
+		claims, ok := AuthUser(c)
+		subject := ""
+		if ok {
+			subject = claims.Subject
+		}
+		c.JSON(http.StatusOK, gin.H{"subject": subject, "actor": audit.FromContext(c.Request.Context()).Actor})
+	}
+	router.POST("/private", authenticated, handler)
+	router.POST("/public/:id", authenticated, handler)
+
+	do := func(target, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	doWithAPIKey := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/private", nil)
+		req.Header.Set(APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		w := do("/private", "Bearer "+token)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"subject":"`+user.ID.String()+`","actor":"`+user.ID.String()+`"}`, w.Body.String())
+	})
+
+	t.Run("scheme is case-insensitive", func(t *testing.T) {
+		w := do("/private", "bearer "+token)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		w := do("/private", "")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+		assert.Contains(t, w.Body.String(), "missing bearer token")
+	})
+
+	t.Run("other scheme", func(t *testing.T) {
+		w := do("/private", "Basic YWxpY2U6c2VjcmV0")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		forged, _, err := auth.NewTokenSigner("other", time.Hour).Issue(user)
+		require.NoError(t, err)
+
+		w := do("/private", "Bearer "+forged)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid access token")
+	})
+
+	t.Run("valid API key", func(t *testing.T) {
+		w := doWithAPIKey("sk_valid")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"subject":"`+user.ID.String()+`","actor":"`+user.ID.String()+`"}`, w.Body.String())
+	})
+
+	t.Run("expired API key", func(t *testing.T) {
+		w := doWithAPIKey("sk_expired")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "API key expired")
+	})
+
+	t.Run("invalid API key", func(t *testing.T) {
+		w := doWithAPIKey("sk_unknown")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid API key")
+	})
+
+	t.Run("public route", func(t *testing.T) {
+		w := do("/public/1", "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"subject":"","actor":"anonymous"}`, w.Body.String())
+	})
+}
+
+// externalVerifier is a Verifier accepting every token as one of an external identity provider for the
+// subject named by the token, prefixed like the subjects of auth.KeySet.
+type externalVerifier struct{ role string }
+
+func (v externalVerifier) Verify(token string) (*auth.Claims, error) {
+	return &auth.Claims{Subject: auth.ExternalSubjectPrefix + token, Role: v.role, External: true}, nil
+}
+
+// fakeUsers is a UserFinder serving users from a map.
+type fakeUsers map[uuid.UUID]*model.User
+
+func (f fakeUsers) GetUser(_ context.Context, id uuid.UUID) (*model.User, error) {
+	user, ok := f[id]
+	if !ok {
+		return nil, &repository.NotFoundError{Resource: "user"}
+	}
+	return user, nil
+}
+
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	signer := auth.NewTokenSigner("secret", time.Hour)
+	policy, err := auth.ParsePolicy([]byte(`{"roles": {"admin": ["*"], "editor": ["products:write"], "viewer": []}}`))
+	require.NoError(t, err)
+
+	editor := &model.User{ID: uuid.New(), Role: model.UserRoleEditor, Status: model.UserStatusActive}
+	suspended := &model.User{ID: uuid.New(), Role: model.UserRoleAdmin, Status: model.UserStatusSuspended}
+	demoted := &model.User{ID: uuid.New(), Role: model.UserRoleViewer, Status: model.UserStatusActive}
+	admin := &model.User{ID: uuid.New(), Role: model.UserRoleAdmin, Status: model.UserStatusActive}
+	users := fakeUsers{editor.ID: editor, suspended.ID: suspended, demoted.ID: demoted, admin.ID: admin}
+	apiKeys := fakeAPIKeys{
+		"sk_products": {Subject: editor.ID.String(), APIKeyID: uuid.NewString(), Scopes: []string{auth.PermissionProductsWrite}},
+		"sk_unscoped": {Subject: editor.ID.String(), APIKeyID: uuid.NewString()},
+		"sk_admin":    {Subject: admin.ID.String(), APIKeyID: uuid.NewString(), Scopes: []string{auth.PermissionProductsWrite}},
+		"sk_deleted":  {Subject: uuid.NewString(), Role: model.UserRoleAdmin, APIKeyID: uuid.NewString(), Scopes: []string{auth.PermissionProductsWrite}},
+	}
+
+	router := gin.New()
+	authenticated := Authenticate(signer, apiKeys, []string{"POST /public"})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/products", authenticated, Authorize(policy, users, auth.PermissionProductsWrite), ok)
+	router.GET("/audit", authenticated, Authorize(policy, users, auth.PermissionAuditRead), ok)
+	router.POST("/public", authenticated, Authorize(policy, users, auth.PermissionProductsWrite), ok)
+
+	do := func(method, target string, user *model.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if user != nil {
+			token, _, err := signer.Issue(user)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("role is granted the permission", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/products", editor).Code)
+	})
+
+	t.Run("role is not granted the permission", func(t *testing.T) {
+		w := do(http.MethodGet, "/audit", editor)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "permission denied")
+	})
+
+	t.Run("suspended user", func(t *testing.T) {
+		w := do(http.MethodGet, "/audit", suspended)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "user is suspended")
+	})
+
+	t.Run("stored role wins over the role of the token", func(t *testing.T) {
+		// The token still says admin, but the user has been demoted since
+		token, _, err := signer.Issue(&model.User{ID: demoted.ID, Role: model.UserRoleAdmin})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/products", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		w := do(http.MethodGet, "/audit", &model.User{ID: uuid.New(), Role: model.UserRoleAdmin})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "user no longer exists")
+	})
+
+	t.Run("users of an external identity provider keep the role of the token", func(t *testing.T) {
+		doExternal := func(role, subject string) int {
+			external := gin.New()
+			external.GET("/audit", Authenticate(externalVerifier{role: role}, apiKeys, nil), Authorize(policy, users, auth.PermissionAuditRead), ok)
+			req := httptest.NewRequest(http.MethodGet, "/audit", nil)
+			req.Header.Set("Authorization", "Bearer "+subject)
+			w := httptest.NewRecorder()
+			external.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, doExternal(model.UserRoleAdmin, uuid.NewString()))
+		assert.Equal(t, http.StatusOK, doExternal(model.UserRoleAdmin, "auth0|12345"))
+		// A subject equal to the ID of a user of this service does not take over the stored user
+		assert.Equal(t, http.StatusOK, doExternal(model.UserRoleAdmin, suspended.ID.String()))
+		assert.Equal(t, http.StatusForbidden, doExternal(model.UserRoleViewer, admin.ID.String()))
+	})
+
+	t.Run("API key scopes", func(t *testing.T) {
+		doWithAPIKey := func(method, target, key string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, nil)
+			req.Header.Set(APIKeyHeader, key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		assert.Equal(t, http.StatusOK, doWithAPIKey(http.MethodPost, "/products", "sk_products").Code)
+
+		w := doWithAPIKey(http.MethodPost, "/products", "sk_unscoped")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "missing the products:write scope")
+
+		// The role of the owner allows reading the audit log, but the key does not
+		assert.Equal(t, http.StatusForbidden, doWithAPIKey(http.MethodGet, "/audit", "sk_admin").Code)
+
+		// The owner of the key has been deleted since
+		w = doWithAPIKey(http.MethodPost, "/products", "sk_deleted")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "user no longer exists")
+	})
+
+	t.Run("public route", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodPost, "/public", nil).Code)
+	})
+}
+
+func TestWhenQueryFlag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	signer := auth.NewTokenSigner("secret", time.Hour)
+	policy, err := auth.ParsePolicy([]byte(`{"roles": {"editor": ["products:write"], "viewer": []}}`))
+	require.NoError(t, err)
+	editor := &model.User{ID: uuid.New(), Role: model.UserRoleEditor, Status: model.UserStatusActive}
+	viewer := &model.User{ID: uuid.New(), Role: model.UserRoleViewer, Status: model.UserStatusActive}
+	users := fakeUsers{editor.ID: editor, viewer.ID: viewer}
+
+	router := gin.New()
+	router.GET("/products",
+		WhenQueryFlag("include_deleted", Authenticate(signer, fakeAPIKeys{}, nil)),
+		WhenQueryFlag("include_deleted", Authorize(policy, users, auth.PermissionProductsWrite)),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(target string, user *model.User) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if user != nil {
+			token, _, err := signer.Issue(user)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("/products", nil))
+	assert.Equal(t, http.StatusOK, do("/products?include_deleted=false", nil))
+	assert.Equal(t, http.StatusOK, do("/products?include_deleted=nope", nil), "invalid values are left to the route")
+	assert.Equal(t, http.StatusUnauthorized, do("/products?include_deleted=true", nil))
+	assert.Equal(t, http.StatusForbidden, do("/products?include_deleted=1", viewer))
+	assert.Equal(t, http.StatusOK, do("/products?include_deleted=true", editor))
+}
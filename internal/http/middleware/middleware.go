@@ -6,6 +6,17 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
+)
+
+const (
+	// RequestIDHeader is the header carrying the ID of a request, both in requests and in responses.
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the gin context key under which RequestID stores the ID of the request.
+	RequestIDKey = "request_id"
+	// maxRequestIDLength bounds the client supplied request IDs that are kept.
+	maxRequestIDLength = 128
 )
 
 // Recovery is a middleware that recovers from panics and returns a 500 Internal Server Error
@@ -34,7 +45,7 @@ func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
 
 		// Handle preflight requests
@@ -65,8 +76,44 @@ func Logger() gin.HandlerFunc {
 			slog.String("method", c.Request.Method),
 			slog.String("path", c.Request.URL.Path),
 			slog.String("remote_ip", c.ClientIP()),
+			slog.String("request_id", c.GetString(RequestIDKey)),
 			slog.Int("status", c.Writer.Status()),
 			slog.Duration("duration", duration),
 		)
 	}
 }
+
+// RequestID is a middleware that identifies every request by the X-Request-ID header sent by the client,
+// or by a generated ID when the header is missing or invalid, and echoes the ID in the response.
+// The ID and the client IP are made available to the audit log through the request context.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		metadata := audit.FromContext(c.Request.Context())
+		metadata.RequestID = requestID
+		metadata.ClientIP = c.ClientIP()
+		c.Request = c.Request.WithContext(audit.WithMetadata(c.Request.Context(), metadata))
+
+		c.Next()
+	}
+}
+
+// validRequestID reports whether a client supplied request ID is short and made of printable ASCII characters only.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < ' ' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
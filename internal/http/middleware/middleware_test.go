@@ -3,11 +3,15 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRecovery(t *testing.T) {
@@ -123,7 +127,7 @@ func TestCORS(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
 		assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
-		assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "Content-Type, Authorization, X-API-Key", w.Header().Get("Access-Control-Allow-Headers"))
 		assert.Equal(t, "86400", w.Header().Get("Access-Control-Max-Age"))
 	})
 
@@ -143,7 +147,7 @@ func TestCORS(t *testing.T) {
 		assert.Equal(t, http.StatusNoContent, w.Code)
 		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
 		assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
-		assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "Content-Type, Authorization, X-API-Key", w.Header().Get("Access-Control-Allow-Headers"))
 		assert.Equal(t, "86400", w.Header().Get("Access-Control-Max-Age"))
 	})
 
@@ -233,3 +237,59 @@ func TestLogger(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 }
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(metadata *audit.Metadata) *gin.Engine {
+		router := gin.New()
+		router.Use(RequestID())
+		router.GET("/test", func(c *gin.Context) {
+			*metadata = audit.FromContext(c.Request.Context())
+			c.Status(http.StatusOK)
+		})
+		return router
+	}
+
+	t.Run("keeps the request ID sent by the client", func(t *testing.T) {
+		var metadata audit.Metadata
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set(RequestIDHeader, "req-42")
+		req.RemoteAddr = "203.0.113.7:51234"
+		w := httptest.NewRecorder()
+
+		newRouter(&metadata).ServeHTTP(w, req)
+
+		assert.Equal(t, "req-42", w.Header().Get(RequestIDHeader))
+		assert.Equal(t, "req-42", metadata.RequestID)
+		assert.Equal(t, "203.0.113.7", metadata.ClientIP)
+		assert.Equal(t, audit.AnonymousActor, metadata.Actor)
+	})
+
+	t.Run("generates a request ID when none is sent", func(t *testing.T) {
+		var metadata audit.Metadata
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		w := httptest.NewRecorder()
+
+		newRouter(&metadata).ServeHTTP(w, req)
+
+		_, err := uuid.Parse(w.Header().Get(RequestIDHeader))
+		require.NoError(t, err)
+		assert.Equal(t, w.Header().Get(RequestIDHeader), metadata.RequestID)
+	})
+
+	t.Run("replaces invalid request IDs", func(t *testing.T) {
+		for _, requestID := range []string{strings.Repeat("a", 129), "bad\tid"} {
+			var metadata audit.Metadata
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set(RequestIDHeader, requestID)
+			w := httptest.NewRecorder()
+
+			newRouter(&metadata).ServeHTTP(w, req)
+
+			assert.NotEqual(t, requestID, metadata.RequestID)
+			_, err := uuid.Parse(metadata.RequestID)
+			assert.NoError(t, err)
+		}
+	})
+}
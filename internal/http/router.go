@@ -2,24 +2,99 @@ package http
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
 	"github.com/iyhunko/microservices-with-sqs/internal/config"
 	"github.com/iyhunko/microservices-with-sqs/internal/http/controller"
 	"github.com/iyhunko/microservices-with-sqs/internal/http/middleware"
-	"github.com/iyhunko/microservices-with-sqs/internal/repository"
 )
 
-func InitRouter(_ *config.Config, _ repository.Repository, server *gin.Engine, productCtr *controller.ProductController) *gin.Engine {
+// AccessControl holds what the router needs to authenticate requests and to authorize them against the policy.
+type AccessControl struct {
+	Verifier auth.Verifier
+	APIKeys  middleware.APIKeyVerifier
+	Policy   *auth.Policy
+	Users    middleware.UserFinder
+}
+
+func InitRouter(cfg *config.Config, access AccessControl, userCtr *controller.UserController, server *gin.Engine, productCtr *controller.ProductController,
+	categoryCtr *controller.CategoryController, inventoryCtr *controller.InventoryController, auditCtr *controller.AuditController,
+	apiKeyCtr *controller.APIKeyController, eventCtr *controller.EventController,
+) *gin.Engine {
 	// Apply global middlewares
-	server.Use(middleware.Recovery()) // Prevent panics from crashing the server
-	server.Use(middleware.CORS())     // Enable Cross-Origin Resource Sharing
-	server.Use(middleware.Logger())   // Log HTTP requests
+	server.Use(middleware.Recovery())  // Prevent panics from crashing the server
+	server.Use(middleware.CORS())      // Enable Cross-Origin Resource Sharing
+	server.Use(middleware.Logger())    // Log HTTP requests
+	server.Use(middleware.RequestID()) // Identify requests in responses, logs and the audit log
+
+	// Protected routes require an authenticated user whose role is granted the permission,
+	// unless the route is configured as public
+	authenticated := middleware.Authenticate(access.Verifier, access.APIKeys, cfg.Auth.PublicRoutes)
+	authorize := func(permission string) gin.HandlerFunc {
+		return middleware.Authorize(access.Policy, access.Users, permission)
+	}
+	canWriteProducts := authorize(auth.PermissionProductsWrite)
+	canWriteCategories := authorize(auth.PermissionCategoriesWrite)
+	// Deleted products are only listed to callers that may restore them
+	canReadDeleted := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return middleware.WhenQueryFlag("include_deleted", handler)
+	}
+	canWriteInventory := authorize(auth.PermissionInventoryWrite)
+
+	// User endpoints
+	server.POST("/users/register", userCtr.Register)
+	server.POST("/auth/login", userCtr.Login)
+	server.GET("/users/:id", authenticated, authorize(auth.PermissionUsersRead), userCtr.GetUser)
+	server.PATCH("/users/:id", authenticated, authorize(auth.PermissionUsersWrite), userCtr.UpdateUser)
+
+	// API key endpoints, managing the keys of the authenticated user
+	canWriteAPIKeys := authorize(auth.PermissionAPIKeysWrite)
+	server.POST("/api-keys", authenticated, canWriteAPIKeys, apiKeyCtr.CreateAPIKey)
+	server.GET("/api-keys", authenticated, canWriteAPIKeys, apiKeyCtr.ListAPIKeys)
+	server.DELETE("/api-keys/:id", authenticated, canWriteAPIKeys, apiKeyCtr.RevokeAPIKey)
 
 	// Product endpoints
 	products := server.Group("/products")
 	{
-		products.POST("", productCtr.CreateProduct)
-		products.GET("", productCtr.ListProducts)
-		products.DELETE("/:id", productCtr.DeleteProduct)
+		products.POST("", authenticated, canWriteProducts, productCtr.CreateProduct)
+		products.GET("", canReadDeleted(authenticated), canReadDeleted(canWriteProducts), productCtr.ListProducts)
+		products.GET("/export", canReadDeleted(authenticated), canReadDeleted(canWriteProducts), productCtr.ExportProducts)
+		products.POST("/import", authenticated, canWriteProducts, productCtr.ImportProducts)
+		products.GET("/:id", productCtr.GetProduct)
+		products.PUT("/:id", authenticated, canWriteProducts, productCtr.ReplaceProduct)
+		products.PATCH("/:id", authenticated, canWriteProducts, productCtr.UpdateProduct)
+		products.DELETE("/:id", authenticated, canWriteProducts, productCtr.DeleteProduct)
+		products.POST("/:id/restore", authenticated, canWriteProducts, productCtr.RestoreProduct)
+		products.GET("/:id/price-history", productCtr.ListPriceHistory)
+		products.GET("/:id/inventory", inventoryCtr.GetInventory)
+		products.POST("/:id/inventory/adjust", authenticated, canWriteInventory, inventoryCtr.AdjustStock)
+		products.POST("/:id/inventory/reserve", authenticated, canWriteInventory, inventoryCtr.ReserveStock)
+		products.POST("/:id/inventory/release", authenticated, canWriteInventory, inventoryCtr.ReleaseStock)
+	}
+	// Custom methods on the collection, e.g. POST /products:batchCreate
+	server.POST("/products:action", authenticated, canWriteProducts, productCtr.ProductAction)
+
+	// Category endpoints
+	categories := server.Group("/categories")
+	{
+		categories.POST("", authenticated, canWriteCategories, categoryCtr.CreateCategory)
+		categories.GET("", categoryCtr.ListCategories)
+		categories.GET("/:id", categoryCtr.GetCategory)
+		categories.PUT("/:id", authenticated, canWriteCategories, categoryCtr.ReplaceCategory)
+		categories.DELETE("/:id", authenticated, canWriteCategories, categoryCtr.DeleteCategory)
+	}
+
+	// Audit log endpoints
+	server.GET("/audit", authenticated, authorize(auth.PermissionAuditRead), auditCtr.ListAuditLog)
+
+	// Admin endpoints for inspecting and replaying the events of the outbox table
+	events := server.Group("/admin/events", authenticated)
+	{
+		canReadEvents := authorize(auth.PermissionEventsRead)
+		canWriteEvents := authorize(auth.PermissionEventsWrite)
+		events.GET("", canReadEvents, eventCtr.ListEvents)
+		events.POST("/requeue", canWriteEvents, eventCtr.RequeueEvents)
+		events.GET("/:id", canReadEvents, eventCtr.GetEvent)
+		events.POST("/:id/requeue", canWriteEvents, eventCtr.RequeueEvent)
 	}
 
 	return server
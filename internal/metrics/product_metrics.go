@@ -12,9 +12,33 @@ var (
 		Help: "The total number of products created",
 	})
 
+	// ProductsUpdated is a Prometheus counter for tracking the total number of products updated.
+	ProductsUpdated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "products_updated_total",
+		Help: "The total number of products updated",
+	})
+
 	// ProductsDeleted is a Prometheus counter for tracking the total number of products deleted.
 	ProductsDeleted = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "products_deleted_total",
 		Help: "The total number of products deleted",
 	})
+
+	// ProductsRestored is a Prometheus counter for tracking the total number of soft-deleted products restored.
+	ProductsRestored = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "products_restored_total",
+		Help: "The total number of soft-deleted products restored",
+	})
+
+	// ProductsPurged is a Prometheus counter for tracking the total number of soft-deleted products permanently removed.
+	ProductsPurged = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "products_purged_total",
+		Help: "The total number of soft-deleted products permanently removed",
+	})
+
+	// IdempotentReplays is a Prometheus counter for tracking requests answered from a stored idempotency key.
+	IdempotentReplays = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "idempotent_replays_total",
+		Help: "The total number of requests answered from a stored idempotency key",
+	})
 )
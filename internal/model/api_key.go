@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey is a long-lived credential of a user for callers that cannot log in interactively.
+// Only the SHA-256 hash of the key is stored; KeyPrefix identifies the key in listings.
+// Scopes are the permissions the key is limited to.
+type APIKey struct {
+	ID         uuid.UUID  `db:"id"`
+	OwnerID    uuid.UUID  `db:"owner_id"`
+	Name       string     `db:"name"`
+	KeyPrefix  string     `db:"key_prefix"`
+	KeyHash    string     `db:"key_hash"`
+	Scopes     []string   `db:"scopes"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// TableName returns the database table name for the APIKey model.
+func (k *APIKey) TableName() string {
+	return "api_keys"
+}
+
+// InitMeta initializes the API key metadata including ID and timestamp.
+func (k *APIKey) InitMeta() {
+	k.ID = uuid.New()
+	k.CreatedAt = time.Now()
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditLogEntry records who changed a resource, how, and through which request.
+// Before and After hold JSON snapshots of the resource; Before is empty for creations.
+type AuditLogEntry struct {
+	ID           uuid.UUID       `db:"id"`
+	Actor        string          `db:"actor"`
+	Action       string          `db:"action"`
+	ResourceType string          `db:"resource_type"`
+	ResourceID   uuid.UUID       `db:"resource_id"`
+	Before       json.RawMessage `db:"before"`
+	After        json.RawMessage `db:"after"`
+	RequestID    string          `db:"request_id"`
+	ClientIP     string          `db:"client_ip"`
+	CreatedAt    time.Time       `db:"created_at"`
+}
+
+// TableName returns the database table name for the AuditLogEntry model.
+func (e *AuditLogEntry) TableName() string {
+	return "audit_log"
+}
+
+// InitMeta initializes the audit log entry metadata including ID and timestamp.
+func (e *AuditLogEntry) InitMeta() {
+	e.ID = uuid.New()
+	e.CreatedAt = time.Now()
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Category represents a product category. Categories form a tree through their parent.
+type Category struct {
+	ID        uuid.UUID  `db:"id"`
+	Name      string     `db:"name"`
+	ParentID  *uuid.UUID `db:"parent_id"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+}
+
+// TableName returns the database table name for the Category model.
+func (c *Category) TableName() string {
+	return "categories"
+}
+
+// InitMeta initializes the category metadata including ID and timestamps.
+func (c *Category) InitMeta() {
+	c.ID = uuid.New()
+	now := time.Now()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+}
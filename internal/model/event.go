@@ -15,18 +15,32 @@ const (
 	EventStatusPending EventStatus = "pending"
 	// EventStatusProcessed indicates the event has been successfully processed.
 	EventStatusProcessed EventStatus = "processed"
-	// EventStatusFailed indicates the event processing has failed.
-	EventStatusFailed EventStatus = "failed"
+	// EventStatusDead indicates the event could not be processed within the maximum number of attempts
+	// and is not retried anymore.
+	EventStatusDead EventStatus = "dead"
 )
 
-// Event represents an event entity for the outbox pattern.
+// Event represents an event entity for the outbox pattern. A pending event claimed by an event worker
+// is leased to it until LockedUntil, after which other workers may claim it again. Attempts counts the failed
+// attempts to process the event, the last of which failed with LastError; a pending event that failed
+// is retried at NextAttemptAt.
+//
+// Events are about an aggregate, e.g. a product, identified by AggregateID. Sequence is assigned by the database
+// in commit order within each aggregate, and the events of an aggregate are published in sequence order.
 type Event struct {
-	ID          uuid.UUID       `db:"id"`
-	EventType   string          `db:"event_type"`
-	EventData   json.RawMessage `db:"event_data"`
-	Status      EventStatus     `db:"status"`
-	CreatedAt   time.Time       `db:"created_at"`
-	ProcessedAt *time.Time      `db:"processed_at"`
+	ID            uuid.UUID       `db:"id"`
+	EventType     string          `db:"event_type"`
+	EventData     json.RawMessage `db:"event_data"`
+	Status        EventStatus     `db:"status"`
+	CreatedAt     time.Time       `db:"created_at"`
+	ProcessedAt   *time.Time      `db:"processed_at"`
+	LockedBy      *string         `db:"locked_by"`
+	LockedUntil   *time.Time      `db:"locked_until"`
+	Attempts      int             `db:"attempts"`
+	LastError     *string         `db:"last_error"`
+	NextAttemptAt *time.Time      `db:"next_attempt_at"`
+	Sequence      int64           `db:"sequence"`
+	AggregateID   uuid.UUID       `db:"aggregate_id"`
 }
 
 // TableName returns the database table name for the Event model.
@@ -35,9 +49,13 @@ func (e *Event) TableName() string {
 }
 
 // InitMeta initializes the event metadata including ID and timestamps.
+// An event without an aggregate is an aggregate of its own.
 func (e *Event) InitMeta() {
 	e.ID = uuid.New()
 	e.CreatedAt = time.Now()
+	if e.AggregateID == uuid.Nil {
+		e.AggregateID = e.ID
+	}
 	if e.Status == "" {
 		e.Status = EventStatusPending
 	}
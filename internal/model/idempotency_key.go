@@ -0,0 +1,30 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey records the outcome of a request sent with an Idempotency-Key header
+// so that retries of the same request can be answered without repeating it.
+// Keys are scoped to the actor that sent them, so different callers may use the same key.
+type IdempotencyKey struct {
+	Actor        string          `db:"actor"`
+	Key          string          `db:"idempotency_key"`
+	RequestHash  string          `db:"request_hash"`
+	ResourceID   uuid.UUID       `db:"resource_id"`
+	ResponseBody json.RawMessage `db:"response_body"`
+	CreatedAt    time.Time       `db:"created_at"`
+}
+
+// TableName returns the database table name for the IdempotencyKey model.
+func (k *IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// InitMeta initializes the idempotency key metadata.
+func (k *IdempotencyKey) InitMeta() {
+	k.CreatedAt = time.Now()
+}
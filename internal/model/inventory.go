@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Inventory represents the stock level of a product.
+// Reserved stock is held for pending orders and is not available for new reservations.
+type Inventory struct {
+	ProductID        uuid.UUID `db:"product_id"`
+	QuantityOnHand   int64     `db:"quantity_on_hand"`
+	QuantityReserved int64     `db:"quantity_reserved"`
+	CreatedAt        time.Time `db:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at"`
+}
+
+// TableName returns the database table name for the Inventory model.
+func (i *Inventory) TableName() string {
+	return "inventory"
+}
+
+// InitMeta initializes the inventory timestamps.
+func (i *Inventory) InitMeta() {
+	now := time.Now()
+	i.CreatedAt = now
+	i.UpdatedAt = now
+}
+
+// Available returns the quantity that can still be reserved.
+func (i *Inventory) Available() int64 {
+	return i.QuantityOnHand - i.QuantityReserved
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency assumed for amounts given without one.
+const DefaultCurrency = "USD"
+
+// maxAmountIntegerDigits bounds the number of digits before the decimal point of an amount so that it fits the
+// NUMERIC(15, 3) price columns, which also keeps its minor units well within an int64.
+const maxAmountIntegerDigits = 12
+
+var (
+	// ErrUnsupportedCurrency is returned for currency codes without known minor units.
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrInvalidAmount is returned when an amount is not a decimal number.
+	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrAmountPrecision is returned when an amount has more decimal places than its currency allows.
+	ErrAmountPrecision = errors.New("amount has more decimal places than the currency allows")
+	// ErrAmountTooLarge is returned when an amount has more than maxAmountIntegerDigits digits before the decimal point.
+	ErrAmountTooLarge = errors.New("amount is too large")
+)
+
+// currencyExponents maps the supported ISO 4217 currency codes to their number of minor unit digits.
+var currencyExponents = map[string]int{
+	"AUD": 2,
+	"BHD": 3,
+	"CAD": 2,
+	"CHF": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"PLN": 2,
+	"UAH": 2,
+	"USD": 2,
+}
+
+// Money is an exact amount of money kept in the minor units of an ISO 4217 currency,
+// e.g. 19.99 USD is Amount 1999 with Currency "USD".
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney creates Money from an amount in minor units after checking the currency and the size of the amount.
+func NewMoney(amount int64, currency string) (Money, error) {
+	exponent, err := CurrencyExponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	money := Money{Amount: amount, Currency: currency}
+	if amount <= -maxMinorUnits(exponent) || amount >= maxMinorUnits(exponent) {
+		return Money{}, fmt.Errorf("%w: %s has more than %d integer digits", ErrAmountTooLarge, money, maxAmountIntegerDigits)
+	}
+	return money, nil
+}
+
+// ParseMoney parses a decimal amount such as "19.99" in the given currency.
+// Trailing zeros beyond the minor units of the currency are accepted, any other extra digit is rejected.
+func ParseMoney(amount, currency string) (Money, error) {
+	exponent, err := CurrencyExponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	digits := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(strings.TrimPrefix(digits, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" && fraction == "" || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if len(strings.TrimLeft(whole, "0")) > maxAmountIntegerDigits {
+		return Money{}, fmt.Errorf("%w: %q has more than %d integer digits", ErrAmountTooLarge, amount, maxAmountIntegerDigits)
+	}
+	if trimmed := strings.TrimRight(fraction, "0"); len(trimmed) > exponent {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimal places for %s", ErrAmountPrecision, amount, exponent, currency)
+	}
+	fraction += strings.Repeat("0", exponent)
+	minorDigits := strings.TrimLeft(whole+fraction[:exponent], "0")
+	if minorDigits == "" {
+		minorDigits = "0"
+	}
+
+	minor, err := strconv.ParseInt(minorDigits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// maxMinorUnits returns the smallest amount in minor units of a currency with the given exponent that has more
+// than maxAmountIntegerDigits integer digits.
+func maxMinorUnits(exponent int) int64 {
+	limit := int64(1)
+	for i := 0; i < maxAmountIntegerDigits+exponent; i++ {
+		limit *= 10
+	}
+	return limit
+}
+
+// CurrencyExponent returns the number of minor unit digits of a supported currency.
+func CurrencyExponent(currency string) (int, error) {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+	return exponent, nil
+}
+
+// Decimal formats the amount as a decimal number with exactly the minor unit digits of the currency, e.g. "19.99".
+func (m Money) Decimal() string {
+	exponent := currencyExponents[m.Currency]
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String formats the money as its decimal amount followed by the currency code, e.g. "19.99 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// moneyJSON is the JSON form of Money. The amount is a decimal string so that clients never see binary floating point.
+type moneyJSON struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON encodes the money as {"amount": "19.99", "currency": "USD"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// UnmarshalJSON decodes money from {"amount": "19.99", "currency": "USD"} where the amount may also be a JSON number.
+// A bare number or numeric string is read as an amount in DefaultCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var value moneyJSON
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &value); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(trimmed, &value.Amount); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidAmount, trimmed)
+	}
+
+	if value.Currency == "" {
+		value.Currency = DefaultCurrency
+	}
+
+	parsed, err := ParseMoney(value.Amount.String(), value.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// isDigits reports whether s consists of ASCII digits only. An empty string counts as digits.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
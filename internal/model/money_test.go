@@ -0,0 +1,107 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		want     Money
+		wantErr  error
+	}{
+		{name: "two decimals", amount: "19.99", currency: "USD", want: Money{Amount: 1999, Currency: "USD"}},
+		{name: "whole amount", amount: "20", currency: "EUR", want: Money{Amount: 2000, Currency: "EUR"}},
+		{name: "single decimal", amount: "0.5", currency: "USD", want: Money{Amount: 50, Currency: "USD"}},
+		{name: "trailing zeros", amount: "19.990", currency: "USD", want: Money{Amount: 1999, Currency: "USD"}},
+		{name: "zero exponent", amount: "1500", currency: "JPY", want: Money{Amount: 1500, Currency: "JPY"}},
+		{name: "three decimals", amount: "1.234", currency: "KWD", want: Money{Amount: 1234, Currency: "KWD"}},
+		{name: "negative", amount: "-3.10", currency: "USD", want: Money{Amount: -310, Currency: "USD"}},
+		{name: "too many decimals", amount: "19.999", currency: "USD", wantErr: ErrAmountPrecision},
+		{name: "decimals for zero exponent", amount: "15.5", currency: "JPY", wantErr: ErrAmountPrecision},
+		{name: "unknown currency", amount: "10", currency: "XYZ", wantErr: ErrUnsupportedCurrency},
+		{name: "not a number", amount: "abc", currency: "USD", wantErr: ErrInvalidAmount},
+		{name: "empty", amount: "", currency: "USD", wantErr: ErrInvalidAmount},
+		{name: "exponent notation", amount: "1e3", currency: "USD", wantErr: ErrInvalidAmount},
+		{name: "largest amount", amount: "999999999999.999", currency: "KWD", want: Money{Amount: 999999999999999, Currency: "KWD"}},
+		{name: "leading zeros", amount: "0000000000000012.50", currency: "USD", want: Money{Amount: 1250, Currency: "USD"}},
+		{name: "too large", amount: "1000000000000", currency: "USD", wantErr: ErrAmountTooLarge},
+		{name: "too large negative", amount: "-1000000000000.00", currency: "USD", wantErr: ErrAmountTooLarge},
+		{name: "beyond int64", amount: "100000000000000000000", currency: "USD", wantErr: ErrAmountTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.amount, tt.currency)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewMoney(t *testing.T) {
+	money, err := NewMoney(999999999999999, "BHD")
+	require.NoError(t, err)
+	assert.Equal(t, "999999999999.999", money.Decimal())
+
+	_, err = NewMoney(100000000000000, "USD")
+	assert.ErrorIs(t, err, ErrAmountTooLarge)
+	_, err = NewMoney(-100000000000000, "USD")
+	assert.ErrorIs(t, err, ErrAmountTooLarge)
+	_, err = NewMoney(100, "XYZ")
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	assert.Equal(t, "19.99", Money{Amount: 1999, Currency: "USD"}.Decimal())
+	assert.Equal(t, "0.05", Money{Amount: 5, Currency: "EUR"}.Decimal())
+	assert.Equal(t, "-1.50", Money{Amount: -150, Currency: "USD"}.Decimal())
+	assert.Equal(t, "1500", Money{Amount: 1500, Currency: "JPY"}.Decimal())
+	assert.Equal(t, "0.001", Money{Amount: 1, Currency: "BHD"}.Decimal())
+	assert.Equal(t, "19.99 USD", Money{Amount: 1999, Currency: "USD"}.String())
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(Money{Amount: 1999, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"19.99","currency":"EUR"}`, string(data))
+
+	tests := []struct {
+		name    string
+		input   string
+		want    Money
+		wantErr error
+	}{
+		{name: "object with string amount", input: `{"amount":"19.99","currency":"EUR"}`, want: Money{Amount: 1999, Currency: "EUR"}},
+		{name: "object with number amount", input: `{"amount":19.99,"currency":"GBP"}`, want: Money{Amount: 1999, Currency: "GBP"}},
+		{name: "object without currency", input: `{"amount":"5"}`, want: Money{Amount: 500, Currency: DefaultCurrency}},
+		{name: "bare number", input: `1299.99`, want: Money{Amount: 129999, Currency: DefaultCurrency}},
+		{name: "numeric string", input: `"0.10"`, want: Money{Amount: 10, Currency: DefaultCurrency}},
+		{name: "too precise", input: `{"amount":"0.001","currency":"USD"}`, wantErr: ErrAmountPrecision},
+		{name: "unknown currency", input: `{"amount":"1","currency":"ABC"}`, wantErr: ErrUnsupportedCurrency},
+		{name: "boolean", input: `true`, wantErr: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceHistoryEntry records a change of the price of a product.
+type PriceHistoryEntry struct {
+	ID        uuid.UUID `db:"id"`
+	ProductID uuid.UUID `db:"product_id"`
+	OldPrice  Money     `db:"old_price"`
+	NewPrice  Money     `db:"new_price"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// TableName returns the database table name for the PriceHistoryEntry model.
+func (e *PriceHistoryEntry) TableName() string {
+	return "product_price_history"
+}
+
+// InitMeta initializes the price history entry metadata including ID and timestamp.
+func (e *PriceHistoryEntry) InitMeta() {
+	e.ID = uuid.New()
+	e.CreatedAt = time.Now()
+}
+
+// PercentChange returns the change from the old to the new price in percent, rounded to two decimal places.
+// It returns nil when the prices are in different currencies or the old price is zero.
+func (e *PriceHistoryEntry) PercentChange() *float64 {
+	if e.OldPrice.Currency != e.NewPrice.Currency || e.OldPrice.Amount == 0 {
+		return nil
+	}
+	percent := float64(e.NewPrice.Amount-e.OldPrice.Amount) / float64(e.OldPrice.Amount) * 100
+	percent = math.Round(percent*100) / 100
+	return &percent
+}
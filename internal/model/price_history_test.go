@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceHistoryEntry_PercentChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		oldPrice Money
+		newPrice Money
+		want     *float64
+	}{
+		{name: "drop", oldPrice: Money{Amount: 10000, Currency: "USD"}, newPrice: Money{Amount: 7500, Currency: "USD"}, want: ptr(-25.0)},
+		{name: "rise rounded", oldPrice: Money{Amount: 300, Currency: "EUR"}, newPrice: Money{Amount: 400, Currency: "EUR"}, want: ptr(33.33)},
+		{name: "currency change", oldPrice: Money{Amount: 100, Currency: "USD"}, newPrice: Money{Amount: 100, Currency: "EUR"}},
+		{name: "from zero", oldPrice: Money{Amount: 0, Currency: "USD"}, newPrice: Money{Amount: 100, Currency: "USD"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := PriceHistoryEntry{OldPrice: tt.oldPrice, NewPrice: tt.newPrice}
+			got := entry.PercentChange()
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.InDelta(t, *tt.want, *got, 0.0001)
+		})
+	}
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
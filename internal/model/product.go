@@ -1,19 +1,37 @@
 package model
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	// MaxProductTags is the number of tags a product can carry.
+	MaxProductTags = 20
+	// MaxTagLength matches the size of the product_tags.tag column.
+	MaxTagLength = 50
+)
+
+// ErrInvalidTags is returned when product tags are empty, too long or too many.
+var ErrInvalidTags = errors.New("invalid tags")
+
 // Product represents a product entity with its properties and metadata.
 type Product struct {
-	ID          uuid.UUID `db:"id"`
-	Name        string    `db:"name"`
-	Description string    `db:"description"`
-	Price       float64   `db:"price"`
-	UpdatedAt   time.Time `db:"updated_at"`
-	CreatedAt   time.Time `db:"created_at"`
+	ID          uuid.UUID  `db:"id"`
+	Name        string     `db:"name"`
+	Description string     `db:"description"`
+	Price       Money      `db:"price"` // stored in the price and currency columns
+	UpdatedAt   time.Time  `db:"updated_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+	Version     int64      `db:"version"`
+	DeletedAt   *time.Time `db:"deleted_at"`
+	CategoryID  *uuid.UUID `db:"category_id"`
+	Tags        []string   `db:"-"` // stored in the product_tags table
 }
 
 // TableName returns the database table name for the Product model.
@@ -21,10 +39,34 @@ func (p *Product) TableName() string {
 	return "products"
 }
 
-// InitMeta initializes the product metadata including ID and timestamps.
+// InitMeta initializes the product metadata including ID, timestamps and version.
 func (p *Product) InitMeta() {
 	p.ID = uuid.New()
 	now := time.Now()
 	p.CreatedAt = now
 	p.UpdatedAt = now
+	p.Version = 1
+}
+
+// NormalizeTags trims and lowercases the given tags, drops duplicates and sorts them.
+// Empty tags, tags longer than MaxTagLength and more than MaxProductTags tags are rejected with ErrInvalidTags.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, fmt.Errorf("%w: tags must not be empty", ErrInvalidTags)
+		}
+		if len([]rune(tag)) > MaxTagLength {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidTags, tag, MaxTagLength)
+		}
+		normalized = append(normalized, tag)
+	}
+
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > MaxProductTags {
+		return nil, fmt.Errorf("%w: a product can have at most %d tags", ErrInvalidTags, MaxProductTags)
+	}
+	return normalized, nil
 }
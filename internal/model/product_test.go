@@ -0,0 +1,43 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tooMany := make([]string, 0, MaxProductTags+1)
+	for i := range MaxProductTags + 1 {
+		tooMany = append(tooMany, fmt.Sprintf("tag-%02d", i))
+	}
+
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr error
+	}{
+		{name: "no tags", tags: nil, want: []string{}},
+		{name: "trimmed and lowercased", tags: []string{" Sale ", "NEW"}, want: []string{"new", "sale"}},
+		{name: "duplicates removed", tags: []string{"sale", "Sale", "new"}, want: []string{"new", "sale"}},
+		{name: "empty tag", tags: []string{"sale", "  "}, wantErr: ErrInvalidTags},
+		{name: "tag too long", tags: []string{strings.Repeat("a", MaxTagLength+1)}, wantErr: ErrInvalidTags},
+		{name: "too many tags", tags: tooMany, wantErr: ErrInvalidTags},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTags(tt.tags)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
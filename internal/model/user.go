@@ -6,6 +6,21 @@ import (
 	"github.com/google/uuid"
 )
 
+// User roles. The permissions of each role are defined by the access control policy.
+const (
+	UserRoleAdmin  = "admin"
+	UserRoleEditor = "editor"
+	UserRoleViewer = "viewer"
+)
+
+// User statuses.
+const (
+	// UserStatusActive is the status of users that may log in and use the API.
+	UserStatusActive = "active"
+	// UserStatusSuspended is the status of users that are denied access to the API.
+	UserStatusSuspended = "suspended"
+)
+
 // User represents a user entity with authentication and profile information.
 type User struct {
 	ID        uuid.UUID `db:"id"`
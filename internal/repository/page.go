@@ -0,0 +1,54 @@
+package repository
+
+import "slices"
+
+// Page represents one page of listed resources and what lies around it.
+type Page struct {
+	// Resources are the resources of the page in the requested order.
+	Resources []Resource
+	// HasNext reports whether more resources follow the last one.
+	HasNext bool
+	// HasPrev reports whether resources precede the first one.
+	HasPrev bool
+	// TotalCount is the number of resources matching the filters, only set when Query.IncludeTotal is requested.
+	// It is counted apart from the page itself, so it is approximate under concurrent writes.
+	TotalCount *int64
+}
+
+// NewPage builds a page from rows fetched with up to Query.FetchLimit rows in Query.Keyset order.
+func NewPage(query Query, rows []Resource) *Page {
+	limit := query.PageLimit()
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	page := &Page{Resources: rows}
+	if query.Backward() {
+		slices.Reverse(page.Resources)
+		page.HasPrev = hasMore
+		// The cursor came from a later page
+		page.HasNext = true
+	} else {
+		page.HasNext = hasMore
+		page.HasPrev = query.Paginator != nil
+	}
+
+	return page
+}
+
+// First returns the first resource of the page, or nil if the page is empty.
+func (p *Page) First() Resource {
+	if len(p.Resources) == 0 {
+		return nil
+	}
+	return p.Resources[0]
+}
+
+// Last returns the last resource of the page, or nil if the page is empty.
+func (p *Page) Last() Resource {
+	if len(p.Resources) == 0 {
+		return nil
+	}
+	return p.Resources[len(p.Resources)-1]
+}
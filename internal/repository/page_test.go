@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type testResource struct {
+	name string
+}
+
+func (r *testResource) InitMeta() {}
+
+func testResources(names ...string) []Resource {
+	resources := make([]Resource, 0, len(names))
+	for _, name := range names {
+		resources = append(resources, &testResource{name: name})
+	}
+	return resources
+}
+
+func resourceNames(resources []Resource) []string {
+	names := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		names = append(names, resource.(*testResource).name)
+	}
+	return names
+}
+
+func TestNewPage(t *testing.T) {
+	t.Run("should report next page when extra row was fetched", func(t *testing.T) {
+		// given
+		query := Query{Limit: 2}
+
+		// when
+		page := NewPage(query, testResources("a", "b", "c"))
+
+		// then
+		assert.Equal(t, []string{"a", "b"}, resourceNames(page.Resources))
+		assert.True(t, page.HasNext)
+		assert.False(t, page.HasPrev)
+	})
+
+	t.Run("should report last page", func(t *testing.T) {
+		// given
+		query := Query{Limit: 2, Paginator: &Paginator{LastID: uuid.New()}}
+
+		// when
+		page := NewPage(query, testResources("c", "d"))
+
+		// then
+		assert.Equal(t, []string{"c", "d"}, resourceNames(page.Resources))
+		assert.False(t, page.HasNext)
+		assert.True(t, page.HasPrev)
+	})
+
+	t.Run("should restore order when walking backward", func(t *testing.T) {
+		// given
+		query := Query{Limit: 2, Paginator: &Paginator{LastID: uuid.New(), Backward: true}}
+
+		// when
+		page := NewPage(query, testResources("c", "b", "a"))
+
+		// then
+		assert.Equal(t, []string{"b", "c"}, resourceNames(page.Resources))
+		assert.True(t, page.HasNext)
+		assert.True(t, page.HasPrev)
+	})
+
+	t.Run("should report first page when walking backward", func(t *testing.T) {
+		// given
+		query := Query{Limit: 2, Paginator: &Paginator{LastID: uuid.New(), Backward: true}}
+
+		// when
+		page := NewPage(query, testResources("b", "a"))
+
+		// then
+		assert.Equal(t, []string{"a", "b"}, resourceNames(page.Resources))
+		assert.True(t, page.HasNext)
+		assert.False(t, page.HasPrev)
+	})
+}
+
+func TestQuery_Keyset(t *testing.T) {
+	tests := []struct {
+		name           string
+		sort           Sort
+		backward       bool
+		wantComparison string
+		wantDirection  string
+	}{
+		{"default sort forward", Sort{}, false, "<", "DESC"},
+		{"default sort backward", Sort{}, true, ">", "ASC"},
+		{"ascending forward", Sort{Field: PriceField, Direction: SortAsc}, false, ">", "ASC"},
+		{"ascending backward", Sort{Field: PriceField, Direction: SortAsc}, true, "<", "DESC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			query := Query{Sort: tt.sort, Paginator: &Paginator{Backward: tt.backward}}
+
+			// when
+			comparison, direction := query.Keyset()
+
+			// then
+			assert.Equal(t, tt.wantComparison, comparison)
+			assert.Equal(t, tt.wantDirection, direction)
+		})
+	}
+}
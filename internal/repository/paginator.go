@@ -1,9 +1,9 @@
 package repository
 
 import (
-	"encoding/base64"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,6 +13,12 @@ import (
 var (
 	// ErrInvalidPaginationToken is returned when a pagination token cannot be decoded.
 	ErrInvalidPaginationToken = errors.New("token is invalid")
+	// ErrPageTokenSignature is returned when a pagination token was forged or modified.
+	ErrPageTokenSignature = errors.New("page token is invalid or has been tampered with")
+	// ErrPageTokenExpired is returned when a pagination token is used after its expiry.
+	ErrPageTokenExpired = errors.New("page token has expired, start again from the first page")
+	// ErrPageTokenSortMismatch is returned when a page token is used with a different sort order than it was issued for.
+	ErrPageTokenSortMismatch = errors.New("page token does not match the requested sort order")
 )
 
 const (
@@ -22,35 +28,48 @@ const (
 )
 
 // Paginator represents pagination state using cursor-based pagination.
+// The cursor is the sort key of the item the next page starts after (or, walking backward, ends before) followed by its ID.
 type Paginator struct {
 	LastID        uuid.UUID
 	LastCreatedAt time.Time
+	// Sort is the ordering the token was issued for. A zero value means DefaultSort.
+	Sort Sort
+	// LastValue is the sort key of the cursor item when sorting by a field other than created_at.
+	LastValue string
+	// Backward makes the token point to the page before the cursor item instead of the one after it.
+	Backward bool
 }
 
-// Encode encodes the paginator state into a base64-encoded token.
-func (t Paginator) Encode() string {
-	key := fmt.Sprintf("%s,%s", t.LastCreatedAt.Format(time.RFC3339Nano), t.LastID)
-	return base64.StdEncoding.EncodeToString([]byte(key))
+// Encode encodes the paginator state into a signed, opaque token.
+func (t Paginator) Encode(signer *PageTokenSigner) string {
+	sort := t.Sort.orDefault()
+	// LastValue goes last because it is free text and may itself contain commas
+	key := fmt.Sprintf("%s,%s,%t,%s,%s,%s", sort.Field, sort.Direction, t.Backward, t.LastCreatedAt.Format(time.RFC3339Nano), t.LastID, t.LastValue)
+	return signer.seal(key)
 }
 
-// DecodePageToken decodes a base64-encoded pagination token into a Paginator.
-func DecodePageToken(encodedToken string) (*Paginator, error) {
-	bytes, err := base64.StdEncoding.DecodeString(encodedToken)
+// DecodePageToken verifies a token issued by Paginator.Encode and decodes it into a Paginator.
+// Forged or modified tokens fail with ErrPageTokenSignature and outdated ones with ErrPageTokenExpired.
+func DecodePageToken(encodedToken string, signer *PageTokenSigner) (*Paginator, error) {
+	decodedStr, err := signer.open(encodedToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode base64 token: %w", err)
+		return nil, err
 	}
-	decodedStr := string(bytes)
-	tokenParts := strings.Split(decodedStr, ",")
-	expectedTokenParts := 2
+	expectedTokenParts := 6
+	tokenParts := strings.SplitN(decodedStr, ",", expectedTokenParts)
 	if len(tokenParts) != expectedTokenParts {
 		return nil, fmt.Errorf("invalid token format: %w", ErrInvalidPaginationToken)
 	}
 
-	createdAt, err := time.Parse(time.RFC3339Nano, tokenParts[0])
+	backward, err := strconv.ParseBool(tokenParts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token direction: %w", err)
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, tokenParts[3])
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token timestamp: %w", err)
 	}
-	id, err := uuid.Parse(tokenParts[1])
+	id, err := uuid.Parse(tokenParts[4])
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token ID: %w", err)
 	}
@@ -58,5 +77,8 @@ func DecodePageToken(encodedToken string) (*Paginator, error) {
 	return &Paginator{
 		LastID:        id,
 		LastCreatedAt: createdAt,
+		Sort:          Sort{Field: QueryField(tokenParts[0]), Direction: SortDirection(tokenParts[1])},
+		LastValue:     tokenParts[5],
+		Backward:      backward,
 	}, nil
 }
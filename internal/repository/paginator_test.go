@@ -3,6 +3,8 @@ package repository
 import (
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,12 +13,14 @@ import (
 )
 
 func TestPaginator(t *testing.T) {
+	signer := NewPageTokenSigner("test-secret", time.Hour)
+
 	t.Run("should fail empty token", func(t *testing.T) {
 		// given
 		pageToken := ""
 
 		// when
-		paginator, err := DecodePageToken(pageToken)
+		paginator, err := DecodePageToken(pageToken, signer)
 
 		// then
 		assert.True(t, errors.Is(err, ErrInvalidPaginationToken))
@@ -28,15 +32,97 @@ func TestPaginator(t *testing.T) {
 		pageToken := "querty123"
 
 		// when
-		paginator, err := DecodePageToken(pageToken)
+		paginator, err := DecodePageToken(pageToken, signer)
+
+		// then
+		assert.True(t, errors.Is(err, ErrInvalidPaginationToken))
+		assert.Nil(t, paginator)
+	})
+
+	t.Run("should fail unsigned legacy token", func(t *testing.T) {
+		// given
+		key := fmt.Sprintf("%s,%s", time.Now().Format(time.RFC3339Nano), uuid.New())
+		pageToken := base64.StdEncoding.EncodeToString([]byte(key))
+
+		// when
+		paginator, err := DecodePageToken(pageToken, signer)
+
+		// then
+		assert.True(t, errors.Is(err, ErrInvalidPaginationToken))
+		assert.Nil(t, paginator)
+	})
+
+	t.Run("should hide paginator state", func(t *testing.T) {
+		// given
+		originalPaginator := Paginator{LastID: uuid.New(), LastCreatedAt: time.Now()}
+
+		// when
+		encodedToken := originalPaginator.Encode(signer)
+
+		// then
+		assert.True(t, strings.HasPrefix(encodedToken, "v1."))
+		body := strings.Split(encodedToken, ".")[1]
+		decodedBody, err := base64.RawURLEncoding.DecodeString(body)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(decodedBody), originalPaginator.LastID.String())
+	})
+
+	t.Run("should fail tampered token", func(t *testing.T) {
+		// given
+		encodedToken := Paginator{LastID: uuid.New(), LastCreatedAt: time.Now()}.Encode(signer)
+		parts := strings.Split(encodedToken, ".")
+		body, err := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.NoError(t, err)
+		body[len(body)-1] ^= 0x01
+		tamperedToken := parts[0] + "." + base64.RawURLEncoding.EncodeToString(body) + "." + parts[2]
+
+		// when
+		paginator, err := DecodePageToken(tamperedToken, signer)
 
 		// then
-		assert.Error(t, err)
-		var corruptInputErr base64.CorruptInputError
-		assert.True(t, errors.As(err, &corruptInputErr))
+		assert.ErrorIs(t, err, ErrPageTokenSignature)
 		assert.Nil(t, paginator)
 	})
 
+	t.Run("should fail token signed with another secret", func(t *testing.T) {
+		// given
+		encodedToken := Paginator{LastID: uuid.New(), LastCreatedAt: time.Now()}.Encode(NewPageTokenSigner("other-secret", time.Hour))
+
+		// when
+		paginator, err := DecodePageToken(encodedToken, signer)
+
+		// then
+		assert.ErrorIs(t, err, ErrPageTokenSignature)
+		assert.Nil(t, paginator)
+	})
+
+	t.Run("should fail expired token", func(t *testing.T) {
+		// given
+		expiringSigner := NewPageTokenSigner("test-secret", time.Minute)
+		encodedToken := Paginator{LastID: uuid.New(), LastCreatedAt: time.Now()}.Encode(expiringSigner)
+		expiringSigner.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+		// when
+		paginator, err := DecodePageToken(encodedToken, expiringSigner)
+
+		// then
+		assert.ErrorIs(t, err, ErrPageTokenExpired)
+		assert.Nil(t, paginator)
+	})
+
+	t.Run("should not expire without ttl", func(t *testing.T) {
+		// given
+		eternalSigner := NewPageTokenSigner("test-secret", 0)
+		encodedToken := Paginator{LastID: uuid.New(), LastCreatedAt: time.Now()}.Encode(eternalSigner)
+		eternalSigner.now = func() time.Time { return time.Now().AddDate(10, 0, 0) }
+
+		// when
+		_, err := DecodePageToken(encodedToken, eternalSigner)
+
+		// then
+		assert.NoError(t, err)
+	})
+
 	t.Run("should succeed", func(t *testing.T) {
 		// given
 		originalPaginator := Paginator{
@@ -45,12 +131,86 @@ func TestPaginator(t *testing.T) {
 		}
 
 		// when
-		encodedToken := originalPaginator.Encode()
-		decodedPaginator, err := DecodePageToken(encodedToken)
+		encodedToken := originalPaginator.Encode(signer)
+		decodedPaginator, err := DecodePageToken(encodedToken, signer)
 
 		// then
 		assert.NoError(t, err)
 		assert.Equal(t, originalPaginator.LastID, decodedPaginator.LastID)
 		assert.Equal(t, originalPaginator.LastCreatedAt.Unix(), decodedPaginator.LastCreatedAt.Unix())
 	})
+	t.Run("should keep sort and last value", func(t *testing.T) {
+		// given
+		originalPaginator := Paginator{
+			LastID:    uuid.New(),
+			Sort:      Sort{Field: NameField, Direction: SortAsc},
+			LastValue: "Laptop, 15 inch",
+		}
+
+		// when
+		decodedPaginator, err := DecodePageToken(originalPaginator.Encode(signer), signer)
+
+		// then
+		assert.NoError(t, err)
+		assert.Equal(t, originalPaginator.Sort, decodedPaginator.Sort)
+		assert.Equal(t, originalPaginator.LastValue, decodedPaginator.LastValue)
+	})
+
+	t.Run("should default to created_at desc", func(t *testing.T) {
+		// given
+		originalPaginator := Paginator{LastID: uuid.New(), LastCreatedAt: time.Now()}
+
+		// when
+		decodedPaginator, err := DecodePageToken(originalPaginator.Encode(signer), signer)
+
+		// then
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultSort, decodedPaginator.Sort)
+	})
+}
+
+func TestQuery_ApplyPagination(t *testing.T) {
+	signer := NewPageTokenSigner("test-secret", time.Hour)
+
+	t.Run("should accept token issued for the same sort", func(t *testing.T) {
+		// given
+		sort := Sort{Field: PriceField, Direction: SortDesc}
+		token := Paginator{LastID: uuid.New(), Sort: sort, LastValue: "9.99"}.Encode(signer)
+		query := NewQuery()
+		query.Sort = sort
+
+		// when
+		err := query.ApplyPagination(5, token, signer)
+
+		// then
+		assert.NoError(t, err)
+		assert.Equal(t, 5, query.Limit)
+		assert.Equal(t, "9.99", query.Paginator.LastValue)
+	})
+
+	t.Run("should reject token issued for another sort", func(t *testing.T) {
+		// given
+		token := Paginator{LastID: uuid.New(), Sort: Sort{Field: PriceField, Direction: SortAsc}, LastValue: "9.99"}.Encode(signer)
+		query := NewQuery()
+		query.Sort = Sort{Field: PriceField, Direction: SortDesc}
+
+		// when
+		err := query.ApplyPagination(5, token, signer)
+
+		// then
+		assert.ErrorIs(t, err, ErrPageTokenSortMismatch)
+		assert.Nil(t, query.Paginator)
+	})
+	t.Run("should reject forged token", func(t *testing.T) {
+		// given
+		token := Paginator{LastID: uuid.New()}.Encode(NewPageTokenSigner("forged-secret", time.Hour))
+		query := NewQuery()
+
+		// when
+		err := query.ApplyPagination(5, token, signer)
+
+		// then
+		assert.ErrorIs(t, err, ErrPageTokenSignature)
+		assert.Nil(t, query.Paginator)
+	})
 }
@@ -21,6 +21,41 @@ const (
 	CreatedAtField QueryField = "created_at"
 	// StatusField represents the status query field.
 	StatusField QueryField = "status"
+	// NamePrefixField represents the query field matching names that start with the value, ignoring case.
+	NamePrefixField QueryField = "name_prefix"
+	// NameContainsField represents the query field matching names that contain the value, ignoring case.
+	NameContainsField QueryField = "name_contains"
+	// MinPriceField represents the inclusive lower price bound query field, formatted as a decimal number.
+	MinPriceField QueryField = "min_price"
+	// MaxPriceField represents the inclusive upper price bound query field, formatted as a decimal number.
+	MaxPriceField QueryField = "max_price"
+	// CurrencyField represents the query field matching the ISO 4217 currency code of a price.
+	// The price bounds are compared in this currency, DefaultCurrency of the model when it is not set.
+	CurrencyField QueryField = "currency"
+	// CreatedAfterField represents the inclusive lower created_at bound query field, formatted as RFC 3339.
+	CreatedAfterField QueryField = "created_after"
+	// CreatedBeforeField represents the exclusive upper created_at bound query field, formatted as RFC 3339.
+	CreatedBeforeField QueryField = "created_before"
+	// CategoryIDField represents the query field matching a category together with all of its subcategories.
+	CategoryIDField QueryField = "category_id"
+	// TagField represents the query field matching resources that carry the tag.
+	TagField QueryField = "tag"
+	// ParentIDField represents the query field matching the parent of a resource, or root resources when set to Empty.
+	ParentIDField QueryField = "parent_id"
+	// ProductIDField represents the query field matching resources that belong to a product.
+	ProductIDField QueryField = "product_id"
+	// ActorField represents the query field matching the actor of an audit log entry.
+	ActorField QueryField = "actor"
+	// ActionField represents the query field matching the action of an audit log entry.
+	ActionField QueryField = "action"
+	// ResourceTypeField represents the query field matching the type of the resource of an audit log entry.
+	ResourceTypeField QueryField = "resource_type"
+	// ResourceIDField represents the query field matching the ID of the resource of an audit log entry.
+	ResourceIDField QueryField = "resource_id"
+	// EventTypeField represents the query field matching the type of an event.
+	EventTypeField QueryField = "event_type"
+	// IncludeDeletedField represents the query field that makes soft-deleted resources visible when set to "true".
+	IncludeDeletedField QueryField = "include_deleted"
 )
 
 // Query represents a database query with filters and pagination options.
@@ -29,7 +64,13 @@ type Query struct {
 
 	Limit int
 
+	// Sort is the requested ordering. A zero value means DefaultSort.
+	Sort Sort
+
 	Paginator *Paginator
+
+	// IncludeTotal requests the number of resources matching the filters regardless of pagination.
+	IncludeTotal bool
 }
 
 // QueryField represents a field name used in database queries.
@@ -51,8 +92,44 @@ func (q *Query) With(field QueryField, val string) *Query {
 	return q
 }
 
+// SortOrder returns the ordering to apply, falling back to DefaultSort.
+func (q *Query) SortOrder() Sort {
+	return q.Sort.orDefault()
+}
+
+// PageLimit returns the page size, falling back to DefaultPaginationLimit.
+func (q *Query) PageLimit() int {
+	if q.Limit <= 0 {
+		return DefaultPaginationLimit
+	}
+	return q.Limit
+}
+
+// FetchLimit returns how many rows to fetch for a page: one more than its size,
+// so that the extra row tells whether another page follows.
+func (q *Query) FetchLimit() int {
+	return q.PageLimit() + 1
+}
+
+// Backward reports whether the query walks the keyset in reverse, towards the previous page.
+func (q *Query) Backward() bool {
+	return q.Paginator != nil && q.Paginator.Backward
+}
+
+// Keyset returns the operator for the cursor condition "(sort key, id) <op> (cursor)"
+// and the direction rows have to be fetched in. Walking backwards inverts both,
+// so the rows right before the cursor come first; NewPage restores the requested order.
+func (q *Query) Keyset() (comparison, direction string) {
+	if q.SortOrder().Desc() != q.Backward() {
+		return "<", "DESC"
+	}
+	return ">", "ASC"
+}
+
 // ApplyPagination applies pagination settings to the query based on limit and page token.
-func (q *Query) ApplyPagination(limit int32, token string) error {
+// The sort order must be set before, since a token is only valid for the ordering it was issued for.
+// Forged and expired tokens are reported with ErrPageTokenSignature and ErrPageTokenExpired.
+func (q *Query) ApplyPagination(limit int32, token string, signer *PageTokenSigner) error {
 	queryLimit := DefaultPaginationLimit
 	if limit > 0 {
 		queryLimit = min(maxPaginationLimit, int(limit))
@@ -63,11 +140,17 @@ func (q *Query) ApplyPagination(limit int32, token string) error {
 		return nil
 	}
 
-	paginator, err := DecodePageToken(token)
+	paginator, err := DecodePageToken(token, signer)
+	if errors.Is(err, ErrPageTokenSignature) || errors.Is(err, ErrPageTokenExpired) {
+		return err
+	}
 	if err != nil {
 		slog.Error("failed to decode page token", slog.Any("err", err), slog.String("token", token))
 		return errors.New("invalid page token")
 	}
+	if paginator.Sort.orDefault() != q.SortOrder() {
+		return ErrPageTokenSortMismatch
+	}
 	q.Paginator = paginator
 	return nil
 }
@@ -10,12 +10,19 @@ import (
 var (
 	// ErrInvalidType is returned when a type assertion fails for a repository Resource.
 	ErrInvalidType = errors.New("invalid resource type")
+	// ErrVersionConflict is returned when a resource was modified since the version the caller expected.
+	ErrVersionConflict = errors.New("resource version conflict")
+	// ErrStillReferenced is returned when a resource cannot be deleted because other resources refer to it.
+	ErrStillReferenced = errors.New("resource is still referenced")
+	// ErrLeaseLost is returned when a worker stores the outcome of a resource it no longer holds the lease of.
+	ErrLeaseLost = errors.New("lease was lost")
 )
 
 // Repository defines the interface for a generic repository that can manage resources.
 type Repository interface {
 	Create(ctx context.Context, resource Resource) (result Resource, err error)
 	List(ctx context.Context, query Query) (result []Resource, err error)
+	ListPage(ctx context.Context, query Query) (result *Page, err error)
 	DeleteByID(ctx context.Context, id uuid.UUID) error
 	FindByID(ctx context.Context, id uuid.UUID) (result Resource, err error) // find one
 	WithinTransaction(ctx context.Context, fn func(repo Repository) error) error
@@ -35,3 +42,13 @@ type UniqueConstraintError struct {
 func (u *UniqueConstraintError) Error() string {
 	return "resource must be unique: " + u.Detail
 }
+
+// NotFoundError represents a lookup for a resource that does not exist.
+type NotFoundError struct {
+	Resource string
+}
+
+// Error returns the error message for NotFoundError.
+func (n *NotFoundError) Error() string {
+	return n.Resource + " not found"
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// SortAsc orders results from the smallest to the largest value.
+	SortAsc SortDirection = "asc"
+	// SortDesc orders results from the largest to the smallest value.
+	SortDesc SortDirection = "desc"
+
+	// PriceField represents the price query field.
+	PriceField QueryField = "price"
+)
+
+var (
+	// ErrInvalidSort is returned when a sort expression names an unsupported field or direction.
+	ErrInvalidSort = errors.New("invalid sort")
+
+	// DefaultSort is the ordering used when none is requested: newest first.
+	DefaultSort = Sort{Field: CreatedAtField, Direction: SortDesc}
+)
+
+// SortDirection represents the direction of an ordering.
+type SortDirection string
+
+// Sort represents the ordering of listed resources. Ties are always broken by id in the same direction.
+type Sort struct {
+	Field     QueryField
+	Direction SortDirection
+}
+
+// Desc reports whether the sort is descending.
+func (s Sort) Desc() bool {
+	return s.Direction == SortDesc
+}
+
+// String returns the sort in the "field:direction" form accepted by ParseSort.
+func (s Sort) String() string {
+	return fmt.Sprintf("%s:%s", s.Field, s.Direction)
+}
+
+// orDefault returns DefaultSort for a zero Sort.
+func (s Sort) orDefault() Sort {
+	if s == (Sort{}) {
+		return DefaultSort
+	}
+	return s
+}
+
+// ParseSort parses a sort expression of the form "field" or "field:asc|desc".
+// An empty expression yields DefaultSort, a missing direction means ascending.
+// Only the allowed fields are accepted.
+func ParseSort(value string, allowed ...QueryField) (Sort, error) {
+	if value == "" {
+		return DefaultSort, nil
+	}
+
+	field, direction, hasDirection := strings.Cut(value, ":")
+	sort := Sort{Field: QueryField(field), Direction: SortAsc}
+	if hasDirection {
+		sort.Direction = SortDirection(direction)
+	}
+
+	if !slices.Contains(allowed, sort.Field) {
+		return Sort{}, fmt.Errorf("%w: unsupported field %q", ErrInvalidSort, field)
+	}
+	if sort.Direction != SortAsc && sort.Direction != SortDesc {
+		return Sort{}, fmt.Errorf("%w: direction must be %q or %q", ErrInvalidSort, SortAsc, SortDesc)
+	}
+
+	return sort, nil
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	allowed := []QueryField{CreatedAtField, PriceField, NameField}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    Sort
+		wantErr bool
+	}{
+		{"empty uses default", "", DefaultSort, false},
+		{"field without direction is ascending", "price", Sort{Field: PriceField, Direction: SortAsc}, false},
+		{"field with direction", "name:desc", Sort{Field: NameField, Direction: SortDesc}, false},
+		{"unsupported field", "description:asc", Sort{}, true},
+		{"unsupported direction", "price:up", Sort{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			got, err := ParseSort(tt.value, allowed...)
+
+			// then
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSort)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+)
+
+// APIKeyRepository stores the hashed API keys of users in the api_keys table.
+type APIKeyRepository struct {
+	db  *sql.DB
+	txn *sql.Tx
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository instance.
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// NewAPIKeyRepositoryWithTx creates a new APIKeyRepository instance with an existing transaction.
+func NewAPIKeyRepositoryWithTx(db *sql.DB, tx *sql.Tx) *APIKeyRepository {
+	return &APIKeyRepository{db: db, txn: tx}
+}
+
+// getExecutor returns the active executor (transaction if exists, otherwise db).
+func (r *APIKeyRepository) getExecutor() dbExecutor {
+	if r.txn != nil {
+		return r.txn
+	}
+	return r.db
+}
+
+// Create inserts a new API key into the database. An owner that is not a stored user is reported
+// as a *repository.NotFoundError.
+func (r *APIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	key.InitMeta()
+
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to encode API key scopes: %w", err)
+	}
+
+	query := `INSERT INTO api_keys (id, owner_id, name, key_prefix, key_hash, scopes, expires_at, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, key.ID, key.OwnerID, key.Name, key.KeyPrefix, key.KeyHash, string(scopes), key.ExpiresAt, key.CreatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return &repository.NotFoundError{Resource: "user"}
+		}
+		return fmt.Errorf("failed to insert API key: %w", err)
+	}
+
+	return nil
+}
+
+// FindByID retrieves a single API key by ID.
+func (r *APIKeyRepository) FindByID(ctx context.Context, id uuid.UUID) (*model.APIKey, error) {
+	return r.findOne(ctx, `SELECT * FROM api_keys WHERE id = $1`, id)
+}
+
+// FindByHash retrieves the API key with the given SHA-256 hash.
+func (r *APIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	return r.findOne(ctx, `SELECT * FROM api_keys WHERE key_hash = $1`, keyHash)
+}
+
+func (r *APIKeyRepository) findOne(ctx context.Context, query string, arg interface{}) (*model.APIKey, error) {
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	key, err := scanAPIKey(stmt.QueryRowContext(ctx, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Resource: "API key"}
+		}
+		return nil, fmt.Errorf("failed to query API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// ListByOwner retrieves all API keys of a user, newest first, including revoked and expired ones.
+func (r *APIKeyRepository) ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]*model.APIKey, error) {
+	query := `SELECT * FROM api_keys WHERE owner_id = $1 ORDER BY created_at DESC, id DESC`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*model.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke marks an API key as revoked at the given time. Keys that are unknown or already revoked
+// are reported as a *repository.NotFoundError.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &repository.NotFoundError{Resource: "API key"}
+	}
+
+	return nil
+}
+
+// TouchLastUsed records that an API key was used at the given time.
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, at, id); err != nil {
+		return fmt.Errorf("failed to update API key last use: %w", err)
+	}
+
+	return nil
+}
+
+// scanAPIKey scans an api_keys row in table column order.
+func scanAPIKey(row rowScanner) (*model.APIKey, error) {
+	var key model.APIKey
+	var scopes []byte
+	err := row.Scan(&key.ID, &key.OwnerID, &key.Name, &key.KeyPrefix, &key.KeyHash, &scopes,
+		&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scopes, &key.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode API key scopes: %w", err)
+	}
+	return &key, nil
+}
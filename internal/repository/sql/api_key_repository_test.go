@@ -0,0 +1,141 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var apiKeyColumns = []string{"id", "owner_id", "name", "key_prefix", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"}
+
+func TestAPIKeyRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db)
+	expiresAt := time.Now().Add(24 * time.Hour)
+	key := &model.APIKey{
+		OwnerID: uuid.New(), Name: "nightly import", KeyPrefix: "sk_abcd", KeyHash: "hash",
+		Scopes: []string{"products:write"}, ExpiresAt: &expiresAt,
+	}
+
+	mock.ExpectPrepare("INSERT INTO api_keys").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), key.OwnerID, "nightly import", "sk_abcd", "hash", `["products:write"]`, &expiresAt, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, repo.Create(context.Background(), key))
+	assert.NotEqual(t, uuid.Nil, key.ID)
+	assert.False(t, key.CreatedAt.IsZero())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_FindByHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db)
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		id := uuid.New()
+		usedAt := time.Now()
+		mock.ExpectPrepare("SELECT \\* FROM api_keys WHERE key_hash = \\$1").
+			ExpectQuery().
+			WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+				AddRow(id, uuid.New(), "nightly import", "sk_abcd", "hash", []byte(`["products:write","inventory:write"]`), nil, usedAt, nil, time.Now()))
+
+		key, err := repo.FindByHash(ctx, "hash")
+		require.NoError(t, err)
+		assert.Equal(t, id, key.ID)
+		assert.Equal(t, []string{"products:write", "inventory:write"}, key.Scopes)
+		assert.Nil(t, key.ExpiresAt)
+		require.NotNil(t, key.LastUsedAt)
+		assert.Nil(t, key.RevokedAt)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectPrepare("SELECT \\* FROM api_keys WHERE key_hash = \\$1").
+			ExpectQuery().
+			WithArgs("unknown").
+			WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+
+		_, err := repo.FindByHash(ctx, "unknown")
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, "API key", notFoundErr.Resource)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAPIKeyRepository_ListByOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db)
+	ownerID := uuid.New()
+
+	mock.ExpectPrepare("SELECT \\* FROM api_keys WHERE owner_id = \\$1 ORDER BY created_at DESC, id DESC").
+		ExpectQuery().
+		WithArgs(ownerID).
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+			AddRow(uuid.New(), ownerID, "new", "sk_new", "hash-2", []byte(`[]`), nil, nil, nil, time.Now()).
+			AddRow(uuid.New(), ownerID, "old", "sk_old", "hash-1", []byte(`["audit:read"]`), nil, nil, time.Now(), time.Now()))
+
+	keys, err := repo.ListByOwner(context.Background(), ownerID)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "new", keys[0].Name)
+	assert.Empty(t, keys[0].Scopes)
+	assert.NotNil(t, keys[1].RevokedAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_Revoke(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db)
+	ctx := context.Background()
+	id := uuid.New()
+	now := time.Now()
+
+	t.Run("active key", func(t *testing.T) {
+		mock.ExpectPrepare("UPDATE api_keys SET revoked_at = \\$1 WHERE id = \\$2 AND revoked_at IS NULL").
+			ExpectExec().
+			WithArgs(now, id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, repo.Revoke(ctx, id, now))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already revoked", func(t *testing.T) {
+		mock.ExpectPrepare("UPDATE api_keys SET revoked_at").
+			ExpectExec().
+			WithArgs(now, id).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Revoke(ctx, id, now)
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
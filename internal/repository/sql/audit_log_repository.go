@@ -0,0 +1,196 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+)
+
+// AuditLogRepository stores the audit trail of changed resources in the audit_log table.
+type AuditLogRepository struct {
+	db  *sql.DB
+	txn *sql.Tx
+}
+
+// NewAuditLogRepository creates a new AuditLogRepository instance.
+func NewAuditLogRepository(db *sql.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+// NewAuditLogRepositoryWithTx creates a new AuditLogRepository instance with an existing transaction.
+func NewAuditLogRepositoryWithTx(db *sql.DB, tx *sql.Tx) *AuditLogRepository {
+	return &AuditLogRepository{db: db, txn: tx}
+}
+
+// getExecutor returns the active executor (transaction if exists, otherwise db).
+func (r *AuditLogRepository) getExecutor() dbExecutor {
+	if r.txn != nil {
+		return r.txn
+	}
+	return r.db
+}
+
+// Create inserts a new audit log entry into the database.
+func (r *AuditLogRepository) Create(ctx context.Context, entry *model.AuditLogEntry) error {
+	return r.CreateBatch(ctx, []*model.AuditLogEntry{entry})
+}
+
+// CreateBatch inserts all given audit log entries with a single multi-row INSERT.
+func (r *AuditLogRepository) CreateBatch(ctx context.Context, entries []*model.AuditLogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	const columns = 10
+	args := make([]interface{}, 0, len(entries)*columns)
+	for _, entry := range entries {
+		entry.InitMeta()
+		args = append(args, entry.ID, entry.Actor, entry.Action, entry.ResourceType, entry.ResourceID,
+			nullableJSON(entry.Before), nullableJSON(entry.After), entry.RequestID, entry.ClientIP, entry.CreatedAt)
+	}
+
+	query := `INSERT INTO audit_log (id, actor, action, resource_type, resource_id, before, after, request_id, client_ip, created_at)
+	          VALUES ` + valuesPlaceholders(len(entries), columns)
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, args...); err != nil {
+		return fmt.Errorf("failed to insert audit log entries: %w", err)
+	}
+
+	return nil
+}
+
+// nullableJSON returns the JSON document as a string, or nil to store NULL for an empty document.
+func nullableJSON(document []byte) interface{} {
+	if len(document) == 0 {
+		return nil
+	}
+	return string(document)
+}
+
+// ListPage retrieves one page of audit log entries from the database based on the provided query.
+func (r *AuditLogRepository) ListPage(ctx context.Context, query repository.Query) (*repository.Page, error) {
+	if query.SortOrder().Field != repository.CreatedAtField {
+		return nil, fmt.Errorf("unsupported sort field %q: %w", query.SortOrder().Field, repository.ErrInvalidSort)
+	}
+
+	filters, args, err := auditLogFilters(query)
+	if err != nil {
+		return nil, err
+	}
+	argIndex := len(args) + 1
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString("SELECT * FROM audit_log WHERE 1=1")
+	queryBuilder.WriteString(filters)
+
+	comparison, direction := query.Keyset()
+
+	// Apply pagination: continue from the cursor (created_at, id) pair in the fetch direction
+	if query.Paginator != nil {
+		queryBuilder.WriteString(fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", comparison, argIndex, argIndex+1))
+		args = append(args, query.Paginator.LastCreatedAt, query.Paginator.LastID)
+		argIndex += 2
+	}
+
+	// Order by created_at with id as a tie-breaker for consistent pagination
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY created_at %s, id %s", direction, direction))
+
+	// Apply limit
+	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", argIndex))
+	args = append(args, query.FetchLimit())
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, queryBuilder.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []repository.Resource
+	for rows.Next() {
+		entry, err := scanAuditLogEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit log entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return repository.NewPage(query, entries), nil
+}
+
+// auditLogFilters builds the WHERE conditions for the query filters, to be appended to "WHERE 1=1",
+// together with their arguments numbered from $1.
+func auditLogFilters(query repository.Query) (string, []interface{}, error) {
+	var filters strings.Builder
+	var args []interface{}
+
+	for _, field := range []repository.QueryField{repository.ActorField, repository.ActionField, repository.ResourceTypeField} {
+		if value, ok := query.Values[field]; ok {
+			filters.WriteString(fmt.Sprintf(" AND %s = $%d", field, len(args)+1))
+			args = append(args, value)
+		}
+	}
+	if value, ok := query.Values[repository.ResourceIDField]; ok {
+		resourceID, err := uuid.Parse(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid resource ID format: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND resource_id = $%d", len(args)+1))
+		args = append(args, resourceID)
+	}
+	if value, ok := query.Values[repository.CreatedAfterField]; ok {
+		createdAfter, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid created after filter: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND created_at >= $%d", len(args)+1))
+		args = append(args, createdAfter)
+	}
+	if value, ok := query.Values[repository.CreatedBeforeField]; ok {
+		createdBefore, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid created before filter: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND created_at < $%d", len(args)+1))
+		args = append(args, createdBefore)
+	}
+
+	return filters.String(), args, nil
+}
+
+// scanAuditLogEntry scans an audit_log row in table column order.
+func scanAuditLogEntry(row rowScanner) (*model.AuditLogEntry, error) {
+	var entry model.AuditLogEntry
+	var before, after []byte
+	err := row.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.ResourceType, &entry.ResourceID,
+		&before, &after, &entry.RequestID, &entry.ClientIP, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	entry.Before = before
+	entry.After = after
+	return &entry, nil
+}
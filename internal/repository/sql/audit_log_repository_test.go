@@ -0,0 +1,131 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogRepository_CreateBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuditLogRepository(db)
+	created := &model.AuditLogEntry{
+		Actor: "alice", Action: "create", ResourceType: "product", ResourceID: uuid.New(),
+		After: json.RawMessage(`{"name":"Keyboard"}`), RequestID: "req-1", ClientIP: "203.0.113.7",
+	}
+	deleted := &model.AuditLogEntry{
+		Actor: "alice", Action: "delete", ResourceType: "product", ResourceID: uuid.New(),
+		Before: json.RawMessage(`{"name":"Mouse"}`), RequestID: "req-1", ClientIP: "203.0.113.7",
+	}
+
+	// Empty snapshots are stored as NULL
+	mock.ExpectPrepare("INSERT INTO audit_log .* VALUES \\(\\$1, .*\\), \\(\\$11, .*\\$20\\)$").
+		ExpectExec().
+		WithArgs(
+			sqlmock.AnyArg(), "alice", "create", "product", created.ResourceID, nil, `{"name":"Keyboard"}`, "req-1", "203.0.113.7", sqlmock.AnyArg(),
+			sqlmock.AnyArg(), "alice", "delete", "product", deleted.ResourceID, `{"name":"Mouse"}`, nil, "req-1", "203.0.113.7", sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	require.NoError(t, repo.CreateBatch(context.Background(), []*model.AuditLogEntry{created, deleted}))
+	assert.NotEqual(t, uuid.Nil, created.ID)
+	assert.NotEqual(t, created.ID, deleted.ID)
+	assert.False(t, deleted.CreatedAt.IsZero())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditLogRepository_ListPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuditLogRepository(db)
+	ctx := context.Background()
+	columns := []string{"id", "actor", "action", "resource_type", "resource_id", "before", "after", "request_id", "client_ip", "created_at"}
+
+	t.Run("filtered next page", func(t *testing.T) {
+		resourceID := uuid.New()
+		createdAfter := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		query := repository.NewQuery().
+			With(repository.ActorField, "alice").
+			With(repository.ResourceTypeField, "product").
+			With(repository.ResourceIDField, resourceID.String()).
+			With(repository.CreatedAfterField, createdAfter.Format(time.RFC3339Nano))
+		query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortDesc}
+		query.Limit = 1
+		lastCreatedAt := time.Now()
+		lastID := uuid.New()
+		query.Paginator = &repository.Paginator{LastID: lastID, LastCreatedAt: lastCreatedAt, Sort: query.Sort}
+
+		now := time.Now()
+		rows := sqlmock.NewRows(columns).
+			AddRow(uuid.New(), "alice", "update", "product", resourceID, []byte(`{"name":"Old"}`), []byte(`{"name":"New"}`), "req-2", "203.0.113.7", now).
+			AddRow(uuid.New(), "alice", "create", "product", resourceID, nil, []byte(`{"name":"Old"}`), "req-1", "203.0.113.7", now)
+
+		mock.ExpectPrepare("SELECT \\* FROM audit_log WHERE 1=1 AND actor = \\$1 AND resource_type = \\$2 AND resource_id = \\$3 "+
+			"AND created_at >= \\$4 AND \\(created_at, id\\) < \\(\\$5, \\$6\\) ORDER BY created_at DESC, id DESC LIMIT \\$7").
+			ExpectQuery().
+			WithArgs("alice", "product", resourceID, createdAfter, lastCreatedAt, lastID, 2).
+			WillReturnRows(rows)
+
+		page, err := repo.ListPage(ctx, *query)
+		require.NoError(t, err)
+		require.Len(t, page.Resources, 1)
+		entry := page.Resources[0].(*model.AuditLogEntry)
+		assert.Equal(t, "update", entry.Action)
+		assert.JSONEq(t, `{"name":"Old"}`, string(entry.Before))
+		assert.JSONEq(t, `{"name":"New"}`, string(entry.After))
+		assert.Equal(t, "req-2", entry.RequestID)
+		assert.True(t, page.HasNext)
+		assert.True(t, page.HasPrev)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty before snapshot", func(t *testing.T) {
+		query := repository.NewQuery().With(repository.ActionField, "create")
+		query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortDesc}
+
+		rows := sqlmock.NewRows(columns).
+			AddRow(uuid.New(), "anonymous", "create", "product", uuid.New(), nil, []byte(`{}`), "", "", time.Now())
+		mock.ExpectPrepare("SELECT \\* FROM audit_log WHERE 1=1 AND action = \\$1 ORDER BY created_at DESC, id DESC LIMIT \\$2").
+			ExpectQuery().
+			WithArgs("create", sqlmock.AnyArg()).
+			WillReturnRows(rows)
+
+		page, err := repo.ListPage(ctx, *query)
+		require.NoError(t, err)
+		require.Len(t, page.Resources, 1)
+		assert.Nil(t, page.Resources[0].(*model.AuditLogEntry).Before)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid resource ID", func(t *testing.T) {
+		query := repository.NewQuery().With(repository.ResourceIDField, "not-a-uuid")
+		query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortDesc}
+
+		_, err := repo.ListPage(ctx, *query)
+		assert.ErrorContains(t, err, "invalid resource ID format")
+	})
+
+	t.Run("unsupported sort", func(t *testing.T) {
+		query := repository.NewQuery()
+		query.Sort = repository.Sort{Field: repository.NameField, Direction: repository.SortAsc}
+
+		_, err := repo.ListPage(ctx, *query)
+		assert.ErrorIs(t, err, repository.ErrInvalidSort)
+	})
+}
@@ -0,0 +1,378 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+)
+
+// categorySubtreeQuery selects the IDs of the category given as $%d and of all its descendants.
+const categorySubtreeQuery = `WITH RECURSIVE subtree AS (
+	SELECT id FROM categories WHERE id = $%d
+	UNION ALL
+	SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+) SELECT id FROM subtree`
+
+// CategoryRepository implements the Repository interface for Category entities.
+type CategoryRepository struct {
+	db  *sql.DB
+	txn *sql.Tx
+}
+
+// NewCategoryRepository creates a new CategoryRepository instance.
+func NewCategoryRepository(db *sql.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// NewCategoryRepositoryWithTx creates a new CategoryRepository instance with an existing transaction.
+func NewCategoryRepositoryWithTx(db *sql.DB, tx *sql.Tx) *CategoryRepository {
+	return &CategoryRepository{db: db, txn: tx}
+}
+
+// getExecutor returns the active executor (transaction if exists, otherwise db).
+func (r *CategoryRepository) getExecutor() dbExecutor {
+	if r.txn != nil {
+		return r.txn
+	}
+	return r.db
+}
+
+// WithinTransaction executes a function within a database transaction.
+func (r *CategoryRepository) WithinTransaction(ctx context.Context, fn func(repo repository.Repository) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Create a new repository instance with the transaction
+	txRepo := &CategoryRepository{
+		db:  r.db,
+		txn: tx,
+	}
+
+	// Execute the function with the transactional repository
+	if err := fn(txRepo); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			// Log rollback error but return original error
+			return fmt.Errorf("transaction failed (rollback error: %w): %w", rbErr, err)
+		}
+		return err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Create inserts a new category into the database.
+// A parent that does not exist is reported as a *repository.NotFoundError.
+func (r *CategoryRepository) Create(ctx context.Context, resource repository.Resource) (repository.Resource, error) {
+	category, ok := resource.(*model.Category)
+	if !ok {
+		return nil, errors.New("resource must be a *model.Category")
+	}
+
+	category.InitMeta()
+
+	query := `INSERT INTO categories (id, name, parent_id, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5)`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, category.ID, category.Name, category.ParentID, category.CreatedAt, category.UpdatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, &repository.NotFoundError{Resource: "parent category"}
+		}
+		return nil, fmt.Errorf("failed to insert category: %w", err)
+	}
+
+	return category, nil
+}
+
+// List retrieves categories from the database based on the provided query.
+func (r *CategoryRepository) List(ctx context.Context, query repository.Query) ([]repository.Resource, error) {
+	page, err := r.ListPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return page.Resources, nil
+}
+
+// ListPage retrieves one page of categories from the database based on the provided query.
+func (r *CategoryRepository) ListPage(ctx context.Context, query repository.Query) (*repository.Page, error) {
+	if query.SortOrder().Field != repository.CreatedAtField {
+		return nil, fmt.Errorf("unsupported sort field %q: %w", query.SortOrder().Field, repository.ErrInvalidSort)
+	}
+
+	filters, args, err := categoryFilters(query)
+	if err != nil {
+		return nil, err
+	}
+	filterArgs := len(args)
+	argIndex := filterArgs + 1
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString("SELECT * FROM categories WHERE 1=1")
+	queryBuilder.WriteString(filters)
+
+	comparison, direction := query.Keyset()
+
+	// Apply pagination: continue from the cursor (created_at, id) pair in the fetch direction
+	if query.Paginator != nil {
+		queryBuilder.WriteString(fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", comparison, argIndex, argIndex+1))
+		args = append(args, query.Paginator.LastCreatedAt, query.Paginator.LastID)
+		argIndex += 2
+	}
+
+	// Order by created_at with id as a tie-breaker for consistent pagination
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY created_at %s, id %s", direction, direction))
+
+	// Apply limit
+	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", argIndex))
+	args = append(args, query.FetchLimit())
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, queryBuilder.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []repository.Resource
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	page := repository.NewPage(query, categories)
+	if query.IncludeTotal {
+		total, err := countRows(ctx, r.getExecutor(), "SELECT COUNT(*) FROM categories WHERE 1=1"+filters, args[:filterArgs]...)
+		if err != nil {
+			return nil, err
+		}
+		page.TotalCount = &total
+	}
+
+	return page, nil
+}
+
+// categoryFilters builds the WHERE conditions for the query filters, to be appended to "WHERE 1=1",
+// together with their arguments numbered from $1.
+func categoryFilters(query repository.Query) (string, []interface{}, error) {
+	var filters strings.Builder
+	var args []interface{}
+
+	if value, ok := query.Values[repository.ParentIDField]; ok {
+		if value == string(repository.Empty) {
+			filters.WriteString(" AND parent_id IS NULL")
+		} else {
+			parentID, err := uuid.Parse(value)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid parent ID format: %w", err)
+			}
+			filters.WriteString(fmt.Sprintf(" AND parent_id = $%d", len(args)+1))
+			args = append(args, parentID)
+		}
+	}
+
+	return filters.String(), args, nil
+}
+
+// FindByID retrieves a single category by ID.
+func (r *CategoryRepository) FindByID(ctx context.Context, id uuid.UUID) (repository.Resource, error) {
+	query := `SELECT * FROM categories WHERE id = $1`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := scanCategory(stmt.QueryRowContext(ctx, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Resource: "category"}
+		}
+		return nil, fmt.Errorf("failed to query category: %w", err)
+	}
+
+	return result, nil
+}
+
+// FindByIDs retrieves the categories with the given IDs keyed by ID. IDs of missing categories are left out.
+func (r *CategoryRepository) FindByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*model.Category, error) {
+	categories := make(map[uuid.UUID]*model.Category, len(ids))
+	if len(ids) == 0 {
+		return categories, nil
+	}
+
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	query := `SELECT * FROM categories WHERE id IN ` + placeholderList(1, len(ids))
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories[category.ID] = category
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return categories, nil
+}
+
+// InSubtree reports whether the category with the given ID is root itself or one of its descendants.
+func (r *CategoryRepository) InSubtree(ctx context.Context, root, id uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM (` + fmt.Sprintf(categorySubtreeQuery, 1) + `) subtree_ids WHERE id = $2)`
+
+	var exists bool
+	if err := r.getExecutor().QueryRowContext(ctx, query, root, id).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to query category subtree: %w", err)
+	}
+	return exists, nil
+}
+
+// categoryMovesLockKey identifies the advisory lock that serializes moves of categories.
+const categoryMovesLockKey = "categories.move"
+
+// LockMoves takes a transaction-level advisory lock that serializes moves of categories. A move that takes it
+// before its cycle check sees every move committed before it, so two concurrent moves, e.g. of A below B and of
+// B below A, cannot close a cycle together. Without a transaction, there is nothing to serialize.
+func (r *CategoryRepository) LockMoves(ctx context.Context) error {
+	if r.txn == nil {
+		return nil
+	}
+
+	if _, err := r.txn.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, categoryMovesLockKey); err != nil {
+		return fmt.Errorf("failed to lock category moves: %w", err)
+	}
+	return nil
+}
+
+// scanCategory scans a categories row in table column order.
+func scanCategory(row rowScanner) (*model.Category, error) {
+	var category model.Category
+	err := row.Scan(&category.ID, &category.Name, &category.ParentID, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// Update persists the name and parent of an existing category and bumps its updated_at timestamp.
+// A parent that does not exist is reported as a *repository.NotFoundError.
+func (r *CategoryRepository) Update(ctx context.Context, category *model.Category) error {
+	updatedAt := time.Now()
+
+	query := `UPDATE categories SET name = $1, parent_id = $2, updated_at = $3 WHERE id = $4`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, category.Name, category.ParentID, updatedAt, category.ID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return &repository.NotFoundError{Resource: "parent category"}
+		}
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &repository.NotFoundError{Resource: "category"}
+	}
+
+	category.UpdatedAt = updatedAt
+
+	return nil
+}
+
+// DeleteByID permanently deletes a category by ID.
+// Categories that still have subcategories or products are kept and reported with repository.ErrStillReferenced.
+func (r *CategoryRepository) DeleteByID(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM categories WHERE id = $1`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare delete statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return repository.ErrStillReferenced
+		}
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &repository.NotFoundError{Resource: "category"}
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	t.Run("successful creation", func(t *testing.T) {
+		parentID := uuid.New()
+		category := &model.Category{Name: "Laptops", ParentID: &parentID}
+
+		mock.ExpectPrepare("INSERT INTO categories").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "Laptops", &parentID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		result, err := repo.Create(ctx, category)
+		require.NoError(t, err)
+
+		created := result.(*model.Category)
+		assert.NotEqual(t, uuid.Nil, created.ID)
+		assert.False(t, created.CreatedAt.IsZero())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing parent", func(t *testing.T) {
+		parentID := uuid.New()
+		category := &model.Category{Name: "Laptops", ParentID: &parentID}
+
+		mock.ExpectPrepare("INSERT INTO categories").
+			ExpectExec().
+			WillReturnError(&pgconn.PgError{Code: pqForeignKeyViolationErrCode})
+
+		result, err := repo.Create(ctx, category)
+		assert.Nil(t, result)
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, "parent category", notFoundErr.Resource)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCategoryRepository_ListPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+	columns := []string{"id", "name", "parent_id", "created_at", "updated_at"}
+
+	t.Run("root categories", func(t *testing.T) {
+		query := repository.NewQuery().With(repository.ParentIDField, string(repository.Empty))
+		query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortAsc}
+		query.Limit = 1
+
+		now := time.Now()
+		rows := sqlmock.NewRows(columns).
+			AddRow(uuid.New(), "Electronics", nil, now, now).
+			AddRow(uuid.New(), "Books", nil, now, now)
+
+		mock.ExpectPrepare("SELECT \\* FROM categories WHERE 1=1 AND parent_id IS NULL ORDER BY created_at ASC, id ASC LIMIT \\$1").
+			ExpectQuery().
+			WithArgs(2).
+			WillReturnRows(rows)
+
+		page, err := repo.ListPage(ctx, *query)
+		require.NoError(t, err)
+		require.Len(t, page.Resources, 1)
+		assert.Equal(t, "Electronics", page.Resources[0].(*model.Category).Name)
+		assert.True(t, page.HasNext)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("children of a category", func(t *testing.T) {
+		parentID := uuid.New()
+		query := repository.NewQuery().With(repository.ParentIDField, parentID.String())
+		query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortAsc}
+		lastCreatedAt := time.Now().Add(-time.Hour)
+		lastID := uuid.New()
+		query.Paginator = &repository.Paginator{LastID: lastID, LastCreatedAt: lastCreatedAt, Sort: query.Sort}
+
+		now := time.Now()
+		rows := sqlmock.NewRows(columns).AddRow(uuid.New(), "Laptops", parentID, now, now)
+
+		mock.ExpectPrepare("SELECT \\* FROM categories WHERE 1=1 AND parent_id = \\$1 AND \\(created_at, id\\) > \\(\\$2, \\$3\\) ORDER BY created_at ASC, id ASC LIMIT \\$4").
+			ExpectQuery().
+			WithArgs(parentID, lastCreatedAt, lastID, repository.DefaultPaginationLimit+1).
+			WillReturnRows(rows)
+
+		page, err := repo.ListPage(ctx, *query)
+		require.NoError(t, err)
+		require.Len(t, page.Resources, 1)
+		assert.Equal(t, &parentID, page.Resources[0].(*model.Category).ParentID)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unsupported sort", func(t *testing.T) {
+		query := repository.NewQuery()
+		query.Sort = repository.Sort{Field: repository.NameField, Direction: repository.SortAsc}
+
+		page, err := repo.ListPage(ctx, *query)
+		assert.Nil(t, page)
+		require.ErrorIs(t, err, repository.ErrInvalidSort)
+	})
+}
+
+func TestCategoryRepository_FindByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	t.Run("missing categories are left out", func(t *testing.T) {
+		found, missing := uuid.New(), uuid.New()
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "name", "parent_id", "created_at", "updated_at"}).
+			AddRow(found, "Books", nil, now, now)
+
+		mock.ExpectPrepare("SELECT \\* FROM categories WHERE id IN \\(\\$1, \\$2\\)").
+			ExpectQuery().
+			WithArgs(found, missing).
+			WillReturnRows(rows)
+
+		categories, err := repo.FindByIDs(ctx, []uuid.UUID{found, missing})
+		require.NoError(t, err)
+		require.Len(t, categories, 1)
+		assert.Equal(t, "Books", categories[found].Name)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no IDs", func(t *testing.T) {
+		categories, err := repo.FindByIDs(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, categories)
+	})
+}
+
+func TestCategoryRepository_InSubtree(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewCategoryRepository(db)
+	root, id := uuid.New(), uuid.New()
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM \\(WITH RECURSIVE subtree AS .*\\) subtree_ids WHERE id = \\$2\\)").
+		WithArgs(root, id).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	inSubtree, err := repo.InSubtree(context.Background(), root, id)
+	require.NoError(t, err)
+	assert.True(t, inSubtree)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryRepository_LockMoves(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("without a transaction", func(t *testing.T) {
+		require.NoError(t, NewCategoryRepository(db).LockMoves(ctx))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("within a transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT pg_advisory_xact_lock\\(hashtextextended\\(\\$1, 0\\)\\)").
+			WithArgs(categoryMovesLockKey).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		tx, err := db.Begin()
+		require.NoError(t, err)
+
+		require.NoError(t, NewCategoryRepositoryWithTx(db, tx).LockMoves(ctx))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCategoryRepository_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	t.Run("successful update", func(t *testing.T) {
+		category := &model.Category{ID: uuid.New(), Name: "Notebooks"}
+
+		mock.ExpectPrepare("UPDATE categories SET name = \\$1, parent_id = \\$2, updated_at = \\$3 WHERE id = \\$4").
+			ExpectExec().
+			WithArgs("Notebooks", nil, sqlmock.AnyArg(), category.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, repo.Update(ctx, category))
+		assert.False(t, category.UpdatedAt.IsZero())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("category not found", func(t *testing.T) {
+		category := &model.Category{ID: uuid.New(), Name: "Notebooks"}
+
+		mock.ExpectPrepare("UPDATE categories SET").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Update(ctx, category)
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, "category", notFoundErr.Resource)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCategoryRepository_DeleteByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewCategoryRepository(db)
+	ctx := context.Background()
+
+	t.Run("successful deletion", func(t *testing.T) {
+		id := uuid.New()
+
+		mock.ExpectPrepare("DELETE FROM categories WHERE id = \\$1").
+			ExpectExec().
+			WithArgs(id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, repo.DeleteByID(ctx, id))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("category still referenced", func(t *testing.T) {
+		id := uuid.New()
+
+		mock.ExpectPrepare("DELETE FROM categories WHERE id = \\$1").
+			ExpectExec().
+			WithArgs(id).
+			WillReturnError(&pgconn.PgError{Code: pqForeignKeyViolationErrCode})
+
+		require.ErrorIs(t, repo.DeleteByID(ctx, id), repository.ErrStillReferenced)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
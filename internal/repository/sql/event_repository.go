@@ -1,10 +1,12 @@
 package sql
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -67,7 +69,7 @@ func (r *EventRepository) WithinTransaction(ctx context.Context, fn func(repo re
 	return nil
 }
 
-// Create inserts a new event into the database.
+// Create inserts a new event into the database. Its sequence is assigned by the database.
 func (r *EventRepository) Create(ctx context.Context, resource repository.Resource) (repository.Resource, error) {
 	event, ok := resource.(*model.Event)
 	if !ok {
@@ -76,8 +78,12 @@ func (r *EventRepository) Create(ctx context.Context, resource repository.Resour
 
 	event.InitMeta()
 
-	query := `INSERT INTO events (id, event_type, event_data, status, created_at, processed_at) 
-	          VALUES ($1, $2, $3, $4, $5, $6)`
+	if err := r.lockAggregates(ctx, []*model.Event{event}); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO events (id, event_type, event_data, status, created_at, processed_at, aggregate_id) 
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
 
 	executor := r.getExecutor()
 	stmt, err := executor.PrepareContext(ctx, query)
@@ -86,7 +92,8 @@ func (r *EventRepository) Create(ctx context.Context, resource repository.Resour
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, event.ID, event.EventType, event.EventData, event.Status, event.CreatedAt, event.ProcessedAt)
+	_, err = stmt.ExecContext(ctx, event.ID, event.EventType, event.EventData, event.Status, event.CreatedAt, event.ProcessedAt,
+		event.AggregateID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert event: %w", err)
 	}
@@ -94,38 +101,83 @@ func (r *EventRepository) Create(ctx context.Context, resource repository.Resour
 	return event, nil
 }
 
+// CreateBatch inserts all given events with a single multi-row INSERT, in the order given.
+// The metadata of every event is initialized like in Create.
+func (r *EventRepository) CreateBatch(ctx context.Context, events []*model.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	const columns = 7
+	args := make([]interface{}, 0, len(events)*columns)
+	for _, event := range events {
+		event.InitMeta()
+		args = append(args, event.ID, event.EventType, event.EventData, event.Status, event.CreatedAt, event.ProcessedAt,
+			event.AggregateID)
+	}
+
+	if err := r.lockAggregates(ctx, events); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO events (id, event_type, event_data, status, created_at, processed_at, aggregate_id) 
+	          VALUES ` + valuesPlaceholders(len(events), columns)
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, args...); err != nil {
+		return fmt.Errorf("failed to insert events: %w", err)
+	}
+
+	return nil
+}
+
 // List retrieves events from the database based on the provided query.
 func (r *EventRepository) List(ctx context.Context, query repository.Query) ([]repository.Resource, error) {
-	var queryBuilder strings.Builder
-	queryBuilder.WriteString("SELECT * FROM events WHERE 1=1")
+	page, err := r.ListPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return page.Resources, nil
+}
 
-	var args []interface{}
-	argIndex := 1
+// ListPage retrieves one page of events from the database based on the provided query.
+func (r *EventRepository) ListPage(ctx context.Context, query repository.Query) (*repository.Page, error) {
+	if query.SortOrder().Field != repository.CreatedAtField {
+		return nil, fmt.Errorf("unsupported sort field %q: %w", query.SortOrder().Field, repository.ErrInvalidSort)
+	}
 
-	// Apply status filter if provided
-	if status, ok := query.Values[repository.StatusField]; ok {
-		queryBuilder.WriteString(fmt.Sprintf(" AND status = $%d", argIndex))
-		args = append(args, status)
-		argIndex++
+	filters, args, err := eventFilters(query)
+	if err != nil {
+		return nil, err
 	}
+	filterArgs := len(args)
+	argIndex := filterArgs + 1
 
-	// Apply pagination
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString("SELECT * FROM events WHERE 1=1")
+	queryBuilder.WriteString(filters)
+
+	comparison, direction := query.Keyset()
+
+	// Apply pagination: continue from the cursor (created_at, id) pair in the fetch direction
 	if query.Paginator != nil {
-		queryBuilder.WriteString(fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", argIndex, argIndex+1))
+		queryBuilder.WriteString(fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", comparison, argIndex, argIndex+1))
 		args = append(args, query.Paginator.LastCreatedAt, query.Paginator.LastID)
 		argIndex += 2
 	}
 
-	// Order by created_at DESC, id DESC for consistent pagination
-	queryBuilder.WriteString(" ORDER BY created_at DESC, id DESC")
+	// Order by created_at with id as a tie-breaker for consistent pagination
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY created_at %s, id %s", direction, direction))
 
 	// Apply limit
-	limit := query.Limit
-	if limit <= 0 {
-		limit = repository.DefaultPaginationLimit
-	}
 	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", argIndex))
-	args = append(args, limit)
+	args = append(args, query.FetchLimit())
 
 	executor := r.getExecutor()
 	stmt, err := executor.PrepareContext(ctx, queryBuilder.String())
@@ -142,19 +194,27 @@ func (r *EventRepository) List(ctx context.Context, query repository.Query) ([]r
 
 	var events []repository.Resource
 	for rows.Next() {
-		var event model.Event
-		err := rows.Scan(&event.ID, &event.EventType, &event.EventData, &event.Status, &event.CreatedAt, &event.ProcessedAt)
+		event, err := scanEvent(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan event: %w", err)
 		}
-		events = append(events, &event)
+		events = append(events, event)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return events, nil
+	page := repository.NewPage(query, events)
+	if query.IncludeTotal {
+		total, err := countRows(ctx, r.getExecutor(), "SELECT COUNT(*) FROM events WHERE 1=1"+filters, args[:filterArgs]...)
+		if err != nil {
+			return nil, err
+		}
+		page.TotalCount = &total
+	}
+
+	return page, nil
 }
 
 // FindByID retrieves a single event by ID.
@@ -168,18 +228,15 @@ func (r *EventRepository) FindByID(ctx context.Context, id uuid.UUID) (repositor
 	}
 	defer stmt.Close()
 
-	var result model.Event
-	err = stmt.QueryRowContext(ctx, id).Scan(
-		&result.ID, &result.EventType, &result.EventData, &result.Status, &result.CreatedAt, &result.ProcessedAt,
-	)
+	result, err := scanEvent(stmt.QueryRowContext(ctx, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("event not found: %w", err)
+			return nil, &repository.NotFoundError{Resource: "event"}
 		}
 		return nil, fmt.Errorf("failed to query event: %w", err)
 	}
 
-	return &result, nil
+	return result, nil
 }
 
 // DeleteByID deletes an event by ID.
@@ -204,15 +261,76 @@ func (r *EventRepository) DeleteByID(ctx context.Context, id uuid.UUID) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("event not found")
+		return &repository.NotFoundError{Resource: "event"}
 	}
 
 	return nil
 }
 
-// UpdateStatus updates the status of an event by ID.
-func (r *EventRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status model.EventStatus) error {
-	query := `UPDATE events SET status = $1, processed_at = $2 WHERE id = $3`
+// ClaimPending leases up to limit of the pending events that are due to the given worker until now+lease
+// and returns them in sequence order. Events waiting for a retry are due at their next attempt time.
+// Only the first pending event of every aggregate is claimed: the later ones wait until it is processed
+// or dead, so that the events of an aggregate are published one at a time in sequence order.
+// Events leased to another worker are skipped until their lease expires,
+// so that a crashed worker releases its events; rows being claimed concurrently are skipped
+// as well (FOR UPDATE SKIP LOCKED), so that every event is claimed by one worker at a time.
+func (r *EventRepository) ClaimPending(ctx context.Context, workerID string, now time.Time, lease time.Duration, limit int) ([]*model.Event, error) {
+	query := `WITH claimable AS (
+	              SELECT id FROM events pending
+	              WHERE status = $1 AND (locked_until IS NULL OR locked_until <= $2)
+	                AND (next_attempt_at IS NULL OR next_attempt_at <= $2)
+	                AND NOT EXISTS (
+	                    SELECT 1 FROM events earlier
+	                    WHERE earlier.aggregate_id = pending.aggregate_id AND earlier.sequence < pending.sequence
+	                      AND earlier.status = $1
+	                )
+	              ORDER BY sequence
+	              LIMIT $3
+	              FOR UPDATE SKIP LOCKED
+	          )
+	          UPDATE events SET locked_by = $4, locked_until = $5
+	          FROM claimable WHERE events.id = claimable.id
+	          RETURNING events.*`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare claim statement: %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, model.EventStatusPending, now, limit, workerID, now.Add(lease))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*model.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	// RETURNING does not keep the order of the claimed rows
+	slices.SortFunc(events, func(a, b *model.Event) int {
+		return cmp.Compare(a.Sequence, b.Sequence)
+	})
+
+	return events, nil
+}
+
+// UpdateStatus updates the status of an event by ID and releases its lease. The event must still be leased to
+// the given worker, otherwise it fails with repository.ErrLeaseLost and leaves the event to its new owner.
+func (r *EventRepository) UpdateStatus(ctx context.Context, id uuid.UUID, workerID string, status model.EventStatus) error {
+	query := `UPDATE events SET status = $1, processed_at = $2, locked_by = NULL, locked_until = NULL
+	          WHERE id = $3 AND locked_by = $4`
 
 	executor := r.getExecutor()
 	stmt, err := executor.PrepareContext(ctx, query)
@@ -222,12 +340,12 @@ func (r *EventRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status
 	defer stmt.Close()
 
 	var processedAt interface{}
-	if status == model.EventStatusProcessed || status == model.EventStatusFailed {
+	if status == model.EventStatusProcessed || status == model.EventStatusDead {
 		now := time.Now()
 		processedAt = &now
 	}
 
-	result, err := stmt.ExecContext(ctx, status, processedAt, id)
+	result, err := stmt.ExecContext(ctx, status, processedAt, id, workerID)
 	if err != nil {
 		return fmt.Errorf("failed to update event status: %w", err)
 	}
@@ -238,8 +356,173 @@ func (r *EventRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("event not found")
+		return repository.ErrLeaseLost
+	}
+
+	return nil
+}
+
+// LockByID retrieves an event by ID and locks its row until the transaction ends, so that workers cannot
+// claim it meanwhile. It must be called on a repository with a transaction.
+func (r *EventRepository) LockByID(ctx context.Context, id uuid.UUID) (*model.Event, error) {
+	if r.txn == nil {
+		return nil, errors.New("locking an event requires a transaction")
+	}
+
+	event, err := scanEvent(r.txn.QueryRowContext(ctx, `SELECT * FROM events WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Resource: "event"}
+		}
+		return nil, fmt.Errorf("failed to lock event: %w", err)
+	}
+
+	return event, nil
+}
+
+// Requeue moves an event back to pending with no failed attempts, so that workers publish it again right away.
+// The last error is kept for reference. Its lease is released, so a worker still holding it can no longer
+// store an outcome.
+func (r *EventRepository) Requeue(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE events SET status = $1, attempts = 0, next_attempt_at = NULL, processed_at = NULL,
+	          locked_by = NULL, locked_until = NULL WHERE id = $2`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, model.EventStatusPending, id)
+	if err != nil {
+		return fmt.Errorf("failed to requeue event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &repository.NotFoundError{Resource: "event"}
 	}
 
 	return nil
 }
+
+// RecordFailure stores a failed attempt to process an event: its status, attempts, last error and next attempt
+// time, as updated by the caller, and releases its lease. Like UpdateStatus, it fails with repository.ErrLeaseLost
+// when the event is no longer leased to the given worker.
+func (r *EventRepository) RecordFailure(ctx context.Context, event *model.Event, workerID string) error {
+	query := `UPDATE events SET status = $1, attempts = $2, last_error = $3, next_attempt_at = $4, processed_at = $5,
+	          locked_by = NULL, locked_until = NULL WHERE id = $6 AND locked_by = $7`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	var processedAt *time.Time
+	if event.Status == model.EventStatusDead {
+		now := time.Now()
+		processedAt = &now
+	}
+
+	result, err := stmt.ExecContext(ctx, event.Status, event.Attempts, event.LastError, event.NextAttemptAt, processedAt, event.ID, workerID)
+	if err != nil {
+		return fmt.Errorf("failed to record event failure: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrLeaseLost
+	}
+
+	event.ProcessedAt = processedAt
+	event.LockedBy = nil
+	event.LockedUntil = nil
+	return nil
+}
+
+// lockAggregates takes a transaction-level advisory lock on the aggregate of every given event before
+// the events are inserted. Transactions creating events of the same aggregate are thus serialized from
+// the insert on, so that sequence order is commit order within every aggregate and workers never see
+// a later event of an aggregate committed before an earlier one. The locks are taken in aggregate ID
+// order to avoid deadlocks between batches. Without a transaction, there is nothing to serialize.
+func (r *EventRepository) lockAggregates(ctx context.Context, events []*model.Event) error {
+	if r.txn == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(events))
+	for _, event := range events {
+		keys = append(keys, event.AggregateID.String())
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	args := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, key)
+	}
+
+	query := `SELECT pg_advisory_xact_lock(hashtextextended(aggregate_id, 0))
+	          FROM (VALUES ` + valuesPlaceholders(len(keys), 1) + `) AS aggregates (aggregate_id)`
+	if _, err := r.txn.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to lock event aggregates: %w", err)
+	}
+
+	return nil
+}
+
+// scanEvent scans an events row in table column order.
+func scanEvent(row rowScanner) (*model.Event, error) {
+	var event model.Event
+	err := row.Scan(&event.ID, &event.EventType, &event.EventData, &event.Status, &event.CreatedAt, &event.ProcessedAt,
+		&event.LockedBy, &event.LockedUntil, &event.Attempts, &event.LastError, &event.NextAttemptAt, &event.Sequence,
+		&event.AggregateID)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// eventFilters builds the WHERE conditions for the query filters, to be appended to "WHERE 1=1",
+// together with their arguments numbered from $1.
+func eventFilters(query repository.Query) (string, []interface{}, error) {
+	var filters strings.Builder
+	var args []interface{}
+
+	// Apply status and event type filters if provided
+	for _, field := range []repository.QueryField{repository.StatusField, repository.EventTypeField} {
+		if value, ok := query.Values[field]; ok {
+			filters.WriteString(fmt.Sprintf(" AND %s = $%d", field, len(args)+1))
+			args = append(args, value)
+		}
+	}
+	if value, ok := query.Values[repository.CreatedAfterField]; ok {
+		createdAfter, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid created after filter: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND created_at >= $%d", len(args)+1))
+		args = append(args, createdAfter)
+	}
+	if value, ok := query.Values[repository.CreatedBeforeField]; ok {
+		createdBefore, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid created before filter: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND created_at < $%d", len(args)+1))
+		args = append(args, createdBefore)
+	}
+
+	return filters.String(), args, nil
+}
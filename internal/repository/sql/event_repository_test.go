@@ -15,6 +15,9 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+var eventColumns = []string{"id", "event_type", "event_data", "status", "created_at", "processed_at", "locked_by", "locked_until",
+	"attempts", "last_error", "next_attempt_at", "sequence", "aggregate_id"}
+
 func TestEventRepository_Create(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -33,7 +36,7 @@ func TestEventRepository_Create(t *testing.T) {
 
 		mock.ExpectPrepare("INSERT INTO events").
 			ExpectExec().
-			WithArgs(sqlmock.AnyArg(), event.EventType, event.EventData, event.Status, sqlmock.AnyArg(), nil).
+			WithArgs(sqlmock.AnyArg(), event.EventType, event.EventData, event.Status, sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		result, err := repo.Create(ctx, event)
@@ -47,11 +50,78 @@ func TestEventRepository_Create(t *testing.T) {
 		assert.Equal(t, eventData, createdEvent.EventData)
 		assert.Equal(t, model.EventStatusPending, createdEvent.Status)
 		assert.False(t, createdEvent.CreatedAt.IsZero())
+		// An event without an aggregate is an aggregate of its own
+		assert.Equal(t, createdEvent.ID, createdEvent.AggregateID)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
+func TestEventRepository_CreateBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewEventRepository(db)
+	ctx := context.Background()
+
+	events := []*model.Event{
+		{EventType: "product.created", EventData: json.RawMessage(`{"product_id": "1"}`)},
+		{EventType: "product.created", EventData: json.RawMessage(`{"product_id": "2"}`)},
+	}
+
+	mock.ExpectPrepare("INSERT INTO events .*VALUES \\(\\$1, .*\\$7\\), \\(\\$8, .*\\$14\\)").
+		ExpectExec().
+		WithArgs(
+			sqlmock.AnyArg(), "product.created", events[0].EventData, model.EventStatusPending, sqlmock.AnyArg(), nil, sqlmock.AnyArg(),
+			sqlmock.AnyArg(), "product.created", events[1].EventData, model.EventStatusPending, sqlmock.AnyArg(), nil, sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = repo.CreateBatch(ctx, events)
+	require.NoError(t, err)
+	assert.NotEqual(t, events[0].ID, events[1].ID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventRepository_CreateBatch_LocksAggregates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	first := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	second := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	events := []*model.Event{
+		{EventType: "product.updated", EventData: json.RawMessage(`{}`), AggregateID: second},
+		{EventType: "product.updated", EventData: json.RawMessage(`{}`), AggregateID: first},
+		{EventType: "product.price_changed", EventData: json.RawMessage(`{}`), AggregateID: second},
+	}
+
+	// Every aggregate is locked once, in aggregate ID order, before the events are inserted
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(hashtextextended\\(aggregate_id, 0\\)\\) FROM \\(VALUES \\(\\$1\\), \\(\\$2\\)\\)").
+		WithArgs(first.String(), second.String()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare("INSERT INTO events").
+		ExpectExec().
+		WithArgs(
+			sqlmock.AnyArg(), "product.updated", sqlmock.AnyArg(), model.EventStatusPending, sqlmock.AnyArg(), nil, second,
+			sqlmock.AnyArg(), "product.updated", sqlmock.AnyArg(), model.EventStatusPending, sqlmock.AnyArg(), nil, first,
+			sqlmock.AnyArg(), "product.price_changed", sqlmock.AnyArg(), model.EventStatusPending, sqlmock.AnyArg(), nil, second,
+		).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, NewEventRepositoryWithTx(db, tx).CreateBatch(ctx, events))
+	require.NoError(t, tx.Commit())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestEventRepository_FindByID(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -65,8 +135,8 @@ func TestEventRepository_FindByID(t *testing.T) {
 		eventData := json.RawMessage(`{"product_id": "123"}`)
 		createdAt := time.Now()
 
-		rows := sqlmock.NewRows([]string{"id", "event_type", "event_data", "status", "created_at", "processed_at"}).
-			AddRow(id, "product.created", eventData, model.EventStatusPending, createdAt, nil)
+		rows := sqlmock.NewRows(eventColumns).
+			AddRow(id, "product.created", eventData, model.EventStatusPending, createdAt, nil, nil, nil, 0, nil, nil, int64(1), id)
 
 		mock.ExpectPrepare("SELECT \\* FROM events WHERE id").
 			ExpectQuery().
@@ -93,7 +163,7 @@ func TestEventRepository_FindByID(t *testing.T) {
 		mock.ExpectPrepare("SELECT \\* FROM events WHERE id").
 			ExpectQuery().
 			WithArgs(id).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "event_type", "event_data", "status", "created_at", "processed_at"}))
+			WillReturnRows(sqlmock.NewRows(eventColumns))
 
 		result, err := repo.FindByID(ctx, id)
 		require.Error(t, err)
@@ -119,13 +189,13 @@ func TestEventRepository_List(t *testing.T) {
 		eventData2 := json.RawMessage(`{"product_id": "456"}`)
 		createdAt := time.Now()
 
-		rows := sqlmock.NewRows([]string{"id", "event_type", "event_data", "status", "created_at", "processed_at"}).
-			AddRow(id1, "product.created", eventData1, model.EventStatusPending, createdAt, nil).
-			AddRow(id2, "product.deleted", eventData2, model.EventStatusProcessed, createdAt, &createdAt)
+		rows := sqlmock.NewRows(eventColumns).
+			AddRow(id1, "product.created", eventData1, model.EventStatusPending, createdAt, nil, nil, nil, 0, nil, nil, int64(1), id1).
+			AddRow(id2, "product.deleted", eventData2, model.EventStatusProcessed, createdAt, &createdAt, nil, nil, 0, nil, nil, int64(2), id2)
 
 		mock.ExpectPrepare("SELECT \\* FROM events").
 			ExpectQuery().
-			WithArgs(10).
+			WithArgs(11).
 			WillReturnRows(rows)
 
 		query := repository.NewQuery()
@@ -201,9 +271,11 @@ func TestEventRepository_WithinTransaction_Commit(t *testing.T) {
 	}
 
 	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectPrepare("INSERT INTO events").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), event.EventType, event.EventData, event.Status, sqlmock.AnyArg(), nil).
+		WithArgs(sqlmock.AnyArg(), event.EventType, event.EventData, event.Status, sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
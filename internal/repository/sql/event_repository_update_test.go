@@ -26,40 +26,144 @@ func TestEventRepository_UpdateStatus(t *testing.T) {
 
 		mock.ExpectPrepare("UPDATE events SET status").
 			ExpectExec().
-			WithArgs(model.EventStatusProcessed, sqlmock.AnyArg(), id).
+			WithArgs(model.EventStatusProcessed, sqlmock.AnyArg(), id, "worker-1").
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.UpdateStatus(ctx, id, model.EventStatusProcessed)
+		err := repo.UpdateStatus(ctx, id, "worker-1", model.EventStatusProcessed)
 		require.NoError(t, err)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("successful status update to failed", func(t *testing.T) {
+	t.Run("successful status update to dead", func(t *testing.T) {
 		id := uuid.New()
 
 		mock.ExpectPrepare("UPDATE events SET status").
 			ExpectExec().
-			WithArgs(model.EventStatusFailed, sqlmock.AnyArg(), id).
+			WithArgs(model.EventStatusDead, sqlmock.AnyArg(), id, "worker-1").
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.UpdateStatus(ctx, id, model.EventStatusFailed)
+		err := repo.UpdateStatus(ctx, id, "worker-1", model.EventStatusDead)
 		require.NoError(t, err)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("event not found", func(t *testing.T) {
+	t.Run("lease lost", func(t *testing.T) {
 		id := uuid.New()
 
+		// The event was claimed again by another worker or requeued, so no row is leased to worker-1
+		mock.ExpectPrepare("UPDATE events SET status .* WHERE id = \\$3 AND locked_by = \\$4").
+			ExpectExec().
+			WithArgs(model.EventStatusProcessed, sqlmock.AnyArg(), id, "worker-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.UpdateStatus(ctx, id, "worker-1", model.EventStatusProcessed)
+		require.ErrorIs(t, err, repository.ErrLeaseLost)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEventRepository_RecordFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewEventRepository(db)
+	ctx := context.Background()
+
+	t.Run("schedules a retry", func(t *testing.T) {
+		lastError := "connection refused"
+		nextAttemptAt := time.Now().Add(time.Minute)
+		event := &model.Event{ID: uuid.New(), Status: model.EventStatusPending, Attempts: 2, LastError: &lastError, NextAttemptAt: &nextAttemptAt}
+
+		mock.ExpectPrepare("UPDATE events SET status = \\$1, attempts = \\$2, last_error = \\$3, next_attempt_at = \\$4").
+			ExpectExec().
+			WithArgs(model.EventStatusPending, 2, &lastError, &nextAttemptAt, nil, event.ID, "worker-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, repo.RecordFailure(ctx, event, "worker-1"))
+		assert.Nil(t, event.ProcessedAt)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("gives up on a dead event", func(t *testing.T) {
+		lastError := "connection refused"
+		event := &model.Event{ID: uuid.New(), Status: model.EventStatusDead, Attempts: 5, LastError: &lastError}
+
 		mock.ExpectPrepare("UPDATE events SET status").
 			ExpectExec().
-			WithArgs(model.EventStatusProcessed, sqlmock.AnyArg(), id).
+			WithArgs(model.EventStatusDead, 5, &lastError, nil, sqlmock.AnyArg(), event.ID, "worker-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, repo.RecordFailure(ctx, event, "worker-1"))
+		assert.NotNil(t, event.ProcessedAt)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lease lost", func(t *testing.T) {
+		lastError := "connection refused"
+		event := &model.Event{ID: uuid.New(), Status: model.EventStatusDead, Attempts: 5, LastError: &lastError}
+
+		mock.ExpectPrepare("UPDATE events SET status .* WHERE id = \\$6 AND locked_by = \\$7").
+			ExpectExec().
+			WithArgs(model.EventStatusDead, 5, &lastError, nil, sqlmock.AnyArg(), event.ID, "worker-1").
 			WillReturnResult(sqlmock.NewResult(0, 0))
 
-		err := repo.UpdateStatus(ctx, id, model.EventStatusProcessed)
-		require.Error(t, err)
-		assert.Contains(t, err.Error(), "event not found")
+		require.ErrorIs(t, repo.RecordFailure(ctx, event, "worker-1"), repository.ErrLeaseLost)
+		assert.Nil(t, event.ProcessedAt)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEventRepository_ClaimPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewEventRepository(db)
+	ctx := context.Background()
+
+	t.Run("leases the first pending event of every aggregate", func(t *testing.T) {
+		now := time.Now()
+		older, newer := uuid.New(), uuid.New()
+		workerID := "worker-1"
+		lockedUntil := now.Add(time.Minute)
+
+		// RETURNING yields the rows in no particular order
+		rows := sqlmock.NewRows(eventColumns).
+			AddRow(newer, "product.updated", []byte(`{}`), model.EventStatusPending, now, nil, workerID, lockedUntil, 0, nil, nil, int64(8), newer).
+			AddRow(older, "product.created", []byte(`{}`), model.EventStatusPending, now.Add(-time.Second), nil, workerID, lockedUntil, 0, nil, nil, int64(3), older)
+
+		mock.ExpectPrepare("(?s)SELECT id FROM events pending.*NOT EXISTS.*earlier.aggregate_id = pending.aggregate_id"+
+			".*ORDER BY sequence.*FOR UPDATE SKIP LOCKED.*UPDATE events SET locked_by").
+			ExpectQuery().
+			WithArgs(model.EventStatusPending, now, 10, workerID, lockedUntil).
+			WillReturnRows(rows)
+
+		events, err := repo.ClaimPending(ctx, workerID, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, older, events[0].ID)
+		assert.Equal(t, newer, events[1].ID)
+		require.NotNil(t, events[0].LockedBy)
+		assert.Equal(t, workerID, *events[0].LockedBy)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nothing to claim", func(t *testing.T) {
+		mock.ExpectPrepare("FOR UPDATE SKIP LOCKED").
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows(eventColumns))
+
+		events, err := repo.ClaimPending(ctx, "worker-1", time.Now(), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, events)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -78,12 +182,12 @@ func TestEventRepository_ListWithStatusFilter(t *testing.T) {
 		eventData := []byte(`{"product_id": "123"}`)
 		createdAt := time.Now()
 
-		rows := sqlmock.NewRows([]string{"id", "event_type", "event_data", "status", "created_at", "processed_at"}).
-			AddRow(id, "product.created", eventData, model.EventStatusPending, createdAt, nil)
+		rows := sqlmock.NewRows(eventColumns).
+			AddRow(id, "product.created", eventData, model.EventStatusPending, createdAt, nil, nil, nil, 0, nil, nil, int64(1), id)
 
 		mock.ExpectPrepare("SELECT \\* FROM events").
 			ExpectQuery().
-			WithArgs(string(model.EventStatusPending), 10).
+			WithArgs(string(model.EventStatusPending), 11).
 			WillReturnRows(rows)
 
 		query := repository.NewQuery().With(repository.StatusField, string(model.EventStatusPending))
@@ -97,4 +201,79 @@ func TestEventRepository_ListWithStatusFilter(t *testing.T) {
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("list with event type and created range filters", func(t *testing.T) {
+		createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		createdBefore := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+		mock.ExpectPrepare("SELECT \\* FROM events WHERE 1=1 AND status = \\$1 AND event_type = \\$2 AND created_at >= \\$3 AND created_at < \\$4").
+			ExpectQuery().
+			WithArgs(string(model.EventStatusDead), "product.created", createdAfter, createdBefore, 11).
+			WillReturnRows(sqlmock.NewRows(eventColumns))
+
+		query := repository.NewQuery().
+			With(repository.StatusField, string(model.EventStatusDead)).
+			With(repository.EventTypeField, "product.created").
+			With(repository.CreatedAfterField, createdAfter.Format(time.RFC3339Nano)).
+			With(repository.CreatedBeforeField, createdBefore.Format(time.RFC3339Nano))
+		results, err := repo.List(ctx, *query)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEventRepository_Requeue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("locks and requeues an event", func(t *testing.T) {
+		id := uuid.New()
+		lastError := "connection refused"
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT \\* FROM events WHERE id = \\$1 FOR UPDATE").
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(eventColumns).
+				AddRow(id, "product.created", []byte(`{}`), model.EventStatusDead, time.Now(), time.Now(), nil, nil, 10, lastError, nil, int64(1), id))
+		mock.ExpectPrepare("UPDATE events SET status = \\$1, attempts = 0").
+			ExpectExec().
+			WithArgs(model.EventStatusPending, id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		txRepo := NewEventRepositoryWithTx(db, tx)
+
+		event, err := txRepo.LockByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, model.EventStatusDead, event.Status)
+		assert.Equal(t, 10, event.Attempts)
+		require.NoError(t, txRepo.Requeue(ctx, id))
+		require.NoError(t, tx.Commit())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("locking requires a transaction", func(t *testing.T) {
+		_, err := NewEventRepository(db).LockByID(ctx, uuid.New())
+		assert.Error(t, err)
+	})
+
+	t.Run("event not found", func(t *testing.T) {
+		mock.ExpectPrepare("UPDATE events SET status").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := NewEventRepository(db).Requeue(ctx, uuid.New())
+		var notFoundErr *repository.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
@@ -3,6 +3,8 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 )
 
 // dbExecutor is an interface that represents either *sql.DB or *sql.Tx.
@@ -12,3 +14,41 @@ type dbExecutor interface {
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
+
+// rowScanner is an interface that represents either *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// countRows runs a "SELECT COUNT(*) ..." statement and returns the count.
+func countRows(ctx context.Context, executor dbExecutor, query string, args ...interface{}) (int64, error) {
+	var count int64
+	if err := executor.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+	return count, nil
+}
+
+// placeholderList returns a parenthesized list of count placeholders numbered from $first, e.g. "($2, $3, $4)".
+func placeholderList(first, count int) string {
+	var list strings.Builder
+	list.WriteString("(")
+	for i := range count {
+		if i > 0 {
+			list.WriteString(", ")
+		}
+		fmt.Fprintf(&list, "$%d", first+i)
+	}
+	list.WriteString(")")
+	return list.String()
+}
+
+// valuesPlaceholders returns the row tuples of a multi-row VALUES clause numbered from $1,
+// e.g. "($1, $2), ($3, $4)" for two rows of two columns.
+func valuesPlaceholders(rows, columns int) string {
+	tuples := make([]string, rows)
+	for i := range tuples {
+		tuples[i] = placeholderList(i*columns+1, columns)
+	}
+	return strings.Join(tuples, ", ")
+}
@@ -0,0 +1,126 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+)
+
+// IdempotencyKeyRepository stores the outcome of requests sent with an Idempotency-Key header.
+type IdempotencyKeyRepository struct {
+	db  *sql.DB
+	txn *sql.Tx
+}
+
+// NewIdempotencyKeyRepository creates a new IdempotencyKeyRepository instance.
+func NewIdempotencyKeyRepository(db *sql.DB) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{db: db}
+}
+
+// NewIdempotencyKeyRepositoryWithTx creates a new IdempotencyKeyRepository instance with an existing transaction.
+func NewIdempotencyKeyRepositoryWithTx(db *sql.DB, tx *sql.Tx) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{db: db, txn: tx}
+}
+
+// getExecutor returns the active executor (transaction if exists, otherwise db).
+func (r *IdempotencyKeyRepository) getExecutor() dbExecutor {
+	if r.txn != nil {
+		return r.txn
+	}
+	return r.db
+}
+
+// Create inserts a new idempotency key into the database.
+// A key of the same actor stored at or after notBefore results in a *repository.UniqueConstraintError,
+// while an older one has expired and is replaced.
+func (r *IdempotencyKeyRepository) Create(ctx context.Context, key *model.IdempotencyKey, notBefore time.Time) error {
+	key.InitMeta()
+
+	query := `INSERT INTO idempotency_keys (actor, idempotency_key, request_hash, resource_id, response_body, created_at) 
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (actor, idempotency_key) DO UPDATE
+	          SET request_hash = EXCLUDED.request_hash, resource_id = EXCLUDED.resource_id,
+	              response_body = EXCLUDED.response_body, created_at = EXCLUDED.created_at
+	          WHERE idempotency_keys.created_at < $7`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, key.Actor, key.Key, key.RequestHash, key.ResourceID, key.ResponseBody, key.CreatedAt, notBefore)
+	if err != nil {
+		if uniqueErr := uniqueViolation(err); uniqueErr != nil {
+			return uniqueErr
+		}
+		return fmt.Errorf("failed to insert idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return &repository.UniqueConstraintError{Detail: fmt.Sprintf("idempotency key %q is already in use", key.Key)}
+	}
+
+	return nil
+}
+
+// FindByKey retrieves an idempotency key stored by actor at or after notBefore.
+// Keys stored earlier have expired and are reported as not found.
+func (r *IdempotencyKeyRepository) FindByKey(ctx context.Context, actor, key string, notBefore time.Time) (*model.IdempotencyKey, error) {
+	query := `SELECT actor, idempotency_key, request_hash, resource_id, response_body, created_at FROM idempotency_keys
+	          WHERE actor = $1 AND idempotency_key = $2 AND created_at >= $3`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	var result model.IdempotencyKey
+	err = stmt.QueryRowContext(ctx, actor, key, notBefore).Scan(
+		&result.Actor, &result.Key, &result.RequestHash, &result.ResourceID, &result.ResponseBody, &result.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Resource: "idempotency key"}
+		}
+		return nil, fmt.Errorf("failed to query idempotency key: %w", err)
+	}
+
+	return &result, nil
+}
+
+// DeleteExpired removes idempotency keys created before the given time and returns how many rows were removed.
+func (r *IdempotencyKeyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE created_at < $1`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare delete statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete idempotency keys: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,160 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeyRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewIdempotencyKeyRepository(db)
+	ctx := context.Background()
+
+	notBefore := time.Now().Add(-time.Hour)
+
+	t.Run("successful creation", func(t *testing.T) {
+		key := &model.IdempotencyKey{
+			Actor:        "alice",
+			Key:          "create-1",
+			RequestHash:  "abc",
+			ResourceID:   uuid.New(),
+			ResponseBody: json.RawMessage(`{"ID":"1"}`),
+		}
+
+		mock.ExpectPrepare("INSERT INTO idempotency_keys").
+			ExpectExec().
+			WithArgs("alice", key.Key, key.RequestHash, key.ResourceID, key.ResponseBody, sqlmock.AnyArg(), notBefore).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		require.NoError(t, repo.Create(ctx, key, notBefore))
+		assert.False(t, key.CreatedAt.IsZero())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("key still in use", func(t *testing.T) {
+		key := &model.IdempotencyKey{
+			Actor:        "alice",
+			Key:          "create-1",
+			RequestHash:  "abc",
+			ResourceID:   uuid.New(),
+			ResponseBody: json.RawMessage(`{}`),
+		}
+
+		// The stored key has not expired, so the conflicting insert changes nothing
+		mock.ExpectPrepare("INSERT INTO idempotency_keys .* ON CONFLICT \\(actor, idempotency_key\\) DO UPDATE .* WHERE idempotency_keys.created_at < \\$7").
+			ExpectExec().
+			WithArgs("alice", key.Key, key.RequestHash, key.ResourceID, key.ResponseBody, sqlmock.AnyArg(), notBefore).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Create(ctx, key, notBefore)
+		var uniqueErr *repository.UniqueConstraintError
+		require.ErrorAs(t, err, &uniqueErr)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unique violation", func(t *testing.T) {
+		key := &model.IdempotencyKey{
+			Actor:        "alice",
+			Key:          "create-1",
+			RequestHash:  "abc",
+			ResourceID:   uuid.New(),
+			ResponseBody: json.RawMessage(`{}`),
+		}
+
+		mock.ExpectPrepare("INSERT INTO idempotency_keys").
+			ExpectExec().
+			WithArgs("alice", key.Key, key.RequestHash, key.ResourceID, key.ResponseBody, sqlmock.AnyArg(), notBefore).
+			WillReturnError(&pgconn.PgError{Code: pqUniqueViolationErrCode, Detail: "Key (actor, idempotency_key)=(alice, create-1) already exists."})
+
+		err := repo.Create(ctx, key, notBefore)
+		var uniqueErr *repository.UniqueConstraintError
+		require.ErrorAs(t, err, &uniqueErr)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestIdempotencyKeyRepository_FindByKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewIdempotencyKeyRepository(db)
+	ctx := context.Background()
+
+	notBefore := time.Now().Add(-time.Hour)
+
+	t.Run("found", func(t *testing.T) {
+		resourceID := uuid.New()
+		createdAt := time.Now()
+		rows := sqlmock.NewRows(idempotencyKeyColumns).
+			AddRow("alice", "create-1", "abc", resourceID, []byte(`{"ID":"1"}`), createdAt)
+
+		mock.ExpectPrepare("SELECT .* FROM idempotency_keys\\s+WHERE actor = \\$1 AND idempotency_key = \\$2 AND created_at >= \\$3").
+			ExpectQuery().
+			WithArgs("alice", "create-1", notBefore).
+			WillReturnRows(rows)
+
+		key, err := repo.FindByKey(ctx, "alice", "create-1", notBefore)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", key.Actor)
+		assert.Equal(t, "abc", key.RequestHash)
+		assert.Equal(t, resourceID, key.ResourceID)
+		assert.JSONEq(t, `{"ID":"1"}`, string(key.ResponseBody))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectPrepare("SELECT .* FROM idempotency_keys").
+			ExpectQuery().
+			WithArgs("alice", "missing", notBefore).
+			WillReturnRows(sqlmock.NewRows(idempotencyKeyColumns))
+
+		key, err := repo.FindByKey(ctx, "alice", "missing", notBefore)
+		assert.Nil(t, key)
+		var notFoundErr *repository.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+var idempotencyKeyColumns = []string{"actor", "idempotency_key", "request_hash", "resource_id", "response_body", "created_at"}
+
+func TestIdempotencyKeyRepository_DeleteExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewIdempotencyKeyRepository(db)
+	ctx := context.Background()
+	before := time.Now().Add(-24 * time.Hour)
+
+	mock.ExpectPrepare("DELETE FROM idempotency_keys WHERE created_at < \\$1").
+		ExpectExec().
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	deleted, err := repo.DeleteExpired(ctx, before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,130 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+)
+
+// InventoryRepository stores the stock levels of products in the inventory table.
+type InventoryRepository struct {
+	db  *sql.DB
+	txn *sql.Tx
+}
+
+// NewInventoryRepository creates a new InventoryRepository instance.
+func NewInventoryRepository(db *sql.DB) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// NewInventoryRepositoryWithTx creates a new InventoryRepository instance with an existing transaction.
+func NewInventoryRepositoryWithTx(db *sql.DB, tx *sql.Tx) *InventoryRepository {
+	return &InventoryRepository{db: db, txn: tx}
+}
+
+// getExecutor returns the active executor (transaction if exists, otherwise db).
+func (r *InventoryRepository) getExecutor() dbExecutor {
+	if r.txn != nil {
+		return r.txn
+	}
+	return r.db
+}
+
+// FindByProductID retrieves the stock level of a product.
+// Products whose stock was never changed have no inventory row and are reported as a *repository.NotFoundError.
+func (r *InventoryRepository) FindByProductID(ctx context.Context, productID uuid.UUID) (*model.Inventory, error) {
+	query := `SELECT * FROM inventory WHERE product_id = $1`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	inventory, err := scanInventory(stmt.QueryRowContext(ctx, productID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Resource: "inventory"}
+		}
+		return nil, fmt.Errorf("failed to query inventory: %w", err)
+	}
+
+	return inventory, nil
+}
+
+// LockByProductID retrieves the stock level of a product and locks its row until the transaction ends,
+// so that concurrent stock changes of the same product are applied one after another.
+// A missing inventory row is created with zero stock first. It must be called on a repository with a transaction.
+func (r *InventoryRepository) LockByProductID(ctx context.Context, productID uuid.UUID) (*model.Inventory, error) {
+	if r.txn == nil {
+		return nil, errors.New("locking inventory requires a transaction")
+	}
+
+	now := time.Now()
+	insert := `INSERT INTO inventory (product_id, quantity_on_hand, quantity_reserved, created_at, updated_at)
+	           VALUES ($1, 0, 0, $2, $3) ON CONFLICT (product_id) DO NOTHING`
+	if _, err := r.txn.ExecContext(ctx, insert, productID, now, now); err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, &repository.NotFoundError{Resource: "product"}
+		}
+		return nil, fmt.Errorf("failed to insert inventory: %w", err)
+	}
+
+	query := `SELECT * FROM inventory WHERE product_id = $1 FOR UPDATE`
+	inventory, err := scanInventory(r.txn.QueryRowContext(ctx, query, productID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock inventory: %w", err)
+	}
+
+	return inventory, nil
+}
+
+// Update persists the quantities of an existing inventory row and bumps its updated_at timestamp.
+func (r *InventoryRepository) Update(ctx context.Context, inventory *model.Inventory) error {
+	updatedAt := time.Now()
+
+	query := `UPDATE inventory SET quantity_on_hand = $1, quantity_reserved = $2, updated_at = $3 WHERE product_id = $4`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, inventory.QuantityOnHand, inventory.QuantityReserved, updatedAt, inventory.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to update inventory: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &repository.NotFoundError{Resource: "inventory"}
+	}
+
+	inventory.UpdatedAt = updatedAt
+
+	return nil
+}
+
+// scanInventory scans an inventory row in table column order.
+func scanInventory(row rowScanner) (*model.Inventory, error) {
+	var inventory model.Inventory
+	err := row.Scan(&inventory.ProductID, &inventory.QuantityOnHand, &inventory.QuantityReserved,
+		&inventory.CreatedAt, &inventory.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &inventory, nil
+}
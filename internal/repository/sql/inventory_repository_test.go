@@ -0,0 +1,135 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventoryRepository_FindByProductID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewInventoryRepository(db)
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		productID := uuid.New()
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"product_id", "quantity_on_hand", "quantity_reserved", "created_at", "updated_at"}).
+			AddRow(productID, int64(10), int64(3), now, now)
+
+		mock.ExpectPrepare("SELECT \\* FROM inventory WHERE product_id = \\$1").
+			ExpectQuery().
+			WithArgs(productID).
+			WillReturnRows(rows)
+
+		inventory, err := repo.FindByProductID(ctx, productID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), inventory.QuantityOnHand)
+		assert.Equal(t, int64(3), inventory.QuantityReserved)
+		assert.Equal(t, int64(7), inventory.Available())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		productID := uuid.New()
+
+		mock.ExpectPrepare("SELECT \\* FROM inventory WHERE product_id = \\$1").
+			ExpectQuery().
+			WithArgs(productID).
+			WillReturnRows(sqlmock.NewRows([]string{"product_id", "quantity_on_hand", "quantity_reserved", "created_at", "updated_at"}))
+
+		inventory, err := repo.FindByProductID(ctx, productID)
+		assert.Nil(t, inventory)
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInventoryRepository_LockByProductID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("creates a missing row and locks it", func(t *testing.T) {
+		productID := uuid.New()
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO inventory .* ON CONFLICT \\(product_id\\) DO NOTHING").
+			WithArgs(productID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT \\* FROM inventory WHERE product_id = \\$1 FOR UPDATE").
+			WithArgs(productID).
+			WillReturnRows(sqlmock.NewRows([]string{"product_id", "quantity_on_hand", "quantity_reserved", "created_at", "updated_at"}).
+				AddRow(productID, int64(0), int64(0), now, now))
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		inventory, err := NewInventoryRepositoryWithTx(db, tx).LockByProductID(ctx, productID)
+		require.NoError(t, err)
+		assert.Equal(t, productID, inventory.ProductID)
+		assert.Zero(t, inventory.Available())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing product", func(t *testing.T) {
+		productID := uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO inventory").
+			WithArgs(productID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnError(&pgconn.PgError{Code: pqForeignKeyViolationErrCode})
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		inventory, err := NewInventoryRepositoryWithTx(db, tx).LockByProductID(ctx, productID)
+		assert.Nil(t, inventory)
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, "product", notFoundErr.Resource)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("without transaction", func(t *testing.T) {
+		inventory, err := NewInventoryRepository(db).LockByProductID(ctx, uuid.New())
+		assert.Nil(t, inventory)
+		require.Error(t, err)
+	})
+}
+
+func TestInventoryRepository_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewInventoryRepository(db)
+	inventory := &model.Inventory{ProductID: uuid.New(), QuantityOnHand: 5, QuantityReserved: 2}
+
+	mock.ExpectPrepare("UPDATE inventory SET quantity_on_hand = \\$1, quantity_reserved = \\$2, updated_at = \\$3 WHERE product_id = \\$4").
+		ExpectExec().
+		WithArgs(int64(5), int64(2), sqlmock.AnyArg(), inventory.ProductID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.Update(context.Background(), inventory))
+	assert.False(t, inventory.UpdatedAt.IsZero())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -11,11 +11,15 @@ import (
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file" // Register file source driver for migrations
 	"github.com/iyhunko/microservices-with-sqs/internal/config"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib" // Register pgx driver for database/sql
+	"github.com/lib/pq"
 )
 
 const (
-	pqUniqueViolationErrCode = "23505" // PostgreSQL unique violation error code. See https://www.postgresql.org/docs/14/errcodes-appendix.html
+	pqUniqueViolationErrCode     = "23505" // PostgreSQL unique violation error code. See https://www.postgresql.org/docs/14/errcodes-appendix.html
+	pqForeignKeyViolationErrCode = "23503" // PostgreSQL foreign key violation error code.
 )
 
 func StartDB(ctx context.Context, dbConf config.DB) (*sql.DB, error) {
@@ -68,3 +72,27 @@ func RunMigrations(db *sql.DB) error {
 
 	return nil
 }
+
+// uniqueViolation converts a unique violation reported by either PostgreSQL driver into a UniqueConstraintError.
+// It returns nil for any other error.
+func uniqueViolation(err error) *repository.UniqueConstraintError {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pqUniqueViolationErrCode {
+		return &repository.UniqueConstraintError{Detail: pgErr.Detail}
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolationErrCode {
+		return &repository.UniqueConstraintError{Detail: pqErr.Detail}
+	}
+	return nil
+}
+
+// isForeignKeyViolation reports whether either PostgreSQL driver rejected a write because of a foreign key constraint.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pqForeignKeyViolationErrCode
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqForeignKeyViolationErrCode
+}
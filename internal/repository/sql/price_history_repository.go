@@ -0,0 +1,138 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+)
+
+// PriceHistoryRepository stores the price changes of products in the product_price_history table.
+type PriceHistoryRepository struct {
+	db  *sql.DB
+	txn *sql.Tx
+}
+
+// NewPriceHistoryRepository creates a new PriceHistoryRepository instance.
+func NewPriceHistoryRepository(db *sql.DB) *PriceHistoryRepository {
+	return &PriceHistoryRepository{db: db}
+}
+
+// NewPriceHistoryRepositoryWithTx creates a new PriceHistoryRepository instance with an existing transaction.
+func NewPriceHistoryRepositoryWithTx(db *sql.DB, tx *sql.Tx) *PriceHistoryRepository {
+	return &PriceHistoryRepository{db: db, txn: tx}
+}
+
+// getExecutor returns the active executor (transaction if exists, otherwise db).
+func (r *PriceHistoryRepository) getExecutor() dbExecutor {
+	if r.txn != nil {
+		return r.txn
+	}
+	return r.db
+}
+
+// Create inserts a new price history entry into the database.
+func (r *PriceHistoryRepository) Create(ctx context.Context, entry *model.PriceHistoryEntry) error {
+	entry.InitMeta()
+
+	query := `INSERT INTO product_price_history (id, product_id, old_price, old_currency, new_price, new_currency, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, entry.ID, entry.ProductID, entry.OldPrice.Decimal(), entry.OldPrice.Currency,
+		entry.NewPrice.Decimal(), entry.NewPrice.Currency, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert price history entry: %w", err)
+	}
+
+	return nil
+}
+
+// ListPage retrieves one page of the price changes of the product given as repository.ProductIDField.
+func (r *PriceHistoryRepository) ListPage(ctx context.Context, query repository.Query) (*repository.Page, error) {
+	if query.SortOrder().Field != repository.CreatedAtField {
+		return nil, fmt.Errorf("unsupported sort field %q: %w", query.SortOrder().Field, repository.ErrInvalidSort)
+	}
+
+	productID, err := uuid.Parse(query.Values[repository.ProductIDField])
+	if err != nil {
+		return nil, fmt.Errorf("invalid product ID format: %w", err)
+	}
+
+	var queryBuilder strings.Builder
+	queryBuilder.WriteString("SELECT * FROM product_price_history WHERE product_id = $1")
+	args := []interface{}{productID}
+	argIndex := 2
+
+	comparison, direction := query.Keyset()
+
+	// Apply pagination: continue from the cursor (created_at, id) pair in the fetch direction
+	if query.Paginator != nil {
+		queryBuilder.WriteString(fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", comparison, argIndex, argIndex+1))
+		args = append(args, query.Paginator.LastCreatedAt, query.Paginator.LastID)
+		argIndex += 2
+	}
+
+	// Order by created_at with id as a tie-breaker for consistent pagination
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY created_at %s, id %s", direction, direction))
+
+	// Apply limit
+	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", argIndex))
+	args = append(args, query.FetchLimit())
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, queryBuilder.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []repository.Resource
+	for rows.Next() {
+		entry, err := scanPriceHistoryEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price history entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return repository.NewPage(query, entries), nil
+}
+
+// scanPriceHistoryEntry scans a product_price_history row in table column order.
+func scanPriceHistoryEntry(row rowScanner) (*model.PriceHistoryEntry, error) {
+	var entry model.PriceHistoryEntry
+	var oldPrice, oldCurrency, newPrice, newCurrency string
+	err := row.Scan(&entry.ID, &entry.ProductID, &oldPrice, &oldCurrency, &newPrice, &newCurrency, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.OldPrice, err = model.ParseMoney(oldPrice, strings.TrimSpace(oldCurrency)); err != nil {
+		return nil, fmt.Errorf("invalid stored old price of entry %s: %w", entry.ID, err)
+	}
+	if entry.NewPrice, err = model.ParseMoney(newPrice, strings.TrimSpace(newCurrency)); err != nil {
+		return nil, fmt.Errorf("invalid stored new price of entry %s: %w", entry.ID, err)
+	}
+	return &entry, nil
+}
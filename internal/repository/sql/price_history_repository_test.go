@@ -0,0 +1,88 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceHistoryRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPriceHistoryRepository(db)
+	entry := &model.PriceHistoryEntry{
+		ProductID: uuid.New(),
+		OldPrice:  model.Money{Amount: 1999, Currency: "USD"},
+		NewPrice:  model.Money{Amount: 1500, Currency: "JPY"},
+	}
+
+	mock.ExpectPrepare("INSERT INTO product_price_history").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), entry.ProductID, "19.99", "USD", "1500", "JPY", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, repo.Create(context.Background(), entry))
+	assert.NotEqual(t, uuid.Nil, entry.ID)
+	assert.False(t, entry.CreatedAt.IsZero())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPriceHistoryRepository_ListPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPriceHistoryRepository(db)
+	ctx := context.Background()
+	columns := []string{"id", "product_id", "old_price", "old_currency", "new_price", "new_currency", "created_at"}
+
+	t.Run("next page of a product", func(t *testing.T) {
+		productID := uuid.New()
+		query := repository.NewQuery().With(repository.ProductIDField, productID.String())
+		query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortDesc}
+		query.Limit = 1
+		lastCreatedAt := time.Now()
+		lastID := uuid.New()
+		query.Paginator = &repository.Paginator{LastID: lastID, LastCreatedAt: lastCreatedAt, Sort: query.Sort}
+
+		now := time.Now()
+		rows := sqlmock.NewRows(columns).
+			AddRow(uuid.New(), productID, "20.000", "USD", "15.500", "USD", now).
+			AddRow(uuid.New(), productID, "25.000", "USD", "20.000", "USD", now)
+
+		mock.ExpectPrepare("SELECT \\* FROM product_price_history WHERE product_id = \\$1 AND \\(created_at, id\\) < \\(\\$2, \\$3\\) ORDER BY created_at DESC, id DESC LIMIT \\$4").
+			ExpectQuery().
+			WithArgs(productID, lastCreatedAt, lastID, 2).
+			WillReturnRows(rows)
+
+		page, err := repo.ListPage(ctx, *query)
+		require.NoError(t, err)
+		require.Len(t, page.Resources, 1)
+		entry := page.Resources[0].(*model.PriceHistoryEntry)
+		assert.Equal(t, model.Money{Amount: 2000, Currency: "USD"}, entry.OldPrice)
+		assert.Equal(t, model.Money{Amount: 1550, Currency: "USD"}, entry.NewPrice)
+		assert.True(t, page.HasNext)
+		assert.True(t, page.HasPrev)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing product", func(t *testing.T) {
+		query := repository.NewQuery()
+		query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortDesc}
+
+		page, err := repo.ListPage(ctx, *query)
+		assert.Nil(t, page)
+		require.Error(t, err)
+	})
+}
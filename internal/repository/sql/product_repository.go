@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iyhunko/microservices-with-sqs/internal/model"
@@ -75,8 +77,8 @@ func (r *ProductRepository) Create(ctx context.Context, resource repository.Reso
 
 	product.InitMeta()
 
-	query := `INSERT INTO products (id, name, description, price, created_at, updated_at) 
-	          VALUES ($1, $2, $3, $4, $5, $6)`
+	query := `INSERT INTO products (id, name, description, price, currency, category_id, created_at, updated_at, version) 
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
 	executor := r.getExecutor()
 	stmt, err := executor.PrepareContext(ctx, query)
@@ -85,39 +87,99 @@ func (r *ProductRepository) Create(ctx context.Context, resource repository.Reso
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, product.ID, product.Name, product.Description, product.Price, product.CreatedAt, product.UpdatedAt)
+	_, err = stmt.ExecContext(ctx, product.ID, product.Name, product.Description, product.Price.Decimal(), product.Price.Currency,
+		product.CategoryID, product.CreatedAt, product.UpdatedAt, product.Version)
 	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, &repository.NotFoundError{Resource: "category"}
+		}
 		return nil, fmt.Errorf("failed to insert product: %w", err)
 	}
 
 	return product, nil
 }
 
+// CreateBatch inserts all given products with a single multi-row INSERT.
+// The metadata of every product is initialized like in Create.
+func (r *ProductRepository) CreateBatch(ctx context.Context, products []*model.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	const columns = 9
+	args := make([]interface{}, 0, len(products)*columns)
+	for _, product := range products {
+		product.InitMeta()
+		args = append(args, product.ID, product.Name, product.Description, product.Price.Decimal(), product.Price.Currency,
+			product.CategoryID, product.CreatedAt, product.UpdatedAt, product.Version)
+	}
+
+	query := `INSERT INTO products (id, name, description, price, currency, category_id, created_at, updated_at, version) 
+	          VALUES ` + valuesPlaceholders(len(products), columns)
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, args...); err != nil {
+		if isForeignKeyViolation(err) {
+			return &repository.NotFoundError{Resource: "category"}
+		}
+		return fmt.Errorf("failed to insert products: %w", err)
+	}
+
+	return nil
+}
+
 // List retrieves products from the database based on the provided query.
 func (r *ProductRepository) List(ctx context.Context, query repository.Query) ([]repository.Resource, error) {
+	page, err := r.ListPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return page.Resources, nil
+}
+
+// ListPage retrieves one page of products from the database based on the provided query.
+func (r *ProductRepository) ListPage(ctx context.Context, query repository.Query) (*repository.Page, error) {
+	filters, args, err := productFilters(query)
+	if err != nil {
+		return nil, err
+	}
+	filterArgs := len(args)
+	argIndex := filterArgs + 1
+
 	var queryBuilder strings.Builder
 	queryBuilder.WriteString("SELECT * FROM products WHERE 1=1")
+	queryBuilder.WriteString(filters)
 
-	var args []interface{}
-	argIndex := 1
+	sort := query.SortOrder()
+	column, ok := productSortColumns[sort.Field]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q: %w", sort.Field, repository.ErrInvalidSort)
+	}
+	comparison, direction := query.Keyset()
 
-	// Apply pagination
+	// Apply pagination: continue from the cursor (sort key, id) pair in the fetch direction
 	if query.Paginator != nil {
-		queryBuilder.WriteString(fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", argIndex, argIndex+1))
-		args = append(args, query.Paginator.LastCreatedAt, query.Paginator.LastID)
+		lastValue, err := productSortValue(sort.Field, query.Paginator)
+		if err != nil {
+			return nil, err
+		}
+		queryBuilder.WriteString(fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", column, comparison, argIndex, argIndex+1))
+		args = append(args, lastValue, query.Paginator.LastID)
 		argIndex += 2
 	}
 
-	// Order by created_at DESC, id DESC for consistent pagination
-	queryBuilder.WriteString(" ORDER BY created_at DESC, id DESC")
+	// Order by the sort key with id as a tie-breaker for consistent pagination
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction))
 
 	// Apply limit
-	limit := query.Limit
-	if limit <= 0 {
-		limit = repository.DefaultPaginationLimit
-	}
 	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", argIndex))
-	args = append(args, limit)
+	args = append(args, query.FetchLimit())
 
 	executor := r.getExecutor()
 	stmt, err := executor.PrepareContext(ctx, queryBuilder.String())
@@ -134,25 +196,171 @@ func (r *ProductRepository) List(ctx context.Context, query repository.Query) ([
 
 	var products []repository.Resource
 	for rows.Next() {
-		var product model.Product
-		err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.Price, &product.CreatedAt, &product.UpdatedAt)
+		product, err := scanProduct(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
-		products = append(products, &product)
+		products = append(products, product)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return products, nil
+	page := repository.NewPage(query, products)
+	if query.IncludeTotal {
+		total, err := countRows(ctx, r.getExecutor(), "SELECT COUNT(*) FROM products WHERE 1=1"+filters, args[:filterArgs]...)
+		if err != nil {
+			return nil, err
+		}
+		page.TotalCount = &total
+	}
+
+	return page, nil
 }
 
-// FindByID retrieves a single product by ID.
+// productFilters builds the WHERE conditions for the query filters, to be appended to "WHERE 1=1",
+// together with their arguments numbered from $1.
+func productFilters(query repository.Query) (string, []interface{}, error) {
+	var filters strings.Builder
+	var args []interface{}
+
+	// Hide soft-deleted products unless explicitly requested
+	if query.Values[repository.IncludeDeletedField] != "true" {
+		filters.WriteString(" AND deleted_at IS NULL")
+	}
+
+	// Apply query filters in a fixed order so equal filters always produce the same statement
+	if prefix, ok := query.Values[repository.NamePrefixField]; ok {
+		filters.WriteString(fmt.Sprintf(` AND name ILIKE $%d ESCAPE '\'`, len(args)+1))
+		args = append(args, escapeLike(prefix)+"%")
+	}
+	if substring, ok := query.Values[repository.NameContainsField]; ok {
+		filters.WriteString(fmt.Sprintf(` AND name ILIKE $%d ESCAPE '\'`, len(args)+1))
+		args = append(args, "%"+escapeLike(substring)+"%")
+	}
+	// Price bounds are exact amounts of one currency and passed on as NUMERIC text. Prices of different
+	// currencies are not comparable, so price bounds and the price order require the currency filter
+	currency, hasCurrency := query.Values[repository.CurrencyField]
+	_, hasMinPrice := query.Values[repository.MinPriceField]
+	_, hasMaxPrice := query.Values[repository.MaxPriceField]
+	if !hasCurrency && (hasMinPrice || hasMaxPrice || query.Sort.Field == repository.PriceField) {
+		return "", nil, errors.New("price filters and the price sort require a currency filter")
+	}
+	if hasCurrency {
+		if _, err := model.CurrencyExponent(currency); err != nil {
+			return "", nil, fmt.Errorf("invalid currency filter: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND currency = $%d", len(args)+1))
+		args = append(args, currency)
+	}
+	if value, ok := query.Values[repository.MinPriceField]; ok {
+		minPrice, err := model.ParseMoney(value, currency)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid min price filter: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND price >= $%d::numeric", len(args)+1))
+		args = append(args, minPrice.Decimal())
+	}
+	if value, ok := query.Values[repository.MaxPriceField]; ok {
+		maxPrice, err := model.ParseMoney(value, currency)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid max price filter: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND price <= $%d::numeric", len(args)+1))
+		args = append(args, maxPrice.Decimal())
+	}
+	if value, ok := query.Values[repository.CategoryIDField]; ok {
+		categoryID, err := uuid.Parse(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid category filter: %w", err)
+		}
+		filters.WriteString(" AND category_id IN (" + fmt.Sprintf(categorySubtreeQuery, len(args)+1) + ")")
+		args = append(args, categoryID)
+	}
+	if tag, ok := query.Values[repository.TagField]; ok {
+		filters.WriteString(fmt.Sprintf(" AND id IN (SELECT product_id FROM product_tags WHERE tag = $%d)", len(args)+1))
+		args = append(args, tag)
+	}
+	if value, ok := query.Values[repository.CreatedAfterField]; ok {
+		createdAfter, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid created after filter: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND created_at >= $%d", len(args)+1))
+		args = append(args, createdAfter)
+	}
+	if value, ok := query.Values[repository.CreatedBeforeField]; ok {
+		createdBefore, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid created before filter: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND created_at < $%d", len(args)+1))
+		args = append(args, createdBefore)
+	}
+
+	return filters.String(), args, nil
+}
+
+// decimalPattern matches the decimal numbers formatted by Money.Decimal.
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// productSortColumns maps the sortable query fields to product columns.
+var productSortColumns = map[repository.QueryField]string{
+	repository.CreatedAtField: "created_at",
+	repository.PriceField:     "price",
+	repository.NameField:      "name",
+}
+
+// productSortValue returns the typed sort key of the last listed product stored in the paginator.
+func productSortValue(field repository.QueryField, paginator *repository.Paginator) (interface{}, error) {
+	switch field {
+	case repository.PriceField:
+		// Kept as the decimal string of Money.Decimal so that the NUMERIC comparison stays exact
+		if !decimalPattern.MatchString(paginator.LastValue) {
+			return nil, fmt.Errorf("invalid page token price %q", paginator.LastValue)
+		}
+		return paginator.LastValue, nil
+	case repository.NameField:
+		return paginator.LastValue, nil
+	default:
+		return paginator.LastCreatedAt, nil
+	}
+}
+
+// FindByID retrieves a single product by ID. Soft-deleted products are reported as not found.
 func (r *ProductRepository) FindByID(ctx context.Context, id uuid.UUID) (repository.Resource, error) {
-	query := `SELECT * FROM products WHERE id = $1`
+	product, err := r.findOne(ctx, `SELECT * FROM products WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// FindByIDIncludingDeleted retrieves a single product by ID, whether or not it is soft-deleted.
+func (r *ProductRepository) FindByIDIncludingDeleted(ctx context.Context, id uuid.UUID) (*model.Product, error) {
+	return r.findOne(ctx, `SELECT * FROM products WHERE id = $1`, id)
+}
+
+// LockByID retrieves a product by ID like FindByID and locks its row until the transaction ends, so that
+// concurrent writes wait instead of changing it in between. It must be called on a repository with a transaction.
+func (r *ProductRepository) LockByID(ctx context.Context, id uuid.UUID) (*model.Product, error) {
+	if r.txn == nil {
+		return nil, errors.New("locking a product requires a transaction")
+	}
+	return r.findOne(ctx, `SELECT * FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id)
+}
 
+// LockByIDIncludingDeleted retrieves and locks a product like LockByID, whether or not it is soft-deleted.
+func (r *ProductRepository) LockByIDIncludingDeleted(ctx context.Context, id uuid.UUID) (*model.Product, error) {
+	if r.txn == nil {
+		return nil, errors.New("locking a product requires a transaction")
+	}
+	return r.findOne(ctx, `SELECT * FROM products WHERE id = $1 FOR UPDATE`, id)
+}
+
+// findOne runs a single-row product query that takes the product ID as its only argument.
+func (r *ProductRepository) findOne(ctx context.Context, query string, id uuid.UUID) (*model.Product, error) {
 	executor := r.getExecutor()
 	stmt, err := executor.PrepareContext(ctx, query)
 	if err != nil {
@@ -160,21 +368,235 @@ func (r *ProductRepository) FindByID(ctx context.Context, id uuid.UUID) (reposit
 	}
 	defer stmt.Close()
 
-	var result model.Product
-	err = stmt.QueryRowContext(ctx, id).Scan(
-		&result.ID, &result.Name, &result.Description, &result.Price, &result.CreatedAt, &result.UpdatedAt,
-	)
+	result, err := scanProduct(stmt.QueryRowContext(ctx, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("product not found: %w", err)
+			return nil, &repository.NotFoundError{Resource: "product"}
 		}
 		return nil, fmt.Errorf("failed to query product: %w", err)
 	}
 
-	return &result, nil
+	return result, nil
+}
+
+// scanProduct scans a products row in table column order.
+func scanProduct(row rowScanner) (*model.Product, error) {
+	var product model.Product
+	var price, currency string
+	err := row.Scan(
+		&product.ID, &product.Name, &product.Description, &price, &product.CreatedAt, &product.UpdatedAt,
+		&product.Version, &product.DeletedAt, &currency, &product.CategoryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Price, err = model.ParseMoney(price, strings.TrimSpace(currency))
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored price of product %s: %w", product.ID, err)
+	}
+	return &product, nil
+}
+
+// Update persists the mutable fields of an existing, not deleted product and bumps its updated_at timestamp.
+// The write only succeeds if the stored version still equals product.Version, which is then incremented.
+func (r *ProductRepository) Update(ctx context.Context, resource repository.Resource) (repository.Resource, error) {
+	product, ok := resource.(*model.Product)
+	if !ok {
+		return nil, errors.New("resource must be a *model.Product")
+	}
+
+	updatedAt := time.Now()
+
+	query := `UPDATE products SET name = $1, description = $2, price = $3, currency = $4, category_id = $5, updated_at = $6,
+	          version = version + 1
+	          WHERE id = $7 AND version = $8 AND deleted_at IS NULL`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, product.Name, product.Description, product.Price.Decimal(), product.Price.Currency,
+		product.CategoryID, updatedAt, product.ID, product.Version)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, &repository.NotFoundError{Resource: "category"}
+		}
+		return nil, fmt.Errorf("failed to update product: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, r.versionMismatchError(ctx, product.ID, false)
+	}
+
+	product.UpdatedAt = updatedAt
+	product.Version++
+
+	return product, nil
 }
 
-// DeleteByID deletes a product by ID.
+// SoftDelete marks a product as deleted, guarded by its version like Update.
+// On success product.DeletedAt and product.UpdatedAt are set and product.Version is incremented.
+func (r *ProductRepository) SoftDelete(ctx context.Context, product *model.Product) error {
+	deletedAt := time.Now()
+
+	query := `UPDATE products SET deleted_at = $1, updated_at = $1, version = version + 1
+	          WHERE id = $2 AND version = $3 AND deleted_at IS NULL`
+
+	if err := r.execVersioned(ctx, query, deletedAt, product.ID, product.Version, false); err != nil {
+		return err
+	}
+
+	product.DeletedAt = &deletedAt
+	product.UpdatedAt = deletedAt
+	product.Version++
+
+	return nil
+}
+
+// Restore clears the deleted mark of a soft-deleted product, guarded by its version like Update.
+// On success product.DeletedAt is reset, product.UpdatedAt is set and product.Version is incremented.
+func (r *ProductRepository) Restore(ctx context.Context, product *model.Product) error {
+	restoredAt := time.Now()
+
+	query := `UPDATE products SET deleted_at = NULL, updated_at = $1, version = version + 1
+	          WHERE id = $2 AND version = $3 AND deleted_at IS NOT NULL`
+
+	if err := r.execVersioned(ctx, query, restoredAt, product.ID, product.Version, true); err != nil {
+		return err
+	}
+
+	product.DeletedAt = nil
+	product.UpdatedAt = restoredAt
+	product.Version++
+
+	return nil
+}
+
+// SoftDeleteBatch marks all products with the given IDs as deleted with a single statement
+// and returns the products that were deleted. IDs of missing or already deleted products are ignored.
+func (r *ProductRepository) SoftDeleteBatch(ctx context.Context, ids []uuid.UUID) ([]*model.Product, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, time.Now())
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	query := `UPDATE products SET deleted_at = $1, updated_at = $1, version = version + 1
+	          WHERE deleted_at IS NULL AND id IN ` + placeholderList(2, len(ids)) + `
+	          RETURNING *`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete products: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := make([]*model.Product, 0, len(ids))
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		deleted = append(deleted, product)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// execVersioned runs a conditional write whose args are (timestamp, id, version) on a product that is expected
+// to be soft-deleted or not, and translates an empty result into a not found or version conflict error.
+func (r *ProductRepository) execVersioned(ctx context.Context, query string, at time.Time, id uuid.UUID, version int64, deleted bool) error {
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, at, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return r.versionMismatchError(ctx, id, deleted)
+	}
+
+	return nil
+}
+
+// PurgeDeleted permanently removes products that were soft-deleted before the given time
+// and returns how many rows were removed.
+func (r *ProductRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM products WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare delete statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge products: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// versionMismatchError tells apart a missing product from a stale version after a conditional write matched no rows.
+// A product that is no longer in the expected soft-deleted state, e.g. one deleted concurrently by an update, is missing.
+func (r *ProductRepository) versionMismatchError(ctx context.Context, id uuid.UUID, deleted bool) error {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1 AND (deleted_at IS NOT NULL) = $2)`
+	err := r.getExecutor().QueryRowContext(ctx, query, id, deleted).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check product existence: %w", err)
+	}
+
+	if !exists {
+		return &repository.NotFoundError{Resource: "product"}
+	}
+
+	return repository.ErrVersionConflict
+}
+
+// DeleteByID permanently deletes a product by ID.
 func (r *ProductRepository) DeleteByID(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM products WHERE id = $1`
 
@@ -196,8 +618,13 @@ func (r *ProductRepository) DeleteByID(ctx context.Context, id uuid.UUID) error
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("product not found")
+		return &repository.NotFoundError{Resource: "product"}
 	}
 
 	return nil
 }
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
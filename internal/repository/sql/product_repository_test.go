@@ -3,6 +3,7 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -26,12 +27,12 @@ func TestProductRepository_Create(t *testing.T) {
 		product := &model.Product{
 			Name:        "Test Product",
 			Description: "Test Description",
-			Price:       99.99,
+			Price:       model.Money{Amount: 9999, Currency: "USD"},
 		}
 
 		mock.ExpectPrepare("INSERT INTO products").
 			ExpectExec().
-			WithArgs(sqlmock.AnyArg(), product.Name, product.Description, product.Price, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WithArgs(sqlmock.AnyArg(), product.Name, product.Description, product.Price.Decimal(), product.Price.Currency, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		result, err := repo.Create(ctx, product)
@@ -60,10 +61,10 @@ func TestProductRepository_FindByID(t *testing.T) {
 		id := uuid.New()
 
 		now := time.Now()
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at"}).
-			AddRow(id, "Test Product", "Test Description", 99.99, now, now)
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(id, "Test Product", "Test Description", 99.99, now, now, int64(1), nil, "USD", nil)
 
-		mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1").
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1 AND deleted_at IS NULL").
 			ExpectQuery().
 			WithArgs(id).
 			WillReturnRows(rows)
@@ -75,7 +76,7 @@ func TestProductRepository_FindByID(t *testing.T) {
 		foundProduct := result.(*model.Product)
 		assert.Equal(t, id, foundProduct.ID)
 		assert.Equal(t, "Test Product", foundProduct.Name)
-		assert.Equal(t, 99.99, foundProduct.Price)
+		assert.Equal(t, model.Money{Amount: 9999, Currency: "USD"}, foundProduct.Price)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -83,7 +84,7 @@ func TestProductRepository_FindByID(t *testing.T) {
 	t.Run("product not found", func(t *testing.T) {
 		id := uuid.New()
 
-		mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1").
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1 AND deleted_at IS NULL").
 			ExpectQuery().
 			WithArgs(id).
 			WillReturnError(sql.ErrNoRows)
@@ -93,6 +94,28 @@ func TestProductRepository_FindByID(t *testing.T) {
 		assert.Nil(t, result)
 		assert.Contains(t, err.Error(), "product not found")
 
+		var notFoundErr *repository.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database failure", func(t *testing.T) {
+		id := uuid.New()
+
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1 AND deleted_at IS NULL").
+			ExpectQuery().
+			WithArgs(id).
+			WillReturnError(sql.ErrConnDone)
+
+		result, err := repo.FindByID(ctx, id)
+		require.Error(t, err)
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+
+		var notFoundErr *repository.NotFoundError
+		assert.False(t, errors.As(err, &notFoundErr))
+
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -113,13 +136,13 @@ func TestProductRepository_List(t *testing.T) {
 		id1 := uuid.New()
 		id2 := uuid.New()
 
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at"}).
-			AddRow(id1, "Product 1", "Description 1", 99.99, now, now).
-			AddRow(id2, "Product 2", "Description 2", 149.99, now, now)
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(id1, "Product 1", "Description 1", 99.99, now, now, int64(1), nil, "USD", nil).
+			AddRow(id2, "Product 2", "Description 2", 149.99, now, now, int64(1), nil, "USD", nil)
 
-		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 ORDER BY created_at DESC, id DESC LIMIT").
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL ORDER BY created_at DESC, id DESC LIMIT").
 			ExpectQuery().
-			WithArgs(10).
+			WithArgs(11).
 			WillReturnRows(rows)
 
 		result, err := repo.List(ctx, *query)
@@ -129,6 +152,251 @@ func TestProductRepository_List(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("list including deleted", func(t *testing.T) {
+		query := repository.NewQuery().With(repository.IncludeDeletedField, "true")
+		query.Limit = 10
+
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(uuid.New(), "Product 1", "Description 1", 99.99, now, now, int64(1), nil, "USD", nil).
+			AddRow(uuid.New(), "Product 2", "Description 2", 149.99, now, now, int64(2), now, "USD", nil)
+
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 ORDER BY created_at DESC, id DESC LIMIT").
+			ExpectQuery().
+			WithArgs(11).
+			WillReturnRows(rows)
+
+		result, err := repo.List(ctx, *query)
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		assert.NotNil(t, result[1].(*model.Product).DeletedAt)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list with filters", func(t *testing.T) {
+		createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		createdBefore := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		query := repository.NewQuery().
+			With(repository.NamePrefixField, "lap").
+			With(repository.NameContainsField, "50%_off").
+			With(repository.CurrencyField, "USD").
+			With(repository.MinPriceField, "10").
+			With(repository.MaxPriceField, "99.5").
+			With(repository.CreatedAfterField, createdAfter.Format(time.RFC3339Nano)).
+			With(repository.CreatedBeforeField, createdBefore.Format(time.RFC3339Nano))
+		query.Limit = 10
+		lastCreatedAt := createdBefore.Add(-1 * time.Hour)
+		lastID := uuid.New()
+		query.Paginator = &repository.Paginator{LastID: lastID, LastCreatedAt: lastCreatedAt}
+
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(uuid.New(), "Laptop 50%_off", "", 49.99, createdAfter, createdAfter, int64(1), nil, "USD", nil)
+
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL"+
+			" AND name ILIKE \\$1 ESCAPE '\\\\' AND name ILIKE \\$2 ESCAPE '\\\\'"+
+			" AND currency = \\$3 AND price >= \\$4::numeric AND price <= \\$5::numeric AND created_at >= \\$6 AND created_at < \\$7"+
+			" AND \\(created_at, id\\) < \\(\\$8, \\$9\\) ORDER BY created_at DESC, id DESC LIMIT \\$10").
+			ExpectQuery().
+			WithArgs("lap%", `%50\%\_off%`, "USD", "10.00", "99.50", createdAfter, createdBefore, lastCreatedAt, lastID, 11).
+			WillReturnRows(rows)
+
+		result, err := repo.List(ctx, *query)
+		require.NoError(t, err)
+		assert.Len(t, result, 1)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list by category subtree and tag", func(t *testing.T) {
+		categoryID := uuid.New()
+		query := repository.NewQuery().
+			With(repository.CategoryIDField, categoryID.String()).
+			With(repository.TagField, "sale")
+		query.Limit = 10
+
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(uuid.New(), "Product 1", "", 9.99, now, now, int64(1), nil, "USD", categoryID)
+
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL"+
+			" AND category_id IN \\(WITH RECURSIVE subtree AS \\(.*WHERE id = \\$1.*\\) SELECT id FROM subtree\\)"+
+			" AND id IN \\(SELECT product_id FROM product_tags WHERE tag = \\$2\\) ORDER BY created_at DESC, id DESC LIMIT \\$3").
+			ExpectQuery().
+			WithArgs(categoryID, "sale", 11).
+			WillReturnRows(rows)
+
+		result, err := repo.List(ctx, *query)
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Equal(t, &categoryID, result[0].(*model.Product).CategoryID)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list with invalid category filter", func(t *testing.T) {
+		query := repository.NewQuery().With(repository.CategoryIDField, "books")
+
+		result, err := repo.List(ctx, *query)
+		require.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "invalid category filter")
+	})
+
+	t.Run("list sorted by price ascending with pagination", func(t *testing.T) {
+		query := repository.NewQuery().With(repository.CurrencyField, "USD")
+		query.Limit = 10
+		query.Sort = repository.Sort{Field: repository.PriceField, Direction: repository.SortAsc}
+		lastID := uuid.New()
+		query.Paginator = &repository.Paginator{LastID: lastID, Sort: query.Sort, LastValue: "19.99"}
+
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(uuid.New(), "Product 1", "Description 1", 29.99, now, now, int64(1), nil, "USD", nil)
+
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL AND currency = \\$1 AND \\(price, id\\) > \\(\\$2, \\$3\\) ORDER BY price ASC, id ASC LIMIT \\$4").
+			ExpectQuery().
+			WithArgs("USD", "19.99", lastID, 11).
+			WillReturnRows(rows)
+
+		result, err := repo.List(ctx, *query)
+		require.NoError(t, err)
+		assert.Len(t, result, 1)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list sorted by name descending", func(t *testing.T) {
+		query := repository.NewQuery()
+		query.Sort = repository.Sort{Field: repository.NameField, Direction: repository.SortDesc}
+
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL ORDER BY name DESC, id DESC LIMIT \\$1").
+			ExpectQuery().
+			WithArgs(repository.DefaultPaginationLimit + 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}))
+
+		result, err := repo.List(ctx, *query)
+		require.NoError(t, err)
+		assert.Empty(t, result)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list previous page", func(t *testing.T) {
+		query := repository.NewQuery()
+		query.Limit = 2
+		cursorCreatedAt := time.Now()
+		cursorID := uuid.New()
+		query.Paginator = &repository.Paginator{LastID: cursorID, LastCreatedAt: cursorCreatedAt, Backward: true}
+
+		now := time.Now()
+		id1, id2, id3 := uuid.New(), uuid.New(), uuid.New()
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(id3, "Product 3", "", 3.0, now, now, int64(1), nil, "USD", nil).
+			AddRow(id2, "Product 2", "", 2.0, now, now, int64(1), nil, "USD", nil).
+			AddRow(id1, "Product 1", "", 1.0, now, now, int64(1), nil, "USD", nil)
+
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL AND \\(created_at, id\\) > \\(\\$1, \\$2\\) ORDER BY created_at ASC, id ASC LIMIT \\$3").
+			ExpectQuery().
+			WithArgs(cursorCreatedAt, cursorID, 3).
+			WillReturnRows(rows)
+
+		page, err := repo.ListPage(ctx, *query)
+		require.NoError(t, err)
+		require.Len(t, page.Resources, 2)
+		assert.Equal(t, id2, page.Resources[0].(*model.Product).ID)
+		assert.Equal(t, id3, page.Resources[1].(*model.Product).ID)
+		assert.True(t, page.HasPrev)
+		assert.True(t, page.HasNext)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list with total count", func(t *testing.T) {
+		query := repository.NewQuery().With(repository.CurrencyField, "USD").With(repository.MinPriceField, "5")
+		query.Limit = 1
+		query.IncludeTotal = true
+
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(uuid.New(), "Product 1", "", 10.0, now, now, int64(1), nil, "USD", nil).
+			AddRow(uuid.New(), "Product 2", "", 20.0, now, now, int64(1), nil, "USD", nil)
+
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL AND currency = \\$1 AND price >= \\$2::numeric ORDER BY created_at DESC, id DESC LIMIT \\$3").
+			ExpectQuery().
+			WithArgs("USD", "5.00", 2).
+			WillReturnRows(rows)
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM products WHERE 1=1 AND deleted_at IS NULL AND currency = \\$1 AND price >= \\$2::numeric$").
+			WithArgs("USD", "5.00").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(7)))
+
+		page, err := repo.ListPage(ctx, *query)
+		require.NoError(t, err)
+		assert.Len(t, page.Resources, 1)
+		assert.True(t, page.HasNext)
+		assert.False(t, page.HasPrev)
+		require.NotNil(t, page.TotalCount)
+		assert.Equal(t, int64(7), *page.TotalCount)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list with invalid price filter", func(t *testing.T) {
+		query := repository.NewQuery().With(repository.CurrencyField, "USD").With(repository.MinPriceField, "cheap")
+
+		result, err := repo.List(ctx, *query)
+		require.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "invalid min price filter")
+	})
+
+	t.Run("list by price without currency", func(t *testing.T) {
+		_, err := repo.List(ctx, *repository.NewQuery().With(repository.MaxPriceField, "10"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "require a currency filter")
+
+		query := repository.NewQuery()
+		query.Sort = repository.Sort{Field: repository.PriceField, Direction: repository.SortDesc}
+		_, err = repo.List(ctx, *query)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "require a currency filter")
+	})
+
+	t.Run("list with price filter in another currency", func(t *testing.T) {
+		query := repository.NewQuery().
+			With(repository.CurrencyField, "KWD").
+			With(repository.MaxPriceField, "12345678901.123")
+		query.Limit = 10
+
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL AND currency = \\$1 AND price <= \\$2::numeric ORDER BY created_at DESC, id DESC LIMIT \\$3").
+			ExpectQuery().
+			WithArgs("KWD", "12345678901.123", 11).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}))
+
+		result, err := repo.List(ctx, *query)
+		require.NoError(t, err)
+		assert.Empty(t, result)
+
+		// Amounts are checked against the minor units of the currency
+		_, err = repo.List(ctx, *repository.NewQuery().With(repository.CurrencyField, "JPY").With(repository.MinPriceField, "1.5"))
+		assert.ErrorIs(t, err, model.ErrAmountPrecision)
+
+		_, err = repo.List(ctx, *repository.NewQuery().With(repository.CurrencyField, "XYZ"))
+		assert.ErrorIs(t, err, model.ErrUnsupportedCurrency)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list with invalid price page token", func(t *testing.T) {
+		query := repository.NewQuery().With(repository.CurrencyField, "USD")
+		query.Sort = repository.Sort{Field: repository.PriceField, Direction: repository.SortAsc}
+		query.Paginator = &repository.Paginator{LastID: uuid.New(), Sort: query.Sort, LastValue: "1e3"}
+
+		_, err := repo.List(ctx, *query)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid page token price")
+	})
+
 	t.Run("list with pagination", func(t *testing.T) {
 		query := repository.NewQuery()
 		query.Limit = 10
@@ -142,12 +410,12 @@ func TestProductRepository_List(t *testing.T) {
 		now := time.Now()
 		id := uuid.New()
 
-		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at"}).
-			AddRow(id, "Product 1", "Description 1", 99.99, now, now)
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(id, "Product 1", "Description 1", 99.99, now, now, int64(1), nil, "USD", nil)
 
-		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND \\(created_at, id\\) < \\(\\$1, \\$2\\) ORDER BY created_at DESC, id DESC LIMIT").
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL AND \\(created_at, id\\) < \\(\\$1, \\$2\\) ORDER BY created_at DESC, id DESC LIMIT").
 			ExpectQuery().
-			WithArgs(lastCreatedAt, lastID, 10).
+			WithArgs(lastCreatedAt, lastID, 11).
 			WillReturnRows(rows)
 
 		result, err := repo.List(ctx, *query)
@@ -158,6 +426,275 @@ func TestProductRepository_List(t *testing.T) {
 	})
 }
 
+func TestProductRepository_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	t.Run("successful update", func(t *testing.T) {
+		updatedAt := time.Now().Add(-1 * time.Hour)
+		product := &model.Product{
+			ID:          uuid.New(),
+			Name:        "Updated Product",
+			Description: "Updated Description",
+			Price:       model.Money{Amount: 4999, Currency: "USD"},
+			UpdatedAt:   updatedAt,
+			Version:     3,
+		}
+
+		mock.ExpectPrepare("UPDATE products SET name = \\$1, description = \\$2, price = \\$3, currency = \\$4, category_id = \\$5, updated_at = \\$6,\\s+version = version \\+ 1\\s+WHERE id = \\$7 AND version = \\$8 AND deleted_at IS NULL").
+			ExpectExec().
+			WithArgs(product.Name, product.Description, product.Price.Decimal(), product.Price.Currency, nil, sqlmock.AnyArg(), product.ID, int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		result, err := repo.Update(ctx, product)
+		require.NoError(t, err)
+
+		updatedProduct := result.(*model.Product)
+		assert.True(t, updatedProduct.UpdatedAt.After(updatedAt))
+		assert.Equal(t, int64(4), updatedProduct.Version)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("product not found", func(t *testing.T) {
+		product := &model.Product{ID: uuid.New(), Name: "Missing", Price: model.Money{Amount: 100, Currency: "USD"}, Version: 1}
+
+		mock.ExpectPrepare("UPDATE products").
+			ExpectExec().
+			WithArgs(product.Name, product.Description, product.Price.Decimal(), product.Price.Currency, nil, sqlmock.AnyArg(), product.ID, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(product.ID, false).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		result, err := repo.Update(ctx, product)
+		require.Error(t, err)
+		assert.Nil(t, result)
+
+		var notFoundErr *repository.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		product := &model.Product{ID: uuid.New(), Name: "Stale", Price: model.Money{Amount: 100, Currency: "USD"}, Version: 2}
+
+		mock.ExpectPrepare("UPDATE products").
+			ExpectExec().
+			WithArgs(product.Name, product.Description, product.Price.Decimal(), product.Price.Currency, nil, sqlmock.AnyArg(), product.ID, int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(product.ID, false).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		result, err := repo.Update(ctx, product)
+		require.Error(t, err)
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+		assert.Equal(t, int64(2), product.Version)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProductRepository_SoftDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	t.Run("successful soft delete", func(t *testing.T) {
+		product := &model.Product{ID: uuid.New(), Version: 5}
+
+		mock.ExpectPrepare("UPDATE products SET deleted_at = \\$1, updated_at = \\$1, version = version \\+ 1\\s+WHERE id = \\$2 AND version = \\$3 AND deleted_at IS NULL").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), product.ID, int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.SoftDelete(ctx, product)
+		require.NoError(t, err)
+		assert.NotNil(t, product.DeletedAt)
+		assert.Equal(t, int64(6), product.Version)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		product := &model.Product{ID: uuid.New(), Version: 4}
+
+		mock.ExpectPrepare("UPDATE products SET deleted_at").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), product.ID, int64(4)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(product.ID, false).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		err := repo.SoftDelete(ctx, product)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+		assert.Nil(t, product.DeletedAt)
+		assert.Equal(t, int64(4), product.Version)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deleted concurrently", func(t *testing.T) {
+		product := &model.Product{ID: uuid.New(), Version: 4}
+
+		mock.ExpectPrepare("UPDATE products SET deleted_at").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), product.ID, int64(4)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		// The row still exists, but only as a soft-deleted product
+		mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM products WHERE id = \\$1 AND \\(deleted_at IS NOT NULL\\) = \\$2\\)").
+			WithArgs(product.ID, false).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err := repo.SoftDelete(ctx, product)
+		var notFoundErr *repository.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+		assert.NotErrorIs(t, err, repository.ErrVersionConflict)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProductRepository_CreateBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	products := []*model.Product{
+		{Name: "Keyboard", Price: model.Money{Amount: 4999, Currency: "USD"}},
+		{Name: "Mouse", Description: "Wireless", Price: model.Money{Amount: 1999, Currency: "USD"}},
+	}
+
+	mock.ExpectPrepare("INSERT INTO products \\(id, name, description, price, currency, category_id, created_at, updated_at, version\\)\\s+"+
+		"VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9\\), \\(\\$10, \\$11, \\$12, \\$13, \\$14, \\$15, \\$16, \\$17, \\$18\\)").
+		ExpectExec().
+		WithArgs(
+			sqlmock.AnyArg(), "Keyboard", "", "49.99", "USD", nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1),
+			sqlmock.AnyArg(), "Mouse", "Wireless", "19.99", "USD", nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1),
+		).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = repo.CreateBatch(ctx, products)
+	require.NoError(t, err)
+	for _, product := range products {
+		assert.NotEqual(t, uuid.Nil, product.ID)
+		assert.Equal(t, int64(1), product.Version)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_SoftDeleteBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	existing, missing := uuid.New(), uuid.New()
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+		AddRow(existing, "Keyboard", "", 49.99, now, now, int64(2), now, "USD", nil)
+
+	mock.ExpectPrepare("UPDATE products SET deleted_at = \\$1, updated_at = \\$1, version = version \\+ 1\\s+"+
+		"WHERE deleted_at IS NULL AND id IN \\(\\$2, \\$3\\)\\s+RETURNING \\*").
+		ExpectQuery().
+		WithArgs(sqlmock.AnyArg(), existing, missing).
+		WillReturnRows(rows)
+
+	deleted, err := repo.SoftDeleteBatch(ctx, []uuid.UUID{existing, missing})
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, existing, deleted[0].ID)
+	assert.Equal(t, int64(2), deleted[0].Version)
+	assert.NotNil(t, deleted[0].DeletedAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepository_Restore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+
+	t.Run("successful restore", func(t *testing.T) {
+		deletedAt := time.Now().Add(-1 * time.Hour)
+		product := &model.Product{ID: uuid.New(), Version: 2, DeletedAt: &deletedAt}
+
+		mock.ExpectPrepare("UPDATE products SET deleted_at = NULL, updated_at = \\$1, version = version \\+ 1\\s+WHERE id = \\$2 AND version = \\$3 AND deleted_at IS NOT NULL").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), product.ID, int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Restore(ctx, product)
+		require.NoError(t, err)
+		assert.Nil(t, product.DeletedAt)
+		assert.Equal(t, int64(3), product.Version)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("product not found", func(t *testing.T) {
+		product := &model.Product{ID: uuid.New(), Version: 1}
+
+		mock.ExpectPrepare("UPDATE products SET deleted_at = NULL").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), product.ID, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(product.ID, true).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err := repo.Restore(ctx, product)
+		require.Error(t, err)
+
+		var notFoundErr *repository.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProductRepository_PurgeDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProductRepository(db)
+	ctx := context.Background()
+	before := time.Now().Add(-24 * time.Hour)
+
+	mock.ExpectPrepare("DELETE FROM products WHERE deleted_at IS NOT NULL AND deleted_at < \\$1").
+		ExpectExec().
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	purged, err := repo.PurgeDeleted(ctx, before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestProductRepository_DeleteByID(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -192,6 +729,9 @@ func TestProductRepository_DeleteByID(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "product not found")
 
+		var notFoundErr *repository.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -23,7 +23,7 @@ func TestProductRepository_WithinTransaction_Commit(t *testing.T) {
 	product := &model.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       99.99,
+		Price:       model.Money{Amount: 9999, Currency: "USD"},
 	}
 
 	// Expect transaction begin
@@ -32,7 +32,7 @@ func TestProductRepository_WithinTransaction_Commit(t *testing.T) {
 	// Expect insert within transaction
 	mock.ExpectPrepare("INSERT INTO products").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), product.Name, product.Description, product.Price, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), product.Name, product.Description, product.Price.Decimal(), product.Price.Currency, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Expect transaction commit
@@ -59,7 +59,7 @@ func TestProductRepository_WithinTransaction_Rollback(t *testing.T) {
 	product := &model.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       99.99,
+		Price:       model.Money{Amount: 9999, Currency: "USD"},
 	}
 
 	// Expect transaction begin
@@ -68,7 +68,7 @@ func TestProductRepository_WithinTransaction_Rollback(t *testing.T) {
 	// Expect insert within transaction to fail
 	mock.ExpectPrepare("INSERT INTO products").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), product.Name, product.Description, product.Price, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), product.Name, product.Description, product.Price.Decimal(), product.Price.Currency, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
 		WillReturnError(sql.ErrConnDone)
 
 	// Expect transaction rollback due to error
@@ -96,13 +96,13 @@ func TestProductRepository_WithinTransaction_MultipleOperations(t *testing.T) {
 	product1 := &model.Product{
 		Name:        "Product 1",
 		Description: "Description 1",
-		Price:       99.99,
+		Price:       model.Money{Amount: 9999, Currency: "USD"},
 	}
 
 	product2 := &model.Product{
 		Name:        "Product 2",
 		Description: "Description 2",
-		Price:       149.99,
+		Price:       model.Money{Amount: 14999, Currency: "USD"},
 	}
 
 	// Expect transaction begin
@@ -111,13 +111,13 @@ func TestProductRepository_WithinTransaction_MultipleOperations(t *testing.T) {
 	// Expect first insert
 	mock.ExpectPrepare("INSERT INTO products").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), product1.Name, product1.Description, product1.Price, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), product1.Name, product1.Description, product1.Price.Decimal(), product1.Price.Currency, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Expect second insert
 	mock.ExpectPrepare("INSERT INTO products").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), product2.Name, product2.Description, product2.Price, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), product2.Name, product2.Description, product2.Price.Decimal(), product2.Price.Currency, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
 		WillReturnResult(sqlmock.NewResult(2, 1))
 
 	// Expect transaction commit
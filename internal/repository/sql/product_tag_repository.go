@@ -0,0 +1,126 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+)
+
+// ProductTagRepository stores the tags of products in the product_tags table.
+type ProductTagRepository struct {
+	db  *sql.DB
+	txn *sql.Tx
+}
+
+// NewProductTagRepository creates a new ProductTagRepository instance.
+func NewProductTagRepository(db *sql.DB) *ProductTagRepository {
+	return &ProductTagRepository{db: db}
+}
+
+// NewProductTagRepositoryWithTx creates a new ProductTagRepository instance with an existing transaction.
+func NewProductTagRepositoryWithTx(db *sql.DB, tx *sql.Tx) *ProductTagRepository {
+	return &ProductTagRepository{db: db, txn: tx}
+}
+
+// getExecutor returns the active executor (transaction if exists, otherwise db).
+func (r *ProductTagRepository) getExecutor() dbExecutor {
+	if r.txn != nil {
+		return r.txn
+	}
+	return r.db
+}
+
+// CreateBatch inserts the tags of all given products with a single multi-row INSERT.
+func (r *ProductTagRepository) CreateBatch(ctx context.Context, products []*model.Product) error {
+	const columns = 2
+	var args []interface{}
+	for _, product := range products {
+		for _, tag := range product.Tags {
+			args = append(args, product.ID, tag)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO product_tags (product_id, tag) VALUES ` + valuesPlaceholders(len(args)/columns, columns)
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, args...); err != nil {
+		return fmt.Errorf("failed to insert product tags: %w", err)
+	}
+
+	return nil
+}
+
+// Replace replaces all tags of the product with product.Tags.
+func (r *ProductTagRepository) Replace(ctx context.Context, product *model.Product) error {
+	query := `DELETE FROM product_tags WHERE product_id = $1`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare delete statement: %w", err)
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, product.ID); err != nil {
+		return fmt.Errorf("failed to delete product tags: %w", err)
+	}
+
+	return r.CreateBatch(ctx, []*model.Product{product})
+}
+
+// ListByProductIDs returns the tags of the given products in alphabetical order, keyed by product ID.
+// Products without tags are left out.
+func (r *ProductTagRepository) ListByProductIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]string, error) {
+	tags := make(map[uuid.UUID][]string, len(ids))
+	if len(ids) == 0 {
+		return tags, nil
+	}
+
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	query := `SELECT product_id, tag FROM product_tags WHERE product_id IN ` + placeholderList(1, len(ids)) + `
+	          ORDER BY product_id, tag`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID uuid.UUID
+		var tag string
+		if err := rows.Scan(&productID, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan product tag: %w", err)
+		}
+		tags[productID] = append(tags[productID], tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,83 @@
+package sql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductTagRepository_CreateBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProductTagRepository(db)
+	ctx := context.Background()
+
+	t.Run("inserts the tags of all products", func(t *testing.T) {
+		first := &model.Product{ID: uuid.New(), Tags: []string{"new", "sale"}}
+		second := &model.Product{ID: uuid.New(), Tags: []string{"sale"}}
+
+		mock.ExpectPrepare("INSERT INTO product_tags \\(product_id, tag\\) VALUES \\(\\$1, \\$2\\), \\(\\$3, \\$4\\), \\(\\$5, \\$6\\)").
+			ExpectExec().
+			WithArgs(first.ID, "new", first.ID, "sale", second.ID, "sale").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		require.NoError(t, repo.CreateBatch(ctx, []*model.Product{first, second}))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("products without tags", func(t *testing.T) {
+		require.NoError(t, repo.CreateBatch(ctx, []*model.Product{{ID: uuid.New()}}))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProductTagRepository_Replace(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProductTagRepository(db)
+	product := &model.Product{ID: uuid.New(), Tags: []string{"sale"}}
+
+	mock.ExpectPrepare("DELETE FROM product_tags WHERE product_id = \\$1").
+		ExpectExec().
+		WithArgs(product.ID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare("INSERT INTO product_tags").
+		ExpectExec().
+		WithArgs(product.ID, "sale").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.Replace(context.Background(), product))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductTagRepository_ListByProductIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewProductTagRepository(db)
+	tagged, untagged := uuid.New(), uuid.New()
+
+	mock.ExpectPrepare("SELECT product_id, tag FROM product_tags WHERE product_id IN \\(\\$1, \\$2\\) ORDER BY product_id, tag").
+		ExpectQuery().
+		WithArgs(tagged, untagged).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "tag"}).AddRow(tagged, "new").AddRow(tagged, "sale"))
+
+	tags, err := repo.ListByProductIDs(context.Background(), []uuid.UUID{tagged, untagged})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID][]string{tagged: {"new", "sale"}}, tags)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -6,11 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iyhunko/microservices-with-sqs/internal/model"
 	"github.com/iyhunko/microservices-with-sqs/internal/repository"
-	"github.com/lib/pq"
 )
 
 // UserRepository implements the Repository interface for User entities.
@@ -20,10 +20,15 @@ type UserRepository struct {
 }
 
 // NewUserRepository creates a new UserRepository instance.
-func NewUserRepository(db *sql.DB) repository.Repository {
+func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// NewUserRepositoryWithTx creates a new UserRepository instance with an existing transaction.
+func NewUserRepositoryWithTx(db *sql.DB, tx *sql.Tx) *UserRepository {
+	return &UserRepository{db: db, txn: tx}
+}
+
 // getExecutor returns the active executor (transaction if exists, otherwise db).
 func (r *UserRepository) getExecutor() dbExecutor {
 	if r.txn != nil {
@@ -63,6 +68,7 @@ func (r *UserRepository) WithinTransaction(ctx context.Context, fn func(repo rep
 }
 
 // Create inserts a new user into the database.
+// A user whose email is already taken is reported as a *repository.UniqueConstraintError.
 func (r *UserRepository) Create(ctx context.Context, resource repository.Resource) (repository.Resource, error) {
 	user, ok := resource.(*model.User)
 	if !ok {
@@ -83,9 +89,8 @@ func (r *UserRepository) Create(ctx context.Context, resource repository.Resourc
 
 	_, err = stmt.ExecContext(ctx, user.ID, user.Email, user.Password, user.Name, user.Region, user.Status, user.Role, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolationErrCode {
-			return nil, &repository.UniqueConstraintError{Detail: pqErr.Detail}
+		if uniqueErr := uniqueViolation(err); uniqueErr != nil {
+			return nil, uniqueErr
 		}
 		return nil, fmt.Errorf("failed to insert user: %w", err)
 	}
@@ -95,51 +100,45 @@ func (r *UserRepository) Create(ctx context.Context, resource repository.Resourc
 
 // List retrieves users from the database based on the provided query.
 func (r *UserRepository) List(ctx context.Context, query repository.Query) ([]repository.Resource, error) {
+	page, err := r.ListPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return page.Resources, nil
+}
+
+// ListPage retrieves one page of users from the database based on the provided query.
+func (r *UserRepository) ListPage(ctx context.Context, query repository.Query) (*repository.Page, error) {
+	if query.SortOrder().Field != repository.CreatedAtField {
+		return nil, fmt.Errorf("unsupported sort field %q: %w", query.SortOrder().Field, repository.ErrInvalidSort)
+	}
+
+	filters, args, err := userFilters(query)
+	if err != nil {
+		return nil, err
+	}
+	filterArgs := len(args)
+	argIndex := filterArgs + 1
+
 	var queryBuilder strings.Builder
 	queryBuilder.WriteString("SELECT * FROM users WHERE 1=1")
+	queryBuilder.WriteString(filters)
 
-	var args []interface{}
-	argIndex := 1
-
-	// Apply query filters
-	for field, value := range query.Values {
-		switch field {
-		case repository.IDField:
-			queryBuilder.WriteString(fmt.Sprintf(" AND id = $%d", argIndex))
-			id, err := uuid.Parse(value)
-			if err != nil {
-				return nil, fmt.Errorf("invalid ID format: %w", err)
-			}
-			args = append(args, id)
-			argIndex++
-		case repository.NameField:
-			queryBuilder.WriteString(fmt.Sprintf(" AND name = $%d", argIndex))
-			args = append(args, value)
-			argIndex++
-		case repository.Region:
-			queryBuilder.WriteString(fmt.Sprintf(" AND region = $%d", argIndex))
-			args = append(args, value)
-			argIndex++
-		}
-	}
+	comparison, direction := query.Keyset()
 
-	// Apply pagination
+	// Apply pagination: continue from the cursor (created_at, id) pair in the fetch direction
 	if query.Paginator != nil {
-		queryBuilder.WriteString(fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", argIndex, argIndex+1))
+		queryBuilder.WriteString(fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", comparison, argIndex, argIndex+1))
 		args = append(args, query.Paginator.LastCreatedAt, query.Paginator.LastID)
 		argIndex += 2
 	}
 
-	// Order by created_at DESC, id DESC for consistent pagination
-	queryBuilder.WriteString(" ORDER BY created_at DESC, id DESC")
+	// Order by created_at with id as a tie-breaker for consistent pagination
+	queryBuilder.WriteString(fmt.Sprintf(" ORDER BY created_at %s, id %s", direction, direction))
 
 	// Apply limit
-	limit := query.Limit
-	if limit <= 0 {
-		limit = repository.DefaultPaginationLimit
-	}
 	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", argIndex))
-	args = append(args, limit)
+	args = append(args, query.FetchLimit())
 
 	executor := r.getExecutor()
 	stmt, err := executor.PrepareContext(ctx, queryBuilder.String())
@@ -156,19 +155,27 @@ func (r *UserRepository) List(ctx context.Context, query repository.Query) ([]re
 
 	var users []repository.Resource
 	for rows.Next() {
-		var user model.User
-		err := rows.Scan(&user.ID, &user.Email, &user.Password, &user.Name, &user.Region, &user.Status, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
-		users = append(users, &user)
+		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return users, nil
+	page := repository.NewPage(query, users)
+	if query.IncludeTotal {
+		total, err := countRows(ctx, r.getExecutor(), "SELECT COUNT(*) FROM users WHERE 1=1"+filters, args[:filterArgs]...)
+		if err != nil {
+			return nil, err
+		}
+		page.TotalCount = &total
+	}
+
+	return page, nil
 }
 
 // FindByID retrieves a single user by ID.
@@ -182,19 +189,80 @@ func (r *UserRepository) FindByID(ctx context.Context, id uuid.UUID) (repository
 	}
 	defer stmt.Close()
 
-	var result model.User
-	err = stmt.QueryRowContext(ctx, id).Scan(
-		&result.ID, &result.Email, &result.Password, &result.Name, &result.Region,
-		&result.Status, &result.Role, &result.CreatedAt, &result.UpdatedAt,
-	)
+	result, err := scanUser(stmt.QueryRowContext(ctx, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &repository.NotFoundError{Resource: "user"}
+		}
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	return result, nil
+}
+
+// FindByEmail retrieves a single user by email address.
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	query := `SELECT * FROM users WHERE email = $1`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := scanUser(stmt.QueryRowContext(ctx, email))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("user not found: %w", err)
+			return nil, &repository.NotFoundError{Resource: "user"}
 		}
 		return nil, fmt.Errorf("failed to query user: %w", err)
 	}
 
-	return &result, nil
+	return result, nil
+}
+
+// Update updates the name, region, status and role of a user.
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	updatedAt := time.Now()
+
+	query := `UPDATE users SET name = $1, region = $2, status = $3, role = $4, updated_at = $5 WHERE id = $6`
+
+	executor := r.getExecutor()
+	stmt, err := executor.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update statement: %w", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, user.Name, user.Region, user.Status, user.Role, updatedAt, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return &repository.NotFoundError{Resource: "user"}
+	}
+
+	user.UpdatedAt = updatedAt
+
+	return nil
+}
+
+// scanUser scans a users row in table column order.
+func scanUser(row rowScanner) (*model.User, error) {
+	var user model.User
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Name, &user.Region,
+		&user.Status, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
 // DeleteByID deletes a user by ID.
@@ -219,8 +287,35 @@ func (r *UserRepository) DeleteByID(ctx context.Context, id uuid.UUID) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return &repository.NotFoundError{Resource: "user"}
 	}
 
 	return nil
 }
+
+// userFilters builds the WHERE conditions for the query filters, to be appended to "WHERE 1=1",
+// together with their arguments numbered from $1.
+func userFilters(query repository.Query) (string, []interface{}, error) {
+	var filters strings.Builder
+	var args []interface{}
+
+	// Apply query filters in a fixed order so equal filters always produce the same statement
+	if value, ok := query.Values[repository.IDField]; ok {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid ID format: %w", err)
+		}
+		filters.WriteString(fmt.Sprintf(" AND id = $%d", len(args)+1))
+		args = append(args, id)
+	}
+	if name, ok := query.Values[repository.NameField]; ok {
+		filters.WriteString(fmt.Sprintf(" AND name = $%d", len(args)+1))
+		args = append(args, name)
+	}
+	if region, ok := query.Values[repository.Region]; ok {
+		filters.WriteString(fmt.Sprintf(" AND region = $%d", len(args)+1))
+		args = append(args, region)
+	}
+
+	return filters.String(), args, nil
+}
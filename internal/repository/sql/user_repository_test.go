@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/iyhunko/microservices-with-sqs/internal/model"
 	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -78,6 +79,20 @@ func TestUserRepository_Create(t *testing.T) {
 		var uniqueErr *repository.UniqueConstraintError
 		assert.ErrorAs(t, err, &uniqueErr)
 
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("unique constraint violation reported by pgx", func(t *testing.T) {
+		user := &model.User{Email: "duplicate@example.com", Password: "hash", Name: "Test User", Status: "active", Role: "user"}
+
+		mock.ExpectPrepare("INSERT INTO users").
+			ExpectExec().
+			WillReturnError(&pgconn.PgError{Code: pqUniqueViolationErrCode, Detail: "Key (email)=(duplicate@example.com) already exists."})
+
+		_, err := repo.Create(ctx, user)
+		var uniqueErr *repository.UniqueConstraintError
+		require.ErrorAs(t, err, &uniqueErr)
+		assert.Contains(t, uniqueErr.Detail, "duplicate@example.com")
+
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -131,6 +146,85 @@ func TestUserRepository_FindByID(t *testing.T) {
 	})
 }
 
+func TestUserRepository_FindByEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	t.Run("successful find", func(t *testing.T) {
+		id := uuid.New()
+
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "email", "password", "name", "region", "status", "role", "created_at", "updated_at"}).
+			AddRow(id, "test@example.com", "hash", "Test User", "US", "active", "user", now, now)
+
+		mock.ExpectPrepare("SELECT \\* FROM users WHERE email = \\$1").
+			ExpectQuery().
+			WithArgs("test@example.com").
+			WillReturnRows(rows)
+
+		user, err := repo.FindByEmail(ctx, "test@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, id, user.ID)
+		assert.Equal(t, "hash", user.Password)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mock.ExpectPrepare("SELECT \\* FROM users WHERE email = \\$1").
+			ExpectQuery().
+			WithArgs("missing@example.com").
+			WillReturnError(sql.ErrNoRows)
+
+		user, err := repo.FindByEmail(ctx, "missing@example.com")
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Nil(t, user)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserRepository_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	t.Run("successful update", func(t *testing.T) {
+		user := &model.User{ID: uuid.New(), Name: "Alice", Region: "EU", Status: model.UserStatusSuspended, Role: model.UserRoleEditor}
+
+		mock.ExpectPrepare("UPDATE users SET name = \\$1, region = \\$2, status = \\$3, role = \\$4, updated_at = \\$5 WHERE id = \\$6").
+			ExpectExec().
+			WithArgs("Alice", "EU", model.UserStatusSuspended, model.UserRoleEditor, sqlmock.AnyArg(), user.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, repo.Update(ctx, user))
+		assert.False(t, user.UpdatedAt.IsZero())
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mock.ExpectPrepare("UPDATE users SET").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Update(ctx, &model.User{ID: uuid.New()})
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Equal(t, "user", notFoundErr.Resource)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestUserRepository_List(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -153,7 +247,7 @@ func TestUserRepository_List(t *testing.T) {
 
 		mock.ExpectPrepare("SELECT \\* FROM users WHERE 1=1 ORDER BY created_at DESC, id DESC LIMIT").
 			ExpectQuery().
-			WithArgs(10).
+			WithArgs(11).
 			WillReturnRows(rows)
 
 		result, err := repo.List(ctx, *query)
@@ -175,7 +269,7 @@ func TestUserRepository_List(t *testing.T) {
 
 		mock.ExpectPrepare("SELECT \\* FROM users WHERE 1=1 AND region = \\$1 ORDER BY created_at DESC, id DESC LIMIT").
 			ExpectQuery().
-			WithArgs("US", 10).
+			WithArgs("US", 11).
 			WillReturnRows(rows)
 
 		result, err := repo.List(ctx, *query)
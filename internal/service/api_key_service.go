@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+)
+
+const (
+	// apiKeyPrefix starts every API key, so that leaked keys are easy to recognize.
+	apiKeyPrefix = "sk_"
+	// apiKeyRandomBytes is the amount of randomness in an API key.
+	apiKeyRandomBytes = 32
+	// apiKeyDisplayLength is the length of the start of a key that is stored in clear to identify it.
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+	// apiKeyResourceType is the resource type of audit log entries about API keys.
+	apiKeyResourceType = "api_key"
+)
+
+var (
+	// ErrScopeNotAllowed is returned when an API key is requested with a scope that is unknown,
+	// not granted to the role of its owner, or reserved for interactive users.
+	ErrScopeNotAllowed = errors.New("scope not allowed")
+	// ErrExpiryInPast is returned when an API key is requested with an expiry time that has already passed.
+	ErrExpiryInPast = errors.New("expiry time must be in the future")
+)
+
+// APIKeyService provides business logic for the API keys of service-to-service callers.
+type APIKeyService struct {
+	db     *sql.DB
+	repo   *reposql.APIKeyRepository
+	policy *auth.Policy
+	now    func() time.Time
+}
+
+// NewAPIKeyService creates a new APIKeyService with the given DB, API key repository and the access control policy
+// that limits the scopes of new keys.
+func NewAPIKeyService(db *sql.DB, repo *reposql.APIKeyRepository, policy *auth.Policy) *APIKeyService {
+	return &APIKeyService{
+		db:     db,
+		repo:   repo,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// CreateAPIKeyInput describes an API key to create for a user.
+type CreateAPIKeyInput struct {
+	OwnerID   uuid.UUID
+	OwnerRole string
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// apiKeySnapshot is the state of an API key recorded in the audit log. The key and its hash are never included.
+type apiKeySnapshot struct {
+	ID        uuid.UUID  `json:"id"`
+	OwnerID   uuid.UUID  `json:"owner_id"`
+	Name      string     `json:"name"`
+	KeyPrefix string     `json:"key_prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// CreateAPIKey creates an API key limited to the given scopes and returns it together with the key itself,
+// which is not stored and cannot be retrieved later. Every scope must be a permission that the policy grants
+// to the role of the owner, so that a key never allows more than its owner; keys cannot manage other keys.
+func (ks *APIKeyService) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*model.APIKey, string, error) {
+	scopes := make([]string, 0, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		if !auth.IsPermission(scope) || scope == auth.PermissionAPIKeysWrite || !ks.policy.Allows(input.OwnerRole, scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(ks.now()) {
+		return nil, "", ErrExpiryInPast
+	}
+
+	random := make([]byte, apiKeyRandomBytes)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	plaintext := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	key := &model.APIKey{
+		OwnerID:   input.OwnerID,
+		Name:      input.Name,
+		KeyPrefix: plaintext[:apiKeyDisplayLength],
+		KeyHash:   hashAPIKey(plaintext),
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	err := ks.withAudit(ctx, func(tx *sql.Tx) (string, *apiKeySnapshot, *apiKeySnapshot, error) {
+		if err := reposql.NewAPIKeyRepositoryWithTx(ks.db, tx).Create(ctx, key); err != nil {
+			return "", nil, nil, err
+		}
+		return audit.ActionCreate, nil, snapshotAPIKey(key), nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, plaintext, nil
+}
+
+// ListAPIKeys retrieves all API keys of a user, newest first.
+func (ks *APIKeyService) ListAPIKeys(ctx context.Context, ownerID uuid.UUID) ([]*model.APIKey, error) {
+	return ks.repo.ListByOwner(ctx, ownerID)
+}
+
+// RevokeAPIKey revokes an API key of the caller. Callers whose role is granted auth.PermissionUsersWrite
+// may revoke the keys of any user; keys of other users are reported as not found to everyone else.
+func (ks *APIKeyService) RevokeAPIKey(ctx context.Context, id, callerID uuid.UUID, callerRole string) error {
+	return ks.withAudit(ctx, func(tx *sql.Tx) (string, *apiKeySnapshot, *apiKeySnapshot, error) {
+		txRepo := reposql.NewAPIKeyRepositoryWithTx(ks.db, tx)
+		key, err := txRepo.FindByID(ctx, id)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		if key.OwnerID != callerID && !ks.policy.Allows(callerRole, auth.PermissionUsersWrite) {
+			return "", nil, nil, &repository.NotFoundError{Resource: "API key"}
+		}
+
+		if err := txRepo.Revoke(ctx, id, ks.now()); err != nil {
+			return "", nil, nil, err
+		}
+		return audit.ActionDelete, snapshotAPIKey(key), nil, nil
+	})
+}
+
+// VerifyAPIKey checks an API key sent by a caller and returns the claims of its owner, limited to the scopes
+// of the key. Unknown and revoked keys are reported as auth.ErrInvalidToken, expired keys as auth.ErrTokenExpired.
+func (ks *APIKeyService) VerifyAPIKey(ctx context.Context, plaintext string) (*auth.Claims, error) {
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) {
+		return nil, auth.ErrInvalidToken
+	}
+
+	key, err := ks.repo.FindByHash(ctx, hashAPIKey(plaintext))
+	var notFoundErr *repository.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return nil, auth.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := ks.now()
+	if key.RevokedAt != nil {
+		return nil, auth.ErrInvalidToken
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, auth.ErrTokenExpired
+	}
+
+	// The last use is informational, so failing to record it does not fail the request
+	if err := ks.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+		slog.Warn("failed to record API key use", slog.String("api_key_id", key.ID.String()), slog.Any("err", err))
+	}
+
+	claims := &auth.Claims{
+		Subject:  key.OwnerID.String(),
+		IssuedAt: key.CreatedAt.Unix(),
+		APIKeyID: key.ID.String(),
+		Scopes:   key.Scopes,
+	}
+	if key.ExpiresAt != nil {
+		claims.ExpiresAt = key.ExpiresAt.Unix()
+	}
+	return claims, nil
+}
+
+// withAudit runs change in a transaction together with the audit log entry of the changed API key.
+func (ks *APIKeyService) withAudit(ctx context.Context,
+	change func(tx *sql.Tx) (action string, before, after *apiKeySnapshot, err error),
+) (err error) {
+	// Start a transaction
+	tx, err := ks.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	action, before, after, err := change(tx)
+	if err != nil {
+		return err
+	}
+
+	var resourceID uuid.UUID
+	var encodedBefore, encodedAfter json.RawMessage
+	if before != nil {
+		resourceID = before.ID
+		if encodedBefore, err = json.Marshal(before); err != nil {
+			return fmt.Errorf("failed to encode audit snapshot: %w", err)
+		}
+	}
+	if after != nil {
+		resourceID = after.ID
+		if encodedAfter, err = json.Marshal(after); err != nil {
+			return fmt.Errorf("failed to encode audit snapshot: %w", err)
+		}
+	}
+	entry := audit.NewEntry(ctx, action, apiKeyResourceType, resourceID, encodedBefore, encodedAfter)
+	if err = reposql.NewAuditLogRepositoryWithTx(ks.db, tx).Create(ctx, entry); err != nil {
+		return err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func snapshotAPIKey(key *model.APIKey) *apiKeySnapshot {
+	return &apiKeySnapshot{
+		ID:        key.ID,
+		OwnerID:   key.OwnerID,
+		Name:      key.Name,
+		KeyPrefix: key.KeyPrefix,
+		Scopes:    key.Scopes,
+		ExpiresAt: key.ExpiresAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
+
+// hashAPIKey returns the hex encoded SHA-256 hash under which an API key is stored. Keys carry 256 bits of
+// randomness, so unlike passwords they need no salt or slow hash.
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
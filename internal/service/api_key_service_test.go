@@ -0,0 +1,227 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+var apiKeyColumns = []string{"id", "owner_id", "name", "key_prefix", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"}
+
+func newAPIKeyService(t *testing.T) (*service.APIKeyService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	policy, err := auth.ParsePolicy([]byte(`{"roles": {"admin": ["*"], "editor": ["products:write", "api-keys:write"], "viewer": []}}`))
+	require.NoError(t, err)
+	return service.NewAPIKeyService(db, reposql.NewAPIKeyRepository(db), policy), mock
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	ownerID := uuid.New()
+
+	t.Run("stores the hash of a new key", func(t *testing.T) {
+		// given
+		keyService, mock := newAPIKeyService(t)
+		mock.ExpectBegin()
+		mock.ExpectPrepare("INSERT INTO api_keys").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), ownerID, "nightly import", sqlmock.AnyArg(), sqlmock.AnyArg(), `["products:write"]`, nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		var after snapshotCapture
+		mock.ExpectPrepare("INSERT INTO audit_log").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "anonymous", "create", "api_key", sqlmock.AnyArg(), nil, &after, "", "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// when
+		key, plaintext, err := keyService.CreateAPIKey(ctx, service.CreateAPIKeyInput{
+			OwnerID: ownerID, OwnerRole: model.UserRoleEditor, Name: "nightly import",
+			Scopes: []string{"products:write", "products:write"},
+		})
+
+		// then
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(plaintext, "sk_"))
+		assert.True(t, strings.HasPrefix(plaintext, key.KeyPrefix))
+		sum := sha256.Sum256([]byte(plaintext))
+		assert.Equal(t, hex.EncodeToString(sum[:]), key.KeyHash)
+		assert.Equal(t, []string{"products:write"}, key.Scopes)
+		assert.Equal(t, key.ID.String(), after.snapshot["id"])
+		assert.NotContains(t, after.snapshot, "key_hash")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects scopes the owner does not have", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+
+		for _, scope := range []string{"audit:read", "api-keys:write", "*", "products:delete"} {
+			_, _, err := keyService.CreateAPIKey(ctx, service.CreateAPIKeyInput{
+				OwnerID: ownerID, OwnerRole: model.UserRoleEditor, Name: "key", Scopes: []string{scope},
+			})
+			assert.ErrorIs(t, err, service.ErrScopeNotAllowed, scope)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects a past expiry", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+		expiresAt := time.Now().Add(-time.Minute)
+
+		_, _, err := keyService.CreateAPIKey(ctx, service.CreateAPIKeyInput{
+			OwnerID: ownerID, OwnerRole: model.UserRoleEditor, Name: "key", Scopes: []string{"products:write"}, ExpiresAt: &expiresAt,
+		})
+		assert.ErrorIs(t, err, service.ErrExpiryInPast)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestVerifyAPIKey(t *testing.T) {
+	ctx := context.Background()
+	ownerID := uuid.New()
+	keyID := uuid.New()
+	plaintext := "sk_test-key"
+	sum := sha256.Sum256([]byte(plaintext))
+	keyHash := hex.EncodeToString(sum[:])
+
+	expectKey := func(mock sqlmock.Sqlmock, expiresAt, revokedAt *time.Time) {
+		mock.ExpectPrepare("SELECT \\* FROM api_keys WHERE key_hash = \\$1").
+			ExpectQuery().
+			WithArgs(keyHash).
+			WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+				AddRow(keyID, ownerID, "key", "sk_test-k", keyHash, []byte(`["products:write"]`), expiresAt, nil, revokedAt, time.Now()))
+	}
+
+	t.Run("valid key", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+		expectKey(mock, nil, nil)
+		mock.ExpectPrepare("UPDATE api_keys SET last_used_at = \\$1 WHERE id = \\$2").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), keyID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		claims, err := keyService.VerifyAPIKey(ctx, plaintext)
+		require.NoError(t, err)
+		assert.Equal(t, ownerID.String(), claims.Subject)
+		assert.Equal(t, keyID.String(), claims.APIKeyID)
+		assert.Equal(t, []string{"products:write"}, claims.Scopes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("revoked key", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+		revokedAt := time.Now().Add(-time.Hour)
+		expectKey(mock, nil, &revokedAt)
+
+		_, err := keyService.VerifyAPIKey(ctx, plaintext)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("expired key", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+		expiresAt := time.Now().Add(-time.Hour)
+		expectKey(mock, &expiresAt, nil)
+
+		_, err := keyService.VerifyAPIKey(ctx, plaintext)
+		assert.ErrorIs(t, err, auth.ErrTokenExpired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+		mock.ExpectPrepare("SELECT \\* FROM api_keys WHERE key_hash = \\$1").
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+
+		_, err := keyService.VerifyAPIKey(ctx, "sk_unknown")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed key is not looked up", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+
+		_, err := keyService.VerifyAPIKey(ctx, "not-a-key")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	ctx := context.Background()
+	ownerID := uuid.New()
+	keyID := uuid.New()
+
+	expectKey := func(mock sqlmock.Sqlmock) {
+		mock.ExpectPrepare("SELECT \\* FROM api_keys WHERE id = \\$1").
+			ExpectQuery().
+			WithArgs(keyID).
+			WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+				AddRow(keyID, ownerID, "key", "sk_test-k", "hash", []byte(`[]`), nil, nil, nil, time.Now()))
+	}
+
+	t.Run("own key", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+		mock.ExpectBegin()
+		expectKey(mock)
+		mock.ExpectPrepare("UPDATE api_keys SET revoked_at").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), keyID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare("INSERT INTO audit_log").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "anonymous", "delete", "api_key", keyID, sqlmock.AnyArg(), nil, "", "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, keyService.RevokeAPIKey(ctx, keyID, ownerID, model.UserRoleEditor))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("key of another user", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+		mock.ExpectBegin()
+		expectKey(mock)
+		mock.ExpectRollback()
+
+		err := keyService.RevokeAPIKey(ctx, keyID, uuid.New(), model.UserRoleEditor)
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("key of another user revoked by an admin", func(t *testing.T) {
+		keyService, mock := newAPIKeyService(t)
+		mock.ExpectBegin()
+		expectKey(mock)
+		mock.ExpectPrepare("UPDATE api_keys SET revoked_at").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare("INSERT INTO audit_log").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, keyService.RevokeAPIKey(ctx, keyID, uuid.New(), model.UserRoleAdmin))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
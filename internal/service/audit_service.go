@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+)
+
+// AuditService provides read access to the audit log.
+type AuditService struct {
+	repo *reposql.AuditLogRepository
+}
+
+// NewAuditService creates a new AuditService with the given audit log repository.
+func NewAuditService(repo *reposql.AuditLogRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// AuditLogPage represents one page of audit log entries.
+type AuditLogPage struct {
+	Entries []*model.AuditLogEntry
+	HasNext bool
+	HasPrev bool
+}
+
+// ListAuditLog retrieves a page of audit log entries matching the given query criteria.
+func (as *AuditService) ListAuditLog(ctx context.Context, query repository.Query) (*AuditLogPage, error) {
+	page, err := as.repo.ListPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*model.AuditLogEntry, 0, len(page.Resources))
+	for _, resource := range page.Resources {
+		entry, ok := resource.(*model.AuditLogEntry)
+		if !ok {
+			return nil, repository.ErrInvalidType
+		}
+		entries = append(entries, entry)
+	}
+
+	return &AuditLogPage{
+		Entries: entries,
+		HasNext: page.HasNext,
+		HasPrev: page.HasPrev,
+	}, nil
+}
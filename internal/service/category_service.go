@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+)
+
+// ErrCategoryCycle is returned when a category would become its own ancestor.
+var ErrCategoryCycle = errors.New("category cannot be moved below itself or one of its subcategories")
+
+// CategoryService provides business logic for managing the category tree.
+type CategoryService struct {
+	db   *sql.DB
+	repo repository.Repository
+}
+
+// NewCategoryService creates a new CategoryService with the given DB and category repository.
+func NewCategoryService(db *sql.DB, repo repository.Repository) *CategoryService {
+	return &CategoryService{
+		db:   db,
+		repo: repo,
+	}
+}
+
+// CreateCategory creates a category below the given parent, or a root category if parentID is nil.
+// A parent that does not exist is reported as a *repository.NotFoundError.
+func (cs *CategoryService) CreateCategory(ctx context.Context, name string, parentID *uuid.UUID) (*model.Category, error) {
+	created, err := cs.repo.Create(ctx, &model.Category{Name: name, ParentID: parentID})
+	if err != nil {
+		return nil, err
+	}
+
+	category, ok := created.(*model.Category)
+	if !ok {
+		return nil, repository.ErrInvalidType
+	}
+
+	return category, nil
+}
+
+// GetCategory retrieves a single category by ID.
+func (cs *CategoryService) GetCategory(ctx context.Context, id uuid.UUID) (*model.Category, error) {
+	resource, err := cs.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	category, ok := resource.(*model.Category)
+	if !ok {
+		return nil, repository.ErrInvalidType
+	}
+
+	return category, nil
+}
+
+// CategoryPage represents one page of listed categories.
+type CategoryPage struct {
+	Categories []*model.Category
+	HasNext    bool
+	HasPrev    bool
+}
+
+// ListCategories retrieves a page of categories matching the given query criteria.
+func (cs *CategoryService) ListCategories(ctx context.Context, query repository.Query) (*CategoryPage, error) {
+	page, err := cs.repo.ListPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]*model.Category, 0, len(page.Resources))
+	for _, resource := range page.Resources {
+		category, ok := resource.(*model.Category)
+		if !ok {
+			return nil, repository.ErrInvalidType
+		}
+		categories = append(categories, category)
+	}
+
+	return &CategoryPage{
+		Categories: categories,
+		HasNext:    page.HasNext,
+		HasPrev:    page.HasPrev,
+	}, nil
+}
+
+// UpdateCategory renames a category and moves it below the given parent, or to the root if parentID is nil.
+// Moving a category below itself or one of its subcategories is rejected with ErrCategoryCycle; moves below
+// another category are serialized so that concurrent moves cannot close a cycle either.
+func (cs *CategoryService) UpdateCategory(ctx context.Context, id uuid.UUID, name string, parentID *uuid.UUID) (*model.Category, error) {
+	// Start a transaction so that the cycle check and the move see the same tree
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	txRepo := reposql.NewCategoryRepositoryWithTx(cs.db, tx)
+
+	resource, err := txRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	category, ok := resource.(*model.Category)
+	if !ok {
+		err = repository.ErrInvalidType
+		return nil, err
+	}
+
+	if parentID != nil {
+		// Serialize moves so that the check sees the tree that the move is applied to
+		if err = txRepo.LockMoves(ctx); err != nil {
+			return nil, err
+		}
+		var cycle bool
+		cycle, err = txRepo.InSubtree(ctx, id, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			err = ErrCategoryCycle
+			return nil, err
+		}
+	}
+
+	category.Name = name
+	category.ParentID = parentID
+	if err = txRepo.Update(ctx, category); err != nil {
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return category, nil
+}
+
+// DeleteCategory permanently deletes a category by ID.
+// Categories that still have subcategories or products, including soft-deleted ones,
+// are kept and reported with repository.ErrStillReferenced.
+func (cs *CategoryService) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	return cs.repo.DeleteByID(ctx, id)
+}
@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpdateCategory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	categoryService := service.NewCategoryService(db, reposql.NewCategoryRepository(db))
+	columns := []string{"id", "name", "parent_id", "created_at", "updated_at"}
+
+	t.Run("move below another category", func(t *testing.T) {
+		// given
+		id, parentID := uuid.New(), uuid.New()
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectPrepare("SELECT \\* FROM categories WHERE id = \\$1").
+			ExpectQuery().
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(id, "Laptops", nil, now, now))
+		mock.ExpectExec("SELECT pg_advisory_xact_lock\\(hashtextextended\\(\\$1, 0\\)\\)").
+			WithArgs("categories.move").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(id, parentID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectPrepare("UPDATE categories SET").
+			ExpectExec().
+			WithArgs("Notebooks", &parentID, sqlmock.AnyArg(), id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		// when
+		category, err := categoryService.UpdateCategory(ctx, id, "Notebooks", &parentID)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "Notebooks", category.Name)
+		assert.Equal(t, &parentID, category.ParentID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("move below own subcategory", func(t *testing.T) {
+		// given
+		id, childID := uuid.New(), uuid.New()
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectPrepare("SELECT \\* FROM categories WHERE id = \\$1").
+			ExpectQuery().
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(id, "Electronics", nil, now, now))
+		mock.ExpectExec("SELECT pg_advisory_xact_lock\\(hashtextextended\\(\\$1, 0\\)\\)").
+			WithArgs("categories.move").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(id, childID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		// when
+		category, err := categoryService.UpdateCategory(ctx, id, "Electronics", &childID)
+
+		// then
+		require.ErrorIs(t, err, service.ErrCategoryCycle)
+		assert.Nil(t, category)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+)
+
+// eventResourceType is the resource type of audit log entries about outbox events.
+const eventResourceType = "event"
+
+// ErrEventNotRequeueable is returned when an event that has not failed, or that a worker is publishing
+// at the moment, is requested to be requeued.
+var ErrEventNotRequeueable = errors.New("event cannot be requeued")
+
+// EventService provides read access to the events of the outbox table and replays the failed ones.
+type EventService struct {
+	db   *sql.DB
+	repo *reposql.EventRepository
+	now  func() time.Time
+}
+
+// NewEventService creates a new EventService with the given DB and event repository.
+func NewEventService(db *sql.DB, repo *reposql.EventRepository) *EventService {
+	return &EventService{
+		db:   db,
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// EventPage represents one page of events.
+type EventPage struct {
+	Events  []*model.Event
+	HasNext bool
+	HasPrev bool
+}
+
+// eventSnapshot is the state of an event recorded in the audit log of requeues. The event data is not included.
+type eventSnapshot struct {
+	ID            uuid.UUID         `json:"id"`
+	EventType     string            `json:"event_type"`
+	Status        model.EventStatus `json:"status"`
+	Attempts      int               `json:"attempts"`
+	LastError     *string           `json:"last_error"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at"`
+}
+
+// ListEvents retrieves a page of events matching the given query criteria.
+func (es *EventService) ListEvents(ctx context.Context, query repository.Query) (*EventPage, error) {
+	page, err := es.repo.ListPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*model.Event, 0, len(page.Resources))
+	for _, resource := range page.Resources {
+		event, ok := resource.(*model.Event)
+		if !ok {
+			return nil, repository.ErrInvalidType
+		}
+		events = append(events, event)
+	}
+
+	return &EventPage{
+		Events:  events,
+		HasNext: page.HasNext,
+		HasPrev: page.HasPrev,
+	}, nil
+}
+
+// GetEvent retrieves an event by ID.
+func (es *EventService) GetEvent(ctx context.Context, id uuid.UUID) (*model.Event, error) {
+	resource, err := es.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	event, ok := resource.(*model.Event)
+	if !ok {
+		return nil, repository.ErrInvalidType
+	}
+	return event, nil
+}
+
+// RequeueEvents moves dead events, and pending events waiting for a retry, back to pending with no failed attempts,
+// so that the event workers publish them again right away. Every requeue is recorded in the audit log. The events
+// are requeued all together or not at all: an unknown event fails with a *repository.NotFoundError, an event that
+// has not failed or is being published by a worker with ErrEventNotRequeueable. An ID given more than once is
+// requeued once, and the events are returned in the order their IDs first appear.
+func (es *EventService) RequeueEvents(ctx context.Context, ids []uuid.UUID) ([]*model.Event, error) {
+	// Start a transaction
+	tx, err := es.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	txRepo := reposql.NewEventRepositoryWithTx(es.db, tx)
+	auditRepo := reposql.NewAuditLogRepositoryWithTx(es.db, tx)
+	now := es.now()
+	events := make([]*model.Event, 0, len(ids))
+	requeued := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		// A repeated ID would find its event already requeued and fail the whole batch
+		if requeued[id] {
+			continue
+		}
+		requeued[id] = true
+
+		var event *model.Event
+		event, err = txRepo.LockByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkRequeueable(event, now); err != nil {
+			return nil, err
+		}
+		before := snapshotEvent(event)
+
+		if err = txRepo.Requeue(ctx, id); err != nil {
+			return nil, err
+		}
+		event.Status = model.EventStatusPending
+		event.Attempts = 0
+		event.NextAttemptAt = nil
+		event.ProcessedAt = nil
+		event.LockedBy = nil
+		event.LockedUntil = nil
+
+		var encodedBefore, encodedAfter json.RawMessage
+		if encodedBefore, err = json.Marshal(before); err != nil {
+			return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+		}
+		if encodedAfter, err = json.Marshal(snapshotEvent(event)); err != nil {
+			return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+		}
+		entry := audit.NewEntry(ctx, audit.ActionRequeue, eventResourceType, event.ID, encodedBefore, encodedAfter)
+		if err = auditRepo.Create(ctx, entry); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return events, nil
+}
+
+// checkRequeueable reports whether an event can be requeued: it must have failed, and not be leased
+// to a worker that may be publishing it at the moment.
+func checkRequeueable(event *model.Event, now time.Time) error {
+	switch {
+	case event.Status == model.EventStatusPending && event.LockedUntil != nil && event.LockedUntil.After(now):
+		return fmt.Errorf("%w: event %s is being published", ErrEventNotRequeueable, event.ID)
+	case event.Status == model.EventStatusDead:
+		return nil
+	case event.Status == model.EventStatusPending && event.Attempts > 0:
+		return nil
+	default:
+		return fmt.Errorf("%w: event %s is %s and has not failed", ErrEventNotRequeueable, event.ID, event.Status)
+	}
+}
+
+func snapshotEvent(event *model.Event) eventSnapshot {
+	return eventSnapshot{
+		ID:            event.ID,
+		EventType:     event.EventType,
+		Status:        event.Status,
+		Attempts:      event.Attempts,
+		LastError:     event.LastError,
+		NextAttemptAt: event.NextAttemptAt,
+	}
+}
@@ -0,0 +1,158 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+var eventColumns = []string{"id", "event_type", "event_data", "status", "created_at", "processed_at", "locked_by", "locked_until",
+	"attempts", "last_error", "next_attempt_at", "sequence", "aggregate_id"}
+
+func TestRequeueEvents(t *testing.T) {
+	ctx := context.Background()
+	lastError := "connection refused"
+
+	newEventService := func(t *testing.T) (*service.EventService, sqlmock.Sqlmock) {
+		t.Helper()
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return service.NewEventService(db, reposql.NewEventRepository(db)), mock
+	}
+	expectLock := func(mock sqlmock.Sqlmock, id uuid.UUID, status model.EventStatus, attempts int, lockedUntil *time.Time) {
+		mock.ExpectQuery("SELECT \\* FROM events WHERE id = \\$1 FOR UPDATE").
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(eventColumns).
+				AddRow(id, "product.created", []byte(`{}`), status, time.Now(), nil, nil, lockedUntil, attempts, lastError, nil, int64(1), id))
+	}
+
+	t.Run("requeues dead events and records them in the audit log", func(t *testing.T) {
+		// given
+		eventService, mock := newEventService(t)
+		dead, retrying := uuid.New(), uuid.New()
+		mock.ExpectBegin()
+		var before, after snapshotCapture
+		expectLock(mock, dead, model.EventStatusDead, 10, nil)
+		mock.ExpectPrepare("UPDATE events SET status = \\$1, attempts = 0").
+			ExpectExec().
+			WithArgs(model.EventStatusPending, dead).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare("INSERT INTO audit_log").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "anonymous", "requeue", "event", dead, &before, &after, "", "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLock(mock, retrying, model.EventStatusPending, 3, nil)
+		mock.ExpectPrepare("UPDATE events SET status = \\$1, attempts = 0").
+			ExpectExec().
+			WithArgs(model.EventStatusPending, retrying).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare("INSERT INTO audit_log").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "anonymous", "requeue", "event", retrying, sqlmock.AnyArg(), sqlmock.AnyArg(), "", "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// when
+		events, err := eventService.RequeueEvents(ctx, []uuid.UUID{dead, retrying})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, model.EventStatusPending, events[0].Status)
+		assert.Equal(t, 0, events[0].Attempts)
+		assert.Equal(t, lastError, *events[0].LastError)
+		assert.Equal(t, "dead", before.snapshot["status"])
+		assert.Equal(t, float64(10), before.snapshot["attempts"])
+		assert.Equal(t, "pending", after.snapshot["status"])
+		assert.Equal(t, float64(0), after.snapshot["attempts"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("requeues repeated IDs once", func(t *testing.T) {
+		// given
+		eventService, mock := newEventService(t)
+		first, second := uuid.New(), uuid.New()
+		mock.ExpectBegin()
+		for _, id := range []uuid.UUID{first, second} {
+			expectLock(mock, id, model.EventStatusDead, 10, nil)
+			mock.ExpectPrepare("UPDATE events SET status = \\$1, attempts = 0").
+				ExpectExec().
+				WithArgs(model.EventStatusPending, id).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectPrepare("INSERT INTO audit_log").
+				ExpectExec().
+				WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		mock.ExpectCommit()
+
+		// when
+		events, err := eventService.RequeueEvents(ctx, []uuid.UUID{first, second, first, second})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, first, events[0].ID)
+		assert.Equal(t, second, events[1].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects events that have not failed", func(t *testing.T) {
+		for _, status := range []model.EventStatus{model.EventStatusPending, model.EventStatusProcessed} {
+			eventService, mock := newEventService(t)
+			id := uuid.New()
+			mock.ExpectBegin()
+			expectLock(mock, id, status, 0, nil)
+			mock.ExpectRollback()
+
+			_, err := eventService.RequeueEvents(ctx, []uuid.UUID{id})
+			assert.ErrorIs(t, err, service.ErrEventNotRequeueable, status)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("rejects events being published", func(t *testing.T) {
+		eventService, mock := newEventService(t)
+		id := uuid.New()
+		lockedUntil := time.Now().Add(time.Minute)
+		mock.ExpectBegin()
+		expectLock(mock, id, model.EventStatusPending, 2, &lockedUntil)
+		mock.ExpectRollback()
+
+		_, err := eventService.RequeueEvents(ctx, []uuid.UUID{id})
+		assert.ErrorIs(t, err, service.ErrEventNotRequeueable)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown event rolls back the whole requeue", func(t *testing.T) {
+		eventService, mock := newEventService(t)
+		dead, unknown := uuid.New(), uuid.New()
+		mock.ExpectBegin()
+		expectLock(mock, dead, model.EventStatusDead, 10, nil)
+		mock.ExpectPrepare("UPDATE events SET status").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare("INSERT INTO audit_log").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT \\* FROM events WHERE id = \\$1 FOR UPDATE").
+			WithArgs(unknown).
+			WillReturnRows(sqlmock.NewRows(eventColumns))
+		mock.ExpectRollback()
+
+		_, err := eventService.RequeueEvents(ctx, []uuid.UUID{dead, unknown})
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
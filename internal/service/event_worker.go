@@ -3,28 +3,72 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/iyhunko/microservices-with-sqs/internal/model"
 	"github.com/iyhunko/microservices-with-sqs/internal/repository"
 	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
 	"github.com/iyhunko/microservices-with-sqs/internal/sqs"
 )
 
-// EventWorker handles processing of pending events from the outbox table.
+const (
+	// eventBatchSize is the number of pending events a worker claims at a time.
+	eventBatchSize = 100
+	// userEventPrefix starts the types of the events of users, which are published as user messages.
+	userEventPrefix = "user."
+)
+
+// EventRetryPolicy decides when events that failed to process are retried. The delay before a retry doubles
+// with every failed attempt, starting at BaseDelay and capped at MaxDelay; events that failed MaxAttempts times
+// are given up on.
+type EventRetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Backoff returns the delay before retrying an event that failed the given number of times. The delay is
+// randomized between half and the full exponential delay, so that events failing together are not all
+// retried at the same moment.
+func (p EventRetryPolicy) Backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// EventWorker handles processing of pending events from the outbox table. Several workers, e.g. of different
+// replicas, can run at the same time: each claims a batch of events under a lease before publishing them.
 type EventWorker struct {
 	eventRepo *reposql.EventRepository
 	publisher *sqs.Publisher
 	interval  time.Duration
+	lease     time.Duration
+	retry     EventRetryPolicy
+	workerID  string
 }
 
-// NewEventWorker creates a new EventWorker instance.
-func NewEventWorker(eventRepo *reposql.EventRepository, publisher *sqs.Publisher, interval time.Duration) *EventWorker {
+// NewEventWorker creates a new EventWorker instance. The lease must be longer than publishing a batch of events
+// takes, otherwise other workers claim the events again and they are published twice.
+func NewEventWorker(eventRepo *reposql.EventRepository, publisher *sqs.Publisher, interval, lease time.Duration,
+	retry EventRetryPolicy,
+) *EventWorker {
 	return &EventWorker{
 		eventRepo: eventRepo,
 		publisher: publisher,
 		interval:  interval,
+		lease:     lease,
+		retry:     retry,
+		workerID:  newWorkerID(),
 	}
 }
 
@@ -33,7 +77,7 @@ func (ew *EventWorker) Start(ctx context.Context) {
 	ticker := time.NewTicker(ew.interval)
 	defer ticker.Stop()
 
-	slog.Info("Event worker started", slog.Duration("interval", ew.interval))
+	slog.Info("Event worker started", slog.String("worker_id", ew.workerID), slog.Duration("interval", ew.interval), slog.Duration("lease", ew.lease))
 
 	for {
 		select {
@@ -48,34 +92,30 @@ func (ew *EventWorker) Start(ctx context.Context) {
 	}
 }
 
-// processPendingEvents fetches and processes all pending events.
+// processPendingEvents claims and processes batches of pending events until none are due. Only the first pending
+// event of every aggregate can be claimed at a time, so the later events of an aggregate are claimed by the
+// following batches once the earlier ones are processed.
 func (ew *EventWorker) processPendingEvents(ctx context.Context) error {
-	// Query for pending events
-	query := repository.NewQuery().With(repository.StatusField, string(model.EventStatusPending))
-	query.Limit = 100 // Process up to 100 events at a time
-
-	resources, err := ew.eventRepo.List(ctx, *query)
-	if err != nil {
-		return err
-	}
-
-	for _, resource := range resources {
-		event, ok := resource.(*model.Event)
-		if !ok {
-			slog.Error("Invalid resource type", slog.Any("resource", resource))
-			continue
+	for ctx.Err() == nil {
+		events, err := ew.eventRepo.ClaimPending(ctx, ew.workerID, time.Now(), ew.lease, eventBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
 		}
 
-		if err := ew.processEvent(ctx, event); err != nil {
-			slog.Error("Failed to process event", slog.String("event_id", event.ID.String()), slog.Any("err", err))
-			// Mark event as failed
-			if updateErr := ew.eventRepo.UpdateStatus(ctx, event.ID, model.EventStatusFailed); updateErr != nil {
-				slog.Error("Failed to update event status to failed", slog.String("event_id", event.ID.String()), slog.Any("err", updateErr))
-			}
-		} else {
-			// Mark event as processed
-			if updateErr := ew.eventRepo.UpdateStatus(ctx, event.ID, model.EventStatusProcessed); updateErr != nil {
-				slog.Error("Failed to update event status to processed", slog.String("event_id", event.ID.String()), slog.Any("err", updateErr))
+		for _, event := range events {
+			if err := ew.processEvent(ctx, event); err != nil {
+				ew.recordFailure(ctx, event, err)
+			} else {
+				// Mark event as processed
+				updateErr := ew.eventRepo.UpdateStatus(ctx, event.ID, ew.workerID, model.EventStatusProcessed)
+				if errors.Is(updateErr, repository.ErrLeaseLost) {
+					logLeaseLost(event)
+				} else if updateErr != nil {
+					slog.Error("Failed to update event status to processed", slog.String("event_id", event.ID.String()), slog.Any("err", updateErr))
+				}
 			}
 		}
 	}
@@ -83,17 +123,62 @@ func (ew *EventWorker) processPendingEvents(ctx context.Context) error {
 	return nil
 }
 
-// processEvent processes a single event by publishing it to SQS.
+// recordFailure schedules the retry of an event that failed to process, or marks it dead once it has failed
+// the maximum number of times.
+func (ew *EventWorker) recordFailure(ctx context.Context, event *model.Event, err error) {
+	reason := err.Error()
+	event.Attempts++
+	event.LastError = &reason
+	event.NextAttemptAt = nil
+
+	if event.Attempts >= ew.retry.MaxAttempts {
+		event.Status = model.EventStatusDead
+		slog.Error("Failed to process event, giving up", slog.String("event_id", event.ID.String()),
+			slog.Int("attempts", event.Attempts), slog.Any("err", err))
+	} else {
+		nextAttemptAt := time.Now().Add(ew.retry.Backoff(event.Attempts))
+		event.NextAttemptAt = &nextAttemptAt
+		slog.Warn("Failed to process event, retrying later", slog.String("event_id", event.ID.String()),
+			slog.Int("attempts", event.Attempts), slog.Time("next_attempt_at", nextAttemptAt), slog.Any("err", err))
+	}
+
+	updateErr := ew.eventRepo.RecordFailure(ctx, event, ew.workerID)
+	if errors.Is(updateErr, repository.ErrLeaseLost) {
+		logLeaseLost(event)
+	} else if updateErr != nil {
+		slog.Error("Failed to record event failure", slog.String("event_id", event.ID.String()), slog.Any("err", updateErr))
+	}
+}
+
+// logLeaseLost reports an outcome that is dropped because the lease of its event expired and the event was
+// claimed again or requeued meanwhile. The new owner of the event decides what happens to it.
+func logLeaseLost(event *model.Event) {
+	slog.Warn("Lost the lease of event, dropping its outcome", slog.String("event_id", event.ID.String()))
+}
+
+// processEvent processes a single event by publishing it to SQS. Events of users, e.g. user.registered, are
+// published as user messages and all others as product messages.
 func (ew *EventWorker) processEvent(ctx context.Context, event *model.Event) error {
-	// Parse event data
-	var msg sqs.ProductMessage
-	if err := json.Unmarshal(event.EventData, &msg); err != nil {
-		return err
+	// Parse event data and publish to SQS. On FIFO queues, the aggregate is the message group so that the events
+	// of a product stay in order, and the event ID deduplicates an event published again after a crash.
+	groupID, deduplicationID := event.AggregateID.String(), event.ID.String()
+	var publish func() error
+	if strings.HasPrefix(event.EventType, userEventPrefix) {
+		var msg sqs.UserMessage
+		if err := json.Unmarshal(event.EventData, &msg); err != nil {
+			return err
+		}
+		publish = func() error { return ew.publisher.PublishUserMessage(ctx, msg, groupID, deduplicationID) }
+	} else {
+		var msg sqs.ProductMessage
+		if err := json.Unmarshal(event.EventData, &msg); err != nil {
+			return err
+		}
+		publish = func() error { return ew.publisher.PublishProductMessage(ctx, msg, groupID, deduplicationID) }
 	}
 
-	// Publish to SQS
 	if ew.publisher != nil {
-		if err := ew.publisher.PublishProductMessage(ctx, msg); err != nil {
+		if err := publish(); err != nil {
 			return err
 		}
 		slog.Info("Event published to SQS", slog.String("event_id", event.ID.String()), slog.String("event_type", event.EventType))
@@ -101,3 +186,12 @@ func (ew *EventWorker) processEvent(ctx context.Context, event *model.Event) err
 
 	return nil
 }
+
+// newWorkerID identifies a worker in the leases of the events it claims, e.g. "product-service-7d9f-1a2b3c4d".
+func newWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return host + "-" + uuid.NewString()[:8]
+}
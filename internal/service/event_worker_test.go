@@ -0,0 +1,33 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventRetryPolicy_Backoff(t *testing.T) {
+	policy := service.EventRetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{7, time.Minute},
+		{100, time.Minute},
+	}
+
+	for _, tt := range tests {
+		// The jitter keeps the delay between half and the full exponential delay
+		for i := 0; i < 20; i++ {
+			delay := policy.Backoff(tt.attempts)
+			assert.GreaterOrEqual(t, delay, tt.want/2, "attempts %d", tt.attempts)
+			assert.LessOrEqual(t, delay, tt.want, "attempts %d", tt.attempts)
+		}
+	}
+}
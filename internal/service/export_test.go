@@ -0,0 +1,7 @@
+package service
+
+// HashCreateProductRequest exposes hashCreateProductRequest for testing.
+var HashCreateProductRequest = hashCreateProductRequest
+
+// ExportPageSize exposes exportPageSize for testing.
+const ExportPageSize = exportPageSize
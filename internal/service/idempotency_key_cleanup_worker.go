@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+)
+
+// IdempotencyKeyCleanupWorker removes idempotency keys that are older than their TTL.
+type IdempotencyKeyCleanupWorker struct {
+	idempotencyKeyRepo *reposql.IdempotencyKeyRepository
+	ttl                time.Duration
+	interval           time.Duration
+}
+
+// NewIdempotencyKeyCleanupWorker creates a new IdempotencyKeyCleanupWorker instance.
+func NewIdempotencyKeyCleanupWorker(idempotencyKeyRepo *reposql.IdempotencyKeyRepository, ttl, interval time.Duration) *IdempotencyKeyCleanupWorker {
+	return &IdempotencyKeyCleanupWorker{
+		idempotencyKeyRepo: idempotencyKeyRepo,
+		ttl:                ttl,
+		interval:           interval,
+	}
+}
+
+// Start begins the worker loop that removes expired idempotency keys.
+func (iw *IdempotencyKeyCleanupWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(iw.interval)
+	defer ticker.Stop()
+
+	slog.Info("Idempotency key cleanup worker started", slog.Duration("interval", iw.interval), slog.Duration("ttl", iw.ttl))
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Idempotency key cleanup worker stopping")
+			return
+		case <-ticker.C:
+			if err := iw.cleanup(ctx); err != nil {
+				slog.Error("Failed to clean up idempotency keys", slog.Any("err", err))
+			}
+		}
+	}
+}
+
+// cleanup deletes idempotency keys created before the TTL cut-off.
+func (iw *IdempotencyKeyCleanupWorker) cleanup(ctx context.Context) error {
+	deleted, err := iw.idempotencyKeyRepo.DeleteExpired(ctx, time.Now().Add(-iw.ttl))
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		slog.Info("Removed expired idempotency keys", slog.Int64("count", deleted))
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/sqs"
+)
+
+var (
+	// ErrInsufficientStock is returned when a stock change would reserve more than is available
+	// or leave less stock on hand than is reserved.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrReleaseExceedsReserved is returned when releasing more stock than is reserved.
+	ErrReleaseExceedsReserved = errors.New("cannot release more stock than is reserved")
+	// ErrStockOutOfRange is returned when a stock change would take the stock on hand beyond what can be stored.
+	ErrStockOutOfRange = errors.New("stock on hand out of range")
+)
+
+// InventoryService provides business logic for tracking and reserving product stock.
+type InventoryService struct {
+	db                *sql.DB
+	repo              *reposql.InventoryRepository
+	lowStockThreshold int64
+}
+
+// NewInventoryService creates a new InventoryService with the given DB, inventory repository and the available
+// quantity at or below which an inventory.low_stock event is stored.
+func NewInventoryService(db *sql.DB, repo *reposql.InventoryRepository, lowStockThreshold int64) *InventoryService {
+	return &InventoryService{
+		db:                db,
+		repo:              repo,
+		lowStockThreshold: lowStockThreshold,
+	}
+}
+
+// GetInventory retrieves the stock level of a product. Products whose stock was never changed have zero stock.
+func (is *InventoryService) GetInventory(ctx context.Context, productID uuid.UUID) (*model.Inventory, error) {
+	if _, err := reposql.NewProductRepository(is.db).FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	inventory, err := is.repo.FindByProductID(ctx, productID)
+	var notFoundErr *repository.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return &model.Inventory{ProductID: productID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return inventory, nil
+}
+
+// AdjustStock adds delta to the stock on hand, or removes it when delta is negative.
+// Removing stock that is reserved is rejected with ErrInsufficientStock, and a change that would overflow
+// the stock on hand with ErrStockOutOfRange.
+func (is *InventoryService) AdjustStock(ctx context.Context, productID uuid.UUID, delta int64) (*model.Inventory, error) {
+	return is.changeStock(ctx, productID, "stock_adjusted", delta, func(inventory *model.Inventory) error {
+		if (delta > 0 && inventory.QuantityOnHand > math.MaxInt64-delta) || (delta < 0 && inventory.QuantityOnHand < math.MinInt64-delta) {
+			return fmt.Errorf("%w: %d on hand cannot change by %d", ErrStockOutOfRange, inventory.QuantityOnHand, delta)
+		}
+		if inventory.QuantityOnHand+delta < inventory.QuantityReserved {
+			return fmt.Errorf("%w: %d on hand and %d reserved", ErrInsufficientStock, inventory.QuantityOnHand, inventory.QuantityReserved)
+		}
+		inventory.QuantityOnHand += delta
+		return nil
+	})
+}
+
+// ReserveStock reserves quantity of the available stock. Reserving more than is available is rejected
+// with ErrInsufficientStock.
+func (is *InventoryService) ReserveStock(ctx context.Context, productID uuid.UUID, quantity int64) (*model.Inventory, error) {
+	return is.changeStock(ctx, productID, "stock_reserved", quantity, func(inventory *model.Inventory) error {
+		if inventory.Available() < quantity {
+			return fmt.Errorf("%w: %d available", ErrInsufficientStock, inventory.Available())
+		}
+		inventory.QuantityReserved += quantity
+		return nil
+	})
+}
+
+// ReleaseStock makes quantity of the reserved stock available again. Releasing more than is reserved is rejected
+// with ErrReleaseExceedsReserved.
+func (is *InventoryService) ReleaseStock(ctx context.Context, productID uuid.UUID, quantity int64) (*model.Inventory, error) {
+	return is.changeStock(ctx, productID, "stock_released", quantity, func(inventory *model.Inventory) error {
+		if inventory.QuantityReserved < quantity {
+			return fmt.Errorf("%w: %d reserved", ErrReleaseExceedsReserved, inventory.QuantityReserved)
+		}
+		inventory.QuantityReserved -= quantity
+		return nil
+	})
+}
+
+// changeStock applies a stock change to the locked inventory row of a product and stores an inventory.adjusted
+// event in the same transaction (outbox pattern). When the change brings the available stock down to the low stock
+// threshold or below, an inventory.low_stock event is stored as well.
+func (is *InventoryService) changeStock(ctx context.Context, productID uuid.UUID, action string, change int64,
+	apply func(inventory *model.Inventory) error,
+) (*model.Inventory, error) {
+	// Start a transaction
+	tx, err := is.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	// Create transactional repositories
+	txInventoryRepo := reposql.NewInventoryRepositoryWithTx(is.db, tx)
+	txEventRepo := reposql.NewEventRepositoryWithTx(is.db, tx)
+
+	resource, err := reposql.NewProductRepositoryWithTx(is.db, tx).FindByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	product, ok := resource.(*model.Product)
+	if !ok {
+		err = repository.ErrInvalidType
+		return nil, err
+	}
+
+	// Lock the inventory row so that concurrent changes of the same product cannot oversell
+	inventory, err := txInventoryRepo.LockByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	availableBefore := inventory.Available()
+
+	if err = apply(inventory); err != nil {
+		return nil, err
+	}
+
+	if err = txInventoryRepo.Update(ctx, inventory); err != nil {
+		return nil, err
+	}
+
+	// Create events in the same transaction (outbox pattern)
+	msg := sqs.ProductMessage{
+		Action:    action,
+		ProductID: product.ID.String(),
+		Name:      product.Name,
+		Price:     product.Price,
+		Inventory: &sqs.InventoryLevel{
+			Change:    change,
+			OnHand:    inventory.QuantityOnHand,
+			Reserved:  inventory.QuantityReserved,
+			Available: inventory.Available(),
+		},
+	}
+	if err = createInventoryEvent(ctx, txEventRepo, product.ID, "inventory.adjusted", msg); err != nil {
+		return nil, err
+	}
+	if availableBefore > is.lowStockThreshold && inventory.Available() <= is.lowStockThreshold {
+		msg.Action = "low_stock"
+		if err = createInventoryEvent(ctx, txEventRepo, product.ID, "inventory.low_stock", msg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return inventory, nil
+}
+
+// createInventoryEvent stores msg as a pending event of the given type about the given product.
+func createInventoryEvent(ctx context.Context, eventRepo repository.Repository, productID uuid.UUID, eventType string,
+	msg sqs.ProductMessage,
+) error {
+	eventData, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = eventRepo.Create(ctx, &model.Event{
+		EventType:   eventType,
+		EventData:   eventData,
+		Status:      model.EventStatusPending,
+		AggregateID: productID,
+	})
+	return err
+}
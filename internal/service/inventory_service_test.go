@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/iyhunko/microservices-with-sqs/internal/sqs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInventoryService_ReserveStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	inventoryService := service.NewInventoryService(db, reposql.NewInventoryRepository(db), 5)
+
+	expectLockedInventory := func(productID uuid.UUID, onHand, reserved int64) {
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE id").
+			ExpectQuery().
+			WithArgs(productID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+				AddRow(productID, "Laptop", "", 999.0, now, now, int64(1), nil, "USD", nil))
+		mock.ExpectExec("INSERT INTO inventory").
+			WithArgs(productID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT \\* FROM inventory WHERE product_id = \\$1 FOR UPDATE").
+			WithArgs(productID).
+			WillReturnRows(sqlmock.NewRows([]string{"product_id", "quantity_on_hand", "quantity_reserved", "created_at", "updated_at"}).
+				AddRow(productID, onHand, reserved, now, now))
+	}
+
+	t.Run("reservation crossing the low stock threshold", func(t *testing.T) {
+		// given
+		productID := uuid.New()
+		expectLockedInventory(productID, 10, 2)
+		mock.ExpectPrepare("UPDATE inventory SET").
+			ExpectExec().
+			WithArgs(int64(10), int64(6), sqlmock.AnyArg(), productID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		var adjustedEvent, lowStockEvent eventDataCapture
+		mock.ExpectExec("SELECT pg_advisory_xact_lock").
+			WithArgs(productID.String()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare("INSERT INTO events").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "inventory.adjusted", &adjustedEvent, string(model.EventStatusPending), sqlmock.AnyArg(), nil, productID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("SELECT pg_advisory_xact_lock").
+			WithArgs(productID.String()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare("INSERT INTO events").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "inventory.low_stock", &lowStockEvent, string(model.EventStatusPending), sqlmock.AnyArg(), nil, productID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// when
+		inventory, err := inventoryService.ReserveStock(ctx, productID, 4)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), inventory.QuantityReserved)
+		assert.Equal(t, int64(4), inventory.Available())
+		level := &sqs.InventoryLevel{Change: 4, OnHand: 10, Reserved: 6, Available: 4}
+		assert.Equal(t, "stock_reserved", adjustedEvent.msg.Action)
+		assert.Equal(t, level, adjustedEvent.msg.Inventory)
+		assert.Equal(t, "low_stock", lowStockEvent.msg.Action)
+		assert.Equal(t, level, lowStockEvent.msg.Inventory)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("insufficient stock", func(t *testing.T) {
+		// given
+		productID := uuid.New()
+		expectLockedInventory(productID, 3, 1)
+		mock.ExpectRollback()
+
+		// when
+		inventory, err := inventoryService.ReserveStock(ctx, productID, 3)
+
+		// then
+		require.ErrorIs(t, err, service.ErrInsufficientStock)
+		assert.Nil(t, inventory)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInventoryService_ReleaseStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	inventoryService := service.NewInventoryService(db, reposql.NewInventoryRepository(db), 5)
+	productID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("SELECT \\* FROM products WHERE id").
+		ExpectQuery().
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(productID, "Laptop", "", 999.0, now, now, int64(1), nil, "USD", nil))
+	mock.ExpectExec("INSERT INTO inventory").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM inventory WHERE product_id = \\$1 FOR UPDATE").
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "quantity_on_hand", "quantity_reserved", "created_at", "updated_at"}).
+			AddRow(productID, int64(10), int64(2), now, now))
+	mock.ExpectRollback()
+
+	inventory, err := inventoryService.ReleaseStock(context.Background(), productID, 3)
+	require.ErrorIs(t, err, service.ErrReleaseExceedsReserved)
+	assert.Nil(t, inventory)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInventoryService_AdjustStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	inventoryService := service.NewInventoryService(db, reposql.NewInventoryRepository(db), 5)
+	productID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("SELECT \\* FROM products WHERE id").
+		ExpectQuery().
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(productID, "Laptop", "", 999.0, now, now, int64(1), nil, "USD", nil))
+	mock.ExpectExec("INSERT INTO inventory").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM inventory WHERE product_id = \\$1 FOR UPDATE").
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "quantity_on_hand", "quantity_reserved", "created_at", "updated_at"}).
+			AddRow(productID, int64(math.MaxInt64-10), int64(0), now, now))
+	mock.ExpectRollback()
+
+	inventory, err := inventoryService.AdjustStock(context.Background(), productID, 11)
+	require.ErrorIs(t, err, service.ErrStockOutOfRange)
+	assert.Nil(t, inventory)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
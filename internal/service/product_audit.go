@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+)
+
+// productResourceType is the resource type of audit log entries about products.
+const productResourceType = "product"
+
+// productSnapshot is the state of a product recorded before and after a change in the audit log.
+// Tags are left out when they were not loaded for the change.
+type productSnapshot struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       model.Money `json:"price"`
+	CategoryID  *uuid.UUID  `json:"category_id"`
+	Tags        []string    `json:"tags,omitempty"`
+	Version     int64       `json:"version"`
+	DeletedAt   *time.Time  `json:"deleted_at"`
+}
+
+// snapshotProduct captures the current state of the product for the audit log.
+func snapshotProduct(product *model.Product) *productSnapshot {
+	return &productSnapshot{
+		ID:          product.ID,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		CategoryID:  product.CategoryID,
+		Tags:        product.Tags,
+		Version:     product.Version,
+		DeletedAt:   product.DeletedAt,
+	}
+}
+
+// recordProductAudit stores an audit log entry for a change of a product within the given transaction.
+// A nil before or after snapshot is left empty, as for creations and deletions.
+func (ps *ProductService) recordProductAudit(ctx context.Context, tx *sql.Tx, action string, id uuid.UUID,
+	before, after *productSnapshot,
+) error {
+	entry, err := newProductAuditEntry(ctx, action, id, before, after)
+	if err != nil {
+		return err
+	}
+	return reposql.NewAuditLogRepositoryWithTx(ps.db, tx).Create(ctx, entry)
+}
+
+// recordProductsAudit stores one audit log entry per product with a single insert within the given transaction.
+// snapshots returns the before and after state of each product.
+func (ps *ProductService) recordProductsAudit(ctx context.Context, tx *sql.Tx, action string, products []*model.Product,
+	snapshots func(product *model.Product) (before, after *productSnapshot),
+) error {
+	entries := make([]*model.AuditLogEntry, 0, len(products))
+	for _, product := range products {
+		before, after := snapshots(product)
+		entry, err := newProductAuditEntry(ctx, action, product.ID, before, after)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	return reposql.NewAuditLogRepositoryWithTx(ps.db, tx).CreateBatch(ctx, entries)
+}
+
+// newProductAuditEntry encodes the snapshots of a changed product into an audit log entry.
+func newProductAuditEntry(ctx context.Context, action string, id uuid.UUID, before, after *productSnapshot) (*model.AuditLogEntry, error) {
+	encodedBefore, err := encodeSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	encodedAfter, err := encodeSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+	return audit.NewEntry(ctx, action, productResourceType, id, encodedBefore, encodedAfter), nil
+}
+
+// encodeSnapshot encodes a product snapshot as JSON, or returns nil for a nil snapshot.
+func encodeSnapshot(snapshot *productSnapshot) (json.RawMessage, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	return data, nil
+}
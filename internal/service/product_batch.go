@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
+	"github.com/iyhunko/microservices-with-sqs/internal/metrics"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/sqs"
+)
+
+// BatchNotFoundError is returned when an all-or-nothing batch refers to products that do not exist or are already deleted.
+type BatchNotFoundError struct {
+	IDs []uuid.UUID
+}
+
+// Error returns the error message for BatchNotFoundError.
+func (b *BatchNotFoundError) Error() string {
+	return fmt.Sprintf("%d products in the batch were not found", len(b.IDs))
+}
+
+// CreateProducts creates the given products in one transaction using a multi-row insert
+// and stores one product.created event and audit log entry per created product in the same transaction (outbox pattern).
+// If atomic is set and any product refers to a category that does not exist, nothing is created and
+// ErrCategoryNotFound is returned. Otherwise products the database rejects are skipped: the returned products
+// and item errors are aligned with the inputs, and every skipped product is nil with the reason in its item error,
+// e.g. ErrCategoryNotFound.
+func (ps *ProductService) CreateProducts(ctx context.Context, inputs []ProductInput, atomic bool) ([]*model.Product, []error, error) {
+	products := make([]*model.Product, len(inputs))
+	for i, input := range inputs {
+		products[i] = &model.Product{
+			Name:        input.Name,
+			Description: input.Description,
+			Price:       input.Price,
+			CategoryID:  input.CategoryID,
+			Tags:        input.Tags,
+		}
+	}
+
+	// Start a transaction
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	categories, err := findProductCategories(ctx, reposql.NewCategoryRepositoryWithTx(ps.db, tx), products)
+	if err != nil {
+		return nil, nil, err
+	}
+	itemErrs := make([]error, len(products))
+	for i, product := range products {
+		if product.CategoryID != nil && categories[*product.CategoryID] == nil {
+			if atomic {
+				err = ErrCategoryNotFound
+				return nil, nil, err
+			}
+			itemErrs[i] = ErrCategoryNotFound
+		}
+	}
+
+	if atomic {
+		err = insertProducts(ctx, ps.db, tx, products)
+	} else {
+		err = insertProductsBestEffort(ctx, ps.db, tx, products, itemErrs)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	created := make([]*model.Product, 0, len(products))
+	for i, product := range products {
+		if itemErrs[i] != nil {
+			products[i] = nil
+			continue
+		}
+		created = append(created, product)
+	}
+
+	// Create events in the same transaction (outbox pattern)
+	events, err := productEvents("created", created, categories)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = reposql.NewEventRepositoryWithTx(ps.db, tx).CreateBatch(ctx, events); err != nil {
+		return nil, nil, err
+	}
+
+	err = ps.recordProductsAudit(ctx, tx, audit.ActionCreate, created, func(product *model.Product) (before, after *productSnapshot) {
+		return nil, snapshotProduct(product)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Increment metrics
+	metrics.ProductsCreated.Add(float64(len(created)))
+
+	return products, itemErrs, nil
+}
+
+// insertProducts inserts the products and their tags with multi-row inserts.
+func insertProducts(ctx context.Context, db *sql.DB, tx *sql.Tx, products []*model.Product) error {
+	if err := reposql.NewProductRepositoryWithTx(db, tx).CreateBatch(ctx, products); err != nil {
+		return categoryError(err)
+	}
+	return reposql.NewProductTagRepositoryWithTx(db, tx).CreateBatch(ctx, products)
+}
+
+// insertProductsBestEffort inserts the products without an item error like insertProducts, under a savepoint.
+// If the database rejects the multi-row insert, the products are inserted one at a time under savepoints of
+// their own, so that a product that cannot be stored, e.g. because its category was deleted concurrently,
+// gets an item error without sinking the others.
+func insertProductsBestEffort(ctx context.Context, db *sql.DB, tx *sql.Tx, products []*model.Product, itemErrs []error) error {
+	pending := make([]*model.Product, 0, len(products))
+	for i, product := range products {
+		if itemErrs[i] == nil {
+			pending = append(pending, product)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	insertErr, err := withSavepoint(ctx, tx, func() error { return insertProducts(ctx, db, tx, pending) })
+	if err != nil || insertErr == nil {
+		return err
+	}
+	slog.Warn("failed to insert batch of products, inserting them one at a time", slog.Any("err", insertErr))
+
+	for i, product := range products {
+		if itemErrs[i] != nil {
+			continue
+		}
+		itemErrs[i], err = withSavepoint(ctx, tx, func() error { return insertProducts(ctx, db, tx, []*model.Product{product}) })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withSavepoint runs fn under a savepoint of tx. When fn fails, the transaction is rolled back to the savepoint
+// so that it can go on without the changes of fn, and the error of fn is returned as fnErr. err reports failures
+// to manage the savepoint itself, after which the transaction is unusable.
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func() error) (fnErr, err error) {
+	if _, err = tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	if fnErr = fn(); fnErr != nil {
+		if _, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+			return fnErr, fmt.Errorf("failed to roll back to savepoint: %w", err)
+		}
+		return fnErr, nil
+	}
+	if _, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_item"); err != nil {
+		return nil, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil, nil
+}
+
+// DeleteProducts soft-deletes the products with the given IDs in one transaction
+// and stores one product.deleted event and audit log entry per deleted product in the same transaction (outbox pattern).
+// Products that do not exist or are already deleted are skipped, unless atomic is set:
+// then nothing is deleted and a *BatchNotFoundError lists the missing IDs.
+func (ps *ProductService) DeleteProducts(ctx context.Context, ids []uuid.UUID, atomic bool) ([]*model.Product, error) {
+	// Start a transaction
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	deleted, err := reposql.NewProductRepositoryWithTx(ps.db, tx).SoftDeleteBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic && len(deleted) < len(ids) {
+		err = &BatchNotFoundError{IDs: missingProductIDs(ids, deleted)}
+		return nil, err
+	}
+
+	categories, err := findProductCategories(ctx, reposql.NewCategoryRepositoryWithTx(ps.db, tx), deleted)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create events in the same transaction (outbox pattern)
+	events, err := productEvents("deleted", deleted, categories)
+	if err != nil {
+		return nil, err
+	}
+	if err = reposql.NewEventRepositoryWithTx(ps.db, tx).CreateBatch(ctx, events); err != nil {
+		return nil, err
+	}
+
+	err = ps.recordProductsAudit(ctx, tx, audit.ActionDelete, deleted, func(product *model.Product) (before, after *productSnapshot) {
+		// The deletion returned the deleted rows, so rebuild the state they had before it
+		before = snapshotProduct(product)
+		before.DeletedAt = nil
+		before.Version--
+		return before, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Increment metrics
+	metrics.ProductsDeleted.Add(float64(len(deleted)))
+
+	return deleted, nil
+}
+
+// findProductCategories loads the distinct categories of the given products keyed by ID.
+func findProductCategories(ctx context.Context, categoryRepo *reposql.CategoryRepository, products []*model.Product) (map[uuid.UUID]*model.Category, error) {
+	ids := make([]uuid.UUID, 0, len(products))
+	seen := make(map[uuid.UUID]bool, len(products))
+	for _, product := range products {
+		if product.CategoryID != nil && !seen[*product.CategoryID] {
+			seen[*product.CategoryID] = true
+			ids = append(ids, *product.CategoryID)
+		}
+	}
+	return categoryRepo.FindByIDs(ctx, ids)
+}
+
+// productEvents builds one pending product.<action> event per product, carrying its category from categories.
+func productEvents(action string, products []*model.Product, categories map[uuid.UUID]*model.Category) ([]*model.Event, error) {
+	events := make([]*model.Event, 0, len(products))
+	for _, product := range products {
+		msg := sqs.ProductMessage{
+			Action:    action,
+			ProductID: product.ID.String(),
+			Name:      product.Name,
+			Price:     product.Price,
+		}
+		if product.CategoryID != nil {
+			msg.Category = categoryMessage(categories[*product.CategoryID])
+		}
+		eventData, err := json.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, &model.Event{
+			EventType:   "product." + action,
+			EventData:   eventData,
+			Status:      model.EventStatusPending,
+			AggregateID: product.ID,
+		})
+	}
+	return events, nil
+}
+
+// missingProductIDs returns the requested IDs that are not among the given products.
+func missingProductIDs(ids []uuid.UUID, products []*model.Product) []uuid.UUID {
+	found := make(map[uuid.UUID]struct{}, len(products))
+	for _, product := range products {
+		found[product.ID] = struct{}{}
+	}
+
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+)
+
+// exportPageSize is the number of products read per keyset page while exporting.
+const exportPageSize = 500
+
+// ExportProducts walks all products matching the query filters in creation order, one keyset page at a time,
+// and passes each page to yield so that callers can stream them without holding the whole catalog in memory.
+// The sort order and pagination of the query are ignored. Walking stops at the first error returned by yield.
+func (ps *ProductService) ExportProducts(ctx context.Context, query repository.Query, yield func(products []*model.Product) error) error {
+	query.Sort = repository.Sort{Field: repository.CreatedAtField, Direction: repository.SortAsc}
+	query.Limit = exportPageSize
+	query.Paginator = nil
+	query.IncludeTotal = false
+
+	for {
+		page, err := ps.ListProducts(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		if len(page.Products) > 0 {
+			if err := yield(page.Products); err != nil {
+				return err
+			}
+		}
+
+		if !page.HasNext || len(page.Products) == 0 {
+			return nil
+		}
+
+		last := page.Products[len(page.Products)-1]
+		query.Paginator = &repository.Paginator{
+			LastID:        last.ID,
+			LastCreatedAt: last.CreatedAt,
+			Sort:          query.Sort,
+		}
+	}
+}
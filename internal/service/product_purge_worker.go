@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/iyhunko/microservices-with-sqs/internal/metrics"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+)
+
+// ProductPurgeWorker permanently removes products that stayed soft-deleted longer than the retention period.
+type ProductPurgeWorker struct {
+	productRepo *reposql.ProductRepository
+	retention   time.Duration
+	interval    time.Duration
+}
+
+// NewProductPurgeWorker creates a new ProductPurgeWorker instance.
+func NewProductPurgeWorker(productRepo *reposql.ProductRepository, retention, interval time.Duration) *ProductPurgeWorker {
+	return &ProductPurgeWorker{
+		productRepo: productRepo,
+		retention:   retention,
+		interval:    interval,
+	}
+}
+
+// Start begins the worker loop that purges expired soft-deleted products.
+func (pw *ProductPurgeWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(pw.interval)
+	defer ticker.Stop()
+
+	slog.Info("Product purge worker started", slog.Duration("interval", pw.interval), slog.Duration("retention", pw.retention))
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Product purge worker stopping")
+			return
+		case <-ticker.C:
+			if err := pw.purge(ctx); err != nil {
+				slog.Error("Failed to purge deleted products", slog.Any("err", err))
+			}
+		}
+	}
+}
+
+// purge hard-deletes products soft-deleted before the retention cut-off.
+func (pw *ProductPurgeWorker) purge(ctx context.Context) error {
+	purged, err := pw.productRepo.PurgeDeleted(ctx, time.Now().Add(-pw.retention))
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		metrics.ProductsPurged.Add(float64(purged))
+		slog.Info("Purged deleted products", slog.Int64("count", purged))
+	}
+
+	return nil
+}
@@ -2,12 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
 	"github.com/iyhunko/microservices-with-sqs/internal/metrics"
 	"github.com/iyhunko/microservices-with-sqs/internal/model"
 	"github.com/iyhunko/microservices-with-sqs/internal/repository"
@@ -15,32 +21,138 @@ import (
 	"github.com/iyhunko/microservices-with-sqs/internal/sqs"
 )
 
+var (
+	// ErrProductNotDeleted is returned when restoring a product that is not soft-deleted.
+	ErrProductNotDeleted = errors.New("product is not deleted")
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent again with different request details.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+	// ErrCategoryNotFound is returned when a product refers to a category that does not exist.
+	ErrCategoryNotFound = errors.New("category not found")
+)
+
 // ProductService provides business logic for managing products.
 type ProductService struct {
-	db        *sql.DB
-	repo      repository.Repository
-	eventRepo repository.Repository
-	publisher *sqs.Publisher
+	db                *sql.DB
+	repo              repository.Repository
+	eventRepo         repository.Repository
+	publisher         *sqs.Publisher
+	idempotencyKeyTTL time.Duration
 }
 
 // NewProductService creates a new ProductService with the given DB, repositories, and SQS publisher.
-func NewProductService(db *sql.DB, repo repository.Repository, eventRepo repository.Repository, publisher *sqs.Publisher) *ProductService {
+// Idempotency keys older than idempotencyKeyTTL are no longer replayed.
+func NewProductService(db *sql.DB, repo repository.Repository, eventRepo repository.Repository, publisher *sqs.Publisher, idempotencyKeyTTL time.Duration) *ProductService {
 	return &ProductService{
-		db:        db,
-		repo:      repo,
-		eventRepo: eventRepo,
-		publisher: publisher,
+		db:                db,
+		repo:              repo,
+		eventRepo:         eventRepo,
+		publisher:         publisher,
+		idempotencyKeyTTL: idempotencyKeyTTL,
+	}
+}
+
+// ProductInput describes a product to create.
+type ProductInput struct {
+	Name        string
+	Description string
+	Price       model.Money
+	CategoryID  *uuid.UUID
+	// Tags are expected to be normalized with model.NormalizeTags.
+	Tags []string
+}
+
+// CreateProduct creates a new product with the provided details and stores an event and an audit log entry
+// in the same transaction (outbox pattern).
+// A category that does not exist is reported with ErrCategoryNotFound.
+func (ps *ProductService) CreateProduct(ctx context.Context, input ProductInput) (*model.Product, error) {
+	return ps.createProduct(ctx, input, nil)
+}
+
+// CreateProductIdempotent creates a product like CreateProduct and records the outcome under the given idempotency key
+// in the same transaction. Keys belong to the actor of the request and expire after the configured TTL.
+// Repeating the call with the same key and details returns the originally created product
+// with replayed set to true, while reusing the key for different details fails with ErrIdempotencyKeyReused.
+func (ps *ProductService) CreateProductIdempotent(ctx context.Context, key string, input ProductInput) (product *model.Product, replayed bool, err error) {
+	requestHash, err := hashCreateProductRequest(input)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stored := &model.IdempotencyKey{Actor: audit.FromContext(ctx).Actor, Key: key, RequestHash: requestHash}
+	notBefore := time.Now().Add(-ps.idempotencyKeyTTL)
+
+	product, replayed, err = ps.replayIdempotent(ctx, stored, notBefore)
+	if err != nil || replayed {
+		return product, replayed, err
+	}
+
+	product, err = ps.createProduct(ctx, input, stored)
+	var uniqueErr *repository.UniqueConstraintError
+	if errors.As(err, &uniqueErr) {
+		// A concurrent request with the same key committed first, so answer with its outcome
+		return ps.replayIdempotent(ctx, stored, notBefore)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return product, false, nil
+}
+
+// replayIdempotent looks up the idempotency key of the same actor stored at or after notBefore
+// and returns the product recorded under it. replayed is false when the key has not been used yet or has expired.
+func (ps *ProductService) replayIdempotent(ctx context.Context, key *model.IdempotencyKey, notBefore time.Time) (*model.Product, bool, error) {
+	stored, err := reposql.NewIdempotencyKeyRepository(ps.db).FindByKey(ctx, key.Actor, key.Key, notBefore)
+	if err != nil {
+		var notFoundErr *repository.NotFoundError
+		if errors.As(err, &notFoundErr) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	if stored.RequestHash != key.RequestHash {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+
+	var product model.Product
+	if err := json.Unmarshal(stored.ResponseBody, &product); err != nil {
+		return nil, false, fmt.Errorf("failed to decode stored idempotent response: %w", err)
 	}
+
+	metrics.IdempotentReplays.Inc()
+
+	return &product, true, nil
 }
 
-// CreateProduct creates a new product with the provided details and stores an event in the same transaction (outbox pattern).
-func (ps *ProductService) CreateProduct(ctx context.Context, name, description string, price float64) (*model.Product, error) {
+// hashCreateProductRequest returns a fingerprint of the product details used to detect reuse of an idempotency key.
+func hashCreateProductRequest(input ProductInput) (string, error) {
+	payload, err := json.Marshal(struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description"`
+		Price       model.Money `json:"price"`
+		CategoryID  *uuid.UUID  `json:"category_id"`
+		Tags        []string    `json:"tags"`
+	}{input.Name, input.Description, input.Price, input.CategoryID, input.Tags})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request for hashing: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// createProduct creates a product and its product.created event in one transaction.
+// When idempotencyKey is set, it is stored in the same transaction together with the created product,
+// replacing an expired key of the same actor.
+func (ps *ProductService) createProduct(ctx context.Context, input ProductInput, idempotencyKey *model.IdempotencyKey) (*model.Product, error) {
 	var createdProduct *model.Product
 
 	product := &model.Product{
-		Name:        name,
-		Description: description,
-		Price:       price,
+		Name:        input.Name,
+		Description: input.Description,
+		Price:       input.Price,
+		CategoryID:  input.CategoryID,
+		Tags:        input.Tags,
 	}
 
 	// Start a transaction
@@ -60,16 +172,27 @@ func (ps *ProductService) CreateProduct(ctx context.Context, name, description s
 	txProductRepo := reposql.NewProductRepositoryWithTx(ps.db, tx)
 	txEventRepo := reposql.NewEventRepositoryWithTx(ps.db, tx)
 
+	category, err := findProductCategory(ctx, reposql.NewCategoryRepositoryWithTx(ps.db, tx), product)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create product in the transaction
 	created, err := txProductRepo.Create(ctx, product)
 	if err != nil {
+		err = categoryError(err)
 		return nil, err
 	}
 
 	var ok bool
 	createdProduct, ok = created.(*model.Product)
 	if !ok {
-		return nil, repository.ErrInvalidType
+		err = repository.ErrInvalidType
+		return nil, err
+	}
+
+	if err = reposql.NewProductTagRepositoryWithTx(ps.db, tx).CreateBatch(ctx, []*model.Product{createdProduct}); err != nil {
+		return nil, err
 	}
 
 	// Create event in the same transaction (outbox pattern)
@@ -78,6 +201,7 @@ func (ps *ProductService) CreateProduct(ctx context.Context, name, description s
 		ProductID: createdProduct.ID.String(),
 		Name:      createdProduct.Name,
 		Price:     createdProduct.Price,
+		Category:  categoryMessage(category),
 	}
 	eventData, err := json.Marshal(msg)
 	if err != nil {
@@ -85,9 +209,10 @@ func (ps *ProductService) CreateProduct(ctx context.Context, name, description s
 	}
 
 	event := &model.Event{
-		EventType: "product.created",
-		EventData: eventData,
-		Status:    model.EventStatusPending,
+		EventType:   "product.created",
+		EventData:   eventData,
+		Status:      model.EventStatusPending,
+		AggregateID: createdProduct.ID,
 	}
 
 	_, err = txEventRepo.Create(ctx, event)
@@ -95,6 +220,22 @@ func (ps *ProductService) CreateProduct(ctx context.Context, name, description s
 		return nil, err
 	}
 
+	if err = ps.recordProductAudit(ctx, tx, audit.ActionCreate, createdProduct.ID, nil, snapshotProduct(createdProduct)); err != nil {
+		return nil, err
+	}
+
+	// Record the outcome under the idempotency key in the same transaction
+	if idempotencyKey != nil {
+		idempotencyKey.ResourceID = createdProduct.ID
+		idempotencyKey.ResponseBody, err = json.Marshal(createdProduct)
+		if err != nil {
+			return nil, err
+		}
+		if err = reposql.NewIdempotencyKeyRepositoryWithTx(ps.db, tx).Create(ctx, idempotencyKey, time.Now().Add(-ps.idempotencyKeyTTL)); err != nil {
+			return nil, err
+		}
+	}
+
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -106,8 +247,312 @@ func (ps *ProductService) CreateProduct(ctx context.Context, name, description s
 	return createdProduct, nil
 }
 
-// DeleteProduct deletes a product by ID and stores an event in the same transaction (outbox pattern).
-func (ps *ProductService) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+// ProductUpdate describes the product fields to change. Nil fields are left untouched.
+type ProductUpdate struct {
+	Name        *string
+	Description *string
+	Price       *model.Money
+	CategoryID  *uuid.UUID
+	// ClearCategory removes the product from its category. It is ignored when CategoryID is set.
+	ClearCategory bool
+	// Tags replace all tags of the product and are expected to be normalized with model.NormalizeTags.
+	Tags *[]string
+}
+
+// UpdateProduct applies the given changes to a product and stores a product.updated event
+// carrying the old and new field values in the same transaction (outbox pattern).
+// A price change is also recorded in the price history together with a product.price_changed event,
+// and the change is recorded in the audit log with the product state before and after it.
+// If expectedVersion is set, the update is rejected with repository.ErrVersionConflict unless it matches the stored version.
+// A category that does not exist is reported with ErrCategoryNotFound.
+func (ps *ProductService) UpdateProduct(ctx context.Context, id uuid.UUID, update ProductUpdate, expectedVersion *int64) (*model.Product, error) {
+	// Start a transaction
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	// Create transactional repositories
+	txProductRepo := reposql.NewProductRepositoryWithTx(ps.db, tx)
+	txEventRepo := reposql.NewEventRepositoryWithTx(ps.db, tx)
+
+	// Lock the product first to know the values being replaced; concurrent writes wait until this one commits,
+	// so that a request without a precondition is never rejected for a change made after the product was read
+	product, err := txProductRepo.LockByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if expectedVersion != nil && *expectedVersion != product.Version {
+		err = repository.ErrVersionConflict
+		return nil, err
+	}
+
+	txTagRepo := reposql.NewProductTagRepositoryWithTx(ps.db, tx)
+	if err = attachTags(ctx, txTagRepo, product); err != nil {
+		return nil, err
+	}
+
+	oldPrice := product.Price
+	before := snapshotProduct(product)
+	changes := applyProductUpdate(product, update)
+	if len(changes) == 0 {
+		// Nothing to write, so there is nothing to announce either
+		if err = tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return product, nil
+	}
+
+	category, err := findProductCategory(ctx, reposql.NewCategoryRepositoryWithTx(ps.db, tx), product)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = txProductRepo.Update(ctx, product); err != nil {
+		err = categoryError(err)
+		return nil, err
+	}
+
+	if _, ok := changes["tags"]; ok {
+		if err = txTagRepo.Replace(ctx, product); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create event in the same transaction (outbox pattern)
+	msg := sqs.ProductMessage{
+		Action:    "updated",
+		ProductID: product.ID.String(),
+		Name:      product.Name,
+		Price:     product.Price,
+		Category:  categoryMessage(category),
+		Changes:   changes,
+	}
+	eventData, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &model.Event{
+		EventType:   "product.updated",
+		EventData:   eventData,
+		Status:      model.EventStatusPending,
+		AggregateID: product.ID,
+	}
+
+	_, err = txEventRepo.Create(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.Price != oldPrice {
+		if err = ps.recordPriceChange(ctx, tx, product, oldPrice, category); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = ps.recordProductAudit(ctx, tx, audit.ActionUpdate, product.ID, before, snapshotProduct(product)); err != nil {
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Increment metrics
+	metrics.ProductsUpdated.Inc()
+
+	return product, nil
+}
+
+// recordPriceChange stores a price history entry and a product.price_changed event for a product whose price
+// changed from oldPrice, within the given transaction.
+func (ps *ProductService) recordPriceChange(ctx context.Context, tx *sql.Tx, product *model.Product, oldPrice model.Money,
+	category *model.Category,
+) error {
+	entry := &model.PriceHistoryEntry{
+		ProductID: product.ID,
+		OldPrice:  oldPrice,
+		NewPrice:  product.Price,
+	}
+	if err := reposql.NewPriceHistoryRepositoryWithTx(ps.db, tx).Create(ctx, entry); err != nil {
+		return err
+	}
+
+	msg := sqs.ProductMessage{
+		Action:    "price_changed",
+		ProductID: product.ID.String(),
+		Name:      product.Name,
+		Price:     product.Price,
+		Category:  categoryMessage(category),
+		PriceChange: &sqs.PriceChange{
+			OldPrice:      entry.OldPrice,
+			NewPrice:      entry.NewPrice,
+			PercentChange: entry.PercentChange(),
+		},
+	}
+	eventData, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = reposql.NewEventRepositoryWithTx(ps.db, tx).Create(ctx, &model.Event{
+		EventType:   "product.price_changed",
+		EventData:   eventData,
+		Status:      model.EventStatusPending,
+		AggregateID: product.ID,
+	})
+	return err
+}
+
+// PriceHistoryPage represents one page of the price changes of a product.
+type PriceHistoryPage struct {
+	Entries []*model.PriceHistoryEntry
+	HasNext bool
+	HasPrev bool
+}
+
+// ListPriceHistory retrieves a page of the price changes of a product. The product must not be deleted.
+func (ps *ProductService) ListPriceHistory(ctx context.Context, productID uuid.UUID, query repository.Query) (*PriceHistoryPage, error) {
+	if _, err := ps.repo.FindByID(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	query.With(repository.ProductIDField, productID.String())
+	page, err := reposql.NewPriceHistoryRepository(ps.db).ListPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*model.PriceHistoryEntry, 0, len(page.Resources))
+	for _, resource := range page.Resources {
+		entry, ok := resource.(*model.PriceHistoryEntry)
+		if !ok {
+			return nil, repository.ErrInvalidType
+		}
+		entries = append(entries, entry)
+	}
+
+	return &PriceHistoryPage{
+		Entries: entries,
+		HasNext: page.HasNext,
+		HasPrev: page.HasPrev,
+	}, nil
+}
+
+// applyProductUpdate copies the requested values onto the product and returns the fields that actually changed.
+func applyProductUpdate(product *model.Product, update ProductUpdate) map[string]sqs.FieldChange {
+	changes := map[string]sqs.FieldChange{}
+
+	if update.Name != nil && *update.Name != product.Name {
+		changes["name"] = sqs.FieldChange{Old: product.Name, New: *update.Name}
+		product.Name = *update.Name
+	}
+	if update.Description != nil && *update.Description != product.Description {
+		changes["description"] = sqs.FieldChange{Old: product.Description, New: *update.Description}
+		product.Description = *update.Description
+	}
+	if update.Price != nil && *update.Price != product.Price {
+		changes["price"] = sqs.FieldChange{Old: product.Price, New: *update.Price}
+		product.Price = *update.Price
+	}
+	categoryID := product.CategoryID
+	if update.CategoryID != nil {
+		categoryID = update.CategoryID
+	} else if update.ClearCategory {
+		categoryID = nil
+	}
+	if !equalUUIDPointers(categoryID, product.CategoryID) {
+		changes["category_id"] = sqs.FieldChange{Old: product.CategoryID, New: categoryID}
+		product.CategoryID = categoryID
+	}
+	if update.Tags != nil && !slices.Equal(*update.Tags, product.Tags) {
+		changes["tags"] = sqs.FieldChange{Old: product.Tags, New: *update.Tags}
+		product.Tags = *update.Tags
+	}
+
+	return changes
+}
+
+// equalUUIDPointers reports whether both IDs are nil or point to equal values.
+func equalUUIDPointers(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// findProductCategory returns the category of the product, or nil if it has none.
+// A category that does not exist is reported with ErrCategoryNotFound.
+func findProductCategory(ctx context.Context, categoryRepo *reposql.CategoryRepository, product *model.Product) (*model.Category, error) {
+	if product.CategoryID == nil {
+		return nil, nil //nolint:nilnil // products without a category are valid
+	}
+
+	resource, err := categoryRepo.FindByID(ctx, *product.CategoryID)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+
+	category, ok := resource.(*model.Category)
+	if !ok {
+		return nil, repository.ErrInvalidType
+	}
+	return category, nil
+}
+
+// categoryError translates a missing category reported by the repositories into ErrCategoryNotFound.
+func categoryError(err error) error {
+	var notFoundErr *repository.NotFoundError
+	if errors.As(err, &notFoundErr) && notFoundErr.Resource == "category" {
+		return ErrCategoryNotFound
+	}
+	return err
+}
+
+// categoryMessage returns the category reference carried by product messages, or nil for products without a category.
+func categoryMessage(category *model.Category) *sqs.ProductCategory {
+	if category == nil {
+		return nil
+	}
+	return &sqs.ProductCategory{ID: category.ID.String(), Name: category.Name}
+}
+
+// attachTags loads the tags of the given products from the product_tags table.
+func attachTags(ctx context.Context, tagRepo *reposql.ProductTagRepository, products ...*model.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+
+	tags, err := tagRepo.ListByProductIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		product.Tags = tags[product.ID]
+	}
+	return nil
+}
+
+// DeleteProduct soft-deletes a product by ID and stores an event and an audit log entry in the same transaction (outbox pattern).
+// The row is kept until the purge worker removes it, so it can be brought back with RestoreProduct.
+// If expectedVersion is set, the deletion is rejected with repository.ErrVersionConflict unless it matches the stored version.
+func (ps *ProductService) DeleteProduct(ctx context.Context, id uuid.UUID, expectedVersion *int64) error {
 	var product *model.Product
 
 	// Start a transaction
@@ -127,19 +572,25 @@ func (ps *ProductService) DeleteProduct(ctx context.Context, id uuid.UUID) error
 	txProductRepo := reposql.NewProductRepositoryWithTx(ps.db, tx)
 	txEventRepo := reposql.NewEventRepositoryWithTx(ps.db, tx)
 
-	// Find the product first to get its details for the message
-	resource, err := txProductRepo.FindByID(ctx, id)
+	// Lock the product first to get its details for the message, so that concurrent writes wait until this one commits
+	product, err = txProductRepo.LockByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	product, ok := resource.(*model.Product)
-	if !ok {
-		return repository.ErrInvalidType
+	if expectedVersion != nil && *expectedVersion != product.Version {
+		err = repository.ErrVersionConflict
+		return err
+	}
+
+	category, err := findProductCategory(ctx, reposql.NewCategoryRepositoryWithTx(ps.db, tx), product)
+	if err != nil {
+		return err
 	}
 
-	// Delete the product
-	if err = txProductRepo.DeleteByID(ctx, id); err != nil {
+	// Mark the product as deleted
+	before := snapshotProduct(product)
+	if err = txProductRepo.SoftDelete(ctx, product); err != nil {
 		return err
 	}
 
@@ -149,6 +600,7 @@ func (ps *ProductService) DeleteProduct(ctx context.Context, id uuid.UUID) error
 		ProductID: product.ID.String(),
 		Name:      product.Name,
 		Price:     product.Price,
+		Category:  categoryMessage(category),
 	}
 	eventData, err := json.Marshal(msg)
 	if err != nil {
@@ -156,9 +608,10 @@ func (ps *ProductService) DeleteProduct(ctx context.Context, id uuid.UUID) error
 	}
 
 	event := &model.Event{
-		EventType: "product.deleted",
-		EventData: eventData,
-		Status:    model.EventStatusPending,
+		EventType:   "product.deleted",
+		EventData:   eventData,
+		Status:      model.EventStatusPending,
+		AggregateID: product.ID,
 	}
 
 	_, err = txEventRepo.Create(ctx, event)
@@ -166,6 +619,10 @@ func (ps *ProductService) DeleteProduct(ctx context.Context, id uuid.UUID) error
 		return err
 	}
 
+	if err = ps.recordProductAudit(ctx, tx, audit.ActionDelete, product.ID, before, nil); err != nil {
+		return err
+	}
+
 	// Commit the transaction
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -177,15 +634,135 @@ func (ps *ProductService) DeleteProduct(ctx context.Context, id uuid.UUID) error
 	return nil
 }
 
-// ListProducts retrieves a list of products matching the given query criteria.
-func (ps *ProductService) ListProducts(ctx context.Context, query repository.Query) ([]*model.Product, error) {
-	resources, err := ps.repo.List(ctx, query)
+// RestoreProduct brings back a soft-deleted product and stores a product.restored event and an audit log entry
+// in the same transaction (outbox pattern).
+// It returns ErrProductNotDeleted if the product is not deleted. If expectedVersion is set,
+// the restore is rejected with repository.ErrVersionConflict unless it matches the stored version.
+func (ps *ProductService) RestoreProduct(ctx context.Context, id uuid.UUID, expectedVersion *int64) (*model.Product, error) {
+	// Start a transaction
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	// Create transactional repositories
+	txProductRepo := reposql.NewProductRepositoryWithTx(ps.db, tx)
+	txEventRepo := reposql.NewEventRepositoryWithTx(ps.db, tx)
+
+	product, err := txProductRepo.LockByIDIncludingDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.DeletedAt == nil {
+		err = ErrProductNotDeleted
+		return nil, err
+	}
+
+	if expectedVersion != nil && *expectedVersion != product.Version {
+		err = repository.ErrVersionConflict
+		return nil, err
+	}
+
+	before := snapshotProduct(product)
+	if err = txProductRepo.Restore(ctx, product); err != nil {
+		return nil, err
+	}
+
+	if err = attachTags(ctx, reposql.NewProductTagRepositoryWithTx(ps.db, tx), product); err != nil {
+		return nil, err
+	}
+	// Restoring leaves the tags untouched
+	before.Tags = product.Tags
+
+	category, err := findProductCategory(ctx, reposql.NewCategoryRepositoryWithTx(ps.db, tx), product)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create event in the same transaction (outbox pattern)
+	msg := sqs.ProductMessage{
+		Action:    "restored",
+		ProductID: product.ID.String(),
+		Name:      product.Name,
+		Price:     product.Price,
+		Category:  categoryMessage(category),
+	}
+	eventData, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &model.Event{
+		EventType:   "product.restored",
+		EventData:   eventData,
+		Status:      model.EventStatusPending,
+		AggregateID: product.ID,
+	}
+
+	_, err = txEventRepo.Create(ctx, event)
 	if err != nil {
 		return nil, err
 	}
 
-	products := make([]*model.Product, 0, len(resources))
-	for _, resource := range resources {
+	if err = ps.recordProductAudit(ctx, tx, audit.ActionRestore, product.ID, before, snapshotProduct(product)); err != nil {
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Increment metrics
+	metrics.ProductsRestored.Inc()
+
+	return product, nil
+}
+
+// GetProduct retrieves a single product by ID.
+func (ps *ProductService) GetProduct(ctx context.Context, id uuid.UUID) (*model.Product, error) {
+	resource, err := ps.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	product, ok := resource.(*model.Product)
+	if !ok {
+		return nil, repository.ErrInvalidType
+	}
+
+	if err := attachTags(ctx, reposql.NewProductTagRepository(ps.db), product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// ProductPage represents one page of listed products.
+type ProductPage struct {
+	Products   []*model.Product
+	HasNext    bool
+	HasPrev    bool
+	TotalCount *int64
+}
+
+// ListProducts retrieves a page of products matching the given query criteria.
+func (ps *ProductService) ListProducts(ctx context.Context, query repository.Query) (*ProductPage, error) {
+	page, err := ps.repo.ListPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]*model.Product, 0, len(page.Resources))
+	for _, resource := range page.Resources {
 		product, ok := resource.(*model.Product)
 		if !ok {
 			return nil, repository.ErrInvalidType
@@ -193,5 +770,19 @@ func (ps *ProductService) ListProducts(ctx context.Context, query repository.Que
 		products = append(products, product)
 	}
 
-	return products, nil
+	if err := attachTags(ctx, reposql.NewProductTagRepository(ps.db), products...); err != nil {
+		return nil, err
+	}
+
+	return &ProductPage{
+		Products:   products,
+		HasNext:    page.HasNext,
+		HasPrev:    page.HasPrev,
+		TotalCount: page.TotalCount,
+	}, nil
+}
+
+// FindCategories returns the categories with the given IDs keyed by ID. IDs of missing categories are left out.
+func (ps *ProductService) FindCategories(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*model.Category, error) {
+	return reposql.NewCategoryRepository(ps.db).FindByIDs(ctx, ids)
 }
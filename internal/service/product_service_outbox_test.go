@@ -3,15 +3,19 @@ package service_test
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
 	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
 	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
 	"github.com/iyhunko/microservices-with-sqs/internal/service"
 	"github.com/iyhunko/microservices-with-sqs/internal/sqs"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -32,7 +36,7 @@ func TestCreateProduct_OutboxPattern(t *testing.T) {
 	eventRepo := reposql.NewEventRepository(db)
 
 	// Create service with DB (to enable outbox pattern)
-	productService := service.NewProductService(db, productRepo, eventRepo, nil)
+	productService := service.NewProductService(db, productRepo, eventRepo, nil, time.Hour)
 
 	// Expect a transaction to begin
 	mock.ExpectBegin()
@@ -40,20 +44,28 @@ func TestCreateProduct_OutboxPattern(t *testing.T) {
 	// Expect product insertion
 	mock.ExpectPrepare("INSERT INTO products").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), "Test Product", "Test Description", 99.99, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), "Test Product", "Test Description", "99.99", "USD", nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Expect event insertion (within same transaction)
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectPrepare("INSERT INTO events").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), "product.created", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil).
+		WithArgs(sqlmock.AnyArg(), "product.created", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Expect the audit log entry of an anonymous creation without a before snapshot
+	mock.ExpectPrepare("INSERT INTO audit_log").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "anonymous", "create", "product", sqlmock.AnyArg(), nil, sqlmock.AnyArg(), "", "", sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Expect transaction commit
 	mock.ExpectCommit()
 
 	// Execute the product creation
-	product, err := productService.CreateProduct(ctx, "Test Product", "Test Description", 99.99)
+	product, err := productService.CreateProduct(ctx, service.ProductInput{Name: "Test Product", Description: "Test Description", Price: testPrice})
 
 	// Verify results
 	require.NoError(t, err)
@@ -79,37 +91,46 @@ func TestDeleteProduct_OutboxPattern(t *testing.T) {
 	eventRepo := reposql.NewEventRepository(db)
 
 	// Create service with DB (to enable outbox pattern)
-	productService := service.NewProductService(db, productRepo, eventRepo, nil)
+	productService := service.NewProductService(db, productRepo, eventRepo, nil, time.Hour)
 
 	// Expect a transaction to begin
 	mock.ExpectBegin()
 
 	// Expect product lookup
 	now := time.Now()
-	rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at"}).
-		AddRow(productID, "Test Product", "Test Description", 99.99, now, now)
-	mock.ExpectPrepare("SELECT \\* FROM products WHERE id").
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+		AddRow(productID, "Test Product", "Test Description", 99.99, now, now, int64(1), nil, "USD", nil)
+	mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
 		ExpectQuery().
 		WithArgs(productID).
 		WillReturnRows(rows)
 
-	// Expect product deletion
-	mock.ExpectPrepare("DELETE FROM products WHERE id").
+	// Expect the product to be soft-deleted, guarded by the version that was read
+	mock.ExpectPrepare("UPDATE products SET deleted_at = \\$1").
 		ExpectExec().
-		WithArgs(productID).
+		WithArgs(sqlmock.AnyArg(), productID, int64(1)).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Expect event insertion (within same transaction)
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs(productID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectPrepare("INSERT INTO events").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), "product.deleted", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil).
+		WithArgs(sqlmock.AnyArg(), "product.deleted", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil, productID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Expect the audit log entry of the deletion without an after snapshot
+	mock.ExpectPrepare("INSERT INTO audit_log").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "anonymous", "delete", "product", productID, sqlmock.AnyArg(), nil, "", "", sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Expect transaction commit
 	mock.ExpectCommit()
 
 	// Execute the product deletion
-	err = productService.DeleteProduct(ctx, productID)
+	err = productService.DeleteProduct(ctx, productID, nil)
 
 	// Verify results
 	require.NoError(t, err)
@@ -118,6 +139,273 @@ func TestDeleteProduct_OutboxPattern(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+// TestRestoreProduct_OutboxPattern verifies that restoring a soft-deleted product and
+// creating the product.restored event happen within the same transaction.
+func TestRestoreProduct_OutboxPattern(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	productID := uuid.New()
+
+	productRepo := reposql.NewProductRepository(db)
+	eventRepo := reposql.NewEventRepository(db)
+	productService := service.NewProductService(db, productRepo, eventRepo, nil, time.Hour)
+
+	mock.ExpectBegin()
+
+	// Expect lookup of the deleted product
+	now := time.Now()
+	deletedAt := now.Add(-time.Hour)
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+		AddRow(productID, "Test Product", "Test Description", 99.99, now, now, int64(2), deletedAt, "USD", nil)
+	mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1 FOR UPDATE$").
+		ExpectQuery().
+		WithArgs(productID).
+		WillReturnRows(rows)
+
+	// Expect the deleted mark to be cleared
+	mock.ExpectPrepare("UPDATE products SET deleted_at = NULL").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), productID, int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Expect the tags to be loaded for the response
+	mock.ExpectPrepare("SELECT product_id, tag FROM product_tags").
+		ExpectQuery().
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "tag"}))
+
+	// Expect event insertion (within same transaction)
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs(productID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO events").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "product.restored", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil, productID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("INSERT INTO audit_log").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "anonymous", "restore", "product", productID, sqlmock.AnyArg(), sqlmock.AnyArg(), "", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectCommit()
+
+	product, err := productService.RestoreProduct(ctx, productID, nil)
+
+	require.NoError(t, err)
+	assert.Nil(t, product.DeletedAt)
+	assert.Equal(t, int64(3), product.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRestoreProduct_NotDeleted verifies that restoring a product that is not deleted
+// is rejected and the transaction is rolled back.
+func TestRestoreProduct_NotDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	productID := uuid.New()
+
+	productRepo := reposql.NewProductRepository(db)
+	eventRepo := reposql.NewEventRepository(db)
+	productService := service.NewProductService(db, productRepo, eventRepo, nil, time.Hour)
+
+	mock.ExpectBegin()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+		AddRow(productID, "Test Product", "Test Description", 99.99, now, now, int64(1), nil, "USD", nil)
+	mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1 FOR UPDATE$").
+		ExpectQuery().
+		WithArgs(productID).
+		WillReturnRows(rows)
+
+	// Expect rollback, no update and no event
+	mock.ExpectRollback()
+
+	product, err := productService.RestoreProduct(ctx, productID, nil)
+
+	require.ErrorIs(t, err, service.ErrProductNotDeleted)
+	assert.Nil(t, product)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestUpdateProduct_OutboxPattern verifies that product update and event creation
+// happen within the same transaction and the event carries the changed fields.
+func TestUpdateProduct_OutboxPattern(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := audit.WithMetadata(context.Background(), audit.Metadata{Actor: "alice", RequestID: "req-1", ClientIP: "203.0.113.7"})
+	productID := uuid.New()
+
+	// Create repositories
+	productRepo := reposql.NewProductRepository(db)
+	eventRepo := reposql.NewEventRepository(db)
+
+	// Create service with DB (to enable outbox pattern)
+	productService := service.NewProductService(db, productRepo, eventRepo, nil, time.Hour)
+
+	// Expect a transaction to begin
+	mock.ExpectBegin()
+
+	// Expect product lookup
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+		AddRow(productID, "Test Product", "Test Description", 99.99, now, now, int64(1), nil, "USD", nil)
+	mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		ExpectQuery().
+		WithArgs(productID).
+		WillReturnRows(rows)
+
+	// Expect the current tags to be loaded
+	mock.ExpectPrepare("SELECT product_id, tag FROM product_tags").
+		ExpectQuery().
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "tag"}))
+
+	// Expect product update
+	mock.ExpectPrepare("UPDATE products SET").
+		ExpectExec().
+		WithArgs("Test Product", "Test Description", "79.99", "USD", nil, sqlmock.AnyArg(), productID, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Expect event insertion (within same transaction)
+	var eventData eventDataCapture
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs(productID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO events").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "product.updated", &eventData, string(model.EventStatusPending), sqlmock.AnyArg(), nil, productID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Expect the price change to be recorded with its own event
+	mock.ExpectPrepare("INSERT INTO product_price_history").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), productID, "99.99", "USD", "79.99", "USD", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	var priceEventData eventDataCapture
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs(productID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO events").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "product.price_changed", &priceEventData, string(model.EventStatusPending), sqlmock.AnyArg(), nil, productID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Expect the audit log entry carrying the request metadata and both product states
+	var before, after snapshotCapture
+	mock.ExpectPrepare("INSERT INTO audit_log").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "alice", "update", "product", productID, &before, &after, "req-1", "203.0.113.7", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Expect transaction commit
+	mock.ExpectCommit()
+
+	// Execute the product update
+	newPrice := model.Money{Amount: 7999, Currency: "USD"}
+	product, err := productService.UpdateProduct(ctx, productID, service.ProductUpdate{Price: &newPrice}, nil)
+
+	// Verify results
+	require.NoError(t, err)
+	assert.Equal(t, newPrice, product.Price)
+	assert.Equal(t, int64(2), product.Version)
+
+	// Verify the event carries old and new values of the changed field only
+	require.Len(t, eventData.msg.Changes, 1)
+	assert.Equal(t, "updated", eventData.msg.Action)
+	assert.Equal(t, map[string]any{"amount": "99.99", "currency": "USD"}, eventData.msg.Changes["price"].Old)
+	assert.Equal(t, map[string]any{"amount": "79.99", "currency": "USD"}, eventData.msg.Changes["price"].New)
+
+	// Verify the price change event carries both prices and the percentage
+	assert.Equal(t, "price_changed", priceEventData.msg.Action)
+	require.NotNil(t, priceEventData.msg.PriceChange)
+	assert.Equal(t, model.Money{Amount: 9999, Currency: "USD"}, priceEventData.msg.PriceChange.OldPrice)
+	assert.Equal(t, newPrice, priceEventData.msg.PriceChange.NewPrice)
+	require.NotNil(t, priceEventData.msg.PriceChange.PercentChange)
+	assert.InDelta(t, -20.0, *priceEventData.msg.PriceChange.PercentChange, 0.001)
+
+	// Verify the audit snapshots hold the product before and after the update
+	assert.Equal(t, map[string]any{"amount": "99.99", "currency": "USD"}, before.snapshot["price"])
+	assert.InDelta(t, 1, before.snapshot["version"], 0)
+	assert.Equal(t, map[string]any{"amount": "79.99", "currency": "USD"}, after.snapshot["price"])
+	assert.InDelta(t, 2, after.snapshot["version"], 0)
+
+	// Verify all expectations were met
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// eventDataCapture is a sqlmock argument matcher that decodes the event payload for later assertions.
+type eventDataCapture struct {
+	msg sqs.ProductMessage
+}
+
+// Match implements sqlmock.Argument.
+func (e *eventDataCapture) Match(v driver.Value) bool {
+	data, ok := v.([]byte)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, &e.msg) == nil
+}
+
+// snapshotCapture is a sqlmock argument matcher that decodes an audit log snapshot for later assertions.
+type snapshotCapture struct {
+	snapshot map[string]any
+}
+
+// Match implements sqlmock.Argument.
+func (s *snapshotCapture) Match(v driver.Value) bool {
+	data, ok := v.(string)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal([]byte(data), &s.snapshot) == nil
+}
+
+// TestUpdateProduct_VersionMismatch verifies that an update with a stale expected version
+// is rejected before anything is written and the transaction is rolled back.
+func TestUpdateProduct_VersionMismatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	productID := uuid.New()
+
+	productRepo := reposql.NewProductRepository(db)
+	eventRepo := reposql.NewEventRepository(db)
+	productService := service.NewProductService(db, productRepo, eventRepo, nil, time.Hour)
+
+	mock.ExpectBegin()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+		AddRow(productID, "Test Product", "Test Description", 99.99, now, now, int64(3), nil, "USD", nil)
+	mock.ExpectPrepare("SELECT \\* FROM products WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		ExpectQuery().
+		WithArgs(productID).
+		WillReturnRows(rows)
+
+	// Expect rollback, no update and no event
+	mock.ExpectRollback()
+
+	newName := "New Name"
+	staleVersion := int64(2)
+	product, err := productService.UpdateProduct(ctx, productID, service.ProductUpdate{Name: &newName}, &staleVersion)
+
+	require.ErrorIs(t, err, repository.ErrVersionConflict)
+	assert.Nil(t, product)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 // TestCreateProduct_OutboxPattern_Rollback verifies that when an error occurs
 // during event creation, the entire transaction is rolled back.
 func TestCreateProduct_OutboxPattern_Rollback(t *testing.T) {
@@ -132,7 +420,7 @@ func TestCreateProduct_OutboxPattern_Rollback(t *testing.T) {
 	eventRepo := reposql.NewEventRepository(db)
 
 	// Create service with DB (to enable outbox pattern)
-	productService := service.NewProductService(db, productRepo, eventRepo, nil)
+	productService := service.NewProductService(db, productRepo, eventRepo, nil, time.Hour)
 
 	// Expect a transaction to begin
 	mock.ExpectBegin()
@@ -140,20 +428,22 @@ func TestCreateProduct_OutboxPattern_Rollback(t *testing.T) {
 	// Expect product insertion to succeed
 	mock.ExpectPrepare("INSERT INTO products").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), "Test Product", "Test Description", 99.99, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), "Test Product", "Test Description", "99.99", "USD", nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Expect event insertion to fail
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectPrepare("INSERT INTO events").
 		ExpectExec().
-		WithArgs(sqlmock.AnyArg(), "product.created", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil).
+		WithArgs(sqlmock.AnyArg(), "product.created", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
 		WillReturnError(sql.ErrConnDone)
 
 	// Expect transaction rollback (not commit)
 	mock.ExpectRollback()
 
 	// Execute the product creation
-	product, err := productService.CreateProduct(ctx, "Test Product", "Test Description", 99.99)
+	product, err := productService.CreateProduct(ctx, service.ProductInput{Name: "Test Product", Description: "Test Description", Price: testPrice})
 
 	// Verify that creation failed
 	require.Error(t, err)
@@ -163,13 +453,294 @@ func TestCreateProduct_OutboxPattern_Rollback(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+// TestCreateProductIdempotent_OutboxPattern verifies that the idempotency key is stored
+// in the same transaction as the product and its event.
+func TestCreateProductIdempotent_OutboxPattern(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := audit.WithMetadata(context.Background(), audit.Metadata{Actor: "alice"})
+	productService := service.NewProductService(db, reposql.NewProductRepository(db), reposql.NewEventRepository(db), nil, time.Hour)
+
+	requestHash, err := service.HashCreateProductRequest(service.ProductInput{Name: "Test Product", Description: "Test Description", Price: testPrice})
+	require.NoError(t, err)
+
+	// Expect the key to be looked up before anything is written
+	mock.ExpectPrepare("SELECT .* FROM idempotency_keys\\s+WHERE actor = \\$1 AND idempotency_key = \\$2 AND created_at >= \\$3").
+		ExpectQuery().
+		WithArgs("alice", "key-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(idempotencyKeyColumns))
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO products").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "Test Product", "Test Description", "99.99", "USD", nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO events").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "product.created", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("INSERT INTO audit_log").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectPrepare("INSERT INTO idempotency_keys").
+		ExpectExec().
+		WithArgs("alice", "key-1", requestHash, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	product, replayed, err := productService.CreateProductIdempotent(ctx, "key-1", service.ProductInput{Name: "Test Product", Description: "Test Description", Price: testPrice})
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, "Test Product", product.Name)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateProductIdempotent_Replay verifies that a repeated request returns the stored product without writing.
+func TestCreateProductIdempotent_Replay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := audit.WithMetadata(context.Background(), audit.Metadata{Actor: "alice"})
+	productService := service.NewProductService(db, reposql.NewProductRepository(db), reposql.NewEventRepository(db), nil, time.Hour)
+
+	requestHash, err := service.HashCreateProductRequest(service.ProductInput{Name: "Test Product", Description: "Test Description", Price: testPrice})
+	require.NoError(t, err)
+
+	stored := model.Product{ID: uuid.New(), Name: "Test Product", Description: "Test Description", Price: model.Money{Amount: 9999, Currency: "USD"}, Version: 1}
+	storedBody, err := json.Marshal(stored)
+	require.NoError(t, err)
+
+	mock.ExpectPrepare("SELECT .* FROM idempotency_keys\\s+WHERE actor = \\$1 AND idempotency_key = \\$2 AND created_at >= \\$3").
+		ExpectQuery().
+		WithArgs("alice", "key-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(idempotencyKeyColumns).
+			AddRow("alice", "key-1", requestHash, stored.ID, storedBody, time.Now()))
+
+	product, replayed, err := productService.CreateProductIdempotent(ctx, "key-1", service.ProductInput{Name: "Test Product", Description: "Test Description", Price: testPrice})
+	require.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, stored.ID, product.ID)
+	assert.Equal(t, int64(1), product.Version)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateProductIdempotent_KeyReused verifies that reusing a key for a different request is rejected.
+func TestCreateProductIdempotent_KeyReused(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := audit.WithMetadata(context.Background(), audit.Metadata{Actor: "alice"})
+	productService := service.NewProductService(db, reposql.NewProductRepository(db), reposql.NewEventRepository(db), nil, time.Hour)
+
+	requestHash, err := service.HashCreateProductRequest(service.ProductInput{Name: "Test Product", Description: "Test Description", Price: testPrice})
+	require.NoError(t, err)
+
+	mock.ExpectPrepare("SELECT .* FROM idempotency_keys\\s+WHERE actor = \\$1 AND idempotency_key = \\$2 AND created_at >= \\$3").
+		ExpectQuery().
+		WithArgs("alice", "key-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(idempotencyKeyColumns).
+			AddRow("alice", "key-1", requestHash, uuid.New(), []byte(`{}`), time.Now()))
+
+	product, replayed, err := productService.CreateProductIdempotent(ctx, "key-1", service.ProductInput{Name: "Other Product", Description: "Test Description", Price: testPrice})
+	require.ErrorIs(t, err, service.ErrIdempotencyKeyReused)
+	assert.False(t, replayed)
+	assert.Nil(t, product)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateProducts_OutboxPattern verifies that a batch of products and one event per product
+// are written with multi-row inserts in a single transaction.
+func TestCreateProducts_OutboxPattern(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	productService := service.NewProductService(db, reposql.NewProductRepository(db), reposql.NewEventRepository(db), nil, time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO products .*\\$18\\)$").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO events .*\\$14\\)$").
+		ExpectExec().
+		WithArgs(
+			sqlmock.AnyArg(), "product.created", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil, sqlmock.AnyArg(),
+			sqlmock.AnyArg(), "product.created", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil, sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare("INSERT INTO audit_log .*\\$20\\)$").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	products, itemErrs, err := productService.CreateProducts(ctx, []service.ProductInput{
+		{Name: "Keyboard", Price: model.Money{Amount: 4999, Currency: "USD"}},
+		{Name: "Mouse", Price: model.Money{Amount: 1999, Currency: "USD"}},
+	}, true)
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, "Mouse", products[1].Name)
+	assert.Equal(t, []error{nil, nil}, itemErrs)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreateProducts_BestEffort verifies that a best-effort batch falls back to inserting the products one at
+// a time under savepoints when the multi-row insert fails, and skips only the products the database rejects.
+func TestCreateProducts_BestEffort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	productService := service.NewProductService(db, reposql.NewProductRepository(db), reposql.NewEventRepository(db), nil, time.Hour)
+	foreignKeyErr := &pgconn.PgError{Code: "23503"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO products .*\\$18\\)$").
+		ExpectExec().
+		WillReturnError(foreignKeyErr)
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	// The first product is stored on its own
+	mock.ExpectExec("SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO products .*\\$9\\)$").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "Keyboard", "", "49.99", "USD", nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	// The second one is rejected
+	mock.ExpectExec("SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO products .*\\$9\\)$").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "Mouse", "", "19.99", "USD", nil, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
+		WillReturnError(foreignKeyErr)
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO events .*\\$7\\)$").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "product.created", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO audit_log .*\\$10\\)$").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	products, itemErrs, err := productService.CreateProducts(ctx, []service.ProductInput{
+		{Name: "Keyboard", Price: model.Money{Amount: 4999, Currency: "USD"}},
+		{Name: "Mouse", Price: model.Money{Amount: 1999, Currency: "USD"}},
+	}, false)
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	require.NotNil(t, products[0])
+	assert.Equal(t, "Keyboard", products[0].Name)
+	assert.NoError(t, itemErrs[0])
+	assert.Nil(t, products[1])
+	assert.ErrorIs(t, itemErrs[1], service.ErrCategoryNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDeleteProducts_AtomicNotFound verifies that an atomic batch delete is rolled back
+// when some of the products do not exist.
+func TestDeleteProducts_AtomicNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	productService := service.NewProductService(db, reposql.NewProductRepository(db), reposql.NewEventRepository(db), nil, time.Hour)
+
+	existing, missing := uuid.New(), uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE products SET deleted_at").
+		ExpectQuery().
+		WithArgs(sqlmock.AnyArg(), existing, missing).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(existing, "Keyboard", "", 49.99, now, now, int64(2), now, "USD", nil))
+	mock.ExpectRollback()
+
+	deleted, err := productService.DeleteProducts(ctx, []uuid.UUID{existing, missing}, true)
+	var notFoundErr *service.BatchNotFoundError
+	require.ErrorAs(t, err, &notFoundErr)
+	assert.Equal(t, []uuid.UUID{missing}, notFoundErr.IDs)
+	assert.Nil(t, deleted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDeleteProducts_BestEffort verifies that a best-effort batch delete skips missing products
+// and stores an event for each deleted one.
+func TestDeleteProducts_BestEffort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	productService := service.NewProductService(db, reposql.NewProductRepository(db), reposql.NewEventRepository(db), nil, time.Hour)
+
+	existing, missing := uuid.New(), uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE products SET deleted_at").
+		ExpectQuery().
+		WithArgs(sqlmock.AnyArg(), existing, missing).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(existing, "Keyboard", "", 49.99, now, now, int64(2), now, "USD", nil))
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs(existing.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO events").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "product.deleted", sqlmock.AnyArg(), string(model.EventStatusPending), sqlmock.AnyArg(), nil, existing).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	var before snapshotCapture
+	mock.ExpectPrepare("INSERT INTO audit_log").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "anonymous", "delete", "product", existing, &before, nil, "", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deleted, err := productService.DeleteProducts(ctx, []uuid.UUID{existing, missing}, false)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, existing, deleted[0].ID)
+
+	// Verify the audit log holds the product as it was before the deletion
+	assert.Nil(t, before.snapshot["deleted_at"])
+	assert.InDelta(t, 1, before.snapshot["version"], 0)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// testPrice is the price of the product used throughout the outbox tests.
+var testPrice = model.Money{Amount: 9999, Currency: "USD"}
+
+var idempotencyKeyColumns = []string{"actor", "idempotency_key", "request_hash", "resource_id", "response_body", "created_at"}
+
 // TestEventData_SerializationFormat verifies that event data is properly serialized as ProductMessage.
 func TestEventData_SerializationFormat(t *testing.T) {
 	msg := sqs.ProductMessage{
 		Action:    "created",
 		ProductID: uuid.New().String(),
 		Name:      "Test Product",
-		Price:     99.99,
+		Price:     model.Money{Amount: 9999, Currency: "USD"},
 	}
 
 	eventData, err := json.Marshal(msg)
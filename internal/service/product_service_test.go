@@ -0,0 +1,127 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetProduct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	productRepo := reposql.NewProductRepository(db)
+	eventRepo := reposql.NewEventRepository(db)
+	productService := service.NewProductService(db, productRepo, eventRepo, nil, time.Hour)
+
+	t.Run("product found", func(t *testing.T) {
+		// given
+		productID := uuid.New()
+		now := time.Now()
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}).
+			AddRow(productID, "Test Product", "Test Description", 99.99, now, now, int64(1), nil, "USD", nil)
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE id").
+			ExpectQuery().
+			WithArgs(productID).
+			WillReturnRows(rows)
+		mock.ExpectPrepare("SELECT product_id, tag FROM product_tags WHERE product_id IN \\(\\$1\\)").
+			ExpectQuery().
+			WithArgs(productID).
+			WillReturnRows(sqlmock.NewRows([]string{"product_id", "tag"}).AddRow(productID, "sale").AddRow(productID, "new"))
+
+		// when
+		product, err := productService.GetProduct(ctx, productID)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, productID, product.ID)
+		assert.Equal(t, "Test Product", product.Name)
+		assert.Equal(t, []string{"sale", "new"}, product.Tags)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("product not found", func(t *testing.T) {
+		// given
+		productID := uuid.New()
+		mock.ExpectPrepare("SELECT \\* FROM products WHERE id").
+			ExpectQuery().
+			WithArgs(productID).
+			WillReturnError(sql.ErrNoRows)
+
+		// when
+		product, err := productService.GetProduct(ctx, productID)
+
+		// then
+		require.Error(t, err)
+		assert.Nil(t, product)
+		var notFoundErr *repository.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestExportProducts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	productRepo := reposql.NewProductRepository(db)
+	eventRepo := reposql.NewEventRepository(db)
+	productService := service.NewProductService(db, productRepo, eventRepo, nil, time.Hour)
+
+	// given a first page that is full and a second page with a single product
+	columns := []string{"id", "name", "description", "price", "created_at", "updated_at", "version", "deleted_at", "currency", "category_id"}
+	start := time.Now().Add(-time.Hour)
+	firstPage := sqlmock.NewRows(columns)
+	var lastID uuid.UUID
+	var lastCreatedAt time.Time
+	for i := 0; i <= service.ExportPageSize; i++ {
+		id, createdAt := uuid.New(), start.Add(time.Duration(i)*time.Second)
+		firstPage.AddRow(id, "Product", "", 1.0, createdAt, createdAt, int64(1), nil, "USD", nil)
+		if i == service.ExportPageSize-1 {
+			lastID, lastCreatedAt = id, createdAt
+		}
+	}
+	mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL ORDER BY created_at ASC, id ASC LIMIT \\$1").
+		ExpectQuery().
+		WithArgs(service.ExportPageSize + 1).
+		WillReturnRows(firstPage)
+	mock.ExpectPrepare("SELECT product_id, tag FROM product_tags").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "tag"}))
+	mock.ExpectPrepare("SELECT \\* FROM products WHERE 1=1 AND deleted_at IS NULL AND \\(created_at, id\\) > \\(\\$1, \\$2\\) ORDER BY created_at ASC, id ASC LIMIT \\$3").
+		ExpectQuery().
+		WithArgs(lastCreatedAt, lastID, service.ExportPageSize+1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(uuid.New(), "Last", "", 2.0, start, start, int64(1), nil, "USD", nil))
+	mock.ExpectPrepare("SELECT product_id, tag FROM product_tags").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "tag"}))
+
+	// when
+	var pageSizes []int
+	err = productService.ExportProducts(ctx, *repository.NewQuery(), func(products []*model.Product) error {
+		pageSizes = append(pageSizes, len(products))
+		return nil
+	})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []int{service.ExportPageSize, 1}, pageSizes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
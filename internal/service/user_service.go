@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/audit"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/sqs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidCredentials is returned when logging in with an unknown email, a wrong password or an inactive user.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrUserSuspended is returned when a suspended user logs in with the correct password.
+	ErrUserSuspended = errors.New("user is suspended")
+)
+
+// userResourceType is the resource type of audit log entries about users.
+const userResourceType = "user"
+
+// dummyPasswordHash is compared against when logging in with an unknown email,
+// so that the response time does not reveal which emails are registered.
+const dummyPasswordHash = "$2a$10$xV1W91qaBVRQpyyonnB2jeOdk2KoHmcIm0fZSFdYw3jAj5JpF3rCG"
+
+// UserService provides business logic for registering and authenticating users.
+type UserService struct {
+	db     *sql.DB
+	repo   *reposql.UserRepository
+	tokens *auth.TokenSigner
+}
+
+// NewUserService creates a new UserService with the given DB, user repository and access token signer.
+func NewUserService(db *sql.DB, repo *reposql.UserRepository, tokens *auth.TokenSigner) *UserService {
+	return &UserService{
+		db:     db,
+		repo:   repo,
+		tokens: tokens,
+	}
+}
+
+// RegisterInput describes a user to register.
+type RegisterInput struct {
+	Email    string
+	Password string
+	Name     string
+	Region   string
+}
+
+// Register creates an active user with a bcrypt hash of the password and stores a user.registered event
+// in the same transaction (outbox pattern). Emails are compared case-insensitively;
+// an email that is already registered is reported as a *repository.UniqueConstraintError.
+func (us *UserService) Register(ctx context.Context, input RegisterInput) (*model.User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &model.User{
+		Email:    normalizeEmail(input.Email),
+		Password: string(hash),
+		Name:     input.Name,
+		Region:   input.Region,
+		Status:   model.UserStatusActive,
+		Role:     model.UserRoleViewer,
+	}
+
+	// Start a transaction
+	tx, err := us.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	if _, err = reposql.NewUserRepositoryWithTx(us.db, tx).Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Create event in the same transaction (outbox pattern)
+	msg := sqs.UserMessage{
+		Type:   sqs.MessageTypeUser,
+		Action: "registered",
+		User: sqs.UserInfo{
+			ID:     user.ID.String(),
+			Email:  user.Email,
+			Name:   user.Name,
+			Region: user.Region,
+		},
+	}
+	eventData, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = reposql.NewEventRepositoryWithTx(us.db, tx).Create(ctx, &model.Event{
+		EventType:   "user.registered",
+		EventData:   eventData,
+		Status:      model.EventStatusPending,
+		AggregateID: user.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return user, nil
+}
+
+// LoginResult is the outcome of a successful login.
+type LoginResult struct {
+	User        *model.User
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+// Login checks the email and password of an active user and issues an access token for it.
+// Any mismatch is reported as ErrInvalidCredentials without telling which part was wrong;
+// a suspended user with the correct password gets ErrUserSuspended.
+func (us *UserService) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+	user, err := us.repo.FindByEmail(ctx, normalizeEmail(email))
+	var notFoundErr *repository.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		// Spend the same time as for a wrong password
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if user.Status == model.UserStatusSuspended {
+		return nil, ErrUserSuspended
+	}
+	if user.Status != model.UserStatusActive {
+		return nil, ErrInvalidCredentials
+	}
+
+	token, claims, err := us.tokens.Issue(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		User:        user,
+		AccessToken: token,
+		ExpiresAt:   time.Unix(claims.ExpiresAt, 0),
+	}, nil
+}
+
+// GetUser retrieves a user by ID.
+func (us *UserService) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	resource, err := us.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, ok := resource.(*model.User)
+	if !ok {
+		return nil, repository.ErrInvalidType
+	}
+	return user, nil
+}
+
+// UpdateUserInput describes changes of the role and status of a user; nil fields are left unchanged.
+type UpdateUserInput struct {
+	Role   *string
+	Status *string
+}
+
+// userSnapshot is the state of a user recorded before and after a change in the audit log.
+type userSnapshot struct {
+	ID     uuid.UUID `json:"id"`
+	Email  string    `json:"email"`
+	Role   string    `json:"role"`
+	Status string    `json:"status"`
+}
+
+// UpdateUser changes the role and status of a user and records the change in the audit log
+// in the same transaction.
+func (us *UserService) UpdateUser(ctx context.Context, id uuid.UUID, input UpdateUserInput) (*model.User, error) {
+	// Start a transaction
+	tx, err := us.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				slog.Error("failed to rollback transaction", slog.Any("err", rbErr))
+			}
+		}
+	}()
+
+	txRepo := reposql.NewUserRepositoryWithTx(us.db, tx)
+	resource, err := txRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, ok := resource.(*model.User)
+	if !ok {
+		err = repository.ErrInvalidType
+		return nil, err
+	}
+	before := userSnapshot{ID: user.ID, Email: user.Email, Role: user.Role, Status: user.Status}
+
+	if input.Role != nil {
+		user.Role = *input.Role
+	}
+	if input.Status != nil {
+		user.Status = *input.Status
+	}
+	if err = txRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	after := userSnapshot{ID: user.ID, Email: user.Email, Role: user.Role, Status: user.Status}
+	encodedBefore, err := json.Marshal(before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	encodedAfter, err := json.Marshal(after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	entry := audit.NewEntry(ctx, audit.ActionUpdate, userResourceType, user.ID, encodedBefore, encodedAfter)
+	if err = reposql.NewAuditLogRepositoryWithTx(us.db, tx).Create(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	// Commit the transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return user, nil
+}
+
+// normalizeEmail trims and lowercases an email address so that the same address is always stored and found alike.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
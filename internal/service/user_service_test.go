@@ -0,0 +1,250 @@
+package service_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iyhunko/microservices-with-sqs/internal/auth"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
+	"github.com/iyhunko/microservices-with-sqs/internal/repository"
+	reposql "github.com/iyhunko/microservices-with-sqs/internal/repository/sql"
+	"github.com/iyhunko/microservices-with-sqs/internal/service"
+	"github.com/iyhunko/microservices-with-sqs/internal/sqs"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+var userColumns = []string{"id", "email", "password", "name", "region", "status", "role", "created_at", "updated_at"}
+
+// passwordHashOf is a sqlmock argument matcher that accepts a bcrypt hash of the password.
+type passwordHashOf string
+
+// Match implements sqlmock.Argument.
+func (p passwordHashOf) Match(v driver.Value) bool {
+	hash, ok := v.(string)
+	return ok && bcrypt.CompareHashAndPassword([]byte(hash), []byte(p)) == nil
+}
+
+// userEventDataCapture is a sqlmock argument matcher that decodes the payload of a user event for later assertions.
+type userEventDataCapture struct {
+	msg sqs.UserMessage
+}
+
+// Match implements sqlmock.Argument.
+func (e *userEventDataCapture) Match(v driver.Value) bool {
+	data, ok := v.([]byte)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, &e.msg) == nil
+}
+
+func TestRegister(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	userService := service.NewUserService(db, reposql.NewUserRepository(db), auth.NewTokenSigner("secret", time.Hour))
+	input := service.RegisterInput{Email: " Alice@Example.com ", Password: "correct horse", Name: "Alice", Region: "EU"}
+
+	t.Run("stores a hashed password and a user.registered event", func(t *testing.T) {
+		// given
+		mock.ExpectBegin()
+		mock.ExpectPrepare("INSERT INTO users").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "alice@example.com", passwordHashOf("correct horse"), "Alice", "EU",
+				model.UserStatusActive, model.UserRoleViewer, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		var eventData userEventDataCapture
+		mock.ExpectExec("SELECT pg_advisory_xact_lock").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare("INSERT INTO events").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "user.registered", &eventData, string(model.EventStatusPending), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// when
+		user, err := userService.Register(ctx, input)
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", user.Email)
+		assert.NotEqual(t, "correct horse", user.Password)
+		assert.Equal(t, sqs.MessageTypeUser, eventData.msg.Type)
+		assert.Equal(t, "registered", eventData.msg.Action)
+		assert.Equal(t, user.ID.String(), eventData.msg.User.ID)
+		assert.Equal(t, "alice@example.com", eventData.msg.User.Email)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate email", func(t *testing.T) {
+		// given
+		mock.ExpectBegin()
+		mock.ExpectPrepare("INSERT INTO users").
+			ExpectExec().
+			WillReturnError(&pgconn.PgError{Code: "23505", Detail: "Key (email)=(alice@example.com) already exists."})
+		mock.ExpectRollback()
+
+		// when
+		user, err := userService.Register(ctx, input)
+
+		// then
+		var uniqueErr *repository.UniqueConstraintError
+		require.ErrorAs(t, err, &uniqueErr)
+		assert.Nil(t, user)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLogin(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	tokens := auth.NewTokenSigner("secret", time.Hour)
+	userService := service.NewUserService(db, reposql.NewUserRepository(db), tokens)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	id := uuid.New()
+	now := time.Now()
+	expectUser := func(status string) {
+		mock.ExpectPrepare("SELECT \\* FROM users WHERE email = \\$1").
+			ExpectQuery().
+			WithArgs("alice@example.com").
+			WillReturnRows(sqlmock.NewRows(userColumns).
+				AddRow(id, "alice@example.com", string(hash), "Alice", "EU", status, model.UserRoleViewer, now, now))
+	}
+
+	t.Run("issues an access token", func(t *testing.T) {
+		expectUser(model.UserStatusActive)
+
+		result, err := userService.Login(ctx, "ALICE@example.com", "correct horse")
+		require.NoError(t, err)
+		assert.Equal(t, id, result.User.ID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), result.ExpiresAt, time.Minute)
+
+		claims, err := tokens.Verify(result.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, id.String(), claims.Subject)
+		assert.Equal(t, model.UserRoleViewer, claims.Role)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		expectUser(model.UserStatusActive)
+
+		_, err := userService.Login(ctx, "alice@example.com", "wrong password")
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("inactive user", func(t *testing.T) {
+		expectUser("disabled")
+
+		_, err := userService.Login(ctx, "alice@example.com", "correct horse")
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("suspended user", func(t *testing.T) {
+		expectUser(model.UserStatusSuspended)
+
+		_, err := userService.Login(ctx, "alice@example.com", "correct horse")
+		assert.ErrorIs(t, err, service.ErrUserSuspended)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("suspended user with a wrong password", func(t *testing.T) {
+		expectUser(model.UserStatusSuspended)
+
+		_, err := userService.Login(ctx, "alice@example.com", "wrong password")
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		mock.ExpectPrepare("SELECT \\* FROM users WHERE email = \\$1").
+			ExpectQuery().
+			WithArgs("alice@example.com").
+			WillReturnRows(sqlmock.NewRows(userColumns))
+
+		_, err := userService.Login(ctx, "alice@example.com", "correct horse")
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	userService := service.NewUserService(db, reposql.NewUserRepository(db), auth.NewTokenSigner("secret", time.Hour))
+	id := uuid.New()
+	now := time.Now()
+
+	t.Run("changes the role and records it in the audit log", func(t *testing.T) {
+		// given
+		mock.ExpectBegin()
+		mock.ExpectPrepare("SELECT \\* FROM users WHERE id = \\$1").
+			ExpectQuery().
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(userColumns).
+				AddRow(id, "alice@example.com", "hash", "Alice", "EU", model.UserStatusActive, model.UserRoleViewer, now, now))
+		mock.ExpectPrepare("UPDATE users SET").
+			ExpectExec().
+			WithArgs("Alice", "EU", model.UserStatusActive, model.UserRoleEditor, sqlmock.AnyArg(), id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		var before, after snapshotCapture
+		mock.ExpectPrepare("INSERT INTO audit_log").
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "anonymous", "update", "user", id, &before, &after, "", "", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// when
+		role := model.UserRoleEditor
+		user, err := userService.UpdateUser(ctx, id, service.UpdateUserInput{Role: &role})
+
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, model.UserRoleEditor, user.Role)
+		assert.Equal(t, model.UserRoleViewer, before.snapshot["role"])
+		assert.Equal(t, model.UserRoleEditor, after.snapshot["role"])
+		assert.NotContains(t, after.snapshot, "password")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		// given
+		mock.ExpectBegin()
+		mock.ExpectPrepare("SELECT \\* FROM users WHERE id = \\$1").
+			ExpectQuery().
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(userColumns))
+		mock.ExpectRollback()
+
+		// when
+		status := model.UserStatusSuspended
+		user, err := userService.UpdateUser(ctx, id, service.UpdateUserInput{Status: &status})
+
+		// then
+		var notFoundErr *repository.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		assert.Nil(t, user)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
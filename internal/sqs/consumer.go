@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
@@ -78,18 +80,78 @@ func (c *Consumer) processMessage(_ context.Context, message types.Message) erro
 		return fmt.Errorf("message body is nil")
 	}
 
-	var productMsg ProductMessage
-	if err := json.Unmarshal([]byte(*message.Body), &productMsg); err != nil {
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(*message.Body), &envelope); err != nil {
 		return fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
+	switch envelope.Type {
+	case MessageTypeUser:
+		return processUserMessage([]byte(*message.Body))
+	case MessageTypeProduct, "":
+		// Messages published before the type was introduced are product messages
+		return processProductMessage([]byte(*message.Body))
+	default:
+		return fmt.Errorf("unknown message type %q", envelope.Type)
+	}
+}
+
+func processUserMessage(body []byte) error {
+	var userMsg UserMessage
+	if err := json.Unmarshal(body, &userMsg); err != nil {
+		return fmt.Errorf("failed to unmarshal user message: %w", err)
+	}
+
+	slog.Info("Received user notification",
+		slog.String("action", userMsg.Action),
+		slog.Group("user", slog.String("id", userMsg.User.ID), slog.String("email", userMsg.User.Email),
+			slog.String("region", userMsg.User.Region)))
+
+	return nil
+}
+
+func processProductMessage(body []byte) error {
+	var productMsg ProductMessage
+	if err := json.Unmarshal(body, &productMsg); err != nil {
+		return fmt.Errorf("failed to unmarshal product message: %w", err)
+	}
+
 	// Log the received message
-	slog.Info("Received product notification",
+	attrs := []any{
 		slog.String("action", productMsg.Action),
 		slog.String("product_id", productMsg.ProductID),
 		slog.String("name", productMsg.Name),
-		slog.Float64("price", productMsg.Price),
-	)
+		slog.String("price", productMsg.Price.String()),
+	}
+	if productMsg.Category != nil {
+		attrs = append(attrs, slog.Group("category",
+			slog.String("id", productMsg.Category.ID), slog.String("name", productMsg.Category.Name)))
+	}
+	if productMsg.Inventory != nil {
+		attrs = append(attrs, slog.Group("inventory",
+			slog.Int64("change", productMsg.Inventory.Change),
+			slog.Int64("on_hand", productMsg.Inventory.OnHand),
+			slog.Int64("reserved", productMsg.Inventory.Reserved),
+			slog.Int64("available", productMsg.Inventory.Available)))
+	}
+	if change := productMsg.PriceChange; change != nil {
+		priceAttrs := []any{
+			slog.String("old", change.OldPrice.String()),
+			slog.String("new", change.NewPrice.String()),
+		}
+		if change.PercentChange != nil {
+			priceAttrs = append(priceAttrs,
+				slog.Float64("percent", *change.PercentChange), slog.Bool("drop", *change.PercentChange < 0))
+		}
+		attrs = append(attrs, slog.Group("price_change", priceAttrs...))
+	}
+	for _, field := range slices.Sorted(maps.Keys(productMsg.Changes)) {
+		change := productMsg.Changes[field]
+		attrs = append(attrs, slog.Group("changed_"+field, slog.Any("old", change.Old), slog.Any("new", change.New)))
+	}
+	slog.Info("Received product notification", attrs...)
 
 	return nil
 }
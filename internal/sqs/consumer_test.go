@@ -52,6 +52,44 @@ func TestConsumer_processMessage(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("user message processing", func(t *testing.T) {
+		// given
+		consumer := &Consumer{
+			queueURL: "https://sqs.us-east-1.amazonaws.com/123456789/test-queue",
+		}
+
+		messageBody := `{"type":"user","action":"registered","user":{"id":"456","email":"alice@example.com","name":"Alice","region":"EU"}}`
+		message := types.Message{
+			Body:          aws.String(messageBody),
+			ReceiptHandle: aws.String("test-receipt-handle"),
+		}
+
+		// when
+		err := consumer.processMessage(context.Background(), message)
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("unknown message type", func(t *testing.T) {
+		// given
+		consumer := &Consumer{
+			queueURL: "https://sqs.us-east-1.amazonaws.com/123456789/test-queue",
+		}
+
+		message := types.Message{
+			Body:          aws.String(`{"type":"order","action":"created"}`),
+			ReceiptHandle: aws.String("test-receipt-handle"),
+		}
+
+		// when
+		err := consumer.processMessage(context.Background(), message)
+
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown message type "order"`)
+	})
+
 	t.Run("nil message body", func(t *testing.T) {
 		// given
 		consumer := &Consumer{
@@ -3,11 +3,14 @@ package sqs
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
 )
 
 // PublisherAPI defines the interface for SQS operations used by Publisher.
@@ -19,36 +22,125 @@ type PublisherAPI interface {
 type Publisher struct {
 	client   PublisherAPI
 	queueURL string
+	fifo     bool
 }
 
-// NewPublisher creates a new SQS Publisher with the given client and queue URL.
-func NewPublisher(client PublisherAPI, queueURL string) *Publisher {
+// NewPublisher creates a new SQS Publisher with the given client and queue URL. The queue is treated as
+// a FIFO queue if fifo is set or its URL ends in ".fifo", as the names of FIFO queues must.
+func NewPublisher(client PublisherAPI, queueURL string, fifo bool) *Publisher {
 	return &Publisher{
 		client:   client,
 		queueURL: queueURL,
+		fifo:     fifo || strings.HasSuffix(queueURL, ".fifo"),
 	}
 }
 
+const (
+	// MessageTypeProduct is the type of ProductMessage. Messages without a type are product messages as well.
+	MessageTypeProduct = "product"
+	// MessageTypeUser is the type of UserMessage.
+	MessageTypeUser = "user"
+)
+
 // ProductMessage represents a message about a product event.
 type ProductMessage struct {
-	Action    string  `json:"action"`
-	ProductID string  `json:"product_id"`
-	Name      string  `json:"name"`
-	Price     float64 `json:"price"`
+	// Type is MessageTypeProduct; it tells consumers the kind of message before they decode the rest.
+	Type      string                 `json:"type"`
+	Action    string                 `json:"action"`
+	ProductID string                 `json:"product_id"`
+	Name      string                 `json:"name"`
+	Price     model.Money            `json:"price"`
+	Category  *ProductCategory       `json:"category,omitempty"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	Inventory *InventoryLevel        `json:"inventory,omitempty"`
+	// PriceChange is set on product.price_changed messages.
+	PriceChange *PriceChange `json:"price_change,omitempty"`
+}
+
+// UserMessage represents a message about a user event, e.g. user.registered.
+type UserMessage struct {
+	// Type is MessageTypeUser.
+	Type   string   `json:"type"`
+	Action string   `json:"action"`
+	User   UserInfo `json:"user"`
+}
+
+// UserInfo describes a user in a UserMessage. It never carries the password.
+type UserInfo struct {
+	ID     string `json:"id"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Region string `json:"region"`
 }
 
-// PublishProductMessage publishes a product message to the SQS queue.
-func (p *Publisher) PublishProductMessage(ctx context.Context, msg ProductMessage) error {
+// PriceChange describes a change of the price of a product in a ProductMessage.
+// PercentChange is left out when the old and new price are in different currencies or the old price is zero.
+type PriceChange struct {
+	OldPrice      model.Money `json:"old_price"`
+	NewPrice      model.Money `json:"new_price"`
+	PercentChange *float64    `json:"percent_change,omitempty"`
+}
+
+// InventoryLevel describes the stock level of a product after a stock change in a ProductMessage.
+// Change is the on-hand delta of an adjustment or the quantity that was reserved or released.
+type InventoryLevel struct {
+	Change    int64 `json:"change"`
+	OnHand    int64 `json:"on_hand"`
+	Reserved  int64 `json:"reserved"`
+	Available int64 `json:"available"`
+}
+
+// ProductCategory identifies the category of a product in a ProductMessage.
+type ProductCategory struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// FieldChange holds the previous and the new value of a changed product field.
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// PublishProductMessage publishes a product message to the SQS queue. On a FIFO queue, the messages of the same
+// groupID, e.g. of one product, are delivered in the order they are published, and messages with the same
+// deduplicationID, e.g. an outbox event published again after a crash, are delivered once within the five
+// minute deduplication interval of SQS. Both are ignored on standard queues.
+func (p *Publisher) PublishProductMessage(ctx context.Context, msg ProductMessage, groupID, deduplicationID string) error {
+	msg.Type = MessageTypeProduct
+	return p.publish(ctx, msg, groupID, deduplicationID,
+		slog.String("action", msg.Action), slog.String("product_id", msg.ProductID))
+}
+
+// PublishUserMessage publishes a user message to the SQS queue. The group and deduplication IDs are used
+// as by PublishProductMessage.
+func (p *Publisher) PublishUserMessage(ctx context.Context, msg UserMessage, groupID, deduplicationID string) error {
+	msg.Type = MessageTypeUser
+	return p.publish(ctx, msg, groupID, deduplicationID,
+		slog.String("action", msg.Action), slog.String("user_id", msg.User.ID))
+}
+
+// publish sends msg encoded as JSON. logAttrs identify the message in the log when it cannot be encoded.
+func (p *Publisher) publish(ctx context.Context, msg any, groupID, deduplicationID string, logAttrs ...any) error {
 	messageBody, err := json.Marshal(msg)
 	if err != nil {
-		slog.Error("Failed to marshal message", slog.Any("err", err), slog.String("action", msg.Action), slog.String("product_id", msg.ProductID))
+		slog.Error("Failed to marshal message", append([]any{slog.Any("err", err)}, logAttrs...)...)
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	_, err = p.client.SendMessage(ctx, &sqs.SendMessageInput{
+	input := &sqs.SendMessageInput{
 		QueueUrl:    aws.String(p.queueURL),
 		MessageBody: aws.String(string(messageBody)),
-	})
+	}
+	if p.fifo {
+		if groupID == "" || deduplicationID == "" {
+			return errors.New("FIFO queue messages require a message group ID and a deduplication ID")
+		}
+		input.MessageGroupId = aws.String(groupID)
+		input.MessageDeduplicationId = aws.String(deduplicationID)
+	}
+
+	_, err = p.client.SendMessage(ctx, input)
 	if err != nil {
 		slog.Error("Failed to send message to SQS", slog.Any("err", err), slog.String("queue_url", p.queueURL))
 		return fmt.Errorf("failed to send message to SQS: %w", err)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/iyhunko/microservices-with-sqs/internal/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -33,6 +34,8 @@ func TestPublisher_PublishProductMessage(t *testing.T) {
 			sendMessageFunc: func(_ context.Context, params *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
 				assert.Equal(t, queueURL, *params.QueueUrl)
 				assert.NotNil(t, params.MessageBody)
+				assert.Nil(t, params.MessageGroupId, "standard queues take no message group")
+				assert.Nil(t, params.MessageDeduplicationId, "standard queues take no deduplication ID")
 				return &sqs.SendMessageOutput{
 					MessageId: aws.String("test-message-id"),
 				}, nil
@@ -48,11 +51,11 @@ func TestPublisher_PublishProductMessage(t *testing.T) {
 			Action:    "created",
 			ProductID: "123",
 			Name:      "Test Product",
-			Price:     99.99,
+			Price:     model.Money{Amount: 9999, Currency: "USD"},
 		}
 
 		// when
-		err := publisher.PublishProductMessage(ctx, msg)
+		err := publisher.PublishProductMessage(ctx, msg, "123", "event-1")
 
 		// then
 		require.NoError(t, err)
@@ -79,16 +82,84 @@ func TestPublisher_PublishProductMessage(t *testing.T) {
 			Action:    "created",
 			ProductID: "123",
 			Name:      "Test Product",
-			Price:     99.99,
+			Price:     model.Money{Amount: 9999, Currency: "USD"},
 		}
 
 		// when
-		err := publisher.PublishProductMessage(ctx, msg)
+		err := publisher.PublishProductMessage(ctx, msg, "123", "event-1")
 
 		// then
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to send message to SQS")
 	})
+
+	t.Run("FIFO queue messages carry a group and a deduplication ID", func(t *testing.T) {
+		// given
+		queueURL := "https://sqs.us-east-1.amazonaws.com/123456789/test-queue.fifo"
+		ctx := context.Background()
+
+		var sent *sqs.SendMessageInput
+		mockClient := &mockSQSClient{
+			sendMessageFunc: func(_ context.Context, params *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+				sent = params
+				return &sqs.SendMessageOutput{}, nil
+			},
+		}
+		publisher := NewPublisher(mockClient, queueURL, false)
+
+		// when
+		err := publisher.PublishProductMessage(ctx, ProductMessage{Action: "deleted", ProductID: "123"}, "123", "event-1")
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, sent)
+		assert.Equal(t, "123", aws.ToString(sent.MessageGroupId))
+		assert.Equal(t, "event-1", aws.ToString(sent.MessageDeduplicationId))
+	})
+
+	t.Run("FIFO queue messages without a group are rejected", func(t *testing.T) {
+		// given
+		mockClient := &mockSQSClient{
+			sendMessageFunc: func(_ context.Context, _ *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+				t.Fatal("message should not be sent")
+				return nil, nil
+			},
+		}
+		publisher := NewPublisher(mockClient, "https://sqs.us-east-1.amazonaws.com/123456789/test-queue.fifo", false)
+
+		// when
+		err := publisher.PublishProductMessage(context.Background(), ProductMessage{Action: "created"}, "", "event-1")
+
+		// then
+		require.Error(t, err)
+	})
+}
+
+func TestPublisher_PublishUserMessage(t *testing.T) {
+	// given
+	queueURL := "https://sqs.us-east-1.amazonaws.com/123456789/test-queue.fifo"
+	ctx := context.Background()
+
+	var sent *sqs.SendMessageInput
+	mockClient := &mockSQSClient{
+		sendMessageFunc: func(_ context.Context, params *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+			sent = params
+			return &sqs.SendMessageOutput{}, nil
+		},
+	}
+	publisher := NewPublisher(mockClient, queueURL, false)
+	msg := UserMessage{Action: "registered", User: UserInfo{ID: "user-1", Email: "alice@example.com", Name: "Alice", Region: "EU"}}
+
+	// when
+	err := publisher.PublishUserMessage(ctx, msg, "user-1", "event-1")
+
+	// then
+	require.NoError(t, err)
+	require.NotNil(t, sent)
+	assert.JSONEq(t, `{"type":"user","action":"registered","user":{"id":"user-1","email":"alice@example.com","name":"Alice","region":"EU"}}`,
+		aws.ToString(sent.MessageBody))
+	assert.Equal(t, "user-1", aws.ToString(sent.MessageGroupId))
+	assert.Equal(t, "event-1", aws.ToString(sent.MessageDeduplicationId))
 }
 
 func TestNewPublisher(t *testing.T) {
@@ -98,10 +169,18 @@ func TestNewPublisher(t *testing.T) {
 		queueURL := "https://sqs.us-east-1.amazonaws.com/123456789/test-queue"
 
 		// when
-		publisher := NewPublisher(mockClient, queueURL)
+		publisher := NewPublisher(mockClient, queueURL, false)
 
 		// then
 		require.NotNil(t, publisher)
 		assert.Equal(t, queueURL, publisher.queueURL)
+		assert.False(t, publisher.fifo)
+	})
+
+	t.Run("detects FIFO queues from the URL or the configuration", func(t *testing.T) {
+		mockClient := &mockSQSClient{}
+
+		assert.True(t, NewPublisher(mockClient, "https://sqs.us-east-1.amazonaws.com/123456789/test-queue.fifo", false).fifo)
+		assert.True(t, NewPublisher(mockClient, "http://localhost:4566/000000000000/test-queue", true).fifo)
 	})
 }
@@ -2,3 +2,7 @@
 
 awslocal sqs create-queue --queue-name test-queue
 awslocal sqs create-queue --queue-name product-notifications
+
+# FIFO queues keep the events of a product in order and deduplicate re-published outbox events
+awslocal sqs create-queue --queue-name test-queue.fifo --attributes FifoQueue=true
+awslocal sqs create-queue --queue-name product-notifications.fifo --attributes FifoQueue=true
@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Case-insensitive prefix and substring search on name (ILIKE)
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+
+-- Price range filters
+CREATE INDEX IF NOT EXISTS idx_products_price ON products(price);
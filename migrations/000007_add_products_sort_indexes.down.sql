@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_products_price ON products(price);
+
+DROP INDEX IF EXISTS idx_products_name_id;
+DROP INDEX IF EXISTS idx_products_price_id;
@@ -0,0 +1,6 @@
+-- Keyset pagination for the price and name sort orders
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products(price, id);
+CREATE INDEX IF NOT EXISTS idx_products_name_id ON products(name, id);
+
+-- Superseded by idx_products_price_id
+DROP INDEX IF EXISTS idx_products_price;
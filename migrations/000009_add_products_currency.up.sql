@@ -0,0 +1,3 @@
+-- Widen the price to fit currencies with three minor unit digits and store the currency next to it
+ALTER TABLE products ALTER COLUMN price TYPE NUMERIC(15, 3);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_product_tags_tag;
+DROP TABLE IF EXISTS product_tags;
+DROP INDEX IF EXISTS idx_products_category_id;
+ALTER TABLE products DROP COLUMN IF EXISTS category_id;
+DROP INDEX IF EXISTS idx_categories_created_at;
+DROP INDEX IF EXISTS idx_categories_parent_id;
+DROP TABLE IF EXISTS categories;
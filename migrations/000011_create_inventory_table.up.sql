@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS inventory (
+    product_id UUID PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    quantity_on_hand BIGINT NOT NULL DEFAULT 0,
+    quantity_reserved BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Reservations can never exceed the stock on hand, so concurrent writers cannot oversell
+    CONSTRAINT inventory_quantities_check CHECK (quantity_reserved >= 0 AND quantity_reserved <= quantity_on_hand)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_product_price_history_product_id_created_at;
+DROP TABLE IF EXISTS product_price_history;
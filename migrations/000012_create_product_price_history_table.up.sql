@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS product_price_history (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    old_price NUMERIC(15, 3) NOT NULL,
+    old_currency CHAR(3) NOT NULL,
+    new_price NUMERIC(15, 3) NOT NULL,
+    new_currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Keyset pagination over the price changes of one product
+CREATE INDEX IF NOT EXISTS idx_product_price_history_product_id_created_at
+    ON product_price_history(product_id, created_at DESC, id DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_pending;
+ALTER TABLE events DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE events DROP COLUMN IF EXISTS locked_by;
@@ -0,0 +1,5 @@
+ALTER TABLE events ADD COLUMN IF NOT EXISTS locked_by VARCHAR(255);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+-- Oldest first claiming of pending events by the event workers
+CREATE INDEX IF NOT EXISTS idx_events_pending ON events(created_at, id) WHERE status = 'pending';
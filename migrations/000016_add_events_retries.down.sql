@@ -0,0 +1,5 @@
+UPDATE events SET status = 'failed' WHERE status = 'dead';
+
+ALTER TABLE events DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE events DROP COLUMN IF EXISTS last_error;
+ALTER TABLE events DROP COLUMN IF EXISTS attempts;
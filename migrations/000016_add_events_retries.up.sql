@@ -0,0 +1,7 @@
+ALTER TABLE events ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP;
+
+-- Events that failed once used to stay failed for good: retry them
+UPDATE events SET status = 'pending', attempts = 1, last_error = 'failed before retries were introduced', processed_at = NULL
+WHERE status = 'failed';
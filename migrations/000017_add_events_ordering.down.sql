@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_events_pending_aggregate;
+DROP INDEX IF EXISTS idx_events_pending;
+CREATE INDEX IF NOT EXISTS idx_events_pending ON events(created_at, id) WHERE status = 'pending';
+
+DROP INDEX IF EXISTS idx_events_sequence;
+ALTER TABLE events DROP COLUMN IF EXISTS aggregate_id;
+ALTER TABLE events DROP COLUMN IF EXISTS sequence;
+DROP SEQUENCE IF EXISTS events_sequence_seq;
@@ -0,0 +1,20 @@
+ALTER TABLE events ADD COLUMN IF NOT EXISTS sequence BIGINT;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS aggregate_id UUID;
+
+-- Number the existing events in creation order and attribute them to the product or user they are about
+UPDATE events SET sequence = numbered.sequence,
+                  aggregate_id = COALESCE(NULLIF(events.event_data->>'product_id', ''), events.event_data->'user'->>'id', events.id::text)::uuid
+FROM (SELECT id, row_number() OVER (ORDER BY created_at, id) AS sequence FROM events) numbered
+WHERE events.id = numbered.id;
+
+CREATE SEQUENCE IF NOT EXISTS events_sequence_seq OWNED BY events.sequence;
+SELECT setval('events_sequence_seq', COALESCE((SELECT MAX(sequence) FROM events), 0) + 1, false);
+ALTER TABLE events ALTER COLUMN sequence SET DEFAULT nextval('events_sequence_seq');
+ALTER TABLE events ALTER COLUMN sequence SET NOT NULL;
+ALTER TABLE events ALTER COLUMN aggregate_id SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_sequence ON events(sequence);
+
+-- Claiming of pending events in sequence order, and lookup of the earlier pending events of an aggregate
+DROP INDEX IF EXISTS idx_events_pending;
+CREATE INDEX IF NOT EXISTS idx_events_pending ON events(sequence) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_events_pending_aggregate ON events(aggregate_id, sequence) WHERE status = 'pending';
@@ -0,0 +1,9 @@
+-- Keep only the newest outcome of keys that were used by more than one caller
+DELETE FROM idempotency_keys older
+USING idempotency_keys newer
+WHERE older.idempotency_key = newer.idempotency_key
+  AND (older.created_at, older.actor) < (newer.created_at, newer.actor);
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (idempotency_key);
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS actor;
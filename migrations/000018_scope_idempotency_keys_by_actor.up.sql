@@ -0,0 +1,6 @@
+-- Keys are chosen by clients, so the same key sent by two callers must not share one stored outcome.
+-- Keys stored before this migration have no known owner and are left unreachable until they expire.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS actor VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys ALTER COLUMN actor DROP DEFAULT;
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (actor, idempotency_key);
@@ -43,6 +43,13 @@ NEXT_TOKEN=$(echo "$LIST_RESPONSE" | jq -r .next_page_token)
 echo "Next page token: $NEXT_TOKEN"
 echo
 
+# Test 4.1: Update a product
+echo "4.1. Updating product $PRODUCT_ID..."
+curl -s -X PATCH "$BASE_URL/products/$PRODUCT_ID" \
+  -H "Content-Type: application/json" \
+  -d '{"price": 1199.99}' | jq .
+echo
+
 # Test 5: Delete a product
 echo "5. Deleting product $PRODUCT_ID..."
 curl -s -X DELETE "$BASE_URL/products/$PRODUCT_ID" | jq .
@@ -53,9 +60,14 @@ echo "6. Listing products again..."
 curl -s "$BASE_URL/products?limit=10" | jq .
 echo
 
+# Test 6.1: Restore the deleted product
+echo "6.1. Restoring product $PRODUCT_ID..."
+curl -s -X POST "$BASE_URL/products/$PRODUCT_ID/restore" | jq .
+echo
+
 # Test 7: Check metrics
 echo "7. Checking Prometheus metrics..."
-curl -s "$METRICS_URL/metrics" | grep -E "products_(created|deleted)_total"
+curl -s "$METRICS_URL/metrics" | grep -E "products_(created|updated|deleted|restored)_total"
 echo
 
 echo "=== All tests completed successfully ==="